@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -10,8 +11,10 @@ import (
 	"syscall"
 	"time"
 
+	"sejastip.id/api"
 	"sejastip.id/api/infra"
 
+	"sejastip.id/api/search"
 	"sejastip.id/api/storage"
 
 	"sejastip.id/api/delivery"
@@ -40,6 +43,9 @@ type Config struct {
 	}
 
 	PubsubProject string `env:"PUBSUB_PROJECT,required"`
+	// InvalidDeviceSubscription receives the device tokens rejected by the push
+	// service, so they are pruned. Empty disables pruning
+	InvalidDeviceSubscription string `env:"PUBSUB_INVALID_DEVICE_SUBSCRIPTION"`
 
 	GCS struct {
 		Enabled  bool   `env:"GCS_ENABLED,default=false"`
@@ -47,8 +53,62 @@ type Config struct {
 	}
 
 	Port string `env:"PORT,required"`
+	// TrustedProxies is the number of proxies in front of the API appending to
+	// X-Forwarded-For. 0 uses the address of the connection
+	TrustedProxies int `env:"TRUSTED_PROXIES,default=0"`
+
+	OIDC struct {
+		// Providers are formatted as issuer|jwks_url|audience[;audience...]
+		Providers    []string      `env:"OIDC_PROVIDERS"`
+		JWKSCacheTTL time.Duration `env:"OIDC_JWKS_CACHE_TTL,default=1h"`
+	}
+
+	// Notifier is either pubsub (push through the notification function) or log
+	Notifier string `env:"NOTIFIER,default=pubsub"`
+
+	Outbox struct {
+		// RelayInterval is how often the outbox is relayed to pubsub, 0 disables the relay
+		RelayInterval time.Duration `env:"OUTBOX_RELAY_INTERVAL,default=5s"`
+		MaxAttempts   int           `env:"OUTBOX_MAX_ATTEMPTS,default=8"`
+		Backoff       time.Duration `env:"OUTBOX_BACKOFF,default=30s"`
+		MaxBackoff    time.Duration `env:"OUTBOX_MAX_BACKOFF,default=1h"`
+	}
+
+	// SearchBackend is either mysql (FULLTEXT) or memory (embedded index)
+	SearchBackend string `env:"SEARCH_BACKEND,default=mysql"`
+
+	// ProductClosingInterval is how often products past their offering window
+	// are closed, 0 disables the job
+	ProductClosingInterval time.Duration `env:"PRODUCT_CLOSING_INTERVAL,default=1h"`
+
+	Mail struct {
+		// Mailer is either smtp, file (drops .eml files in MAIL_DIR) or none to
+		// send no email
+		Mailer       string `env:"MAILER,default=file"`
+		From         string `env:"MAIL_FROM,default=Sejastip <no-reply@sejastip.id>"`
+		Dir          string `env:"MAIL_DIR,default=tmp/mails"`
+		SMTPHost     string `env:"SMTP_HOST"`
+		SMTPPort     int    `env:"SMTP_PORT,default=587"`
+		SMTPUsername string `env:"SMTP_USERNAME"`
+		SMTPPassword string `env:"SMTP_PASSWORD"`
+		// SMTPTimeout bounds the whole exchange with the SMTP server of an email
+		SMTPTimeout time.Duration `env:"SMTP_TIMEOUT,default=30s"`
+		// QueueSize is how many emails wait to be sent through SMTP in the background
+		QueueSize int `env:"MAIL_QUEUE_SIZE,default=1000"`
+	}
 
-	JWTPrivateKey string `env:"JWT_PRIVATE_KEY,required"`
+	OTP struct {
+		Sender          string `env:"OTP_SENDER,default=log"`
+		VerificationURL string `env:"VERIFICATION_URL,default=https://api.sejastip.id/verifications/email"`
+	}
+
+	JWT struct {
+		// PrivateKey is the legacy HS256 shared secret
+		PrivateKey       string `env:"JWT_PRIVATE_KEY"`
+		KeysDir          string `env:"JWT_KEYS_DIR"`
+		ActiveKeyID      string `env:"JWT_ACTIVE_KEY_ID"`
+		HS256AcceptUntil string `env:"JWT_HS256_ACCEPTED_UNTIL"`
+	}
 }
 
 var config Config
@@ -114,29 +174,133 @@ func main() {
 		log.Fatal("error connecting to mysql server: ", err)
 	}
 
+	var hs256AcceptedUntil time.Time
+	if config.JWT.HS256AcceptUntil != "" {
+		hs256AcceptedUntil, err = time.Parse(time.RFC3339, config.JWT.HS256AcceptUntil)
+		if err != nil {
+			log.Fatal("invalid JWT_HS256_ACCEPTED_UNTIL: ", err)
+		}
+	}
+	keys, err := infra.LoadKeySetDir(config.JWT.KeysDir, config.JWT.ActiveKeyID, config.JWT.PrivateKey, hs256AcceptedUntil)
+	if err != nil {
+		log.Fatal("error loading JWT signing keys: ", err)
+	}
+
+	oidcProviders, err := infra.ParseOIDCProviders(config.OIDC.Providers)
+	if err != nil {
+		log.Fatal("invalid OIDC_PROVIDERS: ", err)
+	}
+	oidcVerifier := infra.NewOIDCVerifier(oidcProviders, config.OIDC.JWKSCacheTTL)
+
 	userRepo := repository.NewMysqlUser(db)
 	bankRepo := repository.NewMysqlBank(db)
 	countryRepo := repository.NewMysqlCountry(db)
 	productRepo := repository.NewMysqlProduct(db)
+	productImageRepo := repository.NewMysqlProductImage(db)
+	categoryRepo := repository.NewMysqlCategory(db)
+	tripRepo := repository.NewMysqlTrip(db)
+	variantRepo := repository.NewMysqlProductVariant(db)
+	buyRequestRepo := repository.NewMysqlBuyRequest(db)
+	offerRepo := repository.NewMysqlBuyRequestOffer(db)
+	reviewRepo := repository.NewMysqlReview(db)
+	notificationRepo := repository.NewMysqlNotification(db)
+	notificationTemplateRepo := repository.NewMysqlNotificationTemplate(db)
+	conversationRepo := repository.NewMysqlConversation(db)
+	messageRepo := repository.NewMysqlMessage(db)
 	addressRepo := repository.NewMysqlUserAddress(db)
 	transactionRepo := repository.NewMysqlTransaction(db)
 	deviceRepo := repository.NewMysqlDevice(db)
 	shippingRepo := repository.NewMysqlShipping(db)
 	invoiceRepo := repository.NewMysqlInvoice(db)
+	verificationRepo := repository.NewMysqlVerification(db)
+	loginThrottleRepo := repository.NewMysqlLoginThrottle(db)
+	loginHistoryRepo := repository.NewMysqlLoginHistory(db)
+	identityRepo := repository.NewMysqlUserIdentity(db)
+	outboxRepo := repository.NewMysqlOutbox(db)
+	transactor := repository.NewMysqlTransactor(db)
+
+	searchIndex := repository.NewMysqlProductSearch(db)
+	if config.SearchBackend == "memory" {
+		searchIndex = search.NewMemoryIndex()
+	}
 
 	appStorage := storage.NewLocalStorage()
 	if config.GCS.Enabled {
 		appStorage = storage.NewGCS(config.GCS.BucketID)
 	}
 
-	uuc := usecase.NewUserUsecase(&usecase.UserProvider{UserRepository: userRepo})
+	var otpSender api.OTPSender = infra.NewLogOTPSender()
+	if config.OTP.Sender == "pubsub" {
+		otpSender = pubsub
+	}
+
+	oc := usecase.NewOutboxUsecase(&usecase.OutboxProvider{
+		OutboxRepo:  outboxRepo,
+		Publisher:   pubsub,
+		MaxAttempts: config.Outbox.MaxAttempts,
+		Backoff:     config.Outbox.Backoff,
+		MaxBackoff:  config.Outbox.MaxBackoff,
+	})
+	oh := delivery.NewOutboxHandler(oc)
+
+	// pushes are queued in the outbox along with the changes they announce, then
+	// relayed to pubsub in the background
+	var notifier api.Notifier = usecase.NewOutboxNotifier(oc)
+	if config.Notifier == "log" {
+		notifier = infra.NewLogNotifier()
+	}
+
+	// domain events go through the outbox as well, each to its own topic
+	events := usecase.NewOutboxEventPublisher(oc)
+
+	var mailer api.Mailer
+	switch config.Mail.Mailer {
+	case "smtp":
+		smtpMailer := infra.NewSMTPMailer(config.Mail.SMTPHost, config.Mail.SMTPPort, config.Mail.SMTPUsername, config.Mail.SMTPPassword, config.Mail.From, config.Mail.SMTPTimeout)
+		mailer = infra.NewQueuedMailer(smtpMailer, config.Mail.QueueSize, config.Mail.SMTPTimeout)
+	case "file":
+		mailer = infra.NewFileMailer(config.Mail.Dir, config.Mail.From)
+	}
+
+	notificationSender := &usecase.NotificationSender{
+		NotificationRepo: notificationRepo,
+		TemplateRepo:     notificationTemplateRepo,
+		UserRepo:         userRepo,
+		DeviceRepo:       deviceRepo,
+		Notifier:         notifier,
+		Mailer:           mailer,
+		Transactor:       transactor,
+	}
+
+	vuc := usecase.NewVerificationUsecase(&usecase.VerificationProvider{
+		UserRepo:         userRepo,
+		VerificationRepo: verificationRepo,
+		OTPSender:        otpSender,
+		VerificationURL:  config.OTP.VerificationURL,
+	})
+	vh := delivery.NewVerificationHandler(vuc)
+
+	uuc := usecase.NewUserUsecase(&usecase.UserProvider{
+		UserRepository: userRepo,
+		Verification:   vuc,
+		Notifications:  notificationSender,
+		Events:         events,
+		Transactor:     transactor,
+		Storage:        appStorage,
+	})
 	uh := delivery.NewUserHandler(uuc)
 
 	auc := usecase.NewAuthUsecase(&usecase.AuthProvider{
-		UserRepository: userRepo,
-		JWTPrivateKey:  config.JWTPrivateKey,
+		UserRepository:   userRepo,
+		ThrottleRepo:     loginThrottleRepo,
+		LoginHistoryRepo: loginHistoryRepo,
+		IdentityRepo:     identityRepo,
+		IDTokenVerifier:  oidcVerifier,
+		Events:           events,
+		Transactor:       transactor,
+		Keys:             keys,
 	})
-	ah := delivery.NewAuthHandler(auc)
+	ah := delivery.NewAuthHandler(auc, config.TrustedProxies)
 
 	buc := usecase.NewBankUsecase(&usecase.BankProvider{
 		BankRepo: bankRepo,
@@ -151,12 +315,63 @@ func main() {
 	ch := delivery.NewCountryHandler(cuc)
 
 	puc := usecase.NewProductUsecase(&usecase.ProductProvider{
+		ProductRepo:      productRepo,
+		ProductImageRepo: productImageRepo,
+		CategoryRepo:     categoryRepo,
+		TripRepo:         tripRepo,
+		VariantRepo:      variantRepo,
+		UserRepo:         userRepo,
+		CountryRepo:      countryRepo,
+		SearchIndex:      searchIndex,
+		Events:           events,
+		Transactor:       transactor,
+		Storage:          appStorage,
+	})
+	ph := delivery.NewProductHandler(puc)
+
+	catuc := usecase.NewCategoryUsecase(&usecase.CategoryProvider{
+		CategoryRepo: categoryRepo,
+		ProductRepo:  productRepo,
+	})
+	cath := delivery.NewCategoryHandler(catuc)
+
+	truc := usecase.NewTripUsecase(&usecase.TripProvider{
+		TripRepo:    tripRepo,
 		ProductRepo: productRepo,
 		UserRepo:    userRepo,
 		CountryRepo: countryRepo,
-		Storage:     appStorage,
+		Transactor:  transactor,
 	})
-	ph := delivery.NewProductHandler(puc)
+	trh := delivery.NewTripHandler(truc)
+
+	// `sejastip-api reindex` rebuilds the product search index, then exits
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		total, err := puc.RebuildSearchIndex(context.Background())
+		if err != nil {
+			log.Fatal("error rebuilding search index: ", err)
+		}
+		log.Printf("%d products indexed\n", total)
+		return
+	}
+
+	// `sejastip-api close-products` closes the products past their offering window, then exits
+	if len(os.Args) > 1 && os.Args[1] == "close-products" {
+		total, err := puc.CloseExpiredProducts(context.Background())
+		if err != nil {
+			log.Fatal("error closing expired products: ", err)
+		}
+		log.Printf("%d products closed\n", total)
+		return
+	}
+
+	// the embedded index lives in memory, so it starts empty
+	if config.SearchBackend == "memory" {
+		total, err := puc.RebuildSearchIndex(context.Background())
+		if err != nil {
+			log.Fatal("error building search index: ", err)
+		}
+		log.Printf("%d products indexed\n", total)
+	}
 
 	uauc := usecase.NewUserAddressUsecase(&usecase.UserAddressProvider{
 		UserAddressRepo: addressRepo,
@@ -168,17 +383,55 @@ func main() {
 		ShippingRepo:    shippingRepo,
 		UserRepo:        userRepo,
 		ProductRepo:     productRepo,
+		VariantRepo:     variantRepo,
 		AddressRepo:     addressRepo,
 		CountryRepo:     countryRepo,
-		DeviceRepo:      deviceRepo,
-		Pubsub:          pubsub,
+		Notifications:   notificationSender,
+		Events:          events,
+		Transactor:      transactor,
 	})
 	th := delivery.NewTransactionHandler(tc)
 
+	bruc := usecase.NewBuyRequestUsecase(&usecase.BuyRequestProvider{
+		BuyRequestRepo:     buyRequestRepo,
+		OfferRepo:          offerRepo,
+		UserRepo:           userRepo,
+		CountryRepo:        countryRepo,
+		ProductUsecase:     puc,
+		TransactionUsecase: tc,
+		Storage:            appStorage,
+		Transactor:         transactor,
+	})
+	brh := delivery.NewBuyRequestHandler(bruc)
+
+	rc := usecase.NewReviewUsecase(&usecase.ReviewProvider{
+		ReviewRepo:      reviewRepo,
+		TransactionRepo: transactionRepo,
+		UserRepo:        userRepo,
+		Transactor:      transactor,
+		Storage:         appStorage,
+	})
+	rh := delivery.NewReviewHandler(rc)
+
+	mc := usecase.NewMessageUsecase(&usecase.MessageProvider{
+		ConversationRepo: conversationRepo,
+		MessageRepo:      messageRepo,
+		TransactionRepo:  transactionRepo,
+		ProductRepo:      productRepo,
+		UserRepo:         userRepo,
+		Notifications:    notificationSender,
+		Storage:          appStorage,
+	})
+	mh := delivery.NewMessageHandler(mc)
+
 	ic := usecase.NewInvoiceUsecase(&usecase.InvoiceProvider{
 		InvoiceRepo:     invoiceRepo,
 		TransactionRepo: transactionRepo,
+		ProductRepo:     productRepo,
 		UserRepo:        userRepo,
+		Notifications:   notificationSender,
+		Events:          events,
+		Transactor:      transactor,
 		Storage:         appStorage,
 	})
 	ih := delivery.NewInvoiceHandler(ic)
@@ -188,7 +441,35 @@ func main() {
 	})
 	dh := delivery.NewDeviceHandler(dc)
 
-	h := handler.NewHandler(config.JWTPrivateKey, &uh, &ah, &bh, &ch, &ph, &uah, &th, &ih, &dh)
+	nc := usecase.NewNotificationUsecase(&usecase.NotificationProvider{
+		NotificationRepo: notificationRepo,
+		TemplateRepo:     notificationTemplateRepo,
+	})
+	nh := delivery.NewNotificationHandler(nc)
+
+	acc := usecase.NewAccountUsecase(&usecase.AccountProvider{
+		UserRepo:         userRepo,
+		AddressRepo:      addressRepo,
+		ProductRepo:      productRepo,
+		CountryRepo:      countryRepo,
+		TransactionRepo:  transactionRepo,
+		InvoiceRepo:      invoiceRepo,
+		LoginHistoryRepo: loginHistoryRepo,
+		IdentityRepo:     identityRepo,
+		TripRepo:         tripRepo,
+		BuyRequestRepo:   buyRequestRepo,
+		OfferRepo:        offerRepo,
+		ReviewRepo:       reviewRepo,
+		ConversationRepo: conversationRepo,
+		MessageRepo:      messageRepo,
+		NotificationRepo: notificationRepo,
+		DeviceRepo:       deviceRepo,
+		SearchIndex:      searchIndex,
+		Transactor:       transactor,
+	})
+	acch := delivery.NewAccountHandler(acc)
+
+	h := handler.NewHandler(keys.Keyfunc, userRepo, &uh, &ah, &bh, &ch, &ph, &cath, &trh, &uah, &th, &brh, &rh, &mh, &ih, &dh, &nh, &vh, &acch, &oh)
 
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%s", config.Port),
@@ -197,6 +478,18 @@ func main() {
 		WriteTimeout: 300 * time.Second,
 	}
 
+	if config.ProductClosingInterval > 0 {
+		go runProductClosing(puc, config.ProductClosingInterval)
+	}
+
+	if config.Outbox.RelayInterval > 0 {
+		go runOutboxRelay(oc, config.Outbox.RelayInterval)
+	}
+
+	if config.InvalidDeviceSubscription != "" {
+		go runDevicePruning(pubsub, config.InvalidDeviceSubscription, dc)
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func(s *http.Server) {
@@ -210,3 +503,81 @@ func main() {
 
 	log.Println("Sejastip API stopped.")
 }
+
+// runProductClosing periodically closes the products past their offering window.
+// Closing is idempotent, so every instance of the API may run it
+func runProductClosing(uc api.ProductUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		total, err := uc.CloseExpiredProducts(context.Background())
+		if err != nil {
+			log.Println("error closing expired products: ", err)
+			continue
+		}
+		if total > 0 {
+			log.Printf("%d products closed\n", total)
+		}
+	}
+}
+
+// runOutboxRelay publishes the outbox to pubsub. Every instance of the API may run
+// it, a message is only claimed by one of them at a time
+func runOutboxRelay(uc api.OutboxUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		total, err := uc.RelayMessages(context.Background())
+		if err != nil {
+			log.Println("error relaying outbox messages: ", err)
+		}
+		if total > 0 {
+			log.Printf("%d outbox messages published\n", total)
+		}
+	}
+}
+
+// the delay before the invalid devices are received again after the subscription
+// failed, doubled for every failure in a row up to devicePruningMaxBackoff
+const (
+	devicePruningBackoff    = 5 * time.Second
+	devicePruningMaxBackoff = 5 * time.Minute
+)
+
+// runDevicePruning unregisters the devices whose tokens are rejected by the push
+// service. Pub/Sub spreads the reports among the instances of the API. Receiving
+// stops on errors of the subscription, so it is started again after a backoff
+func runDevicePruning(pubsub *infra.PubsubClient, subscriptionID string, uc api.DeviceUsecase) {
+	backoff := devicePruningBackoff
+	for {
+		started := time.Now()
+		err := pubsub.ReceiveInvalidDevices(context.Background(), subscriptionID, func(ctx context.Context, deviceIDs []string) error {
+			total, err := uc.PruneDevices(ctx, deviceIDs)
+			if err != nil {
+				return err
+			}
+			if total > 0 {
+				log.Printf("%d invalid devices pruned\n", total)
+			}
+			return nil
+		})
+		// nil is only returned without a pubsub client
+		if err == nil {
+			return
+		}
+
+		// the backoff starts over once the subscription was healthy for a while
+		if time.Since(started) > devicePruningMaxBackoff {
+			backoff = devicePruningBackoff
+		}
+		log.Printf("error receiving invalid devices, retrying in %s: %v\n", backoff, err)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > devicePruningMaxBackoff {
+			backoff = devicePruningMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,38 @@
+class CreateUserVerifications < ActiveRecord::Migration[5.1]
+  def up
+    change_table :users do |t|
+      t.datetime :email_verified_at, null: true
+      t.datetime :phone_verified_at, null: true
+    end
+
+    # users registered before verification existed keep their access, their
+    # email and phone are trusted as they are
+    execute <<-SQL
+      UPDATE users SET email_verified_at = created_at, phone_verified_at = created_at
+    SQL
+
+    create_table :user_verifications do |t|
+      t.bigint   :user_id, null: false
+      t.string   :channel, limit: 10, null: false
+      t.string   :target, limit: 255, null: false
+      t.string   :code, limit: 64, null: false
+      t.integer  :attempts, unsigned: true, limit: 1, default: 0
+      t.datetime :expired_at, null: false
+      t.datetime :verified_at, null: true
+
+      t.timestamps
+
+      t.index [:user_id, :channel, :created_at]
+      t.index [:channel, :code]
+    end
+  end
+
+  def down
+    drop_table :user_verifications
+
+    change_table :users do |t|
+      t.remove :email_verified_at
+      t.remove :phone_verified_at
+    end
+  end
+end
@@ -0,0 +1,28 @@
+class CreateLoginThrottlesAndUserLogins < ActiveRecord::Migration[5.1]
+  def up
+    create_table :login_throttles do |t|
+      t.string   :throttle_key, limit: 255, null: false
+      t.integer  :failed_count, unsigned: true, default: 0
+      t.datetime :locked_until, null: true
+      t.datetime :last_failed_at, null: false
+
+      t.timestamps
+
+      t.index :throttle_key, unique: true
+    end
+
+    create_table :user_logins do |t|
+      t.bigint   :user_id, null: false
+      t.string   :ip_address, limit: 45, default: ""
+      t.string   :user_agent, limit: 255, default: ""
+      t.datetime :created_at, null: false
+
+      t.index [:user_id, :created_at]
+    end
+  end
+
+  def down
+    drop_table :login_throttles
+    drop_table :user_logins
+  end
+end
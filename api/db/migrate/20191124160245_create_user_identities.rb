@@ -0,0 +1,19 @@
+class CreateUserIdentities < ActiveRecord::Migration[5.1]
+  def up
+    create_table :user_identities do |t|
+      t.bigint :user_id, null: false
+      t.string :issuer, limit: 255, null: false
+      t.string :subject, limit: 255, null: false
+      t.string :email, limit: 255, default: ""
+
+      t.timestamps
+
+      t.index [:issuer, :subject], unique: true
+      t.index :user_id
+    end
+  end
+
+  def down
+    drop_table :user_identities
+  end
+end
@@ -0,0 +1,9 @@
+class AddDeletedAtToUsers < ActiveRecord::Migration[5.1]
+  def up
+    add_column :users, :deleted_at, :datetime
+  end
+
+  def down
+    remove_column :users, :deleted_at
+  end
+end
@@ -0,0 +1,11 @@
+class AddFulltextIndexesToProducts < ActiveRecord::Migration[5.1]
+  def up
+    add_index :products, :title, type: :fulltext, name: "fulltext_products_on_title"
+    add_index :products, [:title, :description], type: :fulltext, name: "fulltext_products_on_title_and_description"
+  end
+
+  def down
+    remove_index :products, name: "fulltext_products_on_title"
+    remove_index :products, name: "fulltext_products_on_title_and_description"
+  end
+end
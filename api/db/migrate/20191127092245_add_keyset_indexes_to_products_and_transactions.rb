@@ -0,0 +1,11 @@
+class AddKeysetIndexesToProductsAndTransactions < ActiveRecord::Migration[5.1]
+  def up
+    add_index :products, [:updated_at, :id]
+    add_index :transactions, [:updated_at, :id]
+  end
+
+  def down
+    remove_index :products, [:updated_at, :id]
+    remove_index :transactions, [:updated_at, :id]
+  end
+end
@@ -0,0 +1,26 @@
+class CreateProductImages < ActiveRecord::Migration[5.1]
+  def up
+    create_table :product_images do |t|
+      t.bigint :product_id, null: false
+      t.string :url, limit: 255, null: false
+      t.string :path, limit: 255, default: ""
+      t.integer :position, default: 0, unsigned: true
+
+      t.timestamps
+
+      t.index [:product_id, :position]
+    end
+
+    # the single image of existing products becomes their cover. Its storage
+    # path is unknown, so the file is kept when the image is removed
+    execute <<-SQL
+      INSERT INTO product_images (product_id, url, path, position, created_at, updated_at)
+      SELECT id, image, '', 0, created_at, updated_at FROM products
+      WHERE image <> ''
+    SQL
+  end
+
+  def down
+    drop_table :product_images
+  end
+end
@@ -0,0 +1,37 @@
+class CreateCategoriesAndProductTags < ActiveRecord::Migration[5.1]
+  def up
+    create_table :categories do |t|
+      t.bigint :parent_id
+      t.string :name, limit: 50, null: false
+      t.string :slug, limit: 60, null: false
+
+      t.timestamps
+
+      t.index :parent_id
+      t.index :slug, unique: true
+    end
+
+    create_table :product_tags do |t|
+      t.bigint :product_id, null: false
+      t.string :tag, limit: 30, null: false
+      t.datetime :created_at, null: false
+
+      t.index [:product_id, :tag], unique: true
+      t.index :tag
+    end
+
+    add_column :products, :category_id, :bigint, after: :country_id
+    add_index :products, [:category_id, :deleted_at]
+
+    # admins manage the category tree, the flag is set directly in the database
+    add_column :users, :is_admin, :boolean, default: false, null: false
+  end
+
+  def down
+    remove_column :users, :is_admin
+    remove_index :products, [:category_id, :deleted_at]
+    remove_column :products, :category_id
+    drop_table :product_tags
+    drop_table :categories
+  end
+end
@@ -0,0 +1,24 @@
+class CreateProductVariants < ActiveRecord::Migration[5.1]
+  def up
+    create_table :product_variants do |t|
+      t.bigint :product_id, null: false
+      # ordered option name/value pairs, e.g. [{"name":"size","value":"42"}]
+      t.text :options, null: false
+      # a NULL price uses the product price, a NULL stock is not tracked
+      t.integer :price, unsigned: true
+      t.integer :stock, unsigned: true
+      t.timestamp :deleted_at
+
+      t.timestamps
+
+      t.index [:product_id, :deleted_at]
+    end
+
+    add_column :transactions, :variant_id, :bigint, after: :product_id
+  end
+
+  def down
+    remove_column :transactions, :variant_id
+    drop_table :product_variants
+  end
+end
@@ -0,0 +1,10 @@
+class AddClosingIndexToProducts < ActiveRecord::Migration[5.1]
+  def up
+    # the closing job looks up open products past their offering window
+    add_index :products, [:to_date, :status]
+  end
+
+  def down
+    remove_index :products, [:to_date, :status]
+  end
+end
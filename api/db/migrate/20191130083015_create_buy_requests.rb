@@ -0,0 +1,46 @@
+class CreateBuyRequests < ActiveRecord::Migration[5.1]
+  def up
+    create_table :buy_requests do |t|
+      t.bigint :buyer_id, null: false
+      t.bigint :country_id, null: false
+      t.string :title, limit: 50, null: false
+      t.text :description
+      t.string :image, null: false
+      t.integer :max_budget, null: false, unsigned: true
+      t.integer :quantity, null: false, default: 1, unsigned: true
+      t.date :needed_by, null: false
+      t.integer :status, limit: 1, null: false, default: 0, unsigned: true
+      t.bigint :accepted_offer_id
+
+      t.timestamps
+
+      t.index [:buyer_id]
+      t.index [:status, :country_id, :needed_by]
+    end
+
+    create_table :buy_request_offers do |t|
+      t.bigint :buy_request_id, null: false
+      t.bigint :seller_id, null: false
+      # the price of one item
+      t.integer :price, null: false, unsigned: true
+      # the trip window of the seller, becoming the offering window of the product
+      t.date :from_date, null: false
+      t.date :to_date, null: false
+      t.string :notes, limit: 200
+      t.integer :status, limit: 1, null: false, default: 0, unsigned: true
+      # set once the offer is accepted
+      t.bigint :product_id
+      t.bigint :transaction_id
+
+      t.timestamps
+
+      t.index [:buy_request_id, :status]
+      t.index [:seller_id]
+    end
+  end
+
+  def down
+    drop_table :buy_request_offers
+    drop_table :buy_requests
+  end
+end
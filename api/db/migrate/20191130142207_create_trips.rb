@@ -0,0 +1,31 @@
+class CreateTrips < ActiveRecord::Migration[5.1]
+  def up
+    create_table :trips do |t|
+      t.bigint :seller_id, null: false
+      t.bigint :country_id, null: false
+      # visited cities in order, e.g. ["Tokyo","Osaka"]
+      t.text :cities, null: false
+      t.date :from_date, null: false
+      t.date :to_date, null: false
+      # the last day orders are accepted, the end of the offering window of the trip products
+      t.date :cutoff_date, null: false
+      # in kilograms
+      t.integer :luggage_capacity, null: false, unsigned: true
+      t.timestamp :deleted_at
+
+      t.timestamps
+
+      t.index [:seller_id, :deleted_at]
+      t.index [:country_id, :to_date, :deleted_at]
+    end
+
+    add_column :products, :trip_id, :bigint, after: :category_id
+    add_index :products, [:trip_id, :deleted_at]
+  end
+
+  def down
+    remove_index :products, [:trip_id, :deleted_at]
+    remove_column :products, :trip_id
+    drop_table :trips
+  end
+end
@@ -0,0 +1,31 @@
+class CreateReviews < ActiveRecord::Migration[5.1]
+  def up
+    create_table :reviews do |t|
+      t.bigint :transaction_id, null: false
+      t.bigint :product_id, null: false
+      t.bigint :buyer_id, null: false
+      t.bigint :seller_id, null: false
+      t.integer :rating, limit: 1, null: false, unsigned: true
+      t.text :text
+      # photo URLs, e.g. ["https://.../reviews/a.jpg"]
+      t.text :photos, null: false
+      t.string :reply, limit: 1000, null: false, default: ""
+      t.timestamp :replied_at
+
+      t.timestamps
+
+      t.index [:transaction_id], unique: true
+      t.index [:seller_id, :created_at, :id]
+    end
+
+    # aggregate of the reviews received as a seller
+    add_column :users, :rating_sum, :integer, null: false, default: 0, unsigned: true
+    add_column :users, :review_count, :integer, null: false, default: 0, unsigned: true
+  end
+
+  def down
+    remove_column :users, :review_count
+    remove_column :users, :rating_sum
+    drop_table :reviews
+  end
+end
@@ -0,0 +1,38 @@
+class CreateConversationsAndMessages < ActiveRecord::Migration[5.1]
+  def up
+    create_table :conversations do |t|
+      # transaction:<id> or product:<id>:buyer:<id>, one conversation per scope
+      t.string :scope, limit: 100, null: false
+      t.bigint :product_id, null: false
+      t.bigint :transaction_id
+      t.bigint :buyer_id, null: false
+      t.bigint :seller_id, null: false
+      # read receipts, the ID of the last message read by each participant
+      t.bigint :buyer_last_read_id, null: false, default: 0
+      t.bigint :seller_last_read_id, null: false, default: 0
+      t.timestamp :last_message_at
+
+      t.timestamps
+
+      t.index [:scope], unique: true
+      t.index [:buyer_id, :updated_at, :id]
+      t.index [:seller_id, :updated_at, :id]
+    end
+
+    create_table :messages do |t|
+      t.bigint :conversation_id, null: false
+      t.bigint :sender_id, null: false
+      t.text :body, null: false
+      t.string :attachment, null: false, default: ""
+
+      t.timestamps
+
+      t.index [:conversation_id, :updated_at, :id]
+    end
+  end
+
+  def down
+    drop_table :messages
+    drop_table :conversations
+  end
+end
@@ -0,0 +1,25 @@
+class CreateNotifications < ActiveRecord::Migration[5.1]
+  def up
+    create_table :notifications do |t|
+      t.bigint :user_id, null: false
+      # transaction, invoice, message or account
+      t.string :type, limit: 30, null: false
+      t.string :title, null: false
+      t.text :content, null: false
+      # deep links, only the ones matching the type are set
+      t.bigint :transaction_id
+      t.bigint :invoice_id
+      t.bigint :conversation_id
+      t.timestamp :read_at
+
+      t.timestamps
+
+      t.index [:user_id, :updated_at, :id]
+      t.index [:user_id, :read_at]
+    end
+  end
+
+  def down
+    drop_table :notifications
+  end
+end
@@ -0,0 +1,16 @@
+class AddUniqueDeviceIdToUserDevices < ActiveRecord::Migration[5.1]
+  def up
+    # a device token can only push to one user, keep its latest registration
+    execute <<-SQL
+      DELETE older FROM user_devices older
+      INNER JOIN user_devices newer
+      ON newer.device_id = older.device_id AND newer.id > older.id
+    SQL
+
+    add_index :user_devices, :device_id, unique: true
+  end
+
+  def down
+    remove_index :user_devices, :device_id
+  end
+end
@@ -0,0 +1,24 @@
+class CreateNotificationTemplates < ActiveRecord::Migration[5.1]
+  def up
+    # overrides of the built-in templates, edited by admins
+    create_table :notification_templates do |t|
+      t.string :event, limit: 50, null: false
+      # id or en
+      t.string :locale, limit: 5, null: false
+      t.string :title, null: false
+      t.text :content, null: false
+
+      t.timestamps
+
+      t.index [:event, :locale], unique: true
+    end
+
+    # language of the notifications sent to the user
+    add_column :users, :language, :string, limit: 5, null: false, default: "id"
+  end
+
+  def down
+    remove_column :users, :language
+    drop_table :notification_templates
+  end
+end
@@ -0,0 +1,12 @@
+class AddEmailOptOutsToUsers < ActiveRecord::Migration[5.1]
+  def up
+    # categories of transactional emails the user does not want to receive
+    add_column :users, :order_email_opt_out, :boolean, null: false, default: false
+    add_column :users, :payment_email_opt_out, :boolean, null: false, default: false
+  end
+
+  def down
+    remove_column :users, :payment_email_opt_out
+    remove_column :users, :order_email_opt_out
+  end
+end
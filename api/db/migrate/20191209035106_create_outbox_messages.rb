@@ -0,0 +1,23 @@
+class CreateOutboxMessages < ActiveRecord::Migration[5.1]
+  def up
+    create_table :outbox_messages do |t|
+      t.string :topic, limit: 100, null: false
+      t.text :payload, null: false
+      # 0: pending, 1: published, 2: failed
+      t.integer :status, limit: 1, null: false, default: 0
+      t.integer :attempts, null: false, default: 0
+      t.string :last_error, limit: 1000, null: false, default: ""
+      t.datetime :next_attempt_at, null: false
+      t.datetime :published_at
+
+      t.timestamps
+
+      t.index [:status, :next_attempt_at]
+      t.index [:status, :updated_at, :id]
+    end
+  end
+
+  def down
+    drop_table :outbox_messages
+  end
+end
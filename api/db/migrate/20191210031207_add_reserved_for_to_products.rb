@@ -0,0 +1,12 @@
+class AddReservedForToProducts < ActiveRecord::Migration[5.1]
+  def up
+    # products made for a buy request can only be ordered by its buyer
+    add_column :products, :reserved_for, :bigint, after: :trip_id
+    add_index :products, :reserved_for
+  end
+
+  def down
+    remove_index :products, :reserved_for
+    remove_column :products, :reserved_for
+  end
+end
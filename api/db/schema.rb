@@ -10,7 +10,7 @@
 #
 # It's strongly recommended that you check this file into your version control system.
 
-ActiveRecord::Schema.define(version: 2019_11_16_165239) do
+ActiveRecord::Schema.define(version: 2019_12_10_031207) do
 
   create_table "banks", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
     t.string "name", limit: 30, null: false
@@ -20,6 +20,65 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.index ["name"], name: "index_banks_on_name"
   end
 
+  create_table "buy_request_offers", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "buy_request_id", null: false
+    t.bigint "seller_id", null: false
+    t.integer "price", null: false, unsigned: true
+    t.date "from_date", null: false
+    t.date "to_date", null: false
+    t.string "notes", limit: 200
+    t.integer "status", limit: 1, default: 0, null: false, unsigned: true
+    t.bigint "product_id"
+    t.bigint "transaction_id"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["buy_request_id", "status"], name: "index_buy_request_offers_on_buy_request_id_and_status"
+    t.index ["seller_id"], name: "index_buy_request_offers_on_seller_id"
+  end
+
+  create_table "buy_requests", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "buyer_id", null: false
+    t.bigint "country_id", null: false
+    t.string "title", limit: 50, null: false
+    t.text "description"
+    t.string "image", null: false
+    t.integer "max_budget", null: false, unsigned: true
+    t.integer "quantity", default: 1, null: false, unsigned: true
+    t.date "needed_by", null: false
+    t.integer "status", limit: 1, default: 0, null: false, unsigned: true
+    t.bigint "accepted_offer_id"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["buyer_id"], name: "index_buy_requests_on_buyer_id"
+    t.index ["status", "country_id", "needed_by"], name: "index_buy_requests_on_status_and_country_id_and_needed_by"
+  end
+
+  create_table "categories", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "parent_id"
+    t.string "name", limit: 50, null: false
+    t.string "slug", limit: 60, null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["parent_id"], name: "index_categories_on_parent_id"
+    t.index ["slug"], name: "index_categories_on_slug", unique: true
+  end
+
+  create_table "conversations", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.string "scope", limit: 100, null: false
+    t.bigint "product_id", null: false
+    t.bigint "transaction_id"
+    t.bigint "buyer_id", null: false
+    t.bigint "seller_id", null: false
+    t.bigint "buyer_last_read_id", default: 0, null: false
+    t.bigint "seller_last_read_id", default: 0, null: false
+    t.timestamp "last_message_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["buyer_id", "updated_at", "id"], name: "index_conversations_on_buyer_id_and_updated_at_and_id"
+    t.index ["scope"], name: "index_conversations_on_scope", unique: true
+    t.index ["seller_id", "updated_at", "id"], name: "index_conversations_on_seller_id_and_updated_at_and_id"
+  end
+
   create_table "countries", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
     t.string "name", limit: 30, null: false
     t.string "image", default: ""
@@ -43,12 +102,103 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.index ["transaction_id"], name: "index_invoices_on_transaction_id"
   end
 
+  create_table "login_throttles", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.string "throttle_key", null: false
+    t.integer "failed_count", default: 0, unsigned: true
+    t.datetime "locked_until"
+    t.datetime "last_failed_at", null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["throttle_key"], name: "index_login_throttles_on_throttle_key", unique: true
+  end
+
+  create_table "messages", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "conversation_id", null: false
+    t.bigint "sender_id", null: false
+    t.text "body", null: false
+    t.string "attachment", default: "", null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["conversation_id", "updated_at", "id"], name: "index_messages_on_conversation_id_and_updated_at_and_id"
+  end
+
+  create_table "notification_templates", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.string "event", limit: 50, null: false
+    t.string "locale", limit: 5, null: false
+    t.string "title", null: false
+    t.text "content", null: false
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["event", "locale"], name: "index_notification_templates_on_event_and_locale", unique: true
+  end
+
+  create_table "notifications", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "user_id", null: false
+    t.string "type", limit: 30, null: false
+    t.string "title", null: false
+    t.text "content", null: false
+    t.bigint "transaction_id"
+    t.bigint "invoice_id"
+    t.bigint "conversation_id"
+    t.timestamp "read_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["user_id", "read_at"], name: "index_notifications_on_user_id_and_read_at"
+    t.index ["user_id", "updated_at", "id"], name: "index_notifications_on_user_id_and_updated_at_and_id"
+  end
+
+  create_table "outbox_messages", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.string "topic", limit: 100, null: false
+    t.text "payload", null: false
+    t.integer "status", limit: 1, default: 0, null: false
+    t.integer "attempts", default: 0, null: false
+    t.string "last_error", limit: 1000, default: "", null: false
+    t.datetime "next_attempt_at", null: false
+    t.datetime "published_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["status", "next_attempt_at"], name: "index_outbox_messages_on_status_and_next_attempt_at"
+    t.index ["status", "updated_at", "id"], name: "index_outbox_messages_on_status_and_updated_at_and_id"
+  end
+
+  create_table "product_images", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "product_id", null: false
+    t.string "url", null: false
+    t.string "path", default: ""
+    t.integer "position", default: 0, unsigned: true
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["product_id", "position"], name: "index_product_images_on_product_id_and_position"
+  end
+
+  create_table "product_tags", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "product_id", null: false
+    t.string "tag", limit: 30, null: false
+    t.datetime "created_at", null: false
+    t.index ["product_id", "tag"], name: "index_product_tags_on_product_id_and_tag", unique: true
+    t.index ["tag"], name: "index_product_tags_on_tag"
+  end
+
+  create_table "product_variants", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "product_id", null: false
+    t.text "options", null: false
+    t.integer "price", unsigned: true
+    t.integer "stock", unsigned: true
+    t.timestamp "deleted_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["product_id", "deleted_at"], name: "index_product_variants_on_product_id_and_deleted_at"
+  end
+
   create_table "products", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
     t.string "title", limit: 50, null: false
     t.text "description"
     t.integer "price", default: 0, unsigned: true
     t.bigint "seller_id", null: false
     t.bigint "country_id", null: false
+    t.bigint "category_id"
+    t.bigint "trip_id"
+    t.bigint "reserved_for"
     t.string "image", null: false
     t.integer "status", limit: 1, default: 1, unsigned: true
     t.date "from_date", null: false
@@ -56,13 +206,36 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.timestamp "deleted_at"
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
+    t.index ["title"], name: "fulltext_products_on_title", type: :fulltext
+    t.index ["title", "description"], name: "fulltext_products_on_title_and_description", type: :fulltext
+    t.index ["category_id", "deleted_at"], name: "index_products_on_category_id_and_deleted_at"
     t.index ["country_id", "deleted_at"], name: "index_products_on_country_id_and_deleted_at"
     t.index ["deleted_at"], name: "index_products_on_deleted_at"
+    t.index ["reserved_for"], name: "index_products_on_reserved_for"
     t.index ["seller_id", "deleted_at"], name: "index_products_on_seller_id_and_deleted_at"
     t.index ["title", "country_id", "deleted_at"], name: "index_products_on_title_and_country_id_and_deleted_at"
     t.index ["title", "deleted_at"], name: "index_products_on_title_and_deleted_at"
     t.index ["title", "seller_id", "deleted_at"], name: "index_products_on_title_and_seller_id_and_deleted_at"
     t.index ["title"], name: "index_products_on_title"
+    t.index ["to_date", "status"], name: "index_products_on_to_date_and_status"
+    t.index ["trip_id", "deleted_at"], name: "index_products_on_trip_id_and_deleted_at"
+    t.index ["updated_at", "id"], name: "index_products_on_updated_at_and_id"
+  end
+
+  create_table "reviews", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "transaction_id", null: false
+    t.bigint "product_id", null: false
+    t.bigint "buyer_id", null: false
+    t.bigint "seller_id", null: false
+    t.integer "rating", limit: 1, null: false, unsigned: true
+    t.text "text"
+    t.text "photos", null: false
+    t.string "reply", limit: 1000, default: "", null: false
+    t.timestamp "replied_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["seller_id", "created_at", "id"], name: "index_reviews_on_seller_id_and_created_at_and_id"
+    t.index ["transaction_id"], name: "index_reviews_on_transaction_id", unique: true
   end
 
   create_table "transaction_shippings", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
@@ -77,6 +250,7 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
 
   create_table "transactions", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
     t.bigint "product_id", null: false
+    t.bigint "variant_id"
     t.bigint "buyer_id", null: false
     t.bigint "seller_id", null: false
     t.bigint "buyer_address_id", null: false
@@ -93,6 +267,22 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.index ["buyer_id"], name: "index_transactions_on_buyer_id"
     t.index ["product_id"], name: "index_transactions_on_product_id"
     t.index ["seller_id"], name: "index_transactions_on_seller_id"
+    t.index ["updated_at", "id"], name: "index_transactions_on_updated_at_and_id"
+  end
+
+  create_table "trips", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "seller_id", null: false
+    t.bigint "country_id", null: false
+    t.text "cities", null: false
+    t.date "from_date", null: false
+    t.date "to_date", null: false
+    t.date "cutoff_date", null: false
+    t.integer "luggage_capacity", null: false, unsigned: true
+    t.timestamp "deleted_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["country_id", "to_date", "deleted_at"], name: "index_trips_on_country_id_and_to_date_and_deleted_at"
+    t.index ["seller_id", "deleted_at"], name: "index_trips_on_seller_id_and_deleted_at"
   end
 
   create_table "user_addresses", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
@@ -112,11 +302,45 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.bigint "user_id", null: false
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
+    t.index ["device_id"], name: "index_user_devices_on_device_id", unique: true
     t.index ["platform"], name: "index_user_devices_on_platform"
     t.index ["user_id", "platform"], name: "index_user_devices_on_user_id_and_platform"
     t.index ["user_id"], name: "index_user_devices_on_user_id"
   end
 
+  create_table "user_identities", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "user_id", null: false
+    t.string "issuer", null: false
+    t.string "subject", null: false
+    t.string "email", default: ""
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["issuer", "subject"], name: "index_user_identities_on_issuer_and_subject", unique: true
+    t.index ["user_id"], name: "index_user_identities_on_user_id"
+  end
+
+  create_table "user_logins", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "user_id", null: false
+    t.string "ip_address", limit: 45, default: ""
+    t.string "user_agent", default: ""
+    t.datetime "created_at", null: false
+    t.index ["user_id", "created_at"], name: "index_user_logins_on_user_id_and_created_at"
+  end
+
+  create_table "user_verifications", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
+    t.bigint "user_id", null: false
+    t.string "channel", limit: 10, null: false
+    t.string "target", null: false
+    t.string "code", limit: 64, null: false
+    t.integer "attempts", limit: 1, default: 0, unsigned: true
+    t.datetime "expired_at", null: false
+    t.datetime "verified_at"
+    t.datetime "created_at", null: false
+    t.datetime "updated_at", null: false
+    t.index ["channel", "code"], name: "index_user_verifications_on_channel_and_code"
+    t.index ["user_id", "channel", "created_at"], name: "index_user_verifications_on_user_id_and_channel_and_created_at"
+  end
+
   create_table "users", options: "ENGINE=InnoDB DEFAULT CHARSET=utf8", force: :cascade do |t|
     t.string "email"
     t.string "name", limit: 50, null: false
@@ -128,6 +352,15 @@ ActiveRecord::Schema.define(version: 2019_11_16_165239) do
     t.datetime "last_login_at"
     t.datetime "created_at", null: false
     t.datetime "updated_at", null: false
+    t.datetime "email_verified_at"
+    t.datetime "phone_verified_at"
+    t.datetime "deleted_at"
+    t.boolean "is_admin", default: false, null: false
+    t.integer "rating_sum", default: 0, null: false, unsigned: true
+    t.integer "review_count", default: 0, null: false, unsigned: true
+    t.string "language", limit: 5, default: "id", null: false
+    t.boolean "order_email_opt_out", default: false, null: false
+    t.boolean "payment_email_opt_out", default: false, null: false
     t.index ["email"], name: "index_users_on_email"
     t.index ["phone"], name: "index_users_on_phone"
   end
@@ -0,0 +1,116 @@
+package delivery
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// AccountHandler holds AccountUsecase to be used in the handler
+type AccountHandler struct {
+	uc api.AccountUsecase
+}
+
+// NewAccountHandler creates a new instance of AccountHandler
+// with the provided AccountUsecase
+func NewAccountHandler(uc api.AccountUsecase) AccountHandler {
+	return AccountHandler{uc}
+}
+
+// RegisterHandler registers all route for this handler
+func (h *AccountHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/me/export", handler.Decorate(h.ExportAccount, handler.UserAuth...))
+	r.DELETE("/me", handler.Decorate(h.DeleteAccount, handler.UserAuth...))
+
+	return nil
+}
+
+// ExportAccount is a handler to download all data of the logged in user as a zip archive
+func (h *AccountHandler) ExportAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	ctx := r.Context()
+	userID := api.GetUserID(ctx)
+	export, err := h.uc.ExportAccount(ctx, userID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	archive, err := buildExportArchive(export)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	filename := fmt.Sprintf("sejastip-%d-%s.zip", userID, export.ExportedAt.Format("20060102150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+	return nil
+}
+
+// DeleteAccount is a handler to delete the logged in user's account
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	ctx := r.Context()
+	err := h.uc.DeleteAccount(ctx, api.GetUserID(ctx))
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Akun berhasil dihapus")
+	return nil
+}
+
+// buildExportArchive writes every section of the export as a JSON file in a zip archive
+func buildExportArchive(export *entity.AccountExport) ([]byte, error) {
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"products.json", export.Products},
+		{"transactions.json", export.Transactions},
+		{"invoices.json", export.Invoices},
+		{"logins.json", export.Logins},
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating archive entry")
+		}
+
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return nil, errors.Wrap(err, "error encoding archive entry")
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "error closing archive")
+	}
+
+	return buf.Bytes(), nil
+}
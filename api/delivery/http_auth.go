@@ -15,12 +15,15 @@ import (
 // AuthHandler holds AuthUsecase to be used in the handler
 type AuthHandler struct {
 	uc api.AuthUsecase
+	// trustedProxies is the number of proxies in front of the API, telling which
+	// address of X-Forwarded-For is the client
+	trustedProxies int
 }
 
 // NewAuthHandler creates a new instance of UserHandler
 // with the provided UserUsecase
-func NewAuthHandler(uc api.AuthUsecase) AuthHandler {
-	return AuthHandler{uc}
+func NewAuthHandler(uc api.AuthUsecase, trustedProxies int) AuthHandler {
+	return AuthHandler{uc, trustedProxies}
 }
 
 // RegisterHandler registers all route for this handler
@@ -31,6 +34,9 @@ func (h *AuthHandler) RegisterHandler(r *httprouter.Router) error {
 
 	d := handler.DefaultMiddlewares()
 	r.POST("/auth", handler.Decorate(h.Authenticate, d...))
+	r.POST("/auth/oidc", handler.Decorate(h.AuthenticateOIDC, d...))
+	r.GET("/me/logins", handler.Decorate(h.GetLoginHistory, handler.UserAuth...))
+	r.GET("/.well-known/jwks.json", handler.Decorate(h.GetJWKS, d...))
 
 	return nil
 }
@@ -45,6 +51,9 @@ func (h *AuthHandler) Authenticate(w http.ResponseWriter, r *http.Request, _ htt
 		return err
 	}
 
+	auth.IPAddress = api.GetIPAddress(r, h.trustedProxies)
+	auth.UserAgent = api.GetUserAgent(r)
+
 	ctx := r.Context()
 	authResponse, err := h.uc.AuthenticateUser(ctx, &auth)
 	if err != nil {
@@ -55,3 +64,55 @@ func (h *AuthHandler) Authenticate(w http.ResponseWriter, r *http.Request, _ htt
 	api.OK(w, authResponse, "successfully logged in")
 	return nil
 }
+
+// AuthenticateOIDC is a handler for user authentication with a third-party ID token
+func (h *AuthHandler) AuthenticateOIDC(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	var form entity.OIDCAuthForm
+	if err := decoder.Decode(&form); err != nil {
+		err = api.ErrInvalidParameter
+		api.Error(w, err)
+		return err
+	}
+
+	form.IPAddress = api.GetIPAddress(r, h.trustedProxies)
+	form.UserAgent = api.GetUserAgent(r)
+
+	ctx := r.Context()
+	authResponse, err := h.uc.AuthenticateOIDC(ctx, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, authResponse, "successfully logged in")
+	return nil
+}
+
+// GetLoginHistory is a handler to get the logged in user's login history
+func (h *AuthHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	limit := helper.GetInt("limit", 10)
+	offset := helper.GetInt("offset", 0)
+
+	ctx := r.Context()
+	logins, total, err := h.uc.GetLoginHistory(ctx, api.GetUserID(ctx), limit, offset)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	meta := api.NewMetaPagination(http.StatusOK, limit, offset, int(total))
+	api.OKWithMeta(w, logins, "", meta)
+	return nil
+}
+
+// GetJWKS is a handler to publish the public keys used to verify access tokens.
+// The key set is returned as is, since verifiers expect the standard JWKS format
+func (h *AuthHandler) GetJWKS(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	jwks := h.uc.GetJWKS(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	return json.NewEncoder(w).Encode(jwks)
+}
@@ -0,0 +1,238 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+type BuyRequestHandler struct {
+	uc api.BuyRequestUsecase
+}
+
+func NewBuyRequestHandler(uc api.BuyRequestUsecase) BuyRequestHandler {
+	return BuyRequestHandler{uc}
+}
+
+func (h *BuyRequestHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/buy-requests", handler.Decorate(h.GetBuyRequests, handler.AppAuth...))
+	r.GET("/buy-requests/:id", handler.Decorate(h.GetBuyRequest, handler.AppAuth...))
+	r.POST("/buy-requests", handler.Decorate(h.CreateBuyRequest, handler.UserAuth...))
+	r.DELETE("/buy-requests/:id", handler.Decorate(h.CancelBuyRequest, handler.UserAuth...))
+
+	r.GET("/buy-requests/:id/offers", handler.Decorate(h.GetOffers, handler.UserAuth...))
+	r.POST("/buy-requests/:id/offers", handler.Decorate(h.CreateOffer, handler.UserAuth...))
+	r.DELETE("/buy-requests/:id/offers/:offer_id", handler.Decorate(h.WithdrawOffer, handler.UserAuth...))
+	r.POST("/buy-requests/:id/offers/:offer_id/accept", handler.Decorate(h.AcceptOffer, handler.UserAuth...))
+
+	return nil
+}
+
+func (h *BuyRequestHandler) GetBuyRequests(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	spec, err := helper.GetFilterSpec(entity.BuyRequestFilterSchema)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	requests, total, err := h.uc.GetBuyRequests(r.Context(), spec, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting buy requests")
+	}
+
+	var nextCursor string
+	if len(requests) > 0 {
+		last := requests[len(requests)-1]
+		nextCursor = page.NextCursor(len(requests), last.UpdatedAt, last.ID)
+	}
+
+	meta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, requests, "", meta)
+	return nil
+}
+
+func (h *BuyRequestHandler) GetBuyRequest(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	request, err := h.uc.GetBuyRequest(r.Context(), requestID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, request, "")
+	return nil
+}
+
+func (h *BuyRequestHandler) CreateBuyRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.BuyRequestForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	request, err := h.uc.CreateBuyRequest(ctx, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, request, "")
+	return nil
+}
+
+func (h *BuyRequestHandler) CancelBuyRequest(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	err = h.uc.CancelBuyRequest(ctx, requestID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Permintaan titip berhasil dibatalkan")
+	return nil
+}
+
+// GetOffers lists the offers on a buy request. The buyer sees every offer,
+// sellers only see their own
+func (h *BuyRequestHandler) GetOffers(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	offers, err := h.uc.GetOffers(ctx, requestID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, offers, "")
+	return nil
+}
+
+func (h *BuyRequestHandler) CreateOffer(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.BuyRequestOfferForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	offer, err := h.uc.CreateOffer(ctx, requestID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, offer, "")
+	return nil
+}
+
+func (h *BuyRequestHandler) WithdrawOffer(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	offerID, err := strconv.ParseInt(p.ByName("offer_id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	err = h.uc.WithdrawOffer(ctx, requestID, offerID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Penawaran berhasil ditarik")
+	return nil
+}
+
+// AcceptOffer accepts an offer and places the order, returning the created transaction
+func (h *BuyRequestHandler) AcceptOffer(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	requestID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	offerID, err := strconv.ParseInt(p.ByName("offer_id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.AcceptOfferForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	transaction, err := h.uc.AcceptOffer(ctx, requestID, offerID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, transaction, "")
+	return nil
+}
@@ -0,0 +1,114 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+type CategoryHandler struct {
+	uc api.CategoryUsecase
+}
+
+func NewCategoryHandler(uc api.CategoryUsecase) CategoryHandler {
+	return CategoryHandler{uc}
+}
+
+func (h *CategoryHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/categories", handler.Decorate(h.GetCategories, handler.AppAuth...))
+	r.POST("/categories", handler.Decorate(h.CreateCategory, handler.AdminAuth...))
+	r.PUT("/categories/:id", handler.Decorate(h.UpdateCategory, handler.AdminAuth...))
+	r.DELETE("/categories/:id", handler.Decorate(h.DeleteCategory, handler.AdminAuth...))
+
+	return nil
+}
+
+// GetCategories returns the category tree, counting only the products sold in
+// a country when country_id is provided
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	countryID := int64(helper.GetInt("country_id", 0))
+
+	categories, err := h.uc.GetCategories(r.Context(), countryID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, categories, "")
+	return nil
+}
+
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.CategoryForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	category, err := h.uc.CreateCategory(r.Context(), &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, category, "")
+	return nil
+}
+
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	categoryID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.CategoryForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	category, err := h.uc.UpdateCategory(r.Context(), categoryID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, category, "category successfully updated")
+	return nil
+}
+
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	categoryID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	err = h.uc.DeleteCategory(r.Context(), categoryID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "category has been successfully deleted")
+	return nil
+}
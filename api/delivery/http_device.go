@@ -26,6 +26,7 @@ func (h *DeviceHandler) RegisterHandler(r *httprouter.Router) error {
 	}
 
 	r.PUT("/devices", handler.Decorate(h.UpsertDevice, handler.UserAuth...))
+	r.DELETE("/devices/:device_id", handler.Decorate(h.RemoveDevice, handler.UserAuth...))
 
 	return nil
 }
@@ -41,6 +42,12 @@ func (h *DeviceHandler) UpsertDevice(w http.ResponseWriter, r *http.Request, _ h
 		return err
 	}
 
+	if err := form.Validate(); err != nil {
+		err = api.ValidationError(err)
+		api.Error(w, err)
+		return err
+	}
+
 	device := entity.Device{
 		DeviceID:  form.DeviceID,
 		UserAgent: userAgent,
@@ -57,3 +64,17 @@ func (h *DeviceHandler) UpsertDevice(w http.ResponseWriter, r *http.Request, _ h
 	api.OK(w, nil, "User device registered successfully")
 	return nil
 }
+
+// RemoveDevice unregisters a device of the logged in user, so it stops receiving
+// push notifications after logout
+func (h *DeviceHandler) RemoveDevice(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	ctx := r.Context()
+	err := h.uc.RemoveDevice(ctx, p.ByName("device_id"))
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "User device removed successfully")
+	return nil
+}
@@ -0,0 +1,235 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+// the default and maximum number of seconds a long poll waits for new messages
+const (
+	defaultPollWait = 25
+	maxPollWait     = 30
+)
+
+type MessageHandler struct {
+	uc api.MessageUsecase
+}
+
+func NewMessageHandler(uc api.MessageUsecase) MessageHandler {
+	return MessageHandler{uc}
+}
+
+func (h *MessageHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.POST("/conversations", handler.Decorate(h.StartConversation, handler.UserAuth...))
+	r.GET("/conversations", handler.Decorate(h.GetConversations, handler.UserAuth...))
+	r.GET("/conversations/:id", handler.Decorate(h.GetConversation, handler.UserAuth...))
+	r.POST("/conversations/:id/messages", handler.Decorate(h.SendMessage, handler.UserAuth...))
+	r.GET("/conversations/:id/messages", handler.Decorate(h.GetMessages, handler.UserAuth...))
+	r.GET("/conversations/:id/poll", handler.Decorate(h.PollMessages, handler.UserAuth...))
+	r.PUT("/conversations/:id/read", handler.Decorate(h.MarkRead, handler.UserAuth...))
+
+	return nil
+}
+
+// StartConversation opens the chat of a transaction, or an enquiry on a product
+func (h *MessageHandler) StartConversation(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ConversationForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	conversation, err := h.uc.StartConversation(ctx, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, conversation, "")
+	return nil
+}
+
+// GetConversations lists the conversations of the logged in user, most recently active first
+func (h *MessageHandler) GetConversations(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	conversations, total, err := h.uc.GetConversations(ctx, meta.ID, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting conversations")
+	}
+
+	var nextCursor string
+	if len(conversations) > 0 {
+		last := conversations[len(conversations)-1]
+		nextCursor = page.NextCursor(len(conversations), last.UpdatedAt, last.ID)
+	}
+
+	pageMeta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, conversations, "", pageMeta)
+	return nil
+}
+
+func (h *MessageHandler) GetConversation(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	conversationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	conversation, err := h.uc.GetConversation(ctx, conversationID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, conversation, "")
+	return nil
+}
+
+func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	conversationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.MessageForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	message, err := h.uc.SendMessage(ctx, conversationID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, message, "")
+	return nil
+}
+
+// GetMessages lists the messages of a conversation, latest first
+func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	conversationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(20)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	messages, total, err := h.uc.GetMessages(ctx, conversationID, meta.ID, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting messages")
+	}
+
+	var nextCursor string
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		nextCursor = page.NextCursor(len(messages), last.UpdatedAt, last.ID)
+	}
+
+	pageMeta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, messages, "", pageMeta)
+	return nil
+}
+
+// PollMessages waits for the messages sent after the after_id message, for up to
+// wait seconds. The client polls again with the ID of the last message it received
+func (h *MessageHandler) PollMessages(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	conversationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	helper := api.NewQueryHelper(r)
+	afterID := helper.GetInt("after_id", 0)
+	wait := helper.GetInt("wait", defaultPollWait)
+	if afterID < 0 || wait < 0 || wait > maxPollWait {
+		api.Error(w, api.ErrInvalidParameter)
+		return api.ErrInvalidParameter
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	messages, err := h.uc.WaitMessages(ctx, conversationID, meta.ID, int64(afterID), time.Duration(wait)*time.Second)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, messages, "")
+	return nil
+}
+
+// MarkRead marks the messages of a conversation as read by the logged in user
+func (h *MessageHandler) MarkRead(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	conversationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ReadReceiptForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	err = h.uc.MarkRead(ctx, conversationID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Pesan telah dibaca")
+	return nil
+}
@@ -0,0 +1,145 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+type NotificationHandler struct {
+	uc api.NotificationUsecase
+}
+
+func NewNotificationHandler(uc api.NotificationUsecase) NotificationHandler {
+	return NotificationHandler{uc}
+}
+
+func (h *NotificationHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/notifications", handler.Decorate(h.GetNotifications, handler.UserAuth...))
+	r.PUT("/notifications/read", handler.Decorate(h.MarkAllRead, handler.UserAuth...))
+	r.PUT("/notifications/read/:id", handler.Decorate(h.MarkRead, handler.UserAuth...))
+
+	r.GET("/notification-templates", handler.Decorate(h.GetTemplates, handler.AdminAuth...))
+	r.PUT("/notification-templates/:event/:locale", handler.Decorate(h.UpdateTemplate, handler.AdminAuth...))
+	r.DELETE("/notification-templates/:event/:locale", handler.Decorate(h.ResetTemplate, handler.AdminAuth...))
+
+	return nil
+}
+
+// GetNotifications lists the inbox of the logged in user, latest first. The meta
+// carries the number of unread notifications
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	notifications, total, unread, err := h.uc.GetNotifications(ctx, meta.ID, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting notifications")
+	}
+
+	var nextCursor string
+	if len(notifications) > 0 {
+		last := notifications[len(notifications)-1]
+		nextCursor = page.NextCursor(len(notifications), last.UpdatedAt, last.ID)
+	}
+
+	pageMeta := api.NewUnreadPageMeta(http.StatusOK, page, total, nextCursor, unread)
+	api.OKWithMeta(w, notifications, "", pageMeta)
+	return nil
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	notificationID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	err = h.uc.MarkRead(ctx, notificationID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Notifikasi telah dibaca")
+	return nil
+}
+
+// MarkAllRead marks the whole inbox of the logged in user as read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	total, err := h.uc.MarkAllRead(ctx, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, fmt.Sprintf("%d notifikasi telah dibaca", total))
+	return nil
+}
+
+// GetTemplates lists the notification templates of every event in every language
+func (h *NotificationHandler) GetTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	templates, err := h.uc.GetTemplates(r.Context())
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, templates, "")
+	return nil
+}
+
+func (h *NotificationHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.NotificationTemplateForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	template, err := h.uc.UpdateTemplate(r.Context(), p.ByName("event"), p.ByName("locale"), &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, template, "Template notifikasi berhasil diperbarui")
+	return nil
+}
+
+// ResetTemplate restores the default template of an event in a language
+func (h *NotificationHandler) ResetTemplate(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	template, err := h.uc.ResetTemplate(r.Context(), p.ByName("event"), p.ByName("locale"))
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, template, "Template notifikasi dikembalikan ke bawaan")
+	return nil
+}
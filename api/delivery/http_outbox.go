@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/handler"
+)
+
+type OutboxHandler struct {
+	uc api.OutboxUsecase
+}
+
+func NewOutboxHandler(uc api.OutboxUsecase) OutboxHandler {
+	return OutboxHandler{uc}
+}
+
+func (h *OutboxHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/outbox-messages", handler.Decorate(h.GetMessages, handler.AdminAuth...))
+	r.POST("/outbox-messages/:id/replay", handler.Decorate(h.ReplayMessage, handler.AdminAuth...))
+
+	return nil
+}
+
+// GetMessages lists the outbox messages having the status query, the failed ones
+// by default, latest first
+func (h *OutboxHandler) GetMessages(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(20)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	messages, total, err := h.uc.GetMessages(r.Context(), helper.GetString("status"), page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting outbox messages")
+	}
+
+	var nextCursor string
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		nextCursor = page.NextCursor(len(messages), last.UpdatedAt, last.ID)
+	}
+
+	pageMeta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, messages, "", pageMeta)
+	return nil
+}
+
+// ReplayMessage queues a failed message again
+func (h *OutboxHandler) ReplayMessage(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	messageID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	message, err := h.uc.ReplayMessage(r.Context(), messageID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, message, "Pesan akan dikirim ulang")
+	return nil
+}
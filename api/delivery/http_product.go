@@ -33,28 +33,48 @@ func (h *ProductHandler) RegisterHandler(r *httprouter.Router) error {
 	r.GET("/products/:id", handler.Decorate(h.GetProduct, handler.AppAuth...))
 	r.POST("/products", handler.Decorate(h.CreateProduct, handler.UserAuth...))
 	r.PUT("/products/:id", handler.Decorate(h.UpdateProduct, handler.UserAuth...))
+	r.PATCH("/products/:id", handler.Decorate(h.PatchProduct, handler.UserAuth...))
 	r.DELETE("/products/:id", handler.Decorate(h.DeleteProduct, handler.UserAuth...))
+	r.POST("/products/:id/images", handler.Decorate(h.AddProductImage, handler.UserAuth...))
+	r.PUT("/products/:id/images", handler.Decorate(h.ReorderProductImages, handler.UserAuth...))
+	r.DELETE("/products/:id/images/:image_id", handler.Decorate(h.RemoveProductImage, handler.UserAuth...))
+	r.POST("/products/:id/variants", handler.Decorate(h.AddProductVariant, handler.UserAuth...))
+	r.PUT("/products/:id/variants/:variant_id", handler.Decorate(h.UpdateProductVariant, handler.UserAuth...))
+	r.DELETE("/products/:id/variants/:variant_id", handler.Decorate(h.RemoveProductVariant, handler.UserAuth...))
 
 	return nil
 }
 
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
 	helper := api.NewQueryHelper(r)
-	limit := helper.GetInt("limit", 10)
-	offset := helper.GetInt("offset", 0)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
 
-	filters := helper.GetFilters()
+	spec, err := helper.GetFilterSpec(entity.ProductFilterSchema)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
 
 	// get products by filter
 	ctx := r.Context()
-	banks, total, err := h.uc.GetProductsByFilter(ctx, filters, limit, offset)
+	products, total, err := h.uc.GetProductsByFilter(ctx, spec, page)
 	if err != nil {
 		api.Error(w, err)
 		return errors.Wrap(err, "error getting products")
 	}
 
-	meta := api.NewMetaPagination(http.StatusOK, limit, offset, int(total))
-	api.OKWithMeta(w, banks, "", meta)
+	var nextCursor string
+	if len(products) > 0 {
+		last := products[len(products)-1]
+		nextCursor = page.NextCursor(len(products), last.UpdatedAt, last.ID)
+	}
+
+	meta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, products, "", meta)
 	return nil
 }
 
@@ -66,21 +86,25 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request, _
 		return err
 	}
 
-	// parse the date first
-	fromDateInTime, err := time.Parse(time.RFC3339, productForm.FromDate+"T00:00:00Z")
-	if err != nil {
-		api.Error(w, api.ErrInvalidParameter)
-		return err
-	}
+	// parse the date first, products listed for a trip take the dates of the trip
+	var fromDateInTime, toDateInTime time.Time
+	if productForm.TripID == nil {
+		var err error
+		fromDateInTime, err = time.Parse(time.RFC3339, productForm.FromDate+"T00:00:00Z")
+		if err != nil {
+			api.Error(w, api.ErrInvalidParameter)
+			return err
+		}
 
-	toDateInTime, err := time.Parse(time.RFC3339, productForm.ToDate+"T23:59:59Z")
-	if err != nil {
-		api.Error(w, api.ErrInvalidParameter)
-		return err
+		toDateInTime, err = time.Parse(time.RFC3339, productForm.ToDate+"T23:59:59Z")
+		if err != nil {
+			api.Error(w, api.ErrInvalidParameter)
+			return err
+		}
 	}
 
 	ctx := r.Context()
-	filePath := ""
+	images := []entity.ProductImage{}
 	if productForm.ImageFile != "" {
 		file, extension, err := util.DecodeUploadedBase64File(productForm.ImageFile)
 		if err != nil {
@@ -91,11 +115,12 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request, _
 
 		// upload file
 		filename := fmt.Sprintf("%s%s", uuid.New().String(), extension)
-		filePath, err = h.uc.UploadProductImage(ctx, filename, file)
+		image, err := h.uc.UploadProductImage(ctx, filename, file)
 		if err != nil {
 			api.Error(w, err)
 			return errors.Wrap(err, "error in uploading file")
 		}
+		images = append(images, *image)
 	}
 
 	// Create product, but normalize the inputs first
@@ -106,9 +131,22 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request, _
 		Price:       productForm.Price,
 		SellerID:    meta.ID, // get the user ID from meta acquired from context
 		CountryID:   productForm.CountryID,
+		CategoryID:  productForm.CategoryID,
+		TripID:      productForm.TripID,
+		Tags:        productForm.Tags,
 		FromDate:    fromDateInTime,
 		ToDate:      toDateInTime,
-		Image:       filePath,
+		Images:      images,
+	}
+	if len(images) > 0 {
+		product.Image = images[0].URL
+	}
+	for _, variantForm := range productForm.Variants {
+		product.Variants = append(product.Variants, entity.ProductVariant{
+			Options: variantForm.Options,
+			Price:   variantForm.Price,
+			Stock:   variantForm.Stock,
+		})
 	}
 	product.NormalizeCreate()
 
@@ -142,6 +180,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request, p ht
 	return nil
 }
 
+// UpdateProduct overwrites every field of the product, PatchProduct only writes the provided fields
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
 	if err != nil {
@@ -171,6 +210,35 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request, p
 	return nil
 }
 
+// PatchProduct updates only the fields provided in the request body
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ProductUpdateForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	productPublic, err := h.uc.PatchProduct(ctx, productID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, productPublic, "product successfully updated")
+	return nil
+}
+
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
 	if err != nil {
@@ -191,3 +259,171 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request, p
 	api.OK(w, nil, "product has been successfully deleted")
 	return nil
 }
+
+func (h *ProductHandler) AddProductImage(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ProductImageForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	images, err := h.uc.AddProductImage(ctx, productID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, images, "")
+	return nil
+}
+
+func (h *ProductHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ProductImageOrderForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	images, err := h.uc.ReorderProductImages(ctx, productID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, images, "")
+	return nil
+}
+
+func (h *ProductHandler) RemoveProductImage(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	imageID, err := strconv.ParseInt(p.ByName("image_id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	images, err := h.uc.RemoveProductImage(ctx, productID, imageID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, images, "product image has been successfully deleted")
+	return nil
+}
+
+func (h *ProductHandler) AddProductVariant(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ProductVariantForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	product, err := h.uc.AddProductVariant(ctx, productID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, product, "")
+	return nil
+}
+
+func (h *ProductHandler) UpdateProductVariant(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	variantID, err := strconv.ParseInt(p.ByName("variant_id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ProductVariantForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	product, err := h.uc.UpdateProductVariant(ctx, productID, variantID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, product, "product variant successfully updated")
+	return nil
+}
+
+func (h *ProductHandler) RemoveProductVariant(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	productID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	variantID, err := strconv.ParseInt(p.ByName("variant_id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	product, err := h.uc.RemoveProductVariant(ctx, productID, variantID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, product, "product variant has been successfully deleted")
+	return nil
+}
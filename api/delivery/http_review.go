@@ -0,0 +1,124 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+type ReviewHandler struct {
+	uc api.ReviewUsecase
+}
+
+func NewReviewHandler(uc api.ReviewUsecase) ReviewHandler {
+	return ReviewHandler{uc}
+}
+
+func (h *ReviewHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.POST("/transactions/:id/review", handler.Decorate(h.CreateReview, handler.UserAuth...))
+	r.PUT("/reviews/:id/reply", handler.Decorate(h.ReplyReview, handler.UserAuth...))
+	r.GET("/users/:id/reviews", handler.Decorate(h.GetUserReviews, handler.AppAuth...))
+
+	return nil
+}
+
+// CreateReview reviews a finished transaction of the logged in buyer
+func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	transactionID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ReviewForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	review, err := h.uc.CreateReview(ctx, transactionID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, review, "")
+	return nil
+}
+
+func (h *ReviewHandler) ReplyReview(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	reviewID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.ReviewReplyForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	review, err := h.uc.ReplyReview(ctx, reviewID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, review, "")
+	return nil
+}
+
+// GetUserReviews lists the reviews a user received as a seller, latest first
+func (h *ReviewHandler) GetUserReviews(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	userID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	reviews, total, err := h.uc.GetUserReviews(r.Context(), userID, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting user reviews")
+	}
+
+	var nextCursor string
+	if len(reviews) > 0 {
+		last := reviews[len(reviews)-1]
+		// reviews are ordered by their creation, see GetSellerReviews
+		nextCursor = page.NextCursor(len(reviews), last.CreatedAt, last.ID)
+	}
+
+	meta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, reviews, "", meta)
+	return nil
+}
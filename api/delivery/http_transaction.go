@@ -57,27 +57,35 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 
 func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
 	helper := api.NewQueryHelper(r)
-	limit := helper.GetInt("limit", 10)
-	offset := helper.GetInt("offset", 0)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
 
 	// get filters
-	filters := helper.GetFilters()
+	spec, err := helper.GetFilterSpec(entity.TransactionFilterSchema)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
 
+	// by default: get all transactions of the requesting user, as buyer or seller
 	ctx := r.Context()
 	reqMeta := api.MetaFromContext(ctx)
-	// get requesting user id
-	requestingUserID := strconv.FormatInt(reqMeta.ID, 10)
-	// by default: get all transactions by the user
-	filters["seller_id"] = requestingUserID
-	filters["buyer_id"] = requestingUserID
-
-	transactions, total, err := h.transactionUsecase.GetTransactions(ctx, filters, limit, offset)
+	transactions, total, err := h.transactionUsecase.GetTransactions(ctx, reqMeta.ID, spec, page)
 	if err != nil {
 		api.Error(w, err)
 		return err
 	}
 
-	meta := api.NewMetaPagination(http.StatusOK, limit, offset, int(total))
+	var nextCursor string
+	if len(transactions) > 0 {
+		last := transactions[len(transactions)-1]
+		nextCursor = page.NextCursor(len(transactions), last.UpdatedAt, last.ID)
+	}
+
+	meta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
 	api.OKWithMeta(w, transactions, "", meta)
 	return nil
 }
@@ -0,0 +1,155 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+)
+
+type TripHandler struct {
+	uc api.TripUsecase
+}
+
+func NewTripHandler(uc api.TripUsecase) TripHandler {
+	return TripHandler{uc}
+}
+
+func (h *TripHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.GET("/trips", handler.Decorate(h.GetTrips, handler.AppAuth...))
+	r.GET("/trips/:id", handler.Decorate(h.GetTrip, handler.AppAuth...))
+	r.POST("/trips", handler.Decorate(h.CreateTrip, handler.UserAuth...))
+	r.PUT("/trips/:id", handler.Decorate(h.UpdateTrip, handler.UserAuth...))
+	r.DELETE("/trips/:id", handler.Decorate(h.DeleteTrip, handler.UserAuth...))
+
+	return nil
+}
+
+func (h *TripHandler) GetTrips(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	page, err := helper.GetPage(10)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	spec, err := helper.GetFilterSpec(entity.TripFilterSchema)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	trips, total, err := h.uc.GetTrips(r.Context(), spec, page)
+	if err != nil {
+		api.Error(w, err)
+		return errors.Wrap(err, "error getting trips")
+	}
+
+	var nextCursor string
+	if len(trips) > 0 {
+		last := trips[len(trips)-1]
+		nextCursor = page.NextCursor(len(trips), last.UpdatedAt, last.ID)
+	}
+
+	meta := api.NewPageMeta(http.StatusOK, page, total, nextCursor)
+	api.OKWithMeta(w, trips, "", meta)
+	return nil
+}
+
+func (h *TripHandler) GetTrip(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	tripID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	trip, err := h.uc.GetTrip(r.Context(), tripID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, trip, "")
+	return nil
+}
+
+func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.TripForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	trip, err := h.uc.CreateTrip(ctx, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.Created(w, trip, "")
+	return nil
+}
+
+// UpdateTrip overwrites the trip. The new country and dates are written to the
+// products listed for the trip too
+func (h *TripHandler) UpdateTrip(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	tripID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	var form entity.TripForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	trip, err := h.uc.UpdateTrip(ctx, tripID, meta.ID, &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, trip, "")
+	return nil
+}
+
+func (h *TripHandler) DeleteTrip(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+	tripID, err := strconv.ParseInt(p.ByName("id"), 10, 64)
+	if err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	meta := api.MetaFromContext(ctx)
+	err = h.uc.DeleteTrip(ctx, tripID, meta.ID)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Perjalanan berhasil dihapus")
+	return nil
+}
@@ -33,6 +33,7 @@ func (h *UserHandler) RegisterHandler(r *httprouter.Router) error {
 	r.POST("/users", handler.Decorate(h.Register, handler.AppAuth...))
 	r.GET("/users/:id", handler.Decorate(h.GetUser, handler.AppAuth...))
 	r.GET("/me", handler.Decorate(h.GetMe, handler.UserAuth...))
+	r.PATCH("/me", handler.Decorate(h.UpdateMe, handler.UserAuth...))
 
 	return nil
 }
@@ -85,7 +86,7 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request, _ httprouter
 	claims := api.MetaFromContext(ctx)
 
 	// get user from claim in context
-	user, err := h.uc.GetUser(ctx, claims.ID)
+	user, err := h.uc.GetProfile(ctx, claims.ID)
 	if err != nil {
 		api.Error(w, err)
 		return err
@@ -94,3 +95,24 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request, _ httprouter
 	api.OK(w, user, "")
 	return nil
 }
+
+// UpdateMe is a handler to update the logged in user's profile
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	var form entity.UserUpdateForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	user, err := h.uc.UpdateProfile(ctx, api.GetUserID(ctx), &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, user, "Profil berhasil diperbarui")
+	return nil
+}
@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// VerificationHandler holds VerificationUsecase to be used in the handler
+type VerificationHandler struct {
+	uc api.VerificationUsecase
+}
+
+// NewVerificationHandler creates a new instance of VerificationHandler
+// with the provided VerificationUsecase
+func NewVerificationHandler(uc api.VerificationUsecase) VerificationHandler {
+	return VerificationHandler{uc}
+}
+
+// RegisterHandler registers all route for this handler
+func (h *VerificationHandler) RegisterHandler(r *httprouter.Router) error {
+	if r == nil {
+		return errors.New("Router must not be nil")
+	}
+
+	r.POST("/me/verifications/email", handler.Decorate(h.RequestEmailVerification, handler.UserAuth...))
+	r.GET("/verifications/email", handler.Decorate(h.VerifyEmail, handler.AppAuth...))
+	r.POST("/me/verifications/phone", handler.Decorate(h.RequestPhoneVerification, handler.UserAuth...))
+	r.POST("/me/verifications/phone/confirm", handler.Decorate(h.VerifyPhone, handler.UserAuth...))
+
+	return nil
+}
+
+// RequestEmailVerification is a handler to send a verification link to the logged in user's email
+func (h *VerificationHandler) RequestEmailVerification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	ctx := r.Context()
+	response, err := h.uc.RequestEmailVerification(ctx, api.GetUserID(ctx))
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, response, "Link verifikasi telah dikirim ke email kamu")
+	return nil
+}
+
+// VerifyEmail is a handler for the verification link sent to the user's email
+func (h *VerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	helper := api.NewQueryHelper(r)
+	token := helper.GetString("token")
+
+	ctx := r.Context()
+	err := h.uc.VerifyEmail(ctx, token)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Email berhasil diverifikasi")
+	return nil
+}
+
+// RequestPhoneVerification is a handler to send an OTP to the logged in user's phone
+func (h *VerificationHandler) RequestPhoneVerification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	ctx := r.Context()
+	response, err := h.uc.RequestPhoneVerification(ctx, api.GetUserID(ctx))
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, response, "Kode OTP telah dikirim ke nomor telepon kamu")
+	return nil
+}
+
+// VerifyPhone is a handler to confirm the OTP sent to the logged in user's phone
+func (h *VerificationHandler) VerifyPhone(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+	decoder := json.NewDecoder(r.Body)
+	var form entity.PhoneVerificationForm
+	if err := decoder.Decode(&form); err != nil {
+		api.Error(w, api.ErrInvalidParameter)
+		return err
+	}
+
+	ctx := r.Context()
+	err := h.uc.VerifyPhone(ctx, api.GetUserID(ctx), &form)
+	if err != nil {
+		api.Error(w, err)
+		return err
+	}
+
+	api.OK(w, nil, "Nomor telepon berhasil diverifikasi")
+	return nil
+}
@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// AccountExport is the collection of every data we store about a user,
+// returned when the user requests a copy of its data
+type AccountExport struct {
+	Profile          *UserPublic             `json:"profile"`
+	Language         string                  `json:"language"`
+	EmailPreferences EmailPreferences        `json:"email_preferences"`
+	Addresses        []UserAddressPublic     `json:"addresses"`
+	Products         []ProductPublic         `json:"products"`
+	Trips            []TripPublic            `json:"trips"`
+	BuyRequests      []BuyRequestPublic      `json:"buy_requests"`
+	Offers           []BuyRequestOfferPublic `json:"offers"`
+	Transactions     []TransactionExport     `json:"transactions"`
+	Invoices         []InvoicePublic         `json:"invoices"`
+	Reviews          []ReviewPublic          `json:"reviews"`
+	ReceivedReviews  []ReviewPublic          `json:"received_reviews"`
+	Conversations    []ConversationExport    `json:"conversations"`
+	Notifications    []NotificationPublic    `json:"notifications"`
+	Devices          []DevicePublic          `json:"devices"`
+	Logins           []UserLoginPublic       `json:"logins"`
+	ExportedAt       time.Time               `json:"exported_at"`
+}
+
+// ConversationExport is a conversation of the user in an account export, along
+// with all of its messages
+type ConversationExport struct {
+	ConversationPublic
+	Messages []MessagePublic `json:"messages"`
+}
+
+// TransactionExport is the flat representation of a transaction in an account export
+type TransactionExport struct {
+	ID             int64      `json:"id"`
+	Role           string     `json:"role"`
+	ProductID      int64      `json:"product_id"`
+	VariantID      *int64     `json:"variant_id"`
+	BuyerID        int64      `json:"buyer_id"`
+	SellerID       int64      `json:"seller_id"`
+	BuyerAddressID int64      `json:"buyer_address_id"`
+	InvoiceID      *int64     `json:"invoice_id"`
+	Quantity       uint       `json:"quantity"`
+	Notes          string     `json:"notes"`
+	TotalPrice     int64      `json:"total_price"`
+	Status         string     `json:"status"`
+	PaidAt         *time.Time `json:"paid_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
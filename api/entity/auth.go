@@ -11,6 +11,10 @@ import (
 type AuthCredentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+
+	// IPAddress and UserAgent are filled from the request, not from the body
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // Normalize is a method to normalize all field values
@@ -26,6 +30,8 @@ type ResourceClaims struct {
 	Name         string    `json:"name"`
 	Phone        string    `json:"phone"`
 	RegisteredAt time.Time `json:"registered_at"`
+	// Admin is loaded from the user on every request, it is never read from the token
+	Admin bool `json:"-"`
 	jwt.StandardClaims
 }
 
@@ -35,3 +41,78 @@ type AuthResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
+
+// LoginThrottle stores database row representations of failed login counters,
+// keyed by either an account email or an IP address
+type LoginThrottle struct {
+	ID           int64      `db:"id"`
+	ThrottleKey  string     `db:"throttle_key"`
+	FailedCount  int        `db:"failed_count"`
+	LockedUntil  *time.Time `db:"locked_until"`
+	LastFailedAt time.Time  `db:"last_failed_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
+
+// IsLocked tells whether the throttled key is still locked out
+func (t *LoginThrottle) IsLocked() bool {
+	return t.LockedUntil != nil && time.Now().Before(*t.LockedUntil)
+}
+
+// UserLogin stores database row representations of a successful login
+type UserLogin struct {
+	ID        int64     `db:"id"`
+	UserID    int64     `db:"user_id"`
+	IPAddress string    `db:"ip_address"`
+	UserAgent string    `db:"user_agent"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ConvertToPublic converts the UserLogin model to public representations
+func (l *UserLogin) ConvertToPublic() UserLoginPublic {
+	return UserLoginPublic{
+		ID:         l.ID,
+		IPAddress:  l.IPAddress,
+		UserAgent:  l.UserAgent,
+		LoggedInAt: l.CreatedAt,
+	}
+}
+
+// UserLoginPublic is the public representation of UserLogin
+type UserLoginPublic struct {
+	ID         int64     `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	LoggedInAt time.Time `json:"logged_in_at"`
+}
+
+// OIDCAuthForm is the request body to sign in with a third-party ID token
+type OIDCAuthForm struct {
+	IDToken string `json:"id_token"`
+
+	// IPAddress and UserAgent are filled from the request, not from the body
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// OIDCIdentity is the verified identity asserted by a third-party ID token
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// UserIdentity stores database row representations of a third-party identity
+// linked to a user
+type UserIdentity struct {
+	ID        int64     `db:"id"`
+	UserID    int64     `db:"user_id"`
+	Issuer    string    `db:"issuer"`
+	Subject   string    `db:"subject"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
@@ -0,0 +1,255 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	BuyRequestStatusOpen = iota
+	BuyRequestStatusFulfilled
+	BuyRequestStatusCancelled
+)
+
+var mapBuyRequestStatusToString = map[int]string{
+	BuyRequestStatusOpen:      "open",
+	BuyRequestStatusFulfilled: "fulfilled",
+	BuyRequestStatusCancelled: "cancelled",
+}
+
+const (
+	BuyRequestOfferStatusPending = iota
+	BuyRequestOfferStatusAccepted
+	BuyRequestOfferStatusRejected
+	BuyRequestOfferStatusWithdrawn
+)
+
+var mapBuyRequestOfferStatusToString = map[int]string{
+	BuyRequestOfferStatusPending:   "pending",
+	BuyRequestOfferStatusAccepted:  "accepted",
+	BuyRequestOfferStatusRejected:  "rejected",
+	BuyRequestOfferStatusWithdrawn: "withdrawn",
+}
+
+// BuyRequest stores database row representations of a buyer "titip" request:
+// an item a buyer wants from a country, waiting for a travelling seller
+type BuyRequest struct {
+	ID              int64     `db:"id"`
+	BuyerID         int64     `db:"buyer_id"`
+	CountryID       int64     `db:"country_id"`
+	Title           string    `db:"title"`
+	Description     string    `db:"description"`
+	Image           string    `db:"image"`
+	MaxBudget       uint      `db:"max_budget"`
+	Quantity        uint      `db:"quantity"`
+	NeededBy        time.Time `db:"needed_by"`
+	Status          int       `db:"status"`
+	AcceptedOfferID *int64    `db:"accepted_offer_id"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+// IsOpen tells whether the request still accepts offers on the day of now
+func (b *BuyRequest) IsOpen(now time.Time) bool {
+	return b.Status == BuyRequestStatusOpen && b.NeededBy.Format("2006-01-02") >= now.Format("2006-01-02")
+}
+
+// ConvertToPublic converts the request to its public representation
+func (b *BuyRequest) ConvertToPublic(c *Country, u *User, offerCount int64) BuyRequestPublic {
+	return BuyRequestPublic{
+		ID:              b.ID,
+		Title:           b.Title,
+		Description:     b.Description,
+		Image:           b.Image,
+		MaxBudget:       b.MaxBudget,
+		Quantity:        b.Quantity,
+		NeededBy:        b.NeededBy.Format("2006-01-02"),
+		Status:          mapBuyRequestStatusToString[b.Status],
+		AcceptedOfferID: b.AcceptedOfferID,
+		OfferCount:      offerCount,
+		Buyer:           u.ConvertToPublic(),
+		Country:         c,
+		CreatedAt:       b.CreatedAt,
+		UpdatedAt:       b.UpdatedAt,
+	}
+}
+
+type BuyRequestPublic struct {
+	ID              int64       `json:"id"`
+	Title           string      `json:"title"`
+	Description     string      `json:"description"`
+	Image           string      `json:"image"`
+	MaxBudget       uint        `json:"max_budget"`
+	Quantity        uint        `json:"quantity"`
+	NeededBy        string      `json:"needed_by"`
+	Status          string      `json:"status"`
+	AcceptedOfferID *int64      `json:"accepted_offer_id"`
+	OfferCount      int64       `json:"offer_count"`
+	Buyer           *UserPublic `json:"buyer,omitempty"`
+	Country         *Country    `json:"country,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// BuyRequestForm is the request body to create a buy request
+type BuyRequestForm struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	CountryID   int64  `json:"country_id"`
+	ImageFile   string `json:"image_file"`
+	MaxBudget   uint   `json:"max_budget"`
+	Quantity    uint   `json:"quantity"`
+	NeededBy    string `json:"needed_by"`
+}
+
+// Normalize is a method to normalize all field values
+func (f *BuyRequestForm) Normalize() {
+	f.Title = strings.TrimSpace(f.Title)
+	f.Description = strings.TrimSpace(f.Description)
+	if f.Quantity == 0 {
+		f.Quantity = 1
+	}
+}
+
+// Validate is a function to validate the buy request form
+func (f *BuyRequestForm) Validate() error {
+	if len(f.Title) < 3 || len(f.Title) > 50 {
+		return errors.New("Nama barang harus terdiri dari 3 sampai 50 karakter")
+	}
+
+	if f.CountryID < 1 {
+		return errors.New("Harus memilih negara asal barang")
+	}
+
+	// the photo becomes the product cover once an offer is accepted
+	if f.ImageFile == "" {
+		return errors.New("Harus upload foto barang terlebih dahulu")
+	}
+
+	if f.MaxBudget < 1 {
+		return errors.New("Anggaran maksimum tidak boleh kosong atau negatif")
+	}
+
+	neededBy, err := time.Parse("2006-01-02", f.NeededBy)
+	if err != nil {
+		return errors.New("Format tanggal dibutuhkan harus YYYY-MM-DD")
+	}
+	if neededBy.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+		return errors.New("Tanggal dibutuhkan tidak boleh di waktu yang lalu")
+	}
+
+	return nil
+}
+
+// BuyRequestOffer stores database row representations of a seller offer on a
+// buy request. Price is the price of one item
+type BuyRequestOffer struct {
+	ID            int64     `db:"id"`
+	BuyRequestID  int64     `db:"buy_request_id"`
+	SellerID      int64     `db:"seller_id"`
+	Price         uint      `db:"price"`
+	FromDate      time.Time `db:"from_date"`
+	ToDate        time.Time `db:"to_date"`
+	Notes         string    `db:"notes"`
+	Status        int       `db:"status"`
+	ProductID     *int64    `db:"product_id"`
+	TransactionID *int64    `db:"transaction_id"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// ConvertToPublic converts the offer to its public representation
+func (o *BuyRequestOffer) ConvertToPublic(seller *User) BuyRequestOfferPublic {
+	return BuyRequestOfferPublic{
+		ID:            o.ID,
+		BuyRequestID:  o.BuyRequestID,
+		Seller:        seller.ConvertToPublic(),
+		Price:         o.Price,
+		FromDate:      o.FromDate.Format("2006-01-02"),
+		ToDate:        o.ToDate.Format("2006-01-02"),
+		Notes:         o.Notes,
+		Status:        mapBuyRequestOfferStatusToString[o.Status],
+		ProductID:     o.ProductID,
+		TransactionID: o.TransactionID,
+		CreatedAt:     o.CreatedAt,
+		UpdatedAt:     o.UpdatedAt,
+	}
+}
+
+type BuyRequestOfferPublic struct {
+	ID            int64       `json:"id"`
+	BuyRequestID  int64       `json:"buy_request_id"`
+	Seller        *UserPublic `json:"seller,omitempty"`
+	Price         uint        `json:"price"`
+	FromDate      string      `json:"from_date"`
+	ToDate        string      `json:"to_date"`
+	Notes         string      `json:"notes"`
+	Status        string      `json:"status"`
+	ProductID     *int64      `json:"product_id"`
+	TransactionID *int64      `json:"transaction_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// BuyRequestOfferForm is the request body of a seller offer. FromDate and ToDate
+// are the trip window of the seller
+type BuyRequestOfferForm struct {
+	Price    uint   `json:"price"`
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+	Notes    string `json:"notes"`
+}
+
+// Validate is a function to validate the offer against the buy request
+func (f *BuyRequestOfferForm) Validate(request *BuyRequest) error {
+	f.Notes = strings.TrimSpace(f.Notes)
+
+	if f.Price < 1 {
+		return errors.New("Harga penawaran tidak boleh kosong atau negatif")
+	}
+
+	if f.Price > request.MaxBudget {
+		return errors.New("Harga penawaran melebihi anggaran maksimum pembeli")
+	}
+
+	fromDate, err := time.Parse("2006-01-02", f.FromDate)
+	if err != nil {
+		return errors.New("Format tanggal mulai perjalanan harus YYYY-MM-DD")
+	}
+
+	toDate, err := time.Parse("2006-01-02", f.ToDate)
+	if err != nil {
+		return errors.New("Format tanggal akhir perjalanan harus YYYY-MM-DD")
+	}
+
+	if toDate.Before(fromDate) {
+		return errors.New("Tanggal akhir perjalanan tidak boleh sebelum tanggal mulai perjalanan")
+	}
+
+	if toDate.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+		return errors.New("Tanggal akhir perjalanan tidak boleh di waktu yang lalu")
+	}
+
+	if len(f.Notes) > 200 {
+		return errors.New("Catatan penawaran tidak boleh lebih dari 200 karakter")
+	}
+
+	return nil
+}
+
+// AcceptOfferForm is the request body to accept an offer, which places an order
+// to the address
+type AcceptOfferForm struct {
+	AddressID int64  `json:"address_id"`
+	Notes     string `json:"notes"`
+}
+
+// Validate is a function to validate the accept offer form
+func (f *AcceptOfferForm) Validate() error {
+	if f.AddressID < 1 {
+		return errors.New("Alamat pengiriman belum dipilih")
+	}
+	return nil
+}
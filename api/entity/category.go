@@ -0,0 +1,151 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+)
+
+// Category represents a node of the product category tree in database.
+// Root categories have no parent
+type Category struct {
+	ID        int64     `json:"id" db:"id"`
+	ParentID  *int64    `json:"parent_id" db:"parent_id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+// CategoryForm is the request body to create or update a category
+type CategoryForm struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+// Normalize is a method to normalize all field values. The slug is derived
+// from the name when it is not provided
+func (f *CategoryForm) Normalize() {
+	f.Name = strings.TrimSpace(f.Name)
+	f.Slug = strings.TrimSpace(f.Slug)
+	if f.Slug == "" {
+		f.Slug = f.Name
+	}
+	f.Slug = slug.Make(f.Slug)
+}
+
+// Validate is a function to validate the category form
+func (f *CategoryForm) Validate() error {
+	if len(f.Name) < 2 || len(f.Name) > 50 {
+		return errors.New("Nama kategori harus terdiri dari 2 sampai 50 karakter")
+	}
+
+	if f.Slug == "" {
+		return errors.New("Slug kategori tidak valid")
+	}
+
+	if f.ParentID != nil && *f.ParentID < 1 {
+		return errors.New("Kategori induk tidak valid")
+	}
+
+	return nil
+}
+
+// CategoryPublic is a node of the public category tree. ProductCount includes
+// the products of the descendant categories
+type CategoryPublic struct {
+	ID           int64            `json:"id"`
+	ParentID     *int64           `json:"parent_id"`
+	Name         string           `json:"name"`
+	Slug         string           `json:"slug"`
+	ProductCount int64            `json:"product_count"`
+	Children     []CategoryPublic `json:"children"`
+}
+
+// CategoryTree indexes a flat list of categories by their parent
+type CategoryTree struct {
+	categories map[int64]Category
+	children   map[int64][]int64
+	roots      []int64
+}
+
+// NewCategoryTree builds the tree of the categories, keeping their order
+func NewCategoryTree(categories []Category) *CategoryTree {
+	tree := &CategoryTree{
+		categories: map[int64]Category{},
+		children:   map[int64][]int64{},
+		roots:      []int64{},
+	}
+	for _, category := range categories {
+		tree.categories[category.ID] = category
+	}
+	for _, category := range categories {
+		// categories whose parent is missing are shown at the top level
+		if category.ParentID == nil {
+			tree.roots = append(tree.roots, category.ID)
+			continue
+		}
+		if _, ok := tree.categories[*category.ParentID]; !ok {
+			tree.roots = append(tree.roots, category.ID)
+			continue
+		}
+		tree.children[*category.ParentID] = append(tree.children[*category.ParentID], category.ID)
+	}
+	return tree
+}
+
+// Descendants returns the ID of the category followed by the IDs of all its
+// descendants
+func (t *CategoryTree) Descendants(ID int64) []int64 {
+	ids := []int64{ID}
+	seen := map[int64]bool{ID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// IsDescendant tells whether the category is the ancestor category itself
+// or one of its descendants
+func (t *CategoryTree) IsDescendant(ID, ancestorID int64) bool {
+	for _, id := range t.Descendants(ancestorID) {
+		if id == ID {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertToPublic returns the public tree. counts holds the number of products
+// directly listed in each category
+func (t *CategoryTree) ConvertToPublic(counts map[int64]int64) []CategoryPublic {
+	return t.convertNodes(t.roots, counts)
+}
+
+func (t *CategoryTree) convertNodes(ids []int64, counts map[int64]int64) []CategoryPublic {
+	nodes := []CategoryPublic{}
+	for _, id := range ids {
+		category := t.categories[id]
+		node := CategoryPublic{
+			ID:           category.ID,
+			ParentID:     category.ParentID,
+			Name:         category.Name,
+			Slug:         category.Slug,
+			ProductCount: counts[category.ID],
+			Children:     t.convertNodes(t.children[id], counts),
+		}
+		for _, child := range node.Children {
+			node.ProductCount += child.ProductCount
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
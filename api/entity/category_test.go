@@ -0,0 +1,45 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sejastip.id/api/entity"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// beauty > skincare > serum, food, and a category whose parent was deleted
+var categories = []entity.Category{
+	{ID: 1, Name: "Kecantikan", Slug: "kecantikan"},
+	{ID: 2, ParentID: int64Ptr(1), Name: "Perawatan Kulit", Slug: "perawatan-kulit"},
+	{ID: 3, ParentID: int64Ptr(2), Name: "Serum", Slug: "serum"},
+	{ID: 4, Name: "Makanan", Slug: "makanan"},
+	{ID: 5, ParentID: int64Ptr(99), Name: "Mainan", Slug: "mainan"},
+}
+
+func TestCategoryTreeDescendants(t *testing.T) {
+	tree := entity.NewCategoryTree(categories)
+
+	assert.Equal(t, []int64{1, 2, 3}, tree.Descendants(1))
+	assert.Equal(t, []int64{4}, tree.Descendants(4))
+	assert.True(t, tree.IsDescendant(3, 1))
+	assert.True(t, tree.IsDescendant(1, 1))
+	assert.False(t, tree.IsDescendant(1, 3))
+}
+
+func TestCategoryTreeCountsIncludeDescendants(t *testing.T) {
+	nodes := entity.NewCategoryTree(categories).ConvertToPublic(map[int64]int64{1: 1, 3: 2, 4: 5})
+
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, int64(3), nodes[0].ProductCount)
+	assert.Equal(t, int64(2), nodes[0].Children[0].ProductCount)
+	assert.Equal(t, int64(2), nodes[0].Children[0].Children[0].ProductCount)
+	assert.Equal(t, int64(5), nodes[1].ProductCount)
+
+	// orphans are shown at the top level
+	assert.Equal(t, "mainan", nodes[2].Slug)
+	assert.Equal(t, []entity.CategoryPublic{}, nodes[2].Children)
+}
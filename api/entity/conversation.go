@@ -0,0 +1,199 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Conversation stores database row representations of a chat between the buyer
+// and the seller, either about a transaction or an enquiry on a product. Scope is
+// unique, so there is one conversation per transaction and per product enquirer.
+// The last read IDs are the read receipts of both participants
+type Conversation struct {
+	ID               int64      `db:"id"`
+	Scope            string     `db:"scope"`
+	ProductID        int64      `db:"product_id"`
+	TransactionID    *int64     `db:"transaction_id"`
+	BuyerID          int64      `db:"buyer_id"`
+	SellerID         int64      `db:"seller_id"`
+	BuyerLastReadID  int64      `db:"buyer_last_read_id"`
+	SellerLastReadID int64      `db:"seller_last_read_id"`
+	LastMessageAt    *time.Time `db:"last_message_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at"`
+}
+
+// TransactionConversationScope returns the scope of the conversation of a transaction
+func TransactionConversationScope(transactionID int64) string {
+	return fmt.Sprintf("transaction:%d", transactionID)
+}
+
+// EnquiryConversationScope returns the scope of the conversation of a buyer
+// enquiring about a product
+func EnquiryConversationScope(productID, buyerID int64) string {
+	return fmt.Sprintf("product:%d:buyer:%d", productID, buyerID)
+}
+
+// IsParticipant tells whether the user is the buyer or the seller of the conversation
+func (c *Conversation) IsParticipant(userID int64) bool {
+	return userID == c.BuyerID || userID == c.SellerID
+}
+
+// Counterpart returns the other participant of the conversation
+func (c *Conversation) Counterpart(userID int64) int64 {
+	if userID == c.BuyerID {
+		return c.SellerID
+	}
+	return c.BuyerID
+}
+
+// LastReadID returns the ID of the last message read by a participant
+func (c *Conversation) LastReadID(userID int64) int64 {
+	if userID == c.BuyerID {
+		return c.BuyerLastReadID
+	}
+	return c.SellerLastReadID
+}
+
+// MarkRead moves the read receipt of a participant up to the message, it never
+// moves backwards
+func (c *Conversation) MarkRead(userID, messageID int64) {
+	if userID == c.BuyerID && messageID > c.BuyerLastReadID {
+		c.BuyerLastReadID = messageID
+	}
+	if userID == c.SellerID && messageID > c.SellerLastReadID {
+		c.SellerLastReadID = messageID
+	}
+}
+
+// ConvertToPublic converts the conversation to its public representation.
+// UnreadCount is the number of unread messages of the requesting user
+func (c *Conversation) ConvertToPublic(buyer, seller *User, unreadCount int64) ConversationPublic {
+	return ConversationPublic{
+		ID:            c.ID,
+		ProductID:     c.ProductID,
+		TransactionID: c.TransactionID,
+		Buyer:         buyer.ConvertToPublic(),
+		Seller:        seller.ConvertToPublic(),
+		UnreadCount:   unreadCount,
+		LastMessageAt: c.LastMessageAt,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
+	}
+}
+
+type ConversationPublic struct {
+	ID            int64       `json:"id"`
+	ProductID     int64       `json:"product_id"`
+	TransactionID *int64      `json:"transaction_id"`
+	Buyer         *UserPublic `json:"buyer,omitempty"`
+	Seller        *UserPublic `json:"seller,omitempty"`
+	UnreadCount   int64       `json:"unread_count"`
+	LastMessageAt *time.Time  `json:"last_message_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// ConversationForm is the request body to open the conversation of a transaction,
+// or of an enquiry on a product. Exactly one of them must be set
+type ConversationForm struct {
+	TransactionID *int64 `json:"transaction_id"`
+	ProductID     *int64 `json:"product_id"`
+}
+
+// Validate is a function to validate the conversation form
+func (f *ConversationForm) Validate() error {
+	if (f.TransactionID == nil) == (f.ProductID == nil) {
+		return errors.New("Pilih salah satu transaksi atau produk yang ingin dibicarakan")
+	}
+
+	if f.TransactionID != nil && *f.TransactionID < 1 {
+		return errors.New("Transaksi tidak valid")
+	}
+
+	if f.ProductID != nil && *f.ProductID < 1 {
+		return errors.New("Produk tidak valid")
+	}
+
+	return nil
+}
+
+// Message stores database row representations of a chat message. Attachment is
+// the URL of an attached image
+type Message struct {
+	ID             int64     `db:"id"`
+	ConversationID int64     `db:"conversation_id"`
+	SenderID       int64     `db:"sender_id"`
+	Body           string    `db:"body"`
+	Attachment     string    `db:"attachment"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// ConvertToPublic converts the message to its public representation. A message
+// is read once the recipient read receipt reaches it
+func (m *Message) ConvertToPublic(c *Conversation) MessagePublic {
+	return MessagePublic{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		Attachment:     m.Attachment,
+		Read:           m.ID <= c.LastReadID(c.Counterpart(m.SenderID)),
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+type MessagePublic struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	SenderID       int64     `json:"sender_id"`
+	Body           string    `json:"body"`
+	Attachment     string    `json:"attachment"`
+	Read           bool      `json:"read"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// MessageForm is the request body to send a message. AttachmentFile is a base64
+// encoded image
+type MessageForm struct {
+	Body           string `json:"body"`
+	AttachmentFile string `json:"attachment_file"`
+}
+
+// Normalize is a method to normalize all field values
+func (f *MessageForm) Normalize() {
+	f.Body = strings.TrimSpace(f.Body)
+}
+
+// Validate is a function to validate the message form
+func (f *MessageForm) Validate() error {
+	if f.Body == "" && f.AttachmentFile == "" {
+		return errors.New("Pesan tidak boleh kosong")
+	}
+
+	if len(f.Body) > 2000 {
+		return errors.New("Pesan tidak boleh lebih dari 2000 karakter")
+	}
+
+	return nil
+}
+
+// ReadReceiptForm is the request body to mark the messages of a conversation as
+// read, up to and including the message
+type ReadReceiptForm struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// Validate is a function to validate the read receipt form
+func (f *ReadReceiptForm) Validate() error {
+	if f.MessageID < 1 {
+		return errors.New("Pesan tidak valid")
+	}
+	return nil
+}
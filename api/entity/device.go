@@ -1,7 +1,10 @@
 package entity
 
 import (
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type Device struct {
@@ -14,6 +17,49 @@ type Device struct {
 	UpdatedAt time.Time `db:"updated_at"`
 }
 
+// ConvertToPublic converts the Device model to public representations
+func (d *Device) ConvertToPublic() DevicePublic {
+	return DevicePublic{
+		ID:        d.ID,
+		DeviceID:  d.DeviceID,
+		Platform:  d.Platform,
+		UserAgent: d.UserAgent,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
+
+// DevicePublic is the public representation of Device
+type DevicePublic struct {
+	ID        int64     `json:"id"`
+	DeviceID  string    `json:"device_id"`
+	Platform  string    `json:"platform"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type DeviceForm struct {
 	DeviceID string `json:"device_id"`
 }
+
+// Validate is a function to validate the device form
+func (f *DeviceForm) Validate() error {
+	f.DeviceID = strings.TrimSpace(f.DeviceID)
+
+	if f.DeviceID == "" {
+		return errors.New("Device ID tidak boleh kosong")
+	}
+
+	if len(f.DeviceID) > 270 {
+		return errors.New("Device ID tidak valid")
+	}
+
+	return nil
+}
+
+// InvalidDevicesMessage lists the device IDs the push service rejected, as
+// reported back by the notification function
+type InvalidDevicesMessage struct {
+	DeviceIDs []string `json:"device_ids"`
+}
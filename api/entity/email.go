@@ -0,0 +1,373 @@
+package entity
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strconv"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// email categories, users can opt out of each of them
+const (
+	// EmailCategoryOrder covers the progress of an order: placed, shipped and finished
+	EmailCategoryOrder = "order"
+	// EmailCategoryPayment covers the receipts: invoice issued and payment confirmed
+	EmailCategoryPayment = "payment"
+)
+
+// transactional email events, each of them having its own templates
+const (
+	EmailEventOrderPlaced      = "order_placed"
+	EmailEventInvoiceIssued    = "invoice_issued"
+	EmailEventPaymentConfirmed = "payment_confirmed"
+	EmailEventOrderShipped     = "order_shipped"
+	EmailEventOrderFinished    = "order_finished"
+)
+
+var emailEventCategories = map[string]string{
+	EmailEventOrderPlaced:      EmailCategoryOrder,
+	EmailEventInvoiceIssued:    EmailCategoryPayment,
+	EmailEventPaymentConfirmed: EmailCategoryPayment,
+	EmailEventOrderShipped:     EmailCategoryOrder,
+	EmailEventOrderFinished:    EmailCategoryOrder,
+}
+
+// EmailCategory returns the category of an email event, empty when the event is unknown
+func EmailCategory(event string) string {
+	return emailEventCategories[event]
+}
+
+// Email is a rendered email, carrying both its HTML and plain text bodies
+type Email struct {
+	To       string
+	Subject  string
+	Text     string
+	HTML     string
+	Category string
+}
+
+// OrderEmailParams are the parameters the order emails are rendered with. Only
+// the fields relevant to the event are set
+type OrderEmailParams struct {
+	Event         string
+	RecipientName string
+	TransactionID int64
+	ProductTitle  string
+	Quantity      uint
+	TotalPrice    int64
+	InvoiceCode   InvoiceNumber
+	PaymentMethod string
+	Courier       string
+	AWBNumber     string
+}
+
+type emailTemplate struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var emailFuncs = map[string]interface{}{
+	"rupiah": FormatRupiah,
+}
+
+// RenderOrderEmail renders the email of the event in the language, falling back
+// to the default language
+func RenderOrderEmail(to, locale string, params OrderEmailParams) (*Email, error) {
+	templates, ok := emailTemplates[params.Event]
+	if !ok {
+		return nil, errors.Errorf("no email template for %s", params.Event)
+	}
+	tmpl, ok := templates[locale]
+	if !ok {
+		tmpl = templates[DefaultLocale]
+	}
+
+	email := &Email{To: to, Category: EmailCategory(params.Event)}
+	var err error
+	if email.Subject, err = renderEmailText(tmpl.Subject, params); err != nil {
+		return nil, errors.Wrap(err, "error rendering email subject")
+	}
+	if email.Text, err = renderEmailText(tmpl.Text, params); err != nil {
+		return nil, errors.Wrap(err, "error rendering email text")
+	}
+
+	t, err := htmltemplate.New("email").Funcs(emailFuncs).Parse(emailLayout)
+	if err == nil {
+		_, err = t.New("body").Parse(tmpl.HTML)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing email HTML")
+	}
+	var html bytes.Buffer
+	err = t.Execute(&html, struct {
+		OrderEmailParams
+		Subject string
+	}{params, email.Subject})
+	if err != nil {
+		return nil, errors.Wrap(err, "error rendering email HTML")
+	}
+	email.HTML = html.String()
+
+	return email, nil
+}
+
+func renderEmailText(text string, params OrderEmailParams) (string, error) {
+	t, err := template.New("email").Funcs(emailFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, params); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// FormatRupiah formats an amount in rupiah with dots as thousand separators, e.g. Rp1.250.000
+func FormatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	var b bytes.Buffer
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "Rp" + b.String()
+}
+
+// emailLayout wraps the HTML body of every email
+const emailLayout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Subject}}</title>
+</head>
+<body style="font-family: Arial, sans-serif; color: #333333; background: #f5f5f5; padding: 24px;">
+<div style="max-width: 560px; margin: 0 auto; background: #ffffff; padding: 24px; border-radius: 4px;">
+<h2 style="color: #e8542e;">Sejastip</h2>
+{{template "body" .}}
+</div>
+</body>
+</html>
+`
+
+var emailTemplates = map[string]map[string]emailTemplate{
+	EmailEventOrderPlaced: {
+		LocaleIndonesian: {
+			Subject: "Pesanan {{.ProductTitle}} diterima",
+			Text: `Hai {{.RecipientName}},
+
+Pesanan kamu sudah kami teruskan ke penjual.
+
+Produk: {{.ProductTitle}}
+Jumlah: {{.Quantity}}
+Total: {{rupiah .TotalPrice}}
+
+Kami akan mengabarimu begitu tagihannya terbit.
+`,
+			HTML: `<p>Hai {{.RecipientName}},</p>
+<p>Pesanan kamu sudah kami teruskan ke penjual.</p>
+<table>
+<tr><td>Produk</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Jumlah</td><td>{{.Quantity}}</td></tr>
+<tr><td>Total</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>
+<p>Kami akan mengabarimu begitu tagihannya terbit.</p>`,
+		},
+		LocaleEnglish: {
+			Subject: "Order of {{.ProductTitle}} received",
+			Text: `Hi {{.RecipientName}},
+
+Your order has been forwarded to the seller.
+
+Product: {{.ProductTitle}}
+Quantity: {{.Quantity}}
+Total: {{rupiah .TotalPrice}}
+
+We will let you know as soon as its invoice is issued.
+`,
+			HTML: `<p>Hi {{.RecipientName}},</p>
+<p>Your order has been forwarded to the seller.</p>
+<table>
+<tr><td>Product</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Quantity</td><td>{{.Quantity}}</td></tr>
+<tr><td>Total</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>
+<p>We will let you know as soon as its invoice is issued.</p>`,
+		},
+	},
+	EmailEventInvoiceIssued: {
+		LocaleIndonesian: {
+			Subject: "Tagihan {{.InvoiceCode}} untuk {{.ProductTitle}}",
+			Text: `Hai {{.RecipientName}},
+
+Tagihan pesananmu sudah terbit.
+
+Nomor tagihan: {{.InvoiceCode}}
+Produk: {{.ProductTitle}}
+Metode pembayaran: {{.PaymentMethod}}
+Total: {{rupiah .TotalPrice}}
+
+Segera lakukan pembayaran agar pesananmu diproses.
+`,
+			HTML: `<p>Hai {{.RecipientName}},</p>
+<p>Tagihan pesananmu sudah terbit.</p>
+<table>
+<tr><td>Nomor tagihan</td><td>{{.InvoiceCode}}</td></tr>
+<tr><td>Produk</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Metode pembayaran</td><td>{{.PaymentMethod}}</td></tr>
+<tr><td>Total</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>
+<p>Segera lakukan pembayaran agar pesananmu diproses.</p>`,
+		},
+		LocaleEnglish: {
+			Subject: "Invoice {{.InvoiceCode}} for {{.ProductTitle}}",
+			Text: `Hi {{.RecipientName}},
+
+The invoice of your order has been issued.
+
+Invoice number: {{.InvoiceCode}}
+Product: {{.ProductTitle}}
+Payment method: {{.PaymentMethod}}
+Total: {{rupiah .TotalPrice}}
+
+Please pay it so your order can be processed.
+`,
+			HTML: `<p>Hi {{.RecipientName}},</p>
+<p>The invoice of your order has been issued.</p>
+<table>
+<tr><td>Invoice number</td><td>{{.InvoiceCode}}</td></tr>
+<tr><td>Product</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Payment method</td><td>{{.PaymentMethod}}</td></tr>
+<tr><td>Total</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>
+<p>Please pay it so your order can be processed.</p>`,
+		},
+	},
+	EmailEventPaymentConfirmed: {
+		LocaleIndonesian: {
+			Subject: "Pembayaran {{.InvoiceCode}} berhasil",
+			Text: `Hai {{.RecipientName}},
+
+Pembayaranmu sudah kami terima. Simpan email ini sebagai bukti pembayaran.
+
+Nomor tagihan: {{.InvoiceCode}}
+Produk: {{.ProductTitle}}
+Jumlah: {{.Quantity}}
+Metode pembayaran: {{.PaymentMethod}}
+Total dibayar: {{rupiah .TotalPrice}}
+`,
+			HTML: `<p>Hai {{.RecipientName}},</p>
+<p>Pembayaranmu sudah kami terima. Simpan email ini sebagai bukti pembayaran.</p>
+<table>
+<tr><td>Nomor tagihan</td><td>{{.InvoiceCode}}</td></tr>
+<tr><td>Produk</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Jumlah</td><td>{{.Quantity}}</td></tr>
+<tr><td>Metode pembayaran</td><td>{{.PaymentMethod}}</td></tr>
+<tr><td>Total dibayar</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>`,
+		},
+		LocaleEnglish: {
+			Subject: "Payment of {{.InvoiceCode}} confirmed",
+			Text: `Hi {{.RecipientName}},
+
+We have received your payment. Keep this email as your receipt.
+
+Invoice number: {{.InvoiceCode}}
+Product: {{.ProductTitle}}
+Quantity: {{.Quantity}}
+Payment method: {{.PaymentMethod}}
+Total paid: {{rupiah .TotalPrice}}
+`,
+			HTML: `<p>Hi {{.RecipientName}},</p>
+<p>We have received your payment. Keep this email as your receipt.</p>
+<table>
+<tr><td>Invoice number</td><td>{{.InvoiceCode}}</td></tr>
+<tr><td>Product</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Quantity</td><td>{{.Quantity}}</td></tr>
+<tr><td>Payment method</td><td>{{.PaymentMethod}}</td></tr>
+<tr><td>Total paid</td><td><strong>{{rupiah .TotalPrice}}</strong></td></tr>
+</table>`,
+		},
+	},
+	EmailEventOrderShipped: {
+		LocaleIndonesian: {
+			Subject: "{{.ProductTitle}} sudah dikirim",
+			Text: `Hai {{.RecipientName}},
+
+Pesananmu sudah dikirim oleh penjual.
+
+Produk: {{.ProductTitle}}
+Kurir: {{.Courier}}
+Nomor resi: {{.AWBNumber}}
+
+Konfirmasi pesananmu setelah barang diterima.
+`,
+			HTML: `<p>Hai {{.RecipientName}},</p>
+<p>Pesananmu sudah dikirim oleh penjual.</p>
+<table>
+<tr><td>Produk</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Kurir</td><td>{{.Courier}}</td></tr>
+<tr><td>Nomor resi</td><td><strong>{{.AWBNumber}}</strong></td></tr>
+</table>
+<p>Konfirmasi pesananmu setelah barang diterima.</p>`,
+		},
+		LocaleEnglish: {
+			Subject: "{{.ProductTitle}} has been shipped",
+			Text: `Hi {{.RecipientName}},
+
+Your order has been shipped by the seller.
+
+Product: {{.ProductTitle}}
+Courier: {{.Courier}}
+Airway bill: {{.AWBNumber}}
+
+Please confirm your order once you receive it.
+`,
+			HTML: `<p>Hi {{.RecipientName}},</p>
+<p>Your order has been shipped by the seller.</p>
+<table>
+<tr><td>Product</td><td>{{.ProductTitle}}</td></tr>
+<tr><td>Courier</td><td>{{.Courier}}</td></tr>
+<tr><td>Airway bill</td><td><strong>{{.AWBNumber}}</strong></td></tr>
+</table>
+<p>Please confirm your order once you receive it.</p>`,
+		},
+	},
+	EmailEventOrderFinished: {
+		LocaleIndonesian: {
+			Subject: "Pesanan {{.ProductTitle}} selesai",
+			Text: `Hai {{.RecipientName}},
+
+Pesanan {{.ProductTitle}} sudah selesai. Terima kasih sudah berbelanja di Sejastip!
+
+Jangan lupa beri ulasan untuk penjualnya.
+`,
+			HTML: `<p>Hai {{.RecipientName}},</p>
+<p>Pesanan <strong>{{.ProductTitle}}</strong> sudah selesai. Terima kasih sudah berbelanja di Sejastip!</p>
+<p>Jangan lupa beri ulasan untuk penjualnya.</p>`,
+		},
+		LocaleEnglish: {
+			Subject: "Order of {{.ProductTitle}} completed",
+			Text: `Hi {{.RecipientName}},
+
+Your order of {{.ProductTitle}} is completed. Thank you for shopping at Sejastip!
+
+Don't forget to review the seller.
+`,
+			HTML: `<p>Hi {{.RecipientName}},</p>
+<p>Your order of <strong>{{.ProductTitle}}</strong> is completed. Thank you for shopping at Sejastip!</p>
+<p>Don't forget to review the seller.</p>`,
+		},
+	},
+}
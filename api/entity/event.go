@@ -0,0 +1,202 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// domain event names. The schema of an event is only extended with new optional
+// fields, a breaking change bumps its version instead
+const (
+	EventUserRegistered           = "UserRegistered"
+	EventProductListed            = "ProductListed"
+	EventTransactionCreated       = "TransactionCreated"
+	EventTransactionStatusChanged = "TransactionStatusChanged"
+	EventInvoicePaid              = "InvoicePaid"
+)
+
+// eventTopics are the dedicated pubsub topics of the domain events
+var eventTopics = map[string]string{
+	EventUserRegistered:           "events-user-registered",
+	EventProductListed:            "events-product-listed",
+	EventTransactionCreated:       "events-transaction-created",
+	EventTransactionStatusChanged: "events-transaction-status-changed",
+	EventInvoicePaid:              "events-invoice-paid",
+}
+
+// DomainEvent is the payload of a domain event. Payloads carry IDs rather than
+// personal data, consumers join them with their own copies when needed
+type DomainEvent interface {
+	EventName() string
+	EventVersion() int
+}
+
+// EventTopic returns the pubsub topic the event is published to
+func EventTopic(event DomainEvent) string {
+	return eventTopics[event.EventName()]
+}
+
+// EventEnvelope wraps every published domain event. Consumers pick the schema of
+// the data from the name and the version
+type EventEnvelope struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Version    int         `json:"version"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       DomainEvent `json:"data"`
+}
+
+// NewEventEnvelope wraps the event with a new unique ID, so consumers can drop
+// the ones delivered more than once
+func NewEventEnvelope(event DomainEvent, occurredAt time.Time) *EventEnvelope {
+	return &EventEnvelope{
+		ID:         uuid.New().String(),
+		Name:       event.EventName(),
+		Version:    event.EventVersion(),
+		OccurredAt: occurredAt.UTC(),
+		Data:       event,
+	}
+}
+
+// registration methods of UserRegistered
+const (
+	RegistrationMethodPassword = "password"
+	RegistrationMethodOIDC     = "oidc"
+)
+
+// UserRegisteredV1 is published when a user signs up
+type UserRegisteredV1 struct {
+	UserID       int64     `json:"user_id"`
+	Method       string    `json:"method"`
+	Language     string    `json:"language"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+func (e UserRegisteredV1) EventName() string { return EventUserRegistered }
+func (e UserRegisteredV1) EventVersion() int { return 1 }
+
+// NewUserRegistered creates the event of a user who just signed up with the method
+func NewUserRegistered(user *User, method string) UserRegisteredV1 {
+	return UserRegisteredV1{
+		UserID:       user.ID,
+		Method:       method,
+		Language:     user.Language,
+		RegisteredAt: user.CreatedAt.UTC(),
+	}
+}
+
+// ProductListedV1 is published when a seller lists a new product
+type ProductListedV1 struct {
+	ProductID  int64     `json:"product_id"`
+	SellerID   int64     `json:"seller_id"`
+	CountryID  int64     `json:"country_id"`
+	CategoryID *int64    `json:"category_id"`
+	TripID     *int64    `json:"trip_id"`
+	Price      uint      `json:"price"`
+	Variants   int       `json:"variants"`
+	FromDate   string    `json:"from_date"`
+	ToDate     string    `json:"to_date"`
+	ListedAt   time.Time `json:"listed_at"`
+}
+
+func (e ProductListedV1) EventName() string { return EventProductListed }
+func (e ProductListedV1) EventVersion() int { return 1 }
+
+// NewProductListed creates the event of a product which was just listed
+func NewProductListed(product *Product) ProductListedV1 {
+	return ProductListedV1{
+		ProductID:  product.ID,
+		SellerID:   product.SellerID,
+		CountryID:  product.CountryID,
+		CategoryID: product.CategoryID,
+		TripID:     product.TripID,
+		Price:      product.Price,
+		Variants:   len(product.Variants),
+		FromDate:   product.FromDate.Format("2006-01-02"),
+		ToDate:     product.ToDate.Format("2006-01-02"),
+		ListedAt:   product.CreatedAt.UTC(),
+	}
+}
+
+// TransactionCreatedV1 is published when a buyer places an order
+type TransactionCreatedV1 struct {
+	TransactionID int64     `json:"transaction_id"`
+	ProductID     int64     `json:"product_id"`
+	VariantID     *int64    `json:"variant_id"`
+	BuyerID       int64     `json:"buyer_id"`
+	SellerID      int64     `json:"seller_id"`
+	Quantity      uint      `json:"quantity"`
+	TotalPrice    int64     `json:"total_price"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (e TransactionCreatedV1) EventName() string { return EventTransactionCreated }
+func (e TransactionCreatedV1) EventVersion() int { return 1 }
+
+// NewTransactionCreated creates the event of a transaction which was just placed
+func NewTransactionCreated(transaction *Transaction) TransactionCreatedV1 {
+	return TransactionCreatedV1{
+		TransactionID: transaction.ID,
+		ProductID:     transaction.ProductID,
+		VariantID:     transaction.VariantID,
+		BuyerID:       transaction.BuyerID,
+		SellerID:      transaction.SellerID,
+		Quantity:      transaction.Quantity,
+		TotalPrice:    transaction.TotalPrice,
+		CreatedAt:     transaction.CreatedAt.UTC(),
+	}
+}
+
+// TransactionStatusChangedV1 is published when the status of a transaction changes.
+// Statuses are named like in the API, e.g. in_progress
+type TransactionStatusChangedV1 struct {
+	TransactionID  int64     `json:"transaction_id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	ChangedBy      int64     `json:"changed_by"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+func (e TransactionStatusChangedV1) EventName() string { return EventTransactionStatusChanged }
+func (e TransactionStatusChangedV1) EventVersion() int { return 1 }
+
+// NewTransactionStatusChanged creates the event of a transaction which just left
+// the previous status
+func NewTransactionStatusChanged(transaction *Transaction, previousStatus int, changedBy int64, changedAt time.Time) TransactionStatusChangedV1 {
+	return TransactionStatusChangedV1{
+		TransactionID:  transaction.ID,
+		PreviousStatus: mapStatusToString[previousStatus],
+		Status:         mapStatusToString[transaction.Status],
+		ChangedBy:      changedBy,
+		ChangedAt:      changedAt.UTC(),
+	}
+}
+
+// InvoicePaidV1 is published when the invoice of a transaction is paid
+type InvoicePaidV1 struct {
+	InvoiceID     int64         `json:"invoice_id"`
+	InvoiceCode   InvoiceNumber `json:"invoice_code"`
+	TransactionID int64         `json:"transaction_id"`
+	Amount        int64         `json:"amount"`
+	PaymentMethod string        `json:"payment_method"`
+	PaidAt        time.Time     `json:"paid_at"`
+}
+
+func (e InvoicePaidV1) EventName() string { return EventInvoicePaid }
+func (e InvoicePaidV1) EventVersion() int { return 1 }
+
+// NewInvoicePaid creates the event of an invoice which was just paid
+func NewInvoicePaid(invoice *Invoice) InvoicePaidV1 {
+	event := InvoicePaidV1{
+		InvoiceID:     invoice.ID,
+		InvoiceCode:   invoice.InvoiceCode,
+		TransactionID: invoice.TransactionID,
+		Amount:        invoice.CodedPrice,
+		PaymentMethod: invoice.PaymentMethod,
+	}
+	if invoice.PaidAt != nil {
+		event.PaidAt = invoice.PaidAt.UTC()
+	}
+	return event
+}
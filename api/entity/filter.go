@@ -0,0 +1,233 @@
+package entity
+
+import "sort"
+
+// FilterOperator is a comparison supported by listing filters
+type FilterOperator string
+
+const (
+	FilterOperatorEq   FilterOperator = "eq"
+	FilterOperatorIn   FilterOperator = "in"
+	FilterOperatorGte  FilterOperator = "gte"
+	FilterOperatorLte  FilterOperator = "lte"
+	FilterOperatorLike FilterOperator = "like"
+)
+
+// FilterType is the type values of a filter are parsed into
+type FilterType int
+
+const (
+	FilterTypeString FilterType = iota
+	FilterTypeInt
+	// FilterTypeDate values are formatted as 2006-01-02
+	FilterTypeDate
+	// FilterTypeEnum values must be one of the keys of FilterField.Values
+	FilterTypeEnum
+)
+
+// FilterField describes a filterable field of a listing. A query parameter
+// `key=value` applies the default operator, `key[op]=value` applies op
+type FilterField struct {
+	Key             string
+	Column          string
+	Type            FilterType
+	Operators       []FilterOperator
+	DefaultOperator FilterOperator
+	// Values maps the accepted enum values to their stored values
+	Values map[string]int
+}
+
+// Allows tells whether the field can be filtered with the operator
+func (f FilterField) Allows(op FilterOperator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterSchema describes the filters and sort fields accepted by a listing
+type FilterSchema struct {
+	Fields []FilterField
+	// SortFields maps the accepted sort keys to their columns
+	SortFields map[string]string
+	// SearchKey is the query parameter of the full-text search, if supported
+	SearchKey string
+}
+
+// Field returns the filterable field having the key
+func (s FilterSchema) Field(key string) (FilterField, bool) {
+	for _, field := range s.Fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return FilterField{}, false
+}
+
+// FilterKeys returns the accepted filter keys, sorted
+func (s FilterSchema) FilterKeys() []string {
+	keys := []string{}
+	if s.SearchKey != "" {
+		keys = append(keys, s.SearchKey)
+	}
+	for _, field := range s.Fields {
+		keys = append(keys, field.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SortKeys returns the accepted sort keys, sorted
+func (s FilterSchema) SortKeys() []string {
+	keys := []string{}
+	for key := range s.SortFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Filter is a parsed condition on a column. Values hold int64, string or
+// time.Time values depending on the field type
+type Filter struct {
+	Key      string
+	Column   string
+	Operator FilterOperator
+	Values   []interface{}
+}
+
+// SortOrder is a parsed sort on a column
+type SortOrder struct {
+	Column     string
+	Descending bool
+}
+
+// FilterSpec is the validated filter and sort specification of a listing request.
+// An empty Sort means the listing default order
+type FilterSpec struct {
+	Filters []Filter
+	Sort    []SortOrder
+	Search  string
+}
+
+// NewFilterSpec creates an empty specification
+func NewFilterSpec() *FilterSpec {
+	return &FilterSpec{Filters: []Filter{}, Sort: []SortOrder{}}
+}
+
+// Value returns the first value of the filter having the key
+func (s *FilterSpec) Value(key string) (interface{}, bool) {
+	for _, filter := range s.Filters {
+		if filter.Key == key && len(filter.Values) > 0 {
+			return filter.Values[0], true
+		}
+	}
+	return nil, false
+}
+
+// Where adds a filter to the specification. It is used to apply filters that
+// are not set by the client, e.g. restricting a listing to the requesting user
+func (s *FilterSpec) Where(column string, op FilterOperator, values ...interface{}) *FilterSpec {
+	s.Filters = append(s.Filters, Filter{Key: column, Column: column, Operator: op, Values: values})
+	return s
+}
+
+// ProductFilterSchema lists the filters and sorts of the product feed
+var ProductFilterSchema = FilterSchema{
+	Fields: []FilterField{
+		{Key: "seller_id", Column: "seller_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		{Key: "country_id", Column: "country_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		// a category matches the products of its descendant categories too
+		{Key: "category_id", Column: "category_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		{Key: "trip_id", Column: "trip_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq}, DefaultOperator: FilterOperatorEq},
+		// tag is applied on the product_tags table
+		{Key: "tag", Column: "tag", Type: FilterTypeString, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		{Key: "price", Column: "price", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorGte, FilterOperatorLte}, DefaultOperator: FilterOperatorEq},
+		{Key: "status", Column: "status", Type: FilterTypeEnum, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq, Values: map[string]int{
+			"idle":         ProductStatusIdle,
+			"offered":      ProductStatusOffered,
+			"out_of_stock": ProductStatusOutOfStock,
+		}},
+		// open by default, i.e. the offering window includes today and the product is not closed
+		{Key: "availability", Type: FilterTypeEnum, Operators: []FilterOperator{FilterOperatorEq}, DefaultOperator: FilterOperatorEq, Values: map[string]int{
+			"open":     ProductAvailabilityOpen,
+			"upcoming": ProductAvailabilityUpcoming,
+			"closed":   ProductAvailabilityClosed,
+			"all":      ProductAvailabilityAll,
+		}},
+		// products whose buying window overlaps [active_from, active_until]
+		{Key: "active_from", Column: "to_date", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorGte}, DefaultOperator: FilterOperatorGte},
+		{Key: "active_until", Column: "from_date", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorLte}, DefaultOperator: FilterOperatorLte},
+	},
+	SortFields: map[string]string{
+		"price":      "price",
+		"from_date":  "from_date",
+		"to_date":    "to_date",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	SearchKey: "q",
+}
+
+// TransactionFilterSchema lists the filters and sorts of the transaction listing
+var TransactionFilterSchema = FilterSchema{
+	Fields: []FilterField{
+		// role is applied on the requesting user ID, either buyer or seller
+		{Key: "role", Type: FilterTypeEnum, Operators: []FilterOperator{FilterOperatorEq}, DefaultOperator: FilterOperatorEq, Values: map[string]int{
+			"buyer":  TransactionRoleBuyer,
+			"seller": TransactionRoleSeller,
+		}},
+		{Key: "product_id", Column: "product_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		{Key: "status", Column: "status", Type: FilterTypeEnum, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq, Values: MapStatusToStringReverse},
+		{Key: "created_at", Column: "created_at", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorGte, FilterOperatorLte}, DefaultOperator: FilterOperatorGte},
+		{Key: "invoice_code", Column: "invoice_code", Type: FilterTypeString, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorLike}, DefaultOperator: FilterOperatorEq},
+	},
+	SortFields: map[string]string{
+		"total_price": "total_price",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+	},
+}
+
+// BuyRequestFilterSchema lists the filters and sorts of the buy request feed
+var BuyRequestFilterSchema = FilterSchema{
+	Fields: []FilterField{
+		{Key: "country_id", Column: "country_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		{Key: "buyer_id", Column: "buyer_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq}, DefaultOperator: FilterOperatorEq},
+		// open requests not needed before today are listed by default
+		{Key: "status", Column: "status", Type: FilterTypeEnum, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq, Values: map[string]int{
+			"open":      BuyRequestStatusOpen,
+			"fulfilled": BuyRequestStatusFulfilled,
+			"cancelled": BuyRequestStatusCancelled,
+		}},
+		{Key: "max_budget", Column: "max_budget", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorGte, FilterOperatorLte}, DefaultOperator: FilterOperatorGte},
+		{Key: "needed_by", Column: "needed_by", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorGte, FilterOperatorLte}, DefaultOperator: FilterOperatorLte},
+	},
+	SortFields: map[string]string{
+		"max_budget": "max_budget",
+		"needed_by":  "needed_by",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+}
+
+// TripFilterSchema lists the filters and sorts of the trip listing
+var TripFilterSchema = FilterSchema{
+	Fields: []FilterField{
+		{Key: "seller_id", Column: "seller_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq}, DefaultOperator: FilterOperatorEq},
+		{Key: "country_id", Column: "country_id", Type: FilterTypeInt, Operators: []FilterOperator{FilterOperatorEq, FilterOperatorIn}, DefaultOperator: FilterOperatorEq},
+		// trips overlapping [active_from, active_until], trips ended before today are hidden by default
+		{Key: "active_from", Column: "to_date", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorGte}, DefaultOperator: FilterOperatorGte},
+		{Key: "active_until", Column: "from_date", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorLte}, DefaultOperator: FilterOperatorLte},
+		{Key: "cutoff_date", Column: "cutoff_date", Type: FilterTypeDate, Operators: []FilterOperator{FilterOperatorGte, FilterOperatorLte}, DefaultOperator: FilterOperatorGte},
+	},
+	SortFields: map[string]string{
+		"from_date":   "from_date",
+		"to_date":     "to_date",
+		"cutoff_date": "cutoff_date",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+	},
+}
@@ -0,0 +1,23 @@
+package entity
+
+// JWK is a JSON Web Key representation of a public signing key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA public key parameters
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC public key parameters
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys, published for token verifiers
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -1,10 +1,90 @@
 package entity
 
+import "time"
+
+// notification types, telling clients which screen a notification opens
+const (
+	NotificationTypeTransaction = "transaction"
+	NotificationTypeInvoice     = "invoice"
+	NotificationTypeMessage     = "message"
+	NotificationTypeAccount     = "account"
+)
+
+// NotificationData is the content of a notification, along with the IDs of the
+// records it links to. Only the IDs matching the type are set
+type NotificationData struct {
+	Type           string `json:"type"`
+	Title          string `json:"title"`
+	Content        string `json:"content"`
+	TransactionID  *int64 `json:"transaction_id,omitempty"`
+	InvoiceID      *int64 `json:"invoice_id,omitempty"`
+	ConversationID *int64 `json:"conversation_id,omitempty"`
+}
+
 type NotificationRequest struct {
-	Device string `json:"device"`
-	UserID int64  `json:"user_id"`
-	Data   struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
-	} `json:"data"`
+	Device string           `json:"device"`
+	UserID int64            `json:"user_id"`
+	Data   NotificationData `json:"data"`
+}
+
+// Notification stores database row representations of a notification sent to a
+// user, so it stays in the inbox of the user after the push is gone
+type Notification struct {
+	ID             int64      `db:"id"`
+	UserID         int64      `db:"user_id"`
+	Type           string     `db:"type"`
+	Title          string     `db:"title"`
+	Content        string     `db:"content"`
+	TransactionID  *int64     `db:"transaction_id"`
+	InvoiceID      *int64     `db:"invoice_id"`
+	ConversationID *int64     `db:"conversation_id"`
+	ReadAt         *time.Time `db:"read_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// NewNotification creates the inbox notification of the user from its data
+func NewNotification(userID int64, data NotificationData) *Notification {
+	return &Notification{
+		UserID:         userID,
+		Type:           data.Type,
+		Title:          data.Title,
+		Content:        data.Content,
+		TransactionID:  data.TransactionID,
+		InvoiceID:      data.InvoiceID,
+		ConversationID: data.ConversationID,
+	}
+}
+
+// Data returns the content and links of the notification
+func (n *Notification) Data() NotificationData {
+	return NotificationData{
+		Type:           n.Type,
+		Title:          n.Title,
+		Content:        n.Content,
+		TransactionID:  n.TransactionID,
+		InvoiceID:      n.InvoiceID,
+		ConversationID: n.ConversationID,
+	}
+}
+
+// ConvertToPublic converts the notification to its public representation
+func (n *Notification) ConvertToPublic() NotificationPublic {
+	return NotificationPublic{
+		ID:        n.ID,
+		Data:      n.Data(),
+		Read:      n.ReadAt != nil,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
+
+type NotificationPublic struct {
+	ID        int64            `json:"id"`
+	Data      NotificationData `json:"data"`
+	Read      bool             `json:"read"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
 }
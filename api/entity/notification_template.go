@@ -0,0 +1,397 @@
+package entity
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// supported languages of users, notifications are sent in the language of their user
+const (
+	LocaleIndonesian = "id"
+	LocaleEnglish    = "en"
+
+	DefaultLocale = LocaleIndonesian
+)
+
+// IsSupportedLocale tells whether notifications can be sent in the language
+func IsSupportedLocale(locale string) bool {
+	return locale == LocaleIndonesian || locale == LocaleEnglish
+}
+
+// notification events, each of them having its own templates
+const (
+	NotificationEventTransactionCreated    = "transaction_created"
+	NotificationEventTransactionPaid       = "transaction_paid"
+	NotificationEventTransactionInProgress = "transaction_in_progress"
+	NotificationEventTransactionDelivered  = "transaction_delivered"
+	NotificationEventTransactionFinished   = "transaction_finished"
+	NotificationEventTransactionRejected   = "transaction_rejected"
+	NotificationEventTransactionExpired    = "transaction_expired"
+	NotificationEventInvoiceCreated        = "invoice_created"
+	NotificationEventInvoicePaid           = "invoice_paid"
+	NotificationEventMessageReceived       = "message_received"
+	NotificationEventAccountChanged        = "account_changed"
+)
+
+// NotificationParams are the typed parameters a notification template is rendered with
+type NotificationParams interface {
+	NotificationEvent() string
+	// NotificationLink returns the type and the linked IDs of the notification,
+	// without its title and content
+	NotificationLink() NotificationData
+}
+
+// TransactionNotificationParams are the parameters of the notifications about
+// the status of a transaction
+type TransactionNotificationParams struct {
+	Event         string
+	TransactionID int64
+	RecipientName string
+	ProductTitle  string
+	Courier       string
+	AWBNumber     string
+}
+
+func (p TransactionNotificationParams) NotificationEvent() string {
+	return p.Event
+}
+
+func (p TransactionNotificationParams) NotificationLink() NotificationData {
+	return NotificationData{
+		Type:          NotificationTypeTransaction,
+		TransactionID: &p.TransactionID,
+	}
+}
+
+// InvoiceNotificationParams are the parameters of the notifications about the
+// invoice of a transaction
+type InvoiceNotificationParams struct {
+	Event         string
+	TransactionID int64
+	InvoiceID     int64
+	InvoiceCode   InvoiceNumber
+	Amount        int64
+}
+
+func (p InvoiceNotificationParams) NotificationEvent() string {
+	return p.Event
+}
+
+func (p InvoiceNotificationParams) NotificationLink() NotificationData {
+	return NotificationData{
+		Type:          NotificationTypeInvoice,
+		TransactionID: &p.TransactionID,
+		InvoiceID:     &p.InvoiceID,
+	}
+}
+
+// MessageNotificationParams are the parameters of the notification of a new chat message
+type MessageNotificationParams struct {
+	ConversationID int64
+	TransactionID  *int64
+	SenderName     string
+	Body           string
+}
+
+func (p MessageNotificationParams) NotificationEvent() string {
+	return NotificationEventMessageReceived
+}
+
+func (p MessageNotificationParams) NotificationLink() NotificationData {
+	return NotificationData{
+		Type:           NotificationTypeMessage,
+		TransactionID:  p.TransactionID,
+		ConversationID: &p.ConversationID,
+	}
+}
+
+// AccountNotificationParams are the parameters of the warning about a changed
+// email or bank account
+type AccountNotificationParams struct{}
+
+func (p AccountNotificationParams) NotificationEvent() string {
+	return NotificationEventAccountChanged
+}
+
+func (p AccountNotificationParams) NotificationLink() NotificationData {
+	return NotificationData{Type: NotificationTypeAccount}
+}
+
+// notificationEventParams are empty parameters of every event, to check the
+// templates against the fields their event provides
+var notificationEventParams = map[string]NotificationParams{
+	NotificationEventTransactionCreated:    TransactionNotificationParams{},
+	NotificationEventTransactionPaid:       TransactionNotificationParams{},
+	NotificationEventTransactionInProgress: TransactionNotificationParams{},
+	NotificationEventTransactionDelivered:  TransactionNotificationParams{},
+	NotificationEventTransactionFinished:   TransactionNotificationParams{},
+	NotificationEventTransactionRejected:   TransactionNotificationParams{},
+	NotificationEventTransactionExpired:    TransactionNotificationParams{},
+	NotificationEventInvoiceCreated:        InvoiceNotificationParams{},
+	NotificationEventInvoicePaid:           InvoiceNotificationParams{},
+	NotificationEventMessageReceived:       MessageNotificationParams{},
+	NotificationEventAccountChanged:        AccountNotificationParams{},
+}
+
+// IsNotificationEvent tells whether the event has notification templates
+func IsNotificationEvent(event string) bool {
+	_, ok := notificationEventParams[event]
+	return ok
+}
+
+// NotificationTemplate stores database row representations of the title and
+// content templates of a notification event in a language. They override the
+// default templates, so texts can be edited without deploying the API.
+// Templates use the text/template syntax, e.g. {{.ProductTitle}}
+type NotificationTemplate struct {
+	ID        int64     `db:"id"`
+	Event     string    `db:"event"`
+	Locale    string    `db:"locale"`
+	Title     string    `db:"title"`
+	Content   string    `db:"content"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Render renders the notification with the parameters of its event
+func (t *NotificationTemplate) Render(params NotificationParams) (NotificationData, error) {
+	data := params.NotificationLink()
+
+	var err error
+	data.Title, err = renderNotificationTemplate(t.Title, params)
+	if err != nil {
+		return data, errors.Wrapf(err, "error rendering title of %s (%s)", t.Event, t.Locale)
+	}
+
+	data.Content, err = renderNotificationTemplate(t.Content, params)
+	if err != nil {
+		return data, errors.Wrapf(err, "error rendering content of %s (%s)", t.Event, t.Locale)
+	}
+
+	return data, nil
+}
+
+func renderNotificationTemplate(text string, params NotificationParams) (string, error) {
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ConvertToPublic converts the template to its public representation. Custom
+// tells whether the template overrides the default one
+func (t *NotificationTemplate) ConvertToPublic(custom bool) NotificationTemplatePublic {
+	return NotificationTemplatePublic{
+		Event:     t.Event,
+		Locale:    t.Locale,
+		Title:     t.Title,
+		Content:   t.Content,
+		Custom:    custom,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
+
+type NotificationTemplatePublic struct {
+	Event     string    `json:"event"`
+	Locale    string    `json:"locale"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Custom    bool      `json:"custom"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationTemplateForm is the request body to edit the templates of an event in a language
+type NotificationTemplateForm struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// Validate is a function to validate the template form. The templates must render
+// with the parameters of the event
+func (f *NotificationTemplateForm) Validate(event, locale string) error {
+	params, ok := notificationEventParams[event]
+	if !ok {
+		return errors.New("Jenis notifikasi tidak dikenal")
+	}
+
+	if !IsSupportedLocale(locale) {
+		return errors.New("Bahasa tidak didukung")
+	}
+
+	f.Title = strings.TrimSpace(f.Title)
+	f.Content = strings.TrimSpace(f.Content)
+	if f.Title == "" || f.Content == "" {
+		return errors.New("Judul dan isi notifikasi tidak boleh kosong")
+	}
+
+	if len(f.Title) > 255 {
+		return errors.New("Judul notifikasi tidak boleh lebih dari 255 karakter")
+	}
+
+	tmpl := &NotificationTemplate{Event: event, Locale: locale, Title: f.Title, Content: f.Content}
+	if _, err := tmpl.Render(params); err != nil {
+		return errors.Errorf("Template notifikasi tidak valid: %v", errors.Cause(err))
+	}
+
+	return nil
+}
+
+// DefaultNotificationTemplate returns the built-in template of an event in a
+// language, falling back to the default language
+func DefaultNotificationTemplate(event, locale string) (*NotificationTemplate, bool) {
+	for _, l := range []string{locale, DefaultLocale} {
+		for _, t := range DefaultNotificationTemplates {
+			if t.Event == event && t.Locale == l {
+				return &t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// DefaultNotificationTemplates are the built-in templates, used when the database
+// has no template of an event in a language
+var DefaultNotificationTemplates = []NotificationTemplate{
+	{
+		Event:   NotificationEventTransactionCreated,
+		Locale:  LocaleIndonesian,
+		Title:   "Hi {{.RecipientName}}, ada transaksi baru!",
+		Content: "Ada yang ingin membeli {{.ProductTitle}} dari kamu.",
+	},
+	{
+		Event:   NotificationEventTransactionCreated,
+		Locale:  LocaleEnglish,
+		Title:   "Hi {{.RecipientName}}, you have a new order!",
+		Content: "Someone wants to buy {{.ProductTitle}} from you.",
+	},
+	{
+		Event:   NotificationEventTransactionPaid,
+		Locale:  LocaleIndonesian,
+		Title:   "Pembayaran dikonfirmasi",
+		Content: "Pembayaran untuk {{.ProductTitle}} telah dikonfirmasi.",
+	},
+	{
+		Event:   NotificationEventTransactionPaid,
+		Locale:  LocaleEnglish,
+		Title:   "Payment confirmed",
+		Content: "The payment for {{.ProductTitle}} has been confirmed.",
+	},
+	{
+		Event:   NotificationEventTransactionInProgress,
+		Locale:  LocaleIndonesian,
+		Title:   "Pesanan sedang diproses",
+		Content: "Pesanan {{.ProductTitle}} sedang dibelikan oleh penjual.",
+	},
+	{
+		Event:   NotificationEventTransactionInProgress,
+		Locale:  LocaleEnglish,
+		Title:   "Order in progress",
+		Content: "The seller is buying your {{.ProductTitle}}.",
+	},
+	{
+		Event:   NotificationEventTransactionDelivered,
+		Locale:  LocaleIndonesian,
+		Title:   "Pesanan telah dikirim",
+		Content: "Pesanan {{.ProductTitle}} telah dikirim melalui {{.Courier}} dengan nomor resi {{.AWBNumber}}.",
+	},
+	{
+		Event:   NotificationEventTransactionDelivered,
+		Locale:  LocaleEnglish,
+		Title:   "Order shipped",
+		Content: "Your {{.ProductTitle}} has been shipped with {{.Courier}}, tracking number {{.AWBNumber}}.",
+	},
+	{
+		Event:   NotificationEventTransactionFinished,
+		Locale:  LocaleIndonesian,
+		Title:   "Transaksi selesai",
+		Content: "Transaksi {{.ProductTitle}} telah selesai. Jangan lupa beri ulasan!",
+	},
+	{
+		Event:   NotificationEventTransactionFinished,
+		Locale:  LocaleEnglish,
+		Title:   "Order completed",
+		Content: "Your order of {{.ProductTitle}} is completed. Don't forget to leave a review!",
+	},
+	{
+		Event:   NotificationEventTransactionRejected,
+		Locale:  LocaleIndonesian,
+		Title:   "Pesanan ditolak",
+		Content: "Pesanan {{.ProductTitle}} ditolak oleh penjual.",
+	},
+	{
+		Event:   NotificationEventTransactionRejected,
+		Locale:  LocaleEnglish,
+		Title:   "Order rejected",
+		Content: "Your order of {{.ProductTitle}} was rejected by the seller.",
+	},
+	{
+		Event:   NotificationEventTransactionExpired,
+		Locale:  LocaleIndonesian,
+		Title:   "Transaksi kedaluwarsa",
+		Content: "Transaksi {{.ProductTitle}} telah kedaluwarsa dan dibatalkan.",
+	},
+	{
+		Event:   NotificationEventTransactionExpired,
+		Locale:  LocaleEnglish,
+		Title:   "Order expired",
+		Content: "The order of {{.ProductTitle}} has expired and is cancelled.",
+	},
+	{
+		Event:   NotificationEventInvoiceCreated,
+		Locale:  LocaleIndonesian,
+		Title:   "Tagihan telah dibuat",
+		Content: "Segera lakukan pembayaran sebesar Rp{{.Amount}} untuk tagihan {{.InvoiceCode}}.",
+	},
+	{
+		Event:   NotificationEventInvoiceCreated,
+		Locale:  LocaleEnglish,
+		Title:   "Invoice issued",
+		Content: "Please pay Rp{{.Amount}} for invoice {{.InvoiceCode}}.",
+	},
+	{
+		Event:   NotificationEventInvoicePaid,
+		Locale:  LocaleIndonesian,
+		Title:   "Pembayaran diterima",
+		Content: "Tagihan {{.InvoiceCode}} telah dibayar. Segera proses pesanan pembeli.",
+	},
+	{
+		Event:   NotificationEventInvoicePaid,
+		Locale:  LocaleEnglish,
+		Title:   "Payment received",
+		Content: "Invoice {{.InvoiceCode}} has been paid. Please process the order.",
+	},
+	{
+		Event:   NotificationEventMessageReceived,
+		Locale:  LocaleIndonesian,
+		Title:   "Pesan baru dari {{.SenderName}}",
+		Content: "{{if .Body}}{{.Body}}{{else}}Mengirim gambar{{end}}",
+	},
+	{
+		Event:   NotificationEventMessageReceived,
+		Locale:  LocaleEnglish,
+		Title:   "New message from {{.SenderName}}",
+		Content: "{{if .Body}}{{.Body}}{{else}}Sent a picture{{end}}",
+	},
+	{
+		Event:   NotificationEventAccountChanged,
+		Locale:  LocaleIndonesian,
+		Title:   "Data akun kamu telah diubah",
+		Content: "Email atau rekening bank kamu baru saja diubah. Jika ini bukan kamu, segera hubungi kami.",
+	},
+	{
+		Event:   NotificationEventAccountChanged,
+		Locale:  LocaleEnglish,
+		Title:   "Your account was changed",
+		Content: "Your email or bank account was just changed. If it wasn't you, contact us right away.",
+	},
+}
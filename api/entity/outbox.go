@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// pubsub topics published through the outbox
+const (
+	TopicPushNotification = "send-push-notification"
+)
+
+type OutboxStatus int
+
+const (
+	// OutboxStatusPending messages are waiting for their next publishing attempt
+	OutboxStatusPending OutboxStatus = iota
+	// OutboxStatusPublished messages were accepted by pubsub
+	OutboxStatusPublished
+	// OutboxStatusFailed messages ran out of attempts, they stay until replayed
+	OutboxStatusFailed
+)
+
+var mapOutboxStatusToString = map[OutboxStatus]string{
+	OutboxStatusPending:   "pending",
+	OutboxStatusPublished: "published",
+	OutboxStatusFailed:    "failed",
+}
+
+// ParseOutboxStatus returns the status named by its public representation
+func ParseOutboxStatus(status string) (OutboxStatus, bool) {
+	for s, name := range mapOutboxStatusToString {
+		if name == status {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// OutboxMessage stores database row representations of a message to be published
+// to pubsub. It is written along with the change it announces, then relayed
+type OutboxMessage struct {
+	ID            int64        `db:"id"`
+	Topic         string       `db:"topic"`
+	Payload       string       `db:"payload"`
+	Status        OutboxStatus `db:"status"`
+	Attempts      int          `db:"attempts"`
+	LastError     string       `db:"last_error"`
+	NextAttemptAt time.Time    `db:"next_attempt_at"`
+	PublishedAt   *time.Time   `db:"published_at"`
+	CreatedAt     time.Time    `db:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at"`
+}
+
+// NewOutboxMessage creates a pending message carrying the payload encoded to JSON
+func NewOutboxMessage(topic string, payload interface{}) (*OutboxMessage, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		Topic:         topic,
+		Payload:       string(b),
+		Status:        OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// MarkPublished records a successful publishing attempt
+func (m *OutboxMessage) MarkPublished(now time.Time) {
+	m.Attempts++
+	m.Status = OutboxStatusPublished
+	m.PublishedAt = &now
+	m.LastError = ""
+}
+
+// MarkFailed records a failed publishing attempt. The next attempt is delayed
+// exponentially from the backoff, up to maxBackoff, and the message is dead-lettered
+// once it used all of its attempts
+func (m *OutboxMessage) MarkFailed(err error, now time.Time, maxAttempts int, backoff, maxBackoff time.Duration) {
+	m.Attempts++
+	m.LastError = err.Error()
+	if len(m.LastError) > 1000 {
+		m.LastError = m.LastError[:1000]
+	}
+
+	if m.Attempts >= maxAttempts {
+		m.Status = OutboxStatusFailed
+		return
+	}
+
+	delay := backoff
+	for i := 1; i < m.Attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	m.NextAttemptAt = now.Add(delay)
+}
+
+// Replay puts a dead-lettered message back in the queue with fresh attempts
+func (m *OutboxMessage) Replay(now time.Time) {
+	m.Status = OutboxStatusPending
+	m.Attempts = 0
+	m.NextAttemptAt = now
+}
+
+// ConvertToPublic converts the message to its public representation
+func (m *OutboxMessage) ConvertToPublic() OutboxMessagePublic {
+	return OutboxMessagePublic{
+		ID:            m.ID,
+		Topic:         m.Topic,
+		Payload:       json.RawMessage(m.Payload),
+		Status:        mapOutboxStatusToString[m.Status],
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		PublishedAt:   m.PublishedAt,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+type OutboxMessagePublic struct {
+	ID            int64           `json:"id"`
+	Topic         string          `json:"topic"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	PublishedAt   *time.Time      `json:"published_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cursor points to the last item of a page in a listing ordered by
+// updated_at and id, latest first. Listings ordered by created_at keep it in
+// UpdatedAt as well. Clients only see its opaque encoding
+type Cursor struct {
+	UpdatedAt time.Time `json:"u"`
+	ID        int64     `json:"i"`
+}
+
+// Encode returns the opaque representation of the cursor
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor previously returned by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("Cursor tidak valid")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID < 1 {
+		return nil, errors.New("Cursor tidak valid")
+	}
+	return &c, nil
+}
+
+// Page is the requested page of a listing. Pages are selected either by offset,
+// or in cursor mode by the cursor of the previous page
+type Page struct {
+	Limit  int
+	Offset int
+
+	// CursorMode selects keyset pagination, After is nil on the first page
+	CursorMode bool
+	After      *Cursor
+
+	// SkipCount skips counting the total of matching items
+	SkipCount bool
+}
+
+// Validate is a function to validate the page against the listing filter spec.
+// Cursors follow the default order, so they can't be combined with another order
+func (p Page) Validate(spec *FilterSpec) error {
+	if !p.CursorMode {
+		return nil
+	}
+
+	if len(spec.Sort) > 0 {
+		return errors.New("Parameter sort tidak dapat digunakan bersama cursor")
+	}
+	if spec.Search != "" {
+		return errors.New("Pencarian tidak dapat digunakan bersama cursor")
+	}
+
+	return nil
+}
+
+// NextCursor returns the cursor of the page after a page ending with the
+// provided item, or an empty string if the page is the last one
+func (p Page) NextCursor(count int, lastUpdatedAt time.Time, lastID int64) string {
+	if !p.CursorMode || count < p.Limit {
+		return ""
+	}
+	return Cursor{UpdatedAt: lastUpdatedAt, ID: lastID}.Encode()
+}
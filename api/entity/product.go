@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -11,12 +12,26 @@ const (
 	ProductStatusIdle = iota
 	ProductStatusOffered
 	ProductStatusOutOfStock
+	// ProductStatusClosed is set on products whose offering window has ended
+	ProductStatusClosed
 )
 
+// listings only show open products, unless another availability is requested
+const (
+	ProductAvailabilityOpen = iota
+	ProductAvailabilityUpcoming
+	ProductAvailabilityClosed
+	ProductAvailabilityAll
+)
+
+// MaxProductTags is the maximum number of tags a product can have
+const MaxProductTags = 10
+
 var mapProductStatusToString = map[uint]string{
 	ProductStatusIdle:       "idle",
 	ProductStatusOffered:    "offered",
 	ProductStatusOutOfStock: "out of stock",
+	ProductStatusClosed:     "closed",
 }
 
 // Product stores database row representations of a product data
@@ -27,6 +42,8 @@ type Product struct {
 	Price       uint       `db:"price"`
 	SellerID    int64      `db:"seller_id"`
 	CountryID   int64      `db:"country_id"`
+	CategoryID  *int64     `db:"category_id"`
+	TripID      *int64     `db:"trip_id"`
 	Image       string     `db:"image"`
 	Status      uint       `db:"status"`
 	FromDate    time.Time  `db:"from_date"`
@@ -34,12 +51,63 @@ type Product struct {
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"`
 	DeletedAt   *time.Time `db:"deleted_at"`
+	// ReservedFor is the only buyer who may order the product, as it was made
+	// for their buy request. Reserved products are kept out of the listings
+	ReservedFor *int64 `db:"reserved_for"`
+
+	// Images are loaded separately, ordered by position
+	Images []ProductImage `db:"-"`
+	// Tags are loaded separately, a nil Tags is left untouched on update
+	Tags []string `db:"-"`
+	// Variants are loaded separately, a product without variants is sold as is
+	Variants []ProductVariant `db:"-"`
+}
+
+// IsClosed tells whether the product no longer accepts orders, either because it
+// was closed or because its offering window ended before the day of now
+func (p *Product) IsClosed(now time.Time) bool {
+	return p.Status == ProductStatusClosed || p.ToDate.Format("2006-01-02") < now.Format("2006-01-02")
 }
 
 func (p *Product) NormalizeCreate() {
 	p.Title = strings.TrimSpace(p.Title)
 	p.Description = strings.TrimSpace(p.Description)
 	p.Image = strings.TrimSpace(p.Image)
+	p.Tags = NormalizeTags(p.Tags)
+}
+
+// NormalizeTags lowercases and trims the tags, and removes empty and duplicate tags
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// ValidateTags is a function to validate normalized product tags
+func ValidateTags(tags []string) error {
+	if len(tags) > MaxProductTags {
+		return errors.Errorf("Produk tidak boleh memiliki lebih dari %d tag", MaxProductTags)
+	}
+
+	for _, tag := range tags {
+		if len(tag) > 30 {
+			return errors.New("Tag produk tidak boleh lebih dari 30 karakter")
+		}
+	}
+
+	return nil
 }
 
 func (p *Product) ValidateCreate() error {
@@ -67,26 +135,215 @@ func (p *Product) ValidateCreate() error {
 		return errors.New("Harus memilih negara lokasi penjualan barang")
 	}
 
-	return nil
+	if p.CategoryID != nil && *p.CategoryID < 1 {
+		return errors.New("Kategori produk tidak valid")
+	}
+
+	if p.TripID != nil && *p.TripID < 1 {
+		return errors.New("Perjalanan produk tidak valid")
+	}
+
+	return ValidateTags(p.Tags)
 }
 
 type ProductForm struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Price       uint   `json:"price"`
-	CountryID   int64  `json:"country_id"`
-	ImageFile   string `json:"image_file"`
-	FromDate    string `json:"from_date"`
-	ToDate      string `json:"to_date"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Price       uint                 `json:"price"`
+	CountryID   int64                `json:"country_id"`
+	CategoryID  *int64               `json:"category_id"`
+	TripID      *int64               `json:"trip_id"`
+	Tags        []string             `json:"tags"`
+	Variants    []ProductVariantForm `json:"variants"`
+	ImageFile   string               `json:"image_file"`
+	FromDate    string               `json:"from_date"`
+	ToDate      string               `json:"to_date"`
+}
+
+// ProductUpdateForm is the request body of a partial product update. Fields left
+// out of the request are nil and stay unchanged
+type ProductUpdateForm struct {
+	Title       *string       `json:"title"`
+	Description *string       `json:"description"`
+	Price       *uint         `json:"price"`
+	CountryID   *int64        `json:"country_id"`
+	CategoryID  NullableInt64 `json:"category_id"`
+	TripID      *int64        `json:"trip_id"`
+	Tags        []string      `json:"tags"`
+	Status      *string       `json:"status"`
+	FromDate    *string       `json:"from_date"`
+	ToDate      *string       `json:"to_date"`
+	ImageFile   *string       `json:"image_file"`
+}
+
+// NullableInt64 tells a field that is absent from a field that is null, so a
+// null clears the value instead of leaving it unchanged
+type NullableInt64 struct {
+	Set   bool
+	Value *int64
+}
+
+func (n *NullableInt64) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	n.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+// the product statuses a seller can set, closing is left to the closing job
+var mapStringToSellerProductStatus = map[string]uint{
+	"idle":         ProductStatusIdle,
+	"offered":      ProductStatusOffered,
+	"out_of_stock": ProductStatusOutOfStock,
+}
+
+// Normalize is a method to normalize all provided field values
+func (f *ProductUpdateForm) Normalize() {
+	if f.Title != nil {
+		*f.Title = strings.TrimSpace(*f.Title)
+	}
+	if f.Description != nil {
+		*f.Description = strings.TrimSpace(*f.Description)
+	}
+	if f.Status != nil {
+		*f.Status = strings.ToLower(strings.TrimSpace(*f.Status))
+	}
+	f.Tags = NormalizeTags(f.Tags)
 }
 
+// ValidateUpdate is a function to validate the provided fields of the form
+func (f *ProductUpdateForm) ValidateUpdate() error {
+	if f.Title != nil && len(*f.Title) < 3 {
+		return errors.New("Judul produk harus lebih dari 3 karakter")
+	}
+
+	if f.Price != nil && *f.Price < 1 {
+		return errors.New("Harga produk tidak boleh kosong atau negatif")
+	}
+
+	if f.CountryID != nil && *f.CountryID < 1 {
+		return errors.New("Harus memilih negara lokasi penjualan barang")
+	}
+
+	if f.CategoryID.Value != nil && *f.CategoryID.Value < 1 {
+		return errors.New("Kategori produk tidak valid")
+	}
+
+	if f.TripID != nil && *f.TripID < 1 {
+		return errors.New("Perjalanan produk tidak valid")
+	}
+
+	if f.Status != nil {
+		if _, ok := mapStringToSellerProductStatus[*f.Status]; !ok {
+			return errors.New("Status produk harus salah satu dari idle, offered, out_of_stock")
+		}
+	}
+
+	if f.FromDate != nil {
+		if _, err := time.Parse("2006-01-02", *f.FromDate); err != nil {
+			return errors.New("Format tanggal mulai penawaran harus YYYY-MM-DD")
+		}
+	}
+
+	if f.ToDate != nil {
+		toDate, err := time.Parse("2006-01-02", *f.ToDate)
+		if err != nil {
+			return errors.New("Format tanggal akhir penawaran harus YYYY-MM-DD")
+		}
+		if toDate.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+			return errors.New("Waktu akhir penawaran barang tidak boleh di waktu yang lalu")
+		}
+	}
+
+	if f.ImageFile != nil && strings.TrimSpace(*f.ImageFile) == "" {
+		return errors.New("Foto produk tidak boleh kosong")
+	}
+
+	return ValidateTags(f.Tags)
+}
+
+// Apply writes the provided fields to the product, and returns the changed
+// columns with their new values. The form must be validated first
+func (f *ProductUpdateForm) Apply(p *Product) map[string]interface{} {
+	changes := map[string]interface{}{}
+	if f.Title != nil {
+		p.Title = *f.Title
+		changes["title"] = p.Title
+	}
+	if f.Description != nil {
+		p.Description = *f.Description
+		changes["description"] = p.Description
+	}
+	if f.Price != nil {
+		p.Price = *f.Price
+		changes["price"] = p.Price
+	}
+	if f.CountryID != nil {
+		p.CountryID = *f.CountryID
+		changes["country_id"] = p.CountryID
+	}
+	// a null category_id removes the product from its category
+	if f.CategoryID.Set {
+		p.CategoryID = f.CategoryID.Value
+		changes["category_id"] = p.CategoryID
+	}
+	if f.FromDate != nil {
+		p.FromDate, _ = time.Parse("2006-01-02", *f.FromDate)
+		changes["from_date"] = p.FromDate
+	}
+	if f.ToDate != nil {
+		p.ToDate, _ = time.Parse("2006-01-02", *f.ToDate)
+		changes["to_date"] = p.ToDate
+		// extending the offering window of a closed product opens it again
+		if p.Status == ProductStatusClosed && f.Status == nil {
+			p.Status = ProductStatusIdle
+			changes["status"] = p.Status
+		}
+	}
+	if f.Status != nil {
+		p.Status = mapStringToSellerProductStatus[*f.Status]
+		changes["status"] = p.Status
+	}
+	return changes
+}
+
+// ConvertToPublic converts the product to its public representation.
+// Image is the cover, which is the first of the images
 func (p *Product) ConvertToPublic(c *Country, u *User) ProductPublic {
+	images := []ProductImagePublic{}
+	for _, image := range p.Images {
+		images = append(images, image.ConvertToPublic())
+	}
+
+	cover := p.Image
+	if len(images) > 0 {
+		cover = images[0].URL
+	}
+
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	variants := []ProductVariantPublic{}
+	for _, variant := range p.Variants {
+		variants = append(variants, variant.ConvertToPublic(p.Price))
+	}
+
 	return ProductPublic{
 		ID:          p.ID,
 		Title:       p.Title,
 		Description: p.Description,
 		Price:       p.Price,
-		Image:       p.Image,
+		Image:       cover,
+		Images:      images,
+		CategoryID:  p.CategoryID,
+		TripID:      p.TripID,
+		Tags:        tags,
+		Options:     BuildVariantMatrix(p.Variants),
+		Variants:    variants,
 		Seller:      u.ConvertToPublic(),
 		Country:     c,
 		Status:      mapProductStatusToString[p.Status],
@@ -98,16 +355,22 @@ func (p *Product) ConvertToPublic(c *Country, u *User) ProductPublic {
 }
 
 type ProductPublic struct {
-	ID          int64       `json:"id"`
-	Title       string      `json:"title"`
-	Description string      `json:"description"`
-	Price       uint        `json:"price"`
-	Image       string      `json:"image"`
-	Seller      *UserPublic `json:"seller,omitempty"`
-	Country     *Country    `json:"country,omitempty"`
-	Status      string      `json:"status"`
-	FromDate    string      `json:"from_date"`
-	ToDate      string      `json:"to_date"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
+	ID          int64                  `json:"id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Price       uint                   `json:"price"`
+	Image       string                 `json:"image"`
+	Images      []ProductImagePublic   `json:"images"`
+	CategoryID  *int64                 `json:"category_id"`
+	TripID      *int64                 `json:"trip_id"`
+	Tags        []string               `json:"tags"`
+	Options     []ProductOptionPublic  `json:"options"`
+	Variants    []ProductVariantPublic `json:"variants"`
+	Seller      *UserPublic            `json:"seller,omitempty"`
+	Country     *Country               `json:"country,omitempty"`
+	Status      string                 `json:"status"`
+	FromDate    string                 `json:"from_date"`
+	ToDate      string                 `json:"to_date"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
 }
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxProductImages is the maximum number of images a product can have
+const MaxProductImages = 10
+
+// ProductImage stores database row representations of a product image.
+// Path is the name of the file in the storage, URL is its public address
+type ProductImage struct {
+	ID        int64     `db:"id"`
+	ProductID int64     `db:"product_id"`
+	URL       string    `db:"url"`
+	Path      string    `db:"path"`
+	Position  int       `db:"position"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ConvertToPublic converts the product image to its public representation
+func (i *ProductImage) ConvertToPublic() ProductImagePublic {
+	return ProductImagePublic{
+		ID:       i.ID,
+		URL:      i.URL,
+		Position: i.Position,
+	}
+}
+
+// ProductImagePublic is the public representation of a product image
+type ProductImagePublic struct {
+	ID       int64  `json:"id"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+}
+
+// ProductImageForm is the request body to add an image to a product
+type ProductImageForm struct {
+	ImageFile string `json:"image_file"`
+}
+
+// Validate is a function to validate the uploaded image
+func (f *ProductImageForm) Validate() error {
+	if strings.TrimSpace(f.ImageFile) == "" {
+		return errors.New("Harus upload foto produk terlebih dahulu")
+	}
+	return nil
+}
+
+// ProductImageOrderForm is the request body to reorder the images of a product,
+// the first image becomes the cover
+type ProductImageOrderForm struct {
+	ImageIDs []int64 `json:"image_ids"`
+}
+
+// Validate is a function to validate the new order against the current images.
+// Every image must be listed exactly once
+func (f *ProductImageOrderForm) Validate(images []ProductImage) error {
+	if len(f.ImageIDs) != len(images) {
+		return errors.New("Urutan foto harus mencantumkan semua foto produk")
+	}
+
+	current := map[int64]bool{}
+	for _, image := range images {
+		current[image.ID] = false
+	}
+	for _, id := range f.ImageIDs {
+		listed, ok := current[id]
+		if !ok || listed {
+			return errors.New("Urutan foto harus mencantumkan semua foto produk tepat satu kali")
+		}
+		current[id] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sejastip.id/api/entity"
+)
+
+func TestProductUpdateFormCategory(t *testing.T) {
+	decode := func(body string) entity.ProductUpdateForm {
+		form := entity.ProductUpdateForm{}
+		require.NoError(t, json.Unmarshal([]byte(body), &form))
+		return form
+	}
+
+	// an absent category leaves the product in its category
+	form := decode(`{"title": "Kit Kat Matcha"}`)
+	product := entity.Product{CategoryID: int64Ptr(3)}
+	assert.NotContains(t, form.Apply(&product), "category_id")
+	assert.Equal(t, int64Ptr(3), product.CategoryID)
+
+	// a null category removes it
+	form = decode(`{"category_id": null}`)
+	assert.NoError(t, form.ValidateUpdate())
+	changes := form.Apply(&product)
+	assert.Contains(t, changes, "category_id")
+	assert.Nil(t, product.CategoryID)
+
+	form = decode(`{"category_id": 4}`)
+	assert.NoError(t, form.ValidateUpdate())
+	form.Apply(&product)
+	assert.Equal(t, int64Ptr(4), product.CategoryID)
+
+	form = decode(`{"category_id": 0}`)
+	assert.Error(t, form.ValidateUpdate())
+}
@@ -0,0 +1,196 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxProductVariants is the maximum number of variants a product can have
+const MaxProductVariants = 50
+
+// VariantOption is an option name/value pair of a variant, e.g. size 42
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// VariantOptions are the options of a variant. They are stored as a JSON column
+type VariantOptions []VariantOption
+
+// Value implements driver.Valuer
+func (o VariantOptions) Value() (driver.Value, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (o *VariantOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case nil:
+		*o = VariantOptions{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for variant options", src)
+	}
+}
+
+// Names returns the option names, in order
+func (o VariantOptions) Names() []string {
+	names := []string{}
+	for _, option := range o {
+		names = append(names, option.Name)
+	}
+	return names
+}
+
+// Key identifies the combination of option values, case-insensitively
+func (o VariantOptions) Key() string {
+	parts := []string{}
+	for _, option := range o {
+		parts = append(parts, strings.ToLower(option.Name)+"="+strings.ToLower(option.Value))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ProductVariant stores database row representations of a product variant.
+// A nil Price uses the product price, a nil Stock is not tracked
+type ProductVariant struct {
+	ID        int64          `db:"id"`
+	ProductID int64          `db:"product_id"`
+	Options   VariantOptions `db:"options"`
+	Price     *uint          `db:"price"`
+	Stock     *uint          `db:"stock"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at"`
+	DeletedAt *time.Time     `db:"deleted_at"`
+}
+
+// EffectivePrice returns the price of the variant, falling back to the product price
+func (v *ProductVariant) EffectivePrice(productPrice uint) uint {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
+
+// ConvertToPublic converts the variant to its public representation
+func (v *ProductVariant) ConvertToPublic(productPrice uint) ProductVariantPublic {
+	return ProductVariantPublic{
+		ID:      v.ID,
+		Options: v.Options,
+		Price:   v.EffectivePrice(productPrice),
+		Stock:   v.Stock,
+	}
+}
+
+// ProductVariantPublic is the public representation of a product variant.
+// A null stock means the stock is not tracked
+type ProductVariantPublic struct {
+	ID      int64          `json:"id"`
+	Options VariantOptions `json:"options"`
+	Price   uint           `json:"price"`
+	Stock   *uint          `json:"stock"`
+}
+
+// ProductOptionPublic is an axis of the variant matrix, listing the values
+// of an option in order of appearance
+type ProductOptionPublic struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// BuildVariantMatrix returns the option axes of the variants
+func BuildVariantMatrix(variants []ProductVariant) []ProductOptionPublic {
+	axes := []ProductOptionPublic{}
+	index := map[string]int{}
+	seen := map[string]bool{}
+	for _, variant := range variants {
+		for _, option := range variant.Options {
+			i, ok := index[option.Name]
+			if !ok {
+				i = len(axes)
+				index[option.Name] = i
+				axes = append(axes, ProductOptionPublic{Name: option.Name, Values: []string{}})
+			}
+
+			key := option.Name + "=" + option.Value
+			if !seen[key] {
+				seen[key] = true
+				axes[i].Values = append(axes[i].Values, option.Value)
+			}
+		}
+	}
+	return axes
+}
+
+// ProductVariantForm is the request body to create or update a product variant
+type ProductVariantForm struct {
+	Options VariantOptions `json:"options"`
+	Price   *uint          `json:"price"`
+	Stock   *uint          `json:"stock"`
+}
+
+// Normalize is a method to normalize all field values
+func (f *ProductVariantForm) Normalize() {
+	for i := range f.Options {
+		f.Options[i].Name = strings.TrimSpace(f.Options[i].Name)
+		f.Options[i].Value = strings.TrimSpace(f.Options[i].Value)
+	}
+}
+
+// Validate is a function to validate the variant against the other variants of
+// the product. Every variant must have the same option names, in the same order,
+// and a distinct combination of values
+func (f *ProductVariantForm) Validate(others []ProductVariant) error {
+	if len(f.Options) == 0 {
+		return errors.New("Varian produk harus memiliki minimal satu pilihan")
+	}
+
+	names := map[string]bool{}
+	for _, option := range f.Options {
+		if option.Name == "" || option.Value == "" {
+			return errors.New("Nama dan nilai pilihan varian tidak boleh kosong")
+		}
+		if len(option.Name) > 30 || len(option.Value) > 30 {
+			return errors.New("Nama dan nilai pilihan varian tidak boleh lebih dari 30 karakter")
+		}
+		if names[strings.ToLower(option.Name)] {
+			return errors.Errorf("Pilihan %s tidak boleh diulang", option.Name)
+		}
+		names[strings.ToLower(option.Name)] = true
+	}
+
+	if f.Price != nil && *f.Price < 1 {
+		return errors.New("Harga varian tidak boleh kosong atau negatif")
+	}
+
+	if len(others) == 0 {
+		return nil
+	}
+
+	expected := strings.ToLower(strings.Join(others[0].Options.Names(), ", "))
+	if strings.ToLower(strings.Join(f.Options.Names(), ", ")) != expected {
+		return errors.Errorf("Pilihan varian harus sama dengan varian lainnya: %s", strings.Join(others[0].Options.Names(), ", "))
+	}
+
+	key := f.Options.Key()
+	for _, other := range others {
+		if other.Options.Key() == key {
+			return errors.New("Varian dengan pilihan yang sama sudah ada")
+		}
+	}
+
+	return nil
+}
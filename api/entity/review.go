@@ -0,0 +1,156 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxReviewPhotos is the maximum number of photos a review can have
+const MaxReviewPhotos = 5
+
+// ReviewPhotos are the URLs of the photos of a review. They are stored as a JSON column
+type ReviewPhotos []string
+
+// Value implements driver.Valuer
+func (p ReviewPhotos) Value() (driver.Value, error) {
+	if p == nil {
+		p = ReviewPhotos{}
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (p *ReviewPhotos) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil:
+		*p = ReviewPhotos{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for review photos", src)
+	}
+}
+
+// Review stores database row representations of a buyer review of a finished
+// transaction, along with the reply of the seller
+type Review struct {
+	ID            int64        `db:"id"`
+	TransactionID int64        `db:"transaction_id"`
+	ProductID     int64        `db:"product_id"`
+	BuyerID       int64        `db:"buyer_id"`
+	SellerID      int64        `db:"seller_id"`
+	Rating        uint         `db:"rating"`
+	Text          string       `db:"text"`
+	Photos        ReviewPhotos `db:"photos"`
+	Reply         string       `db:"reply"`
+	RepliedAt     *time.Time   `db:"replied_at"`
+	CreatedAt     time.Time    `db:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at"`
+}
+
+// ConvertToPublic converts the review to its public representation
+func (r *Review) ConvertToPublic(buyer *User) ReviewPublic {
+	photos := r.Photos
+	if photos == nil {
+		photos = ReviewPhotos{}
+	}
+
+	return ReviewPublic{
+		ID:            r.ID,
+		TransactionID: r.TransactionID,
+		ProductID:     r.ProductID,
+		Buyer:         buyer.ConvertToPublic(),
+		Rating:        r.Rating,
+		Text:          r.Text,
+		Photos:        photos,
+		Reply:         r.Reply,
+		RepliedAt:     r.RepliedAt,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+	}
+}
+
+type ReviewPublic struct {
+	ID            int64        `json:"id"`
+	TransactionID int64        `json:"transaction_id"`
+	ProductID     int64        `json:"product_id"`
+	Buyer         *UserPublic  `json:"buyer,omitempty"`
+	Rating        uint         `json:"rating"`
+	Text          string       `json:"text"`
+	Photos        ReviewPhotos `json:"photos"`
+	Reply         string       `json:"reply"`
+	RepliedAt     *time.Time   `json:"replied_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// AverageRating returns the average of the ratings, rounded to one decimal
+func AverageRating(ratingSum, reviewCount uint) float64 {
+	if reviewCount == 0 {
+		return 0
+	}
+	return math.Round(float64(ratingSum)/float64(reviewCount)*10) / 10
+}
+
+// ReviewForm is the request body to review a transaction. PhotoFiles are base64
+// encoded images
+type ReviewForm struct {
+	Rating     uint     `json:"rating"`
+	Text       string   `json:"text"`
+	PhotoFiles []string `json:"photo_files"`
+}
+
+// Normalize is a method to normalize all field values
+func (f *ReviewForm) Normalize() {
+	f.Text = strings.TrimSpace(f.Text)
+}
+
+// Validate is a function to validate the review form
+func (f *ReviewForm) Validate() error {
+	if f.Rating < 1 || f.Rating > 5 {
+		return errors.New("Rating harus di antara 1 sampai 5")
+	}
+
+	if len(f.Text) > 1000 {
+		return errors.New("Ulasan tidak boleh lebih dari 1000 karakter")
+	}
+
+	if len(f.PhotoFiles) > MaxReviewPhotos {
+		return errors.Errorf("Foto ulasan tidak boleh lebih dari %d", MaxReviewPhotos)
+	}
+
+	return nil
+}
+
+// ReviewReplyForm is the request body of the seller reply to a review
+type ReviewReplyForm struct {
+	Reply string `json:"reply"`
+}
+
+// Validate is a function to validate the review reply form
+func (f *ReviewReplyForm) Validate() error {
+	f.Reply = strings.TrimSpace(f.Reply)
+
+	if f.Reply == "" {
+		return errors.New("Balasan ulasan tidak boleh kosong")
+	}
+
+	if len(f.Reply) > 1000 {
+		return errors.New("Balasan ulasan tidak boleh lebih dari 1000 karakter")
+	}
+
+	return nil
+}
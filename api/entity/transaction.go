@@ -16,6 +16,13 @@ const (
 	TransactionStatusExpired
 )
 
+// the transactions of a user can be listed as either party, or only as buyer or seller
+const (
+	TransactionRoleAny = iota
+	TransactionRoleBuyer
+	TransactionRoleSeller
+)
+
 var mapStatusToString = map[int]string{
 	TransactionStatusInit:       "placed",
 	TransactionStatusPaid:       "paid",
@@ -39,6 +46,7 @@ var MapStatusToStringReverse = map[string]int{
 type Transaction struct {
 	ID             int64      `db:"id"`
 	ProductID      int64      `db:"product_id"`
+	VariantID      *int64     `db:"variant_id"`
 	BuyerID        int64      `db:"buyer_id"`
 	SellerID       int64      `db:"seller_id"`
 	BuyerAddressID int64      `db:"buyer_address_id"`
@@ -57,9 +65,46 @@ func (t *Transaction) GetStatusString() string {
 	return mapStatusToString[t.Status]
 }
 
+// OpenTransactionStatuses are the statuses of transactions that are not settled yet
+var OpenTransactionStatuses = []int{
+	TransactionStatusInit,
+	TransactionStatusPaid,
+	TransactionStatusInProgress,
+	TransactionStatusDelivered,
+}
+
+// ConvertToExport converts the transaction to its account export representation,
+// seen from the perspective of the exporting user
+func (t *Transaction) ConvertToExport(userID int64) TransactionExport {
+	role := "buyer"
+	if t.SellerID == userID {
+		role = "seller"
+	}
+
+	return TransactionExport{
+		ID:             t.ID,
+		Role:           role,
+		ProductID:      t.ProductID,
+		VariantID:      t.VariantID,
+		BuyerID:        t.BuyerID,
+		SellerID:       t.SellerID,
+		BuyerAddressID: t.BuyerAddressID,
+		InvoiceID:      t.InvoiceID,
+		Quantity:       t.Quantity,
+		Notes:          t.Notes,
+		TotalPrice:     t.TotalPrice,
+		Status:         t.GetStatusString(),
+		PaidAt:         t.PaidAt,
+		FinishedAt:     t.FinishedAt,
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+	}
+}
+
 type TransactionPublic struct {
 	ID           int64                      `json:"id"`
 	Product      *ProductPublic             `json:"product"`
+	Variant      *ProductVariantPublic      `json:"variant"`
 	Buyer        *UserPublic                `json:"buyer"`
 	BuyerAddress *UserAddressPublic         `json:"buyer_address"`
 	Quantity     uint                       `json:"quantity"`
@@ -76,6 +121,7 @@ type TransactionPublic struct {
 
 type TransactionForm struct {
 	ProductID int64  `json:"product_id"`
+	VariantID *int64 `json:"variant_id"`
 	Quantity  uint   `json:"quantity"`
 	AddressID int64  `json:"address_id"`
 	Notes     string `json:"notes"`
@@ -86,6 +132,10 @@ func (f *TransactionForm) Validate() error {
 		return errors.New("Product is not selected yet")
 	}
 
+	if f.VariantID != nil && *f.VariantID < 1 {
+		return errors.New("Selected variant is invalid")
+	}
+
 	if f.Quantity < 1 {
 		return errors.New("Ordered quantity must be more than 0")
 	}
@@ -0,0 +1,218 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxTripCities is the maximum number of cities a trip can visit
+const MaxTripCities = 10
+
+// TripCities are the cities visited on a trip. They are stored as a JSON column
+type TripCities []string
+
+// Value implements driver.Valuer
+func (c TripCities) Value() (driver.Value, error) {
+	if c == nil {
+		c = TripCities{}
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (c *TripCities) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = TripCities{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for trip cities", src)
+	}
+}
+
+// Trip stores database row representations of a seller trip abroad. The products
+// listed for a trip are sold in its country, from the start of the trip until
+// the order cutoff date
+type Trip struct {
+	ID              int64      `db:"id"`
+	SellerID        int64      `db:"seller_id"`
+	CountryID       int64      `db:"country_id"`
+	Cities          TripCities `db:"cities"`
+	FromDate        time.Time  `db:"from_date"`
+	ToDate          time.Time  `db:"to_date"`
+	CutoffDate      time.Time  `db:"cutoff_date"`
+	LuggageCapacity uint       `db:"luggage_capacity"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	DeletedAt       *time.Time `db:"deleted_at"`
+}
+
+// OrderDeadline returns the end of the cutoff day, when the products of the
+// trip stop accepting orders
+func (t *Trip) OrderDeadline() time.Time {
+	return t.CutoffDate.Add(24*time.Hour - time.Second)
+}
+
+// AcceptsOrders tells whether the cutoff date is not before the day of now
+func (t *Trip) AcceptsOrders(now time.Time) bool {
+	return t.CutoffDate.Format("2006-01-02") >= now.Format("2006-01-02")
+}
+
+// ApplyTo writes the country and the offering window of the trip to a product
+// listed for it, and returns the changed columns with their new values.
+// A closed product opens again when the trip still accepts orders on the day of now
+func (t *Trip) ApplyTo(p *Product, now time.Time) map[string]interface{} {
+	p.TripID = &t.ID
+	p.CountryID = t.CountryID
+	p.FromDate = t.FromDate
+	p.ToDate = t.OrderDeadline()
+	changes := map[string]interface{}{
+		"trip_id":    t.ID,
+		"country_id": p.CountryID,
+		"from_date":  p.FromDate,
+		"to_date":    p.ToDate,
+	}
+	if p.Status == ProductStatusClosed && t.AcceptsOrders(now) {
+		p.Status = ProductStatusIdle
+		changes["status"] = p.Status
+	}
+	return changes
+}
+
+// ConvertToPublic converts the trip to its public representation
+func (t *Trip) ConvertToPublic(c *Country, u *User) TripPublic {
+	cities := t.Cities
+	if cities == nil {
+		cities = TripCities{}
+	}
+
+	return TripPublic{
+		ID:              t.ID,
+		Seller:          u.ConvertToPublic(),
+		Country:         c,
+		Cities:          cities,
+		FromDate:        t.FromDate.Format("2006-01-02"),
+		ToDate:          t.ToDate.Format("2006-01-02"),
+		CutoffDate:      t.CutoffDate.Format("2006-01-02"),
+		LuggageCapacity: t.LuggageCapacity,
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+	}
+}
+
+// TripPublic is the public representation of a trip. LuggageCapacity is in kilograms
+type TripPublic struct {
+	ID              int64       `json:"id"`
+	Seller          *UserPublic `json:"seller,omitempty"`
+	Country         *Country    `json:"country,omitempty"`
+	Cities          TripCities  `json:"cities"`
+	FromDate        string      `json:"from_date"`
+	ToDate          string      `json:"to_date"`
+	CutoffDate      string      `json:"cutoff_date"`
+	LuggageCapacity uint        `json:"luggage_capacity"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// TripForm is the request body to create or update a trip. CutoffDate is the last
+// day orders are accepted, it defaults to the end of the trip
+type TripForm struct {
+	CountryID       int64    `json:"country_id"`
+	Cities          []string `json:"cities"`
+	FromDate        string   `json:"from_date"`
+	ToDate          string   `json:"to_date"`
+	CutoffDate      string   `json:"cutoff_date"`
+	LuggageCapacity uint     `json:"luggage_capacity"`
+}
+
+// Normalize is a method to normalize all field values
+func (f *TripForm) Normalize() {
+	cities := []string{}
+	seen := map[string]bool{}
+	for _, city := range f.Cities {
+		city = strings.Join(strings.Fields(city), " ")
+		if city == "" || seen[strings.ToLower(city)] {
+			continue
+		}
+		seen[strings.ToLower(city)] = true
+		cities = append(cities, city)
+	}
+	f.Cities = cities
+
+	f.CutoffDate = strings.TrimSpace(f.CutoffDate)
+	if f.CutoffDate == "" {
+		f.CutoffDate = f.ToDate
+	}
+}
+
+// Validate is a function to validate the trip form
+func (f *TripForm) Validate() error {
+	if f.CountryID < 1 {
+		return errors.New("Harus memilih negara tujuan perjalanan")
+	}
+
+	if len(f.Cities) > MaxTripCities {
+		return errors.Errorf("Kota tujuan perjalanan tidak boleh lebih dari %d", MaxTripCities)
+	}
+	for _, city := range f.Cities {
+		if len(city) > 50 {
+			return errors.New("Nama kota tidak boleh lebih dari 50 karakter")
+		}
+	}
+
+	fromDate, err := time.Parse("2006-01-02", f.FromDate)
+	if err != nil {
+		return errors.New("Format tanggal mulai perjalanan harus YYYY-MM-DD")
+	}
+
+	toDate, err := time.Parse("2006-01-02", f.ToDate)
+	if err != nil {
+		return errors.New("Format tanggal akhir perjalanan harus YYYY-MM-DD")
+	}
+
+	if toDate.Before(fromDate) {
+		return errors.New("Tanggal akhir perjalanan tidak boleh sebelum tanggal mulai perjalanan")
+	}
+
+	cutoffDate, err := time.Parse("2006-01-02", f.CutoffDate)
+	if err != nil {
+		return errors.New("Format batas akhir pemesanan harus YYYY-MM-DD")
+	}
+
+	if cutoffDate.Before(fromDate) || cutoffDate.After(toDate) {
+		return errors.New("Batas akhir pemesanan harus di antara tanggal mulai dan akhir perjalanan")
+	}
+
+	if cutoffDate.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+		return errors.New("Batas akhir pemesanan tidak boleh di waktu yang lalu")
+	}
+
+	if f.LuggageCapacity < 1 || f.LuggageCapacity > 100 {
+		return errors.New("Kapasitas bagasi harus di antara 1 sampai 100 kg")
+	}
+
+	return nil
+}
+
+// Apply writes the form to the trip. The form must be validated first
+func (f *TripForm) Apply(t *Trip) {
+	t.CountryID = f.CountryID
+	t.Cities = f.Cities
+	t.FromDate, _ = time.Parse("2006-01-02", f.FromDate)
+	t.ToDate, _ = time.Parse("2006-01-02", f.ToDate)
+	t.CutoffDate, _ = time.Parse("2006-01-02", f.CutoffDate)
+	t.LuggageCapacity = f.LuggageCapacity
+}
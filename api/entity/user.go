@@ -19,15 +19,36 @@ type User struct {
 	BankName    string     `json:"bank_name" db:"bank_name"`
 	BankAccount string     `json:"bank_account" db:"bank_account"`
 	Avatar      string     `db:"avatar"`
+	Language    string     `json:"language" db:"language"`
 	LastLoginAt *time.Time `db:"last_login_at"`
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"-" db:"email_verified_at"`
+	PhoneVerifiedAt *time.Time `json:"-" db:"phone_verified_at"`
+	DeletedAt       *time.Time `json:"-" db:"deleted_at"`
+
+	// IsAdmin grants access to the back office endpoints. It is only set directly
+	// in the database
+	IsAdmin bool `json:"-" db:"is_admin"`
+
+	// OrderEmailOptOut and PaymentEmailOptOut stop the emails of their category
+	// from being sent to the user
+	OrderEmailOptOut   bool `json:"-" db:"order_email_opt_out"`
+	PaymentEmailOptOut bool `json:"-" db:"payment_email_opt_out"`
+
+	// RatingSum and ReviewCount aggregate the reviews received as a seller,
+	// they are updated along with every new review
+	RatingSum   uint `json:"-" db:"rating_sum"`
+	ReviewCount uint `json:"-" db:"review_count"`
 }
 
 // Normalize is a method to normalize all field values
 func (u *User) Normalize() {
 	u.Email = strings.TrimSpace(u.Email)
 	u.Name = strings.TrimSpace(u.Name)
+	u.BankName = strings.TrimSpace(u.BankName)
+	u.BankAccount = strings.TrimSpace(u.BankAccount)
 	// removes all non-number char, including + sign
 	u.Phone = regexp.MustCompile(`\D`).ReplaceAllString(strings.TrimSpace(u.Phone), "")
 	r := regexp.MustCompile("^0+")
@@ -37,42 +58,205 @@ func (u *User) Normalize() {
 	}
 
 	u.Password = strings.TrimSpace(u.Password)
+
+	u.Language = strings.ToLower(strings.TrimSpace(u.Language))
+	if u.Language == "" {
+		u.Language = DefaultLocale
+	}
 }
 
 // Validate is a function to validate user input validity
 func (u *User) Validate() error {
+	return u.ValidateUpdate(true)
+}
+
+// ValidateUpdate validates an edited profile. The phone is only checked when it is
+// changed, since older accounts and social sign-ins may not have a valid one
+func (u *User) ValidateUpdate(phoneChanged bool) error {
 	// check name first: must have no special character
 	matched, _ := regexp.Match("^[A-Za-z0-9\\s]+$", []byte(u.Name))
 	if !matched {
 		return errors.New("Nama hanya boleh mengandung karakter alfanumerik")
 	}
 
-	// check phone
+	// check email
+	matched, _ = regexp.Match(`^[^@\s]+@[^@\s]+\.[^@\s]+$`, []byte(u.Email))
+	if !matched {
+		return errors.New("Format email tidak valid")
+	}
+
+	// check phone: must be a normalized indonesian number
+	if phoneChanged {
+		matched, _ = regexp.Match(`^62[0-9]{8,13}$`, []byte(u.Phone))
+		if !matched {
+			return errors.New("Nomor telepon tidak valid")
+		}
+	}
+
+	if !IsSupportedLocale(u.Language) {
+		return errors.New("Bahasa tidak didukung")
+	}
+
 	return nil
 }
 
+// IsEmailVerified tells whether the user has proven the ownership of its email
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsPhoneVerified tells whether the user has proven the ownership of its phone
+func (u *User) IsPhoneVerified() bool {
+	return u.PhoneVerifiedAt != nil
+}
+
+// Anonymize removes every personally identifiable data of a deleted user.
+// The row itself is kept, since transactions still refer to it
+func (u *User) Anonymize(unusablePassword string) {
+	now := time.Now()
+	u.Email = fmt.Sprintf("deleted-%d@deleted.sejastip.id", u.ID)
+	u.Name = "Pengguna Terhapus"
+	u.Phone = ""
+	u.Password = unusablePassword
+	u.BankName = ""
+	u.BankAccount = ""
+	u.Avatar = ""
+	u.Language = DefaultLocale
+	u.OrderEmailOptOut = true
+	u.PaymentEmailOptOut = true
+	u.EmailVerifiedAt = nil
+	u.PhoneVerifiedAt = nil
+	u.DeletedAt = &now
+}
+
+// AcceptsEmail tells whether the user wants to receive the emails of the category
+func (u *User) AcceptsEmail(category string) bool {
+	switch category {
+	case EmailCategoryOrder:
+		return !u.OrderEmailOptOut
+	case EmailCategoryPayment:
+		return !u.PaymentEmailOptOut
+	}
+	return false
+}
+
+// IsVerified tells whether all of the user contact details are verified
+func (u *User) IsVerified() bool {
+	return u.IsEmailVerified() && u.IsPhoneVerified()
+}
+
 // ConvertToPublic converts the User model to public representations
 func (u *User) ConvertToPublic() *UserPublic {
 	return &UserPublic{
-		ID:           u.ID,
-		Email:        u.Email,
-		Name:         u.Name,
-		Phone:        u.Phone,
-		BankName:     u.BankName,
-		BankAccount:  u.BankAccount,
-		RegisteredAt: u.CreatedAt,
-		Avatar:       u.Avatar,
+		ID:            u.ID,
+		Email:         u.Email,
+		Name:          u.Name,
+		Phone:         u.Phone,
+		BankName:      u.BankName,
+		BankAccount:   u.BankAccount,
+		RegisteredAt:  u.CreatedAt,
+		Avatar:        u.Avatar,
+		EmailVerified: u.IsEmailVerified(),
+		PhoneVerified: u.IsPhoneVerified(),
+		Rating:        AverageRating(u.RatingSum, u.ReviewCount),
+		ReviewCount:   u.ReviewCount,
+	}
+}
+
+// ConvertToProfile converts the user to the profile shown to the user itself
+func (u *User) ConvertToProfile() *UserProfile {
+	return &UserProfile{
+		UserPublic: u.ConvertToPublic(),
+		Language:   u.Language,
+		EmailPreferences: EmailPreferences{
+			Order:   !u.OrderEmailOptOut,
+			Payment: !u.PaymentEmailOptOut,
+		},
 	}
 }
 
 // UserPublic is the collection of user data publicly available
 type UserPublic struct {
-	ID           int64     `json:"id"`
-	Email        string    `json:"email"`
-	Name         string    `json:"name"`
-	Phone        string    `json:"phone"`
-	BankName     string    `json:"bank_name"`
-	BankAccount  string    `json:"bank_account"`
-	RegisteredAt time.Time `json:"registered_at"`
-	Avatar       string    `json:"avatar"`
+	ID            int64     `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	Phone         string    `json:"phone"`
+	BankName      string    `json:"bank_name"`
+	BankAccount   string    `json:"bank_account"`
+	RegisteredAt  time.Time `json:"registered_at"`
+	Avatar        string    `json:"avatar"`
+	EmailVerified bool      `json:"email_verified"`
+	PhoneVerified bool      `json:"phone_verified"`
+	Rating        float64   `json:"rating"`
+	ReviewCount   uint      `json:"review_count"`
+}
+
+// UserProfile is the user data shown to the user itself, along with its settings
+// which are kept out of the public data
+type UserProfile struct {
+	*UserPublic
+	Language         string           `json:"language"`
+	EmailPreferences EmailPreferences `json:"email_preferences"`
+}
+
+// EmailPreferences tells which categories of emails the user receives
+type EmailPreferences struct {
+	Order   bool `json:"order"`
+	Payment bool `json:"payment"`
+}
+
+// EmailPreferencesForm updates the email categories the user receives, only the
+// provided categories are changed
+type EmailPreferencesForm struct {
+	Order   *bool `json:"order"`
+	Payment *bool `json:"payment"`
+}
+
+// UserUpdateForm is the request body to update the logged in user's profile.
+// Every field is optional, only the provided fields are updated
+type UserUpdateForm struct {
+	Name             *string               `json:"name"`
+	Email            *string               `json:"email"`
+	Phone            *string               `json:"phone"`
+	BankName         *string               `json:"bank_name"`
+	BankAccount      *string               `json:"bank_account"`
+	AvatarFile       *string               `json:"avatar_file"`
+	Language         *string               `json:"language"`
+	EmailPreferences *EmailPreferencesForm `json:"email_preferences"`
+	CurrentPassword  string                `json:"current_password"`
+}
+
+// Apply copies the provided form fields to the user, then returns whether
+// any sensitive field (email or bank account) is changed
+func (f *UserUpdateForm) Apply(u *User) bool {
+	sensitive := false
+	if f.Name != nil {
+		u.Name = *f.Name
+	}
+	if f.Phone != nil {
+		u.Phone = *f.Phone
+	}
+	if f.Language != nil {
+		u.Language = *f.Language
+	}
+	if f.EmailPreferences != nil && f.EmailPreferences.Order != nil {
+		u.OrderEmailOptOut = !*f.EmailPreferences.Order
+	}
+	if f.EmailPreferences != nil && f.EmailPreferences.Payment != nil {
+		u.PaymentEmailOptOut = !*f.EmailPreferences.Payment
+	}
+	if f.Email != nil && strings.TrimSpace(*f.Email) != u.Email {
+		u.Email = *f.Email
+		sensitive = true
+	}
+	if f.BankName != nil && strings.TrimSpace(*f.BankName) != u.BankName {
+		u.BankName = *f.BankName
+		sensitive = true
+	}
+	if f.BankAccount != nil && strings.TrimSpace(*f.BankAccount) != u.BankAccount {
+		u.BankAccount = *f.BankAccount
+		sensitive = true
+	}
+
+	return sensitive
 }
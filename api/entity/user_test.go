@@ -0,0 +1,25 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sejastip.id/api/entity"
+)
+
+func TestUserSettingsAreOnlyInTheProfile(t *testing.T) {
+	user := entity.User{ID: 4, Name: "Rocky Balboa", Language: entity.LocaleEnglish, PaymentEmailOptOut: true}
+
+	public, err := json.Marshal(user.ConvertToPublic())
+	require.NoError(t, err)
+	assert.NotContains(t, string(public), "language")
+	assert.NotContains(t, string(public), "email_preferences")
+
+	profile, err := json.Marshal(user.ConvertToProfile())
+	require.NoError(t, err)
+	assert.Contains(t, string(profile), `"name":"Rocky Balboa"`)
+	assert.Contains(t, string(profile), `"language":"en"`)
+	assert.Contains(t, string(profile), `"email_preferences":{"order":true,"payment":false}`)
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	VerificationChannelEmail = "email"
+	VerificationChannelPhone = "phone"
+)
+
+// Verification stores database row representations of a contact verification
+// request. Code stores the hashed version of the token or OTP sent to the user
+type Verification struct {
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	Channel    string     `db:"channel"`
+	Target     string     `db:"target"`
+	Code       string     `db:"code"`
+	Attempts   int        `db:"attempts"`
+	ExpiredAt  time.Time  `db:"expired_at"`
+	VerifiedAt *time.Time `db:"verified_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+}
+
+// IsExpired tells whether the verification code can no longer be used
+func (v *Verification) IsExpired() bool {
+	return time.Now().After(v.ExpiredAt)
+}
+
+// VerificationRequestResponse is returned after a verification code is sent
+type VerificationRequestResponse struct {
+	Channel   string    `json:"channel"`
+	Target    string    `json:"target"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// PhoneVerificationForm is the request body to confirm a phone OTP
+type PhoneVerificationForm struct {
+	Code string `json:"code"`
+}
+
+// Validate is a function to validate the submitted OTP
+func (f *PhoneVerificationForm) Validate() error {
+	f.Code = strings.TrimSpace(f.Code)
+	if f.Code == "" {
+		return errors.New("Kode OTP wajib diisi")
+	}
+
+	return nil
+}
+
+// OTPMessage is the payload published for the messaging function to deliver
+// a verification code to the user
+type OTPMessage struct {
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+	Content string `json:"content"`
+}
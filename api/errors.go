@@ -27,6 +27,31 @@ var (
 		HTTPStatus: http.StatusUnauthorized,
 	}
 
+	// ErrInvalidIDToken represents error for third-party ID tokens that can't
+	// be verified against any of the trusted issuers
+	ErrInvalidIDToken = SejastipError{
+		Message:    "Token login tidak valid",
+		ErrorCode:  401,
+		HTTPStatus: http.StatusUnauthorized,
+	}
+
+	// ErrUnverifiedIDTokenEmail represents error for third-party ID tokens whose
+	// email is not verified by the issuer, so it can't be linked to an account
+	ErrUnverifiedIDTokenEmail = SejastipError{
+		Message:    "Email akun kamu belum terverifikasi oleh penyedia login",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrUnverifiedAccountEmail represents error for third-party sign-ins matching an
+	// account whose email is not verified. Linking them would let whoever registered
+	// the account, possibly not the owner of the email, keep using its password
+	ErrUnverifiedAccountEmail = SejastipError{
+		Message:    "Email ini sudah terdaftar namun belum terverifikasi. Masuk dengan password lalu verifikasi email kamu terlebih dahulu",
+		ErrorCode:  409,
+		HTTPStatus: http.StatusConflict,
+	}
+
 	// ErrUnauthorized represents error if a user is unauthorized to request
 	ErrUnauthorized = SejastipError{
 		Message:    "Unauthorized",
@@ -34,6 +59,14 @@ var (
 		HTTPStatus: http.StatusUnauthorized,
 	}
 
+	// ErrLoginLocked represents error for authentication attempts on an account
+	// or from an IP address that is temporarily locked after too many failures
+	ErrLoginLocked = SejastipError{
+		Message:    "Terlalu banyak percobaan login yang gagal, silakan coba lagi nanti",
+		ErrorCode:  429,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+
 	// ErrForbidden represents error when a resource can't be accessed by the
 	// requesting user
 	ErrForbidden = SejastipError{
@@ -50,6 +83,150 @@ var (
 		HTTPStatus: http.StatusForbidden,
 	}
 
+	// ErrTooManyProductImages represents error that thrown when a user tries to
+	// add more images to a product than allowed
+	ErrTooManyProductImages = SejastipError{
+		Message:    "Jumlah foto produk sudah mencapai batas maksimum",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrCategoryInUse represents error that thrown when an admin tries to delete
+	// a category that still has subcategories or products
+	ErrCategoryInUse = SejastipError{
+		Message:    "Kategori yang masih memiliki subkategori atau produk tidak dapat dihapus",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrVariantStockChanged represents error that thrown when a seller updates a
+	// variant whose stock was changed by an order in the meantime
+	ErrVariantStockChanged = SejastipError{
+		Message:    "Stok varian produk telah berubah, silakan muat ulang dan coba lagi",
+		ErrorCode:  409,
+		HTTPStatus: http.StatusConflict,
+	}
+
+	// ErrVariantOutOfStock represents error that thrown when a user orders more
+	// items of a product variant than its remaining stock
+	ErrVariantOutOfStock = SejastipError{
+		Message:    "Stok varian produk tidak mencukupi",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrProductClosed represents error that thrown when a user orders a product
+	// whose offering window has ended
+	ErrProductClosed = SejastipError{
+		Message:    "Produk ini sudah tidak menerima pesanan",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrProductReserved represents error that thrown when a user orders a product
+	// made for the buy request of another user
+	ErrProductReserved = SejastipError{
+		Message:    "Produk ini hanya dapat dipesan oleh pembuat permintaan titipnya",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrEditBuyRequestForbidden represents error that thrown when a user tries to
+	// manage a buy request or an offer that is not owned by itself
+	ErrEditBuyRequestForbidden = SejastipError{
+		Message:    "Kamu tidak bisa mengubah permintaan titip atau penawaran yang bukan milik kamu",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrBuyRequestClosed represents error that thrown when a user offers on or
+	// accepts an offer of a buy request that is no longer open
+	ErrBuyRequestClosed = SejastipError{
+		Message:    "Permintaan titip ini sudah tidak menerima penawaran",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrOfferOwnBuyRequest represents error that thrown when a user offers on its
+	// own buy request
+	ErrOfferOwnBuyRequest = SejastipError{
+		Message:    "Kamu tidak bisa memberi penawaran pada permintaan titip milik kamu sendiri",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrDuplicateOffer represents error that thrown when a seller offers again on
+	// a buy request while its previous offer is still pending
+	ErrDuplicateOffer = SejastipError{
+		Message:    "Kamu masih memiliki penawaran yang menunggu jawaban pada permintaan titip ini",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrOfferNotPending represents error that thrown when an offer is accepted or
+	// withdrawn after it was already answered
+	ErrOfferNotPending = SejastipError{
+		Message:    "Penawaran ini sudah tidak menunggu jawaban",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrEditTripForbidden represents error that thrown when a user tries to edit
+	// a trip, or list a product for a trip, that is not owned by itself
+	ErrEditTripForbidden = SejastipError{
+		Message:    "Kamu tidak bisa mengubah atau menggunakan perjalanan yang bukan milik kamu",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrEditReviewForbidden represents error that thrown when a user tries to
+	// review a transaction it did not buy, or reply a review it did not receive
+	ErrEditReviewForbidden = SejastipError{
+		Message:    "Kamu tidak bisa mengulas transaksi atau membalas ulasan yang bukan milik kamu",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrTransactionNotFinished represents error that thrown when a buyer reviews
+	// a transaction that is not finished yet
+	ErrTransactionNotFinished = SejastipError{
+		Message:    "Ulasan hanya bisa diberikan untuk transaksi yang sudah selesai",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrDuplicateReview represents error that thrown when a buyer reviews a
+	// transaction more than once
+	ErrDuplicateReview = SejastipError{
+		Message:    "Transaksi ini sudah kamu ulas",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrChatForbidden represents error that thrown when a user tries to read or
+	// send messages in a conversation it does not take part in
+	ErrChatForbidden = SejastipError{
+		Message:    "Kamu tidak bisa membuka percakapan yang bukan milik kamu",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrEnquireOwnProduct represents error that thrown when a seller opens an
+	// enquiry on its own product
+	ErrEnquireOwnProduct = SejastipError{
+		Message:    "Kamu tidak bisa bertanya tentang produk yang kamu list sendiri",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrTripInUse represents error that thrown when a seller tries to delete a
+	// trip that still has products
+	ErrTripInUse = SejastipError{
+		Message:    "Perjalanan yang masih memiliki produk tidak dapat dihapus",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
 	// ErrEditTransactionForbidden represents error that thrown when a user tries to
 	// edit a transaction data that is not owned by itself
 	ErrEditTransactionForbidden = SejastipError{
@@ -97,6 +274,54 @@ var (
 		ErrorCode:  422,
 		HTTPStatus: http.StatusUnprocessableEntity,
 	}
+
+	// ErrInvalidCurrentPassword represents error that happens when a user tries
+	// to change sensitive profile data without confirming its current password
+	ErrInvalidCurrentPassword = SejastipError{
+		Message:    "Password saat ini salah atau belum diisi",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrAccountHasOpenTransactions represents error that happens when a user tries
+	// to delete its account while some of its transactions are not settled yet
+	ErrAccountHasOpenTransactions = SejastipError{
+		Message:    "Akun tidak dapat dihapus selama masih ada transaksi yang belum selesai",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrUnverifiedContact represents error that happens when a user tries to
+	// sell or buy products before verifying its email and phone number
+	ErrUnverifiedContact = SejastipError{
+		Message:    "Verifikasi email dan nomor telepon kamu terlebih dahulu",
+		ErrorCode:  403,
+		HTTPStatus: http.StatusForbidden,
+	}
+
+	// ErrContactAlreadyVerified represents error that happens when a user requests
+	// a verification code for a contact detail that is already verified
+	ErrContactAlreadyVerified = SejastipError{
+		Message:    "Kontak ini sudah terverifikasi",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrInvalidVerificationCode represents error that happens when the submitted
+	// verification token or OTP does not match, or has expired
+	ErrInvalidVerificationCode = SejastipError{
+		Message:    "Kode verifikasi salah atau sudah kadaluarsa",
+		ErrorCode:  422,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+
+	// ErrTooManyVerificationAttempts represents error that happens when a user
+	// requests or submits verification codes too often
+	ErrTooManyVerificationAttempts = SejastipError{
+		Message:    "Terlalu banyak percobaan verifikasi, silakan coba lagi nanti",
+		ErrorCode:  429,
+		HTTPStatus: http.StatusTooManyRequests,
+	}
 )
 
 // SejastipError defines our custom error
@@ -17,6 +17,7 @@ func StubbedUser() api.User {
 		BankName:    "BCA",
 		BankAccount: "012341234",
 		Avatar:      "https://sejastip.id/img/rockybalboa.jpg",
+		Language:    "id",
 		LastLoginAt: &now,
 		CreatedAt:   now,
 		UpdatedAt:   now,
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/julienschmidt/httprouter"
+	"sejastip.id/api"
 )
 
 var (
 	dms = DefaultMiddlewares()
 
-	AppAuth  []Middleware
-	UserAuth []Middleware
+	AppAuth   []Middleware
+	UserAuth  []Middleware
+	AdminAuth []Middleware
 )
 
 // Route is a contract to bind our http routers
@@ -19,14 +22,16 @@ type Route interface {
 	RegisterHandler(r *httprouter.Router) error
 }
 
-func initAuthMiddlewares(privateKey string) {
-	UserAuth = append([]Middleware{WithAuthentication(privateKey)}, dms...)
+func initAuthMiddlewares(keyfunc jwt.Keyfunc, users api.UserRepository) {
+	UserAuth = append([]Middleware{WithAuthentication(keyfunc, users)}, dms...)
+	// the admin check reads the claims, so it is applied inside the authentication
+	AdminAuth = append([]Middleware{WithAdminAuthorization(), WithAuthentication(keyfunc, users)}, dms...)
 	AppAuth = dms
 }
 
 // NewHandler return standard handlers for our service
-func NewHandler(jwtPrivateKey string, routes ...Route) http.Handler {
-	initAuthMiddlewares(jwtPrivateKey)
+func NewHandler(keyfunc jwt.Keyfunc, users api.UserRepository, routes ...Route) http.Handler {
+	initAuthMiddlewares(keyfunc, users)
 
 	router := httprouter.New()
 
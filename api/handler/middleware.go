@@ -65,8 +65,9 @@ func WithLogging(logger *zap.Logger) Middleware {
 	}
 }
 
-// WithAuthentication encapsulates standard handlers with authentication
-func WithAuthentication(privateKey string) Middleware {
+// WithAuthentication encapsulates standard handlers with authentication.
+// The verification key of a token is selected by the provided keyfunc
+func WithAuthentication(keyfunc jwt.Keyfunc, users api.UserRepository) Middleware {
 	return func(handle StandardHandler) StandardHandler {
 		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
 			authHeader := r.Header.Get("Authorization")
@@ -79,21 +80,46 @@ func WithAuthentication(privateKey string) Middleware {
 			// get claims
 			claims := entity.ResourceClaims{}
 			tokenString := authHeader[6:]
-			token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(privateKey), nil
-			})
+			token, err := jwt.ParseWithClaims(tokenString, &claims, keyfunc)
 			if err != nil || !token.Valid {
 				api.Error(w, api.ErrUnauthorized)
 				return api.ErrUnauthorized
 			}
 
+			// the token outlives the account, so a deleted user is turned away here
 			ctx := r.Context()
+			user, err := users.GetUser(ctx, claims.ID)
+			if err != nil && err != api.ErrNotFound {
+				api.Error(w, err)
+				return err
+			}
+			if user == nil || user.DeletedAt != nil {
+				api.Error(w, api.ErrUnauthorized)
+				return api.ErrUnauthorized
+			}
+			// a revoked admin keeps its token, so the flag is always read from the user
+			claims.Admin = user.IsAdmin
+
 			ctx = context.WithValue(ctx, api.ContextKeyName, claims)
 			return handle(w, r.WithContext(ctx), p)
 		}
 	}
 }
 
+// WithAdminAuthorization only lets authenticated admins through. It relies on the
+// admin flag loaded by WithAuthentication
+func WithAdminAuthorization() Middleware {
+	return func(handle StandardHandler) StandardHandler {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			if !api.MetaFromContext(r.Context()).Admin {
+				api.Error(w, api.ErrForbidden)
+				return api.ErrForbidden
+			}
+			return handle(w, r, p)
+		}
+	}
+}
+
 // DefaultMiddlewares will return default configured middlewares
 func DefaultMiddlewares() []Middleware {
 	l, _ := zap.NewProduction()
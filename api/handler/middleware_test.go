@@ -0,0 +1,104 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/handler"
+	"sejastip.id/api/infra"
+)
+
+type fakeUserRepo struct {
+	api.UserRepository
+	users map[int64]entity.User
+}
+
+func (r *fakeUserRepo) GetUser(ctx context.Context, ID int64) (*entity.User, error) {
+	user, ok := r.users[ID]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return &user, nil
+}
+
+type authenticationTestSuite struct {
+	suite.Suite
+	keys  *infra.KeySet
+	users *fakeUserRepo
+}
+
+func (s *authenticationTestSuite) SetupTest() {
+	s.keys = infra.NewKeySet("", "secret", time.Time{})
+	s.users = &fakeUserRepo{users: map[int64]entity.User{
+		4: {ID: 4, Email: "rocky@sejastip.id"},
+	}}
+}
+
+// request calls a handler behind the middlewares with a token of the user
+func (s *authenticationTestSuite) request(claims jwt.MapClaims, middlewares ...handler.Middleware) *httptest.ResponseRecorder {
+	token, err := s.keys.Sign(claims)
+	s.Require().NoError(err)
+
+	handle := handler.AppendMiddlewares(
+		func(w http.ResponseWriter, r *http.Request, p httprouter.Params) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		},
+		middlewares...,
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/me", nil)
+	r.Header.Set("Authorization", "Token "+token)
+	handle(w, r, nil)
+	return w
+}
+
+func (s *authenticationTestSuite) userRequest(userID int64) *httptest.ResponseRecorder {
+	return s.request(jwt.MapClaims{"id": userID}, handler.WithAuthentication(s.keys.Keyfunc, s.users))
+}
+
+func (s *authenticationTestSuite) adminRequest(claims jwt.MapClaims) *httptest.ResponseRecorder {
+	return s.request(claims, handler.WithAdminAuthorization(), handler.WithAuthentication(s.keys.Keyfunc, s.users))
+}
+
+func (s *authenticationTestSuite) TestActiveUserIsLetThrough() {
+	s.Equal(http.StatusNoContent, s.userRequest(4).Code)
+}
+
+func (s *authenticationTestSuite) TestDeletedUserIsRejected() {
+	user := s.users.users[4]
+	user.Anonymize("")
+	s.users.users[4] = user
+
+	s.Equal(http.StatusUnauthorized, s.userRequest(4).Code)
+}
+
+func (s *authenticationTestSuite) TestUnknownUserIsRejected() {
+	s.Equal(http.StatusUnauthorized, s.userRequest(5).Code)
+}
+
+func (s *authenticationTestSuite) TestAdminIsReadFromTheUser() {
+	user := s.users.users[4]
+	user.IsAdmin = true
+	s.users.users[4] = user
+
+	s.Equal(http.StatusNoContent, s.adminRequest(jwt.MapClaims{"id": 4}).Code)
+}
+
+func (s *authenticationTestSuite) TestAdminClaimOfTheTokenIsIgnored() {
+	// the token of a revoked admin, or a forged claim
+	s.Equal(http.StatusForbidden, s.adminRequest(jwt.MapClaims{"id": 4, "admin": true}).Code)
+}
+
+func TestAuthentication(t *testing.T) {
+	suite.Run(t, new(authenticationTestSuite))
+}
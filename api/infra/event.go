@@ -0,0 +1,54 @@
+package infra
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sejastip.id/api/entity"
+)
+
+// LocalEventPublisher delivers domain events in process, to the handlers subscribed
+// to them, and records them so tests can check what would have been published
+type LocalEventPublisher struct {
+	mu       sync.Mutex
+	events   []entity.EventEnvelope
+	handlers map[string][]func(ctx context.Context, envelope entity.EventEnvelope)
+}
+
+func NewLocalEventPublisher() *LocalEventPublisher {
+	return &LocalEventPublisher{
+		handlers: map[string][]func(ctx context.Context, envelope entity.EventEnvelope){},
+	}
+}
+
+// Subscribe registers a handler of the events having the name
+func (p *LocalEventPublisher) Subscribe(name string, handler func(ctx context.Context, envelope entity.EventEnvelope)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[name] = append(p.handlers[name], handler)
+}
+
+// PublishEvent records the event, then runs its handlers before returning
+func (p *LocalEventPublisher) PublishEvent(ctx context.Context, event entity.DomainEvent) error {
+	envelope := entity.NewEventEnvelope(event, time.Now())
+
+	p.mu.Lock()
+	p.events = append(p.events, *envelope)
+	handlers := append([]func(ctx context.Context, envelope entity.EventEnvelope){}, p.handlers[envelope.Name]...)
+	p.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, *envelope)
+	}
+	return nil
+}
+
+// Events returns the events published so far, oldest first
+func (p *LocalEventPublisher) Events() []entity.EventEnvelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]entity.EventEnvelope{}, p.events...)
+}
@@ -0,0 +1,199 @@
+package infra
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api/entity"
+)
+
+// SigningKey is an asymmetric private key identified by its key ID (kid)
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+}
+
+// KeySet holds every key that is used to sign and verify our access tokens.
+// New tokens are signed with the active key, while every other key in the set
+// is still accepted, so a key can be rotated without logging everyone out.
+// Legacy HS256 tokens signed with the shared secret are accepted until
+// the end of the migration window, or for as long as the secret is set when
+// the window has no end.
+type KeySet struct {
+	keys      map[string]*SigningKey
+	activeKID string
+
+	hmacSecret        []byte
+	hmacAcceptedUntil time.Time
+}
+
+// NewKeySet creates an empty key set. When activeKID is empty, tokens are
+// signed with HS256 using hmacSecret, as before the asymmetric keys existed
+func NewKeySet(activeKID, hmacSecret string, hmacAcceptedUntil time.Time) *KeySet {
+	return &KeySet{
+		keys:              map[string]*SigningKey{},
+		activeKID:         activeKID,
+		hmacSecret:        []byte(hmacSecret),
+		hmacAcceptedUntil: hmacAcceptedUntil,
+	}
+}
+
+// LoadKeySetDir creates a key set from a directory of PEM-encoded private keys.
+// Every file named <kid>.pem is loaded, using the file name as its key ID
+func LoadKeySetDir(dir, activeKID, hmacSecret string, hmacAcceptedUntil time.Time) (*KeySet, error) {
+	ks := NewKeySet(activeKID, hmacSecret, hmacAcceptedUntil)
+	if dir == "" {
+		return ks, ks.validate()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading key %s", file)
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if err := ks.AddPEM(kid, contents); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, ks.validate()
+}
+
+// AddPEM parses a PEM-encoded RSA (RS256) or EC P-256 (ES256) private key and
+// adds it to the key set
+func (k *KeySet) AddPEM(kid string, contents []byte) error {
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(contents); err == nil {
+		k.keys[kid] = &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Private: rsaKey}
+		return nil
+	}
+
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM(contents)
+	if err != nil {
+		return fmt.Errorf("key %s is neither an RSA nor an EC private key", kid)
+	}
+	if ecKey.Curve.Params().Name != "P-256" {
+		return fmt.Errorf("key %s must use the P-256 curve", kid)
+	}
+
+	k.keys[kid] = &SigningKey{ID: kid, Method: jwt.SigningMethodES256, Private: ecKey}
+	return nil
+}
+
+func (k *KeySet) validate() error {
+	if k.activeKID != "" {
+		if _, ok := k.keys[k.activeKID]; !ok {
+			return fmt.Errorf("active signing key %s is not found in the key set", k.activeKID)
+		}
+	} else if len(k.hmacSecret) == 0 {
+		return errors.New("either an active signing key or an HS256 secret is required")
+	}
+
+	return nil
+}
+
+// Sign creates a signed token string from the claims using the active key
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.activeKID == "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.hmacSecret)
+	}
+
+	key := k.keys[k.activeKID]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc selects the verification key of a token by its kid header.
+// It satisfies jwt.Keyfunc
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() || len(k.hmacSecret) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		// HS256 is accepted for as long as it is still used for signing, then
+		// until the cutoff if any
+		cutoff := k.hmacAcceptedUntil
+		if k.activeKID != "" && !cutoff.IsZero() && time.Now().After(cutoff) {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return k.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	// the algorithm must match the key, to avoid algorithm confusion
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.Private.Public(), nil
+}
+
+// JWKS returns the public keys of the key set, to be published for verifiers
+func (k *KeySet) JWKS() entity.JWKS {
+	kids := []string{}
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := entity.JWKS{Keys: []entity.JWK{}}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := entity.JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch pub := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeJWKInt(pub.N)
+			jwk.E = encodeJWKInt(big.NewInt(int64(pub.E)))
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = encodeJWKCoordinate(pub.X, pub.Curve.Params().BitSize)
+			jwk.Y = encodeJWKCoordinate(pub.Y, pub.Curve.Params().BitSize)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func encodeJWKInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// encodeJWKCoordinate encodes an EC coordinate left-padded to the curve size
+func encodeJWKCoordinate(n *big.Int, bitSize int) string {
+	size := (bitSize + 7) / 8
+	b := n.Bytes()
+	padded := make([]byte, size-len(b), size)
+	return base64.RawURLEncoding.EncodeToString(append(padded, b...))
+}
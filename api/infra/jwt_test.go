@@ -0,0 +1,114 @@
+package infra_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api/infra"
+)
+
+type keySetTestSuite struct {
+	suite.Suite
+	currentPEM  []byte
+	previousPEM []byte
+	ecPEM       []byte
+}
+
+func (s *keySetTestSuite) SetupSuite() {
+	for _, keyPEM := range []*[]byte{&s.currentPEM, &s.previousPEM} {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		s.Require().NoError(err)
+		*keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	s.Require().NoError(err)
+	s.ecPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+// keySet creates the key set of the server, signing with the current key and
+// still accepting the previous one
+func (s *keySetTestSuite) keySet(hmacSecret string, hmacAcceptedUntil time.Time) *infra.KeySet {
+	ks := infra.NewKeySet("current", hmacSecret, hmacAcceptedUntil)
+	s.Require().NoError(ks.AddPEM("current", s.currentPEM))
+	s.Require().NoError(ks.AddPEM("previous", s.previousPEM))
+	return ks
+}
+
+// signWith signs a token with a single key set as active
+func (s *keySetTestSuite) signWith(kid string, keyPEM []byte) string {
+	ks := infra.NewKeySet(kid, "", time.Time{})
+	s.Require().NoError(ks.AddPEM(kid, keyPEM))
+	token, err := ks.Sign(jwt.MapClaims{"sub": "4"})
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *keySetTestSuite) signHS256(secret string) string {
+	token, err := infra.NewKeySet("", secret, time.Time{}).Sign(jwt.MapClaims{"sub": "4"})
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *keySetTestSuite) verify(ks *infra.KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc)
+	return err
+}
+
+func (s *keySetTestSuite) TestActiveKey() {
+	ks := s.keySet("", time.Time{})
+
+	token, err := ks.Sign(jwt.MapClaims{"sub": "4"})
+	s.Require().NoError(err)
+
+	s.NoError(s.verify(ks, token))
+}
+
+func (s *keySetTestSuite) TestRotatedOutKeyIsStillAccepted() {
+	s.NoError(s.verify(s.keySet("", time.Time{}), s.signWith("previous", s.previousPEM)))
+}
+
+func (s *keySetTestSuite) TestUnknownKeyIsRejected() {
+	// a key removed from the set is retired for good
+	err := s.verify(s.keySet("", time.Time{}), s.signWith("retired", s.previousPEM))
+	s.EqualError(err, `unknown signing key "retired"`)
+}
+
+func (s *keySetTestSuite) TestAlgorithmMustMatchTheKey() {
+	// an ES256 token claiming to be signed with our RSA key
+	err := s.verify(s.keySet("", time.Time{}), s.signWith("current", s.ecPEM))
+	s.EqualError(err, "unexpected signing method")
+}
+
+func (s *keySetTestSuite) TestHS256WithinTheWindow() {
+	ks := s.keySet("secret", time.Now().Add(time.Hour))
+
+	s.NoError(s.verify(ks, s.signHS256("secret")))
+	s.Error(s.verify(ks, s.signHS256("guessed")))
+}
+
+func (s *keySetTestSuite) TestHS256AfterTheWindow() {
+	ks := s.keySet("secret", time.Now().Add(-time.Hour))
+
+	s.EqualError(s.verify(ks, s.signHS256("secret")), "HS256 tokens are no longer accepted")
+}
+
+func (s *keySetTestSuite) TestHS256WithoutCutoff() {
+	// the sessions issued before the rotation are kept until the secret is removed
+	s.NoError(s.verify(s.keySet("secret", time.Time{}), s.signHS256("secret")))
+	s.EqualError(s.verify(s.keySet("", time.Time{}), s.signHS256("secret")), "unexpected signing method")
+}
+
+func TestKeySet(t *testing.T) {
+	suite.Run(t, new(keySetTestSuite))
+}
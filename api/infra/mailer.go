@@ -0,0 +1,250 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"sejastip.id/api/entity"
+)
+
+// SMTPMailer delivers emails through an SMTP server. The whole exchange with the
+// server must end within the timeout, or the deadline of the context if sooner
+type SMTPMailer struct {
+	host    string
+	addr    string
+	auth    smtp.Auth
+	from    string
+	timeout time.Duration
+}
+
+// NewSMTPMailer creates a mailer sending through host:port. The server is not
+// authenticated to when the username is empty
+func NewSMTPMailer(host string, port int, username, password, from string, timeout time.Duration) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		host:    host,
+		addr:    fmt.Sprintf("%s:%d", host, port),
+		auth:    auth,
+		from:    from,
+		timeout: timeout,
+	}
+}
+
+func (m *SMTPMailer) SendEmail(ctx context.Context, email *entity.Email) error {
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+	// the envelope only takes the address, without the display name
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return errors.Wrap(err, "error parsing sender address")
+	}
+
+	deadline := time.Now().Add(m.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(email.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailSender is implemented by every mailer
+type emailSender interface {
+	SendEmail(ctx context.Context, email *entity.Email) error
+}
+
+// QueuedMailer sends emails through another mailer in the background, so a slow
+// mail server does not hold up the requests. Emails are dropped when the queue is
+// full, and failures are only logged
+type QueuedMailer struct {
+	mailer  emailSender
+	queue   chan entity.Email
+	timeout time.Duration
+}
+
+// NewQueuedMailer starts sending the emails queued, one at a time, each within
+// the timeout
+func NewQueuedMailer(mailer emailSender, size int, timeout time.Duration) *QueuedMailer {
+	m := &QueuedMailer{
+		mailer:  mailer,
+		queue:   make(chan entity.Email, size),
+		timeout: timeout,
+	}
+	go m.run()
+	return m
+}
+
+func (m *QueuedMailer) SendEmail(ctx context.Context, email *entity.Email) error {
+	select {
+	case m.queue <- *email:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return errors.New("the mail queue is full")
+	}
+}
+
+func (m *QueuedMailer) run() {
+	for email := range m.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		if err := m.mailer.SendEmail(ctx, &email); err != nil {
+			log.Printf("error sending email %q to %s: %v", email.Subject, email.To, err)
+		}
+		cancel()
+	}
+}
+
+// FileMailer drops every email as an .eml file in a directory instead of sending it.
+// It is meant to be used in development, the files open in any mail client
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) SendEmail(ctx context.Context, email *entity.Email) error {
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102150405"), uuid.New().String())
+	return ioutil.WriteFile(filepath.Join(m.dir, filename), message, 0644)
+}
+
+// MemoryMailer records emails instead of sending them, so tests can check what
+// would have been sent
+type MemoryMailer struct {
+	mu     sync.Mutex
+	emails []entity.Email
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) SendEmail(ctx context.Context, email *entity.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emails = append(m.emails, *email)
+	return nil
+}
+
+// Emails returns the emails recorded so far, oldest first
+func (m *MemoryMailer) Emails() []entity.Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]entity.Email{}, m.emails...)
+}
+
+// buildMessage builds a multipart/alternative message carrying both bodies of the
+// email, so mail clients without HTML support show the plain text one
+func buildMessage(from string, email *entity.Email) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	}
+	for _, p := range parts {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", email.To)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
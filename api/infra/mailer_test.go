@@ -0,0 +1,67 @@
+package infra_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/infra"
+)
+
+// slowMailer hands the emails over to the test, then waits to be released
+type slowMailer struct {
+	sent    chan context.Context
+	release chan struct{}
+}
+
+func (m *slowMailer) SendEmail(ctx context.Context, email *entity.Email) error {
+	m.sent <- ctx
+	<-m.release
+	return nil
+}
+
+func TestQueuedMailerSendsInTheBackground(t *testing.T) {
+	slow := &slowMailer{sent: make(chan context.Context), release: make(chan struct{})}
+	defer close(slow.release)
+	mailer := infra.NewQueuedMailer(slow, 1, time.Minute)
+	email := &entity.Email{To: "rocky@sejastip.id"}
+
+	// the first email is being sent, the second one waits in the queue
+	require.NoError(t, mailer.SendEmail(context.Background(), email))
+	ctx := <-slow.sent
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+	require.NoError(t, mailer.SendEmail(context.Background(), email))
+
+	// the queue is full, yet queueing does not wait for the mail server
+	assert.Error(t, mailer.SendEmail(context.Background(), email))
+}
+
+func TestSMTPMailerGivesUpOnASilentServer(t *testing.T) {
+	// the server accepts the connection but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	mailer := infra.NewSMTPMailer("127.0.0.1", port, "", "", "Sejastip <no-reply@sejastip.id>", 100*time.Millisecond)
+
+	started := time.Now()
+	err = mailer.SendEmail(context.Background(), &entity.Email{To: "rocky@sejastip.id"})
+
+	assert.Error(t, err)
+	assert.True(t, time.Since(started) < 5*time.Second)
+}
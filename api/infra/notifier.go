@@ -0,0 +1,49 @@
+package infra
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"sejastip.id/api/entity"
+)
+
+// LogNotifier only prints notifications to the application log.
+// It is meant to be used in development, where no push is sent
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) PublishNotification(ctx context.Context, notification *entity.NotificationRequest) error {
+	log.Printf("Notification for User ID %d: %s - %s", notification.UserID, notification.Data.Title, notification.Data.Content)
+	return nil
+}
+
+// MemoryNotifier records notifications instead of sending them, so tests can
+// check what would have been pushed
+type MemoryNotifier struct {
+	mu            sync.Mutex
+	notifications []entity.NotificationRequest
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (n *MemoryNotifier) PublishNotification(ctx context.Context, notification *entity.NotificationRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.notifications = append(n.notifications, *notification)
+	return nil
+}
+
+// Notifications returns the notifications recorded so far, oldest first
+func (n *MemoryNotifier) Notifications() []entity.NotificationRequest {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]entity.NotificationRequest{}, n.notifications...)
+}
@@ -0,0 +1,278 @@
+package infra
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api/entity"
+)
+
+// the minimum time between two JWKS fetches of an issuer, so tokens with
+// unknown key IDs can't be used to hammer the issuer
+const jwksRefetchInterval = time.Minute
+
+// OIDCProvider is a trusted ID token issuer
+type OIDCProvider struct {
+	Issuer    string
+	JWKSURL   string
+	Audiences []string
+}
+
+// ParseOIDCProviders parses provider specs formatted as
+// issuer|jwks_url|audience[;audience...]
+func ParseOIDCProviders(specs []string) ([]OIDCProvider, error) {
+	providers := []OIDCProvider{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		parts := strings.Split(spec, "|")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid OIDC provider %q, expected issuer|jwks_url|audiences", spec)
+		}
+
+		providers = append(providers, OIDCProvider{
+			Issuer:    parts[0],
+			JWKSURL:   parts[1],
+			Audiences: strings.Split(parts[2], ";"),
+		})
+	}
+
+	return providers, nil
+}
+
+type cachedJWKS struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// OIDCVerifier verifies ID tokens issued by the trusted providers against
+// their published keys, which are cached for cacheTTL
+type OIDCVerifier struct {
+	providers map[string]OIDCProvider
+	client    *http.Client
+	cacheTTL  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*cachedJWKS
+}
+
+// NewOIDCVerifier creates a verifier trusting the provided issuers
+func NewOIDCVerifier(providers []OIDCProvider, cacheTTL time.Duration) *OIDCVerifier {
+	providerMap := map[string]OIDCProvider{}
+	for _, p := range providers {
+		providerMap[p.Issuer] = p
+	}
+
+	return &OIDCVerifier{
+		providers: providerMap,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:  cacheTTL,
+		cache:     map[string]*cachedJWKS{},
+	}
+}
+
+// VerifyIDToken checks the token signature, issuer, audience and expiry,
+// then returns the identity asserted by the token
+func (v *OIDCVerifier) VerifyIDToken(ctx context.Context, rawToken string) (*entity.OIDCIdentity, error) {
+	var provider OIDCProvider
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		issuer, _ := claims["iss"].(string)
+		p, ok := v.providers[issuer]
+		if !ok {
+			return nil, fmt.Errorf("untrusted issuer %q", issuer)
+		}
+		provider = p
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, errors.New("unexpected signing method")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return v.getKey(ctx, provider, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error verifying ID token")
+	}
+
+	// MapClaims only validates the expiry when it is present
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("ID token has no expiry")
+	}
+	if !audienceAllowed(claims["aud"], provider.Audiences) {
+		return nil, errors.New("ID token audience is not allowed")
+	}
+
+	identity := &entity.OIDCIdentity{Issuer: provider.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.Picture, _ = claims["picture"].(string)
+	// some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return identity, nil
+}
+
+func audienceAllowed(aud interface{}, allowed []string) bool {
+	audiences := []string{}
+	switch a := aud.(type) {
+	case string:
+		audiences = append(audiences, a)
+	case []interface{}:
+		for _, item := range a {
+			if s, ok := item.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+
+	for _, a := range audiences {
+		for _, b := range allowed {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getKey returns the cached key of a provider, fetching the JWKS again when
+// the cache is stale or the key ID is unknown, e.g. after the issuer rotated its keys
+func (v *OIDCVerifier) getKey(ctx context.Context, provider OIDCProvider, kid string) (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cached := v.cache[provider.Issuer]
+	if cached != nil {
+		key, ok := cached.keys[kid]
+		age := time.Since(cached.fetchedAt)
+		if ok && age < v.cacheTTL {
+			return key, nil
+		}
+		if !ok && age < jwksRefetchInterval {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	keys, err := v.fetchJWKS(ctx, provider.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	v.cache[provider.Issuer] = &cachedJWKS{keys: keys, fetchedAt: time.Now()}
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *OIDCVerifier) fetchJWKS(ctx context.Context, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching JWKS")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected JWKS response status %d", res.StatusCode)
+	}
+
+	var jwks entity.JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, errors.Wrap(err, "error decoding JWKS")
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		key, err := PublicKeyFromJWK(jwk)
+		if err != nil {
+			// skip keys we don't support, e.g. encryption keys
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// PublicKeyFromJWK converts an RSA or EC JSON Web Key to its public key
+func PublicKeyFromJWK(jwk entity.JWK) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, err := decodeJWKInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid JWK parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,115 @@
+package infra_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api/infra"
+)
+
+const testIssuer = "https://issuer.sejastip.test"
+
+type oidcVerifierTestSuite struct {
+	suite.Suite
+	issuerKeys *infra.KeySet
+	server     *httptest.Server
+	fetches    int
+
+	verifier *infra.OIDCVerifier
+}
+
+func (s *oidcVerifierTestSuite) SetupTest() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	// the issuer publishes its keys the same way we do
+	s.issuerKeys = infra.NewKeySet("issuer-key", "", time.Time{})
+	s.Require().NoError(s.issuerKeys.AddPEM("issuer-key", keyPEM))
+
+	s.fetches = 0
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches++
+		json.NewEncoder(w).Encode(s.issuerKeys.JWKS())
+	}))
+
+	s.verifier = infra.NewOIDCVerifier([]infra.OIDCProvider{
+		{Issuer: testIssuer, JWKSURL: s.server.URL, Audiences: []string{"sejastip-app"}},
+	}, time.Hour)
+}
+
+func (s *oidcVerifierTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *oidcVerifierTestSuite) signToken(claims jwt.MapClaims) string {
+	token, err := s.issuerKeys.Sign(claims)
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *oidcVerifierTestSuite) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"sub":            "1234567890",
+		"aud":            "sejastip-app",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"email":          "rockybalboa@gmail.com",
+		"email_verified": true,
+		"name":           "Rocky Balboa",
+	}
+}
+
+func (s *oidcVerifierTestSuite) TestVerifyIDToken() {
+	ctx := context.Background()
+	identity, err := s.verifier.VerifyIDToken(ctx, s.signToken(s.validClaims()))
+
+	s.NoError(err)
+	s.Equal(testIssuer, identity.Issuer)
+	s.Equal("1234567890", identity.Subject)
+	s.Equal("rockybalboa@gmail.com", identity.Email)
+	s.True(identity.EmailVerified)
+
+	// the second verification is served from the cached JWKS
+	_, err = s.verifier.VerifyIDToken(ctx, s.signToken(s.validClaims()))
+	s.NoError(err)
+	s.Equal(1, s.fetches)
+}
+
+func (s *oidcVerifierTestSuite) TestVerifyIDTokenRejectsWrongAudience() {
+	claims := s.validClaims()
+	claims["aud"] = "another-app"
+
+	_, err := s.verifier.VerifyIDToken(context.Background(), s.signToken(claims))
+	s.Error(err)
+}
+
+func (s *oidcVerifierTestSuite) TestVerifyIDTokenRejectsUntrustedIssuer() {
+	claims := s.validClaims()
+	claims["iss"] = "https://evil.example.com"
+
+	_, err := s.verifier.VerifyIDToken(context.Background(), s.signToken(claims))
+	s.Error(err)
+}
+
+func (s *oidcVerifierTestSuite) TestVerifyIDTokenRejectsExpiredToken() {
+	claims := s.validClaims()
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	_, err := s.verifier.VerifyIDToken(context.Background(), s.signToken(claims))
+	s.Error(err)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	suite.Run(t, new(oidcVerifierTestSuite))
+}
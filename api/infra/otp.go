@@ -0,0 +1,63 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"cloud.google.com/go/pubsub"
+	"sejastip.id/api/entity"
+)
+
+// LogOTPSender only prints verification codes to the application log.
+// It is meant to be used in development, where no real message is sent
+type LogOTPSender struct{}
+
+func NewLogOTPSender() *LogOTPSender {
+	return &LogOTPSender{}
+}
+
+func (s *LogOTPSender) SendEmailVerification(ctx context.Context, email, link string) error {
+	log.Printf("Email verification link for %s: %s", email, link)
+	return nil
+}
+
+func (s *LogOTPSender) SendPhoneOTP(ctx context.Context, phone, code string) error {
+	log.Printf("Phone verification OTP for %s: %s", phone, code)
+	return nil
+}
+
+// SendEmailVerification publishes an email verification link to be delivered
+// by the messaging function subscribing to the topic
+func (p *PubsubClient) SendEmailVerification(ctx context.Context, email, link string) error {
+	return p.publishOTP(ctx, &entity.OTPMessage{
+		Channel: entity.VerificationChannelEmail,
+		Target:  email,
+		Content: link,
+	})
+}
+
+// SendPhoneOTP publishes a phone OTP to be delivered through SMS or WhatsApp
+// by the messaging function subscribing to the topic
+func (p *PubsubClient) SendPhoneOTP(ctx context.Context, phone, code string) error {
+	return p.publishOTP(ctx, &entity.OTPMessage{
+		Channel: entity.VerificationChannelPhone,
+		Target:  phone,
+		Content: code,
+	})
+}
+
+func (p *PubsubClient) publishOTP(ctx context.Context, message *entity.OTPMessage) error {
+	if p.client == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	topic := p.client.Topic("send-otp")
+	_, err = topic.Publish(ctx, &pubsub.Message{Data: b}).Get(ctx)
+	return err
+}
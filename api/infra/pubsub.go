@@ -24,17 +24,53 @@ func NewPubsubClient(projectID string) (*PubsubClient, error) {
 	return &PubsubClient{client}, nil
 }
 
-func (p *PubsubClient) PublishNotification(ctx context.Context, notification *entity.NotificationRequest) {
-	if p.client == nil {
-		return
-	}
-
+// PublishNotification publishes a notification to be pushed to the device of the
+// user by the function subscribing to the topic
+func (p *PubsubClient) PublishNotification(ctx context.Context, notification *entity.NotificationRequest) error {
 	b, err := json.Marshal(notification)
 	if err != nil {
-		return
+		return err
 	}
 
-	topic := p.client.Topic("send-push-notification")
-	_, err = topic.Publish(ctx, &pubsub.Message{Data: b}).Get(ctx)
+	err = p.Publish(ctx, entity.TopicPushNotification, b)
 	log.Printf("Published a notification data to Pub/Sub for User ID %d: %v", notification.UserID, err)
+	return err
+}
+
+// Publish publishes a message to the topic, and waits for pubsub to accept it
+func (p *PubsubClient) Publish(ctx context.Context, topicID string, data []byte) error {
+	if p.client == nil {
+		return nil
+	}
+
+	topic := p.client.Topic(topicID)
+	_, err := topic.Publish(ctx, &pubsub.Message{Data: data}).Get(ctx)
+	return err
+}
+
+// ReceiveInvalidDevices handles the device IDs the notification function reports
+// as rejected by the push service, until the context is done. Messages failing to
+// be handled are redelivered
+func (p *PubsubClient) ReceiveInvalidDevices(ctx context.Context, subscriptionID string, handle func(ctx context.Context, deviceIDs []string) error) error {
+	if p.client == nil {
+		return nil
+	}
+
+	subscription := p.client.Subscription(subscriptionID)
+	return subscription.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		var message entity.InvalidDevicesMessage
+		if err := json.Unmarshal(msg.Data, &message); err != nil {
+			// a malformed message never gets better, so it is dropped
+			log.Printf("Dropped a malformed invalid devices message %s: %v", msg.ID, err)
+			msg.Ack()
+			return
+		}
+
+		if err := handle(ctx, message.DeviceIDs); err != nil {
+			log.Printf("error handling invalid devices message %s: %v", msg.ID, err)
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+	})
 }
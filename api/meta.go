@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strings"
 
@@ -25,6 +26,27 @@ func GetUserAgent(r *http.Request) string {
 	return r.Header.Get("User-Agent")
 }
 
+// GetIPAddress returns the client IP address. Behind trustedProxies proxies, each
+// appending the address it got the request from to X-Forwarded-For, the client is
+// the rightmost address not appended by them. The addresses on its left are sent
+// by the client itself, so they can't be trusted
+func GetIPAddress(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		hops := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+		if len(hops) >= trustedProxies {
+			if hop := strings.TrimSpace(hops[len(hops)-trustedProxies]); hop != "" {
+				return hop
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func GetUserID(ctx context.Context) int64 {
 	return MetaFromContext(ctx).ID
 }
@@ -0,0 +1,36 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sejastip.id/api"
+)
+
+func TestGetIPAddress(t *testing.T) {
+	cases := []struct {
+		name           string
+		forwardedFor   []string
+		trustedProxies int
+		expected       string
+	}{
+		{"without proxy, forwarded addresses are ignored", []string{"10.0.0.1"}, 0, "192.0.2.1"},
+		{"behind a proxy, the address it appended", []string{"198.51.100.7"}, 1, "198.51.100.7"},
+		{"addresses sent by the client are skipped", []string{"10.0.0.1, 10.0.0.2, 198.51.100.7"}, 1, "198.51.100.7"},
+		{"behind two proxies", []string{"10.0.0.1, 198.51.100.7, 203.0.113.9"}, 2, "198.51.100.7"},
+		{"repeated headers are read as one list", []string{"10.0.0.1", "198.51.100.7"}, 1, "198.51.100.7"},
+		{"fewer hops than proxies", []string{"198.51.100.7"}, 2, "192.0.2.1"},
+		{"missing header", nil, 1, "192.0.2.1"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", "/auth", nil)
+		r.RemoteAddr = "192.0.2.1:4321"
+		for _, value := range c.forwardedFor {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+
+		assert.Equal(t, c.expected, api.GetIPAddress(r, c.trustedProxies), c.name)
+	}
+}
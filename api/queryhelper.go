@@ -3,8 +3,10 @@ package api
 import (
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"sejastip.id/api/entity"
 )
@@ -15,22 +17,145 @@ type QueryHelper struct {
 	uv url.Values
 }
 
-// GetFilters build a dynamic filter by iterating through the query parameters
-func (q *QueryHelper) GetFilters() entity.DynamicFilter {
-	reservedParams := map[string]struct{}{
-		"limit":  struct{}{},
-		"offset": struct{}{},
+// reservedParams are query parameters that are never parsed as filters
+var reservedParams = map[string]struct{}{
+	"limit":  struct{}{},
+	"offset": struct{}{},
+	"sort":   struct{}{},
+	"cursor": struct{}{},
+	"count":  struct{}{},
+}
+
+// GetPage parses the pagination query parameters. Sending the cursor parameter,
+// even empty for the first page, selects cursor mode instead of offset mode,
+// and count=false skips counting the total of items
+func (q *QueryHelper) GetPage(defaultLimit int) (entity.Page, error) {
+	page := entity.Page{
+		Limit:     q.GetInt("limit", defaultLimit),
+		Offset:    q.GetInt("offset", 0),
+		SkipCount: !q.GetBool("count", true),
+	}
+
+	if _, ok := q.uv["cursor"]; ok {
+		page.CursorMode = true
+		page.Offset = 0
+		if cursor := q.uv.Get("cursor"); cursor != "" {
+			after, err := entity.DecodeCursor(cursor)
+			if err != nil {
+				return page, ValidationError(err)
+			}
+			page.After = after
+		}
+	}
+
+	return page, nil
+}
+
+// GetFilterSpec parses the filter and sort query parameters accepted by the schema.
+// Filters are formatted as `key=value` or `key[op]=value`, `in` values are comma
+// separated, and sort is a comma separated list of keys, prefixed with - for
+// descending order. Unknown keys, operators and invalid values are rejected
+func (q *QueryHelper) GetFilterSpec(schema entity.FilterSchema) (*entity.FilterSpec, error) {
+	spec := entity.NewFilterSpec()
+	for param, values := range q.uv {
+		param = strings.ToLower(param)
+		if _, ok := reservedParams[param]; ok {
+			continue
+		}
+		if schema.SearchKey != "" && param == schema.SearchKey {
+			spec.Search = strings.TrimSpace(values[0])
+			continue
+		}
+
+		key, op := param, entity.FilterOperator("")
+		if i := strings.Index(param, "["); i > 0 && strings.HasSuffix(param, "]") {
+			key, op = param[:i], entity.FilterOperator(param[i+1:len(param)-1])
+		}
+
+		field, ok := schema.Field(key)
+		if !ok {
+			return nil, CustomValidationError("Filter %s tidak dikenal, filter yang tersedia: %s", key, strings.Join(schema.FilterKeys(), ", "))
+		}
+		if op == "" {
+			op = field.DefaultOperator
+		}
+		if !field.Allows(op) {
+			operators := []string{}
+			for _, allowed := range field.Operators {
+				operators = append(operators, string(allowed))
+			}
+			return nil, CustomValidationError("Operator %s tidak didukung oleh filter %s, operator yang tersedia: %s", op, key, strings.Join(operators, ", "))
+		}
+
+		rawValues := []string{values[0]}
+		if op == entity.FilterOperatorIn {
+			rawValues = strings.Split(strings.Join(values, ","), ",")
+		}
+
+		filter := entity.Filter{Key: key, Column: field.Column, Operator: op, Values: []interface{}{}}
+		for _, raw := range rawValues {
+			value, err := parseFilterValue(field, op, strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+			filter.Values = append(filter.Values, value)
+		}
+		spec.Filters = append(spec.Filters, filter)
 	}
 
-	filters := entity.DynamicFilter{}
-	for key, val := range q.uv {
-		lowerKey := strings.ToLower(key)
-		if _, ok := reservedParams[lowerKey]; !ok {
-			filters[lowerKey] = val[0]
+	if sortParam := strings.TrimSpace(q.uv.Get("sort")); sortParam != "" {
+		for _, key := range strings.Split(sortParam, ",") {
+			key = strings.TrimSpace(key)
+			order := entity.SortOrder{Descending: strings.HasPrefix(key, "-")}
+			key = strings.TrimPrefix(key, "-")
+
+			column, ok := schema.SortFields[key]
+			if !ok {
+				return nil, CustomValidationError("Urutan %s tidak dikenal, urutan yang tersedia: %s", key, strings.Join(schema.SortKeys(), ", "))
+			}
+			order.Column = column
+			spec.Sort = append(spec.Sort, order)
 		}
 	}
 
-	return filters
+	return spec, nil
+}
+
+func parseFilterValue(field entity.FilterField, op entity.FilterOperator, raw string) (interface{}, error) {
+	switch field.Type {
+	case entity.FilterTypeInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, CustomValidationError("Nilai filter %s harus berupa angka", field.Key)
+		}
+		return v, nil
+	case entity.FilterTypeDate:
+		v, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, CustomValidationError("Nilai filter %s harus berupa tanggal dengan format YYYY-MM-DD", field.Key)
+		}
+		// an upper bound date includes the whole day
+		if op == entity.FilterOperatorLte {
+			v = v.Add(24*time.Hour - time.Second)
+		}
+		return v, nil
+	case entity.FilterTypeEnum:
+		v, ok := field.Values[strings.ToLower(raw)]
+		if !ok {
+			accepted := []string{}
+			for value := range field.Values {
+				accepted = append(accepted, value)
+			}
+			sort.Strings(accepted)
+			return nil, CustomValidationError("Nilai filter %s tidak valid, nilai yang tersedia: %s", field.Key, strings.Join(accepted, ", "))
+		}
+		return int64(v), nil
+	default:
+		if raw == "" {
+			return nil, CustomValidationError("Nilai filter %s tidak boleh kosong", field.Key)
+		}
+		return raw, nil
+	}
 }
 
 // GetString to get query url value with string data type, return empty string if query url not found
@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type filterSpecTestSuite struct {
+	suite.Suite
+}
+
+func (s *filterSpecTestSuite) parse(query string, schema entity.FilterSchema) (*entity.FilterSpec, error) {
+	r := httptest.NewRequest("GET", "/products?"+query, nil)
+	return api.NewQueryHelper(r).GetFilterSpec(schema)
+}
+
+func (s *filterSpecTestSuite) TestParseTypedFilters() {
+	spec, err := s.parse("q=matcha&price[gte]=1000&status[in]=idle,offered&active_until=2019-12-31&limit=10&sort=-price,created_at", entity.ProductFilterSchema)
+	s.Require().NoError(err)
+
+	s.Equal("matcha", spec.Search)
+	s.Len(spec.Filters, 3)
+	s.ElementsMatch([]entity.Filter{
+		{Key: "price", Column: "price", Operator: entity.FilterOperatorGte, Values: []interface{}{int64(1000)}},
+		{Key: "status", Column: "status", Operator: entity.FilterOperatorIn, Values: []interface{}{int64(entity.ProductStatusIdle), int64(entity.ProductStatusOffered)}},
+		{Key: "active_until", Column: "from_date", Operator: entity.FilterOperatorLte, Values: []interface{}{time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)}},
+	}, spec.Filters)
+	s.Equal([]entity.SortOrder{{Column: "price", Descending: true}, {Column: "created_at"}}, spec.Sort)
+}
+
+func (s *filterSpecTestSuite) TestParseAvailability() {
+	spec, err := s.parse("availability=closed", entity.ProductFilterSchema)
+	s.Require().NoError(err)
+
+	value, ok := spec.Value("availability")
+	s.True(ok)
+	s.Equal(int64(entity.ProductAvailabilityClosed), value)
+
+	_, err = s.parse("availability=soon", entity.ProductFilterSchema)
+	s.Error(err)
+}
+
+func (s *filterSpecTestSuite) TestUnknownFilterListsAllowedKeys() {
+	_, err := s.parse("colour=red", entity.TransactionFilterSchema)
+
+	s.Require().Error(err)
+	s.Equal(400, err.(api.SejastipError).HTTPStatus)
+	s.Contains(err.Error(), "created_at, invoice_code, product_id, role, status")
+}
+
+func (s *filterSpecTestSuite) TestRejectsUnsupportedOperator() {
+	_, err := s.parse("seller_id[like]=1", entity.ProductFilterSchema)
+	s.Error(err)
+}
+
+func (s *filterSpecTestSuite) TestRejectsInvalidValues() {
+	_, err := s.parse("price=murah", entity.ProductFilterSchema)
+	s.Error(err)
+
+	_, err = s.parse("status=lost", entity.TransactionFilterSchema)
+	s.Error(err)
+
+	_, err = s.parse("created_at[gte]=01-11-2019", entity.TransactionFilterSchema)
+	s.Error(err)
+}
+
+func (s *filterSpecTestSuite) TestRejectsUnknownSort() {
+	_, err := s.parse("sort=-title", entity.ProductFilterSchema)
+	s.Error(err)
+}
+
+func (s *filterSpecTestSuite) TestGetPageOffsetMode() {
+	r := httptest.NewRequest("GET", "/products?limit=20&offset=40", nil)
+	page, err := api.NewQueryHelper(r).GetPage(10)
+
+	s.NoError(err)
+	s.Equal(entity.Page{Limit: 20, Offset: 40}, page)
+}
+
+func (s *filterSpecTestSuite) TestGetPageCursorMode() {
+	cursor := entity.Cursor{UpdatedAt: time.Date(2019, 11, 26, 10, 0, 0, 0, time.UTC), ID: 42}
+	r := httptest.NewRequest("GET", "/products?offset=40&count=false&cursor="+cursor.Encode(), nil)
+	page, err := api.NewQueryHelper(r).GetPage(10)
+
+	s.NoError(err)
+	s.True(page.CursorMode)
+	s.True(page.SkipCount)
+	s.Equal(0, page.Offset)
+	s.Equal(&cursor, page.After)
+}
+
+func (s *filterSpecTestSuite) TestGetPageFirstCursorPage() {
+	r := httptest.NewRequest("GET", "/products?cursor=", nil)
+	page, err := api.NewQueryHelper(r).GetPage(10)
+
+	s.NoError(err)
+	s.True(page.CursorMode)
+	s.Nil(page.After)
+}
+
+func (s *filterSpecTestSuite) TestGetPageInvalidCursor() {
+	r := httptest.NewRequest("GET", "/products?cursor=garbage", nil)
+	_, err := api.NewQueryHelper(r).GetPage(10)
+
+	s.Error(err)
+}
+
+func TestFilterSpec(t *testing.T) {
+	suite.Run(t, new(filterSpecTestSuite))
+}
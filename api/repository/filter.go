@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api/entity"
+)
+
+// likeEscaper escapes the wildcards of LIKE, so the user input only matches itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// buildFilterExpression converts a parsed filter into a condition on the column
+func buildFilterExpression(column string, filter entity.Filter) sqlm.Expression {
+	switch filter.Operator {
+	case entity.FilterOperatorIn:
+		return sqlm.Exp(column, "IN", sqlm.F("(1, 2)", sqlm.P(filter.Values...)))
+	case entity.FilterOperatorGte:
+		return sqlm.Exp(column, ">=", sqlm.P(filter.Values[0]))
+	case entity.FilterOperatorLte:
+		return sqlm.Exp(column, "<=", sqlm.P(filter.Values[0]))
+	case entity.FilterOperatorLike:
+		return sqlm.Exp(column, "LIKE", sqlm.P("%"+likeEscaper.Replace(fmt.Sprint(filter.Values[0]))+"%"))
+	default:
+		return sqlm.Exp(column, "=", sqlm.P(filter.Values[0]))
+	}
+}
+
+// buildOrderBy converts the sort orders of a spec into an ORDER BY list,
+// falling back to defaultOrder when the spec has none
+func buildOrderBy(sortOrders []entity.SortOrder, defaultOrder sqlm.Expression) sqlm.Expression {
+	if len(sortOrders) == 0 {
+		return defaultOrder
+	}
+
+	orders := []interface{}{}
+	for _, order := range sortOrders {
+		direction := "ASC"
+		if order.Descending {
+			direction = "DESC"
+		}
+		orders = append(orders, sqlm.Exp(order.Column, direction))
+	}
+	return sqlm.F("1, 2", orders...)
+}
+
+// defaultOrder is the order of listings without an explicit sort, which is
+// also the order keyset cursors rely on
+var defaultOrder = sqlm.Exp("updated_at DESC, id DESC")
+
+// createdOrder is the order of listings whose items are edited after they are
+// listed, which would otherwise move between the pages of a cursor
+var createdOrder = sqlm.Exp("created_at DESC, id DESC")
+
+// buildKeysetCondition selects the items after the cursor in the default order
+func buildKeysetCondition(after *entity.Cursor) sqlm.Expression {
+	return buildKeysetConditionOn("updated_at", after)
+}
+
+// buildKeysetConditionOn selects the items after the cursor in a listing ordered
+// by the timestamp column, then by id, latest first
+func buildKeysetConditionOn(column string, after *entity.Cursor) sqlm.Expression {
+	return sqlm.Or(
+		sqlm.Exp(column, "<", sqlm.P(after.UpdatedAt)),
+		sqlm.And(
+			sqlm.Exp(column, "=", sqlm.P(after.UpdatedAt)),
+			sqlm.Exp("id", "<", sqlm.P(after.ID)),
+		),
+	)
+}
+
+// buildLimit returns the LIMIT clause of a page, cursor pages never skip rows
+func buildLimit(page entity.Page) sqlm.Expression {
+	if page.CursorMode {
+		return sqlm.Exp("LIMIT", sqlm.P(page.Limit))
+	}
+	return sqlm.Exp("LIMIT", sqlm.P(page.Offset), ",", sqlm.P(page.Limit))
+}
@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type filterTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+}
+
+func (s *filterTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+}
+
+func (s *filterTestSuite) TearDownTest() {
+	s.db.Close()
+}
+
+func (s *filterTestSuite) TestLikeEscapesWildcards() {
+	// an underscore alone would otherwise match every invoice
+	s.mock.ExpectQuery(regexp.QuoteMeta("id IN (SELECT transaction_id FROM invoices WHERE invoice_code LIKE ? )")).WithArgs(
+		`%\_50\%\\%`,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	spec := entity.NewFilterSpec().Where("invoice_code", entity.FilterOperatorLike, `_50%\`)
+	_, _, err := repository.NewMysqlTransaction(s.db).GetTransactions(context.Background(), 5, spec, entity.Page{Limit: 10, SkipCount: true})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestFilter(t *testing.T) {
+	suite.Run(t, new(filterTestSuite))
+}
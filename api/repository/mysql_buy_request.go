@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlBuyRequest struct {
+	db *sqlx.DB
+}
+
+// NewMysqlBuyRequest creates a new instance of MySQL buy request repository
+func NewMysqlBuyRequest(db *sql.DB) api.BuyRequestRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlBuyRequest{newDB}
+}
+
+// CreateBuyRequest inserts a new buy request
+func (m *mysqlBuyRequest) CreateBuyRequest(ctx context.Context, request *entity.BuyRequest) error {
+	now := time.Now()
+	request.CreatedAt = now
+	request.UpdatedAt = now
+
+	query := `INSERT INTO buy_requests
+		(buyer_id, country_id, title, description, image, max_budget, quantity,
+		needed_by, status, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		request.BuyerID, request.CountryID, request.Title, request.Description,
+		request.Image, request.MaxBudget, request.Quantity, request.NeededBy,
+		request.Status, request.CreatedAt, request.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	request.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetBuyRequests fetches a page of the buy requests matching the filter spec
+func (m *mysqlBuyRequest) GetBuyRequests(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.BuyRequest, int64, error) {
+	filteredQueries := []interface{}{sqlm.Exp("1 = 1")}
+	for _, filter := range spec.Filters {
+		filteredQueries = append(filteredQueries, buildFilterExpression(filter.Column, filter))
+	}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM buy_requests",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM buy_requests",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", buildOrderBy(spec.Sort, defaultOrder),
+		buildLimit(page),
+	)
+	results := []entity.BuyRequest{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// GetBuyRequest fetches a buy request by its ID
+func (m *mysqlBuyRequest) GetBuyRequest(ctx context.Context, ID int64) (*entity.BuyRequest, error) {
+	query := `
+		SELECT * FROM buy_requests
+		WHERE id = ?
+	`
+	result := &entity.BuyRequest{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateBuyRequestStatus updates the status and the accepted offer of a buy request.
+// Only an open request is updated, so it is answered once even when it is
+// cancelled or accepted concurrently
+func (m *mysqlBuyRequest) UpdateBuyRequestStatus(ctx context.Context, ID int64, request *entity.BuyRequest) error {
+	request.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE buy_requests SET
+		status = ?, accepted_offer_id = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		request.Status, request.AcceptedOfferID, request.UpdatedAt,
+		ID, entity.BuyRequestStatusOpen,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return api.ErrBuyRequestClosed
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating buy request status (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// AnonymizeUserBuyRequests cancels the open requests of a buyer, and clears the
+// description and image of all of them
+func (m *mysqlBuyRequest) AnonymizeUserBuyRequests(ctx context.Context, buyerID int64) error {
+	query := `
+		UPDATE buy_requests SET
+		status = IF(status = ?, ?, status), description = '', image = '', updated_at = ?
+		WHERE buyer_id = ?
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query,
+		entity.BuyRequestStatusOpen, entity.BuyRequestStatusCancelled, time.Now(),
+		buyerID,
+	)
+	return err
+}
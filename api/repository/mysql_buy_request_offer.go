@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlBuyRequestOffer struct {
+	db *sqlx.DB
+}
+
+// NewMysqlBuyRequestOffer creates a new instance of MySQL buy request offer repository
+func NewMysqlBuyRequestOffer(db *sql.DB) api.BuyRequestOfferRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlBuyRequestOffer{newDB}
+}
+
+// CreateOffer inserts a new offer on a buy request
+func (m *mysqlBuyRequestOffer) CreateOffer(ctx context.Context, offer *entity.BuyRequestOffer) error {
+	now := time.Now()
+	offer.CreatedAt = now
+	offer.UpdatedAt = now
+
+	query := `INSERT INTO buy_request_offers
+		(buy_request_id, seller_id, price, from_date, to_date, notes, status,
+		created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		offer.BuyRequestID, offer.SellerID, offer.Price, offer.FromDate,
+		offer.ToDate, offer.Notes, offer.Status, offer.CreatedAt, offer.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	offer.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetOffers fetches the offers on a buy request, latest first
+func (m *mysqlBuyRequestOffer) GetOffers(ctx context.Context, buyRequestID int64) ([]entity.BuyRequestOffer, error) {
+	query := `
+		SELECT * FROM buy_request_offers
+		WHERE buy_request_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+	results := []entity.BuyRequestOffer{}
+	err := m.db.SelectContext(ctx, &results, query, buyRequestID)
+	return results, err
+}
+
+// GetOffer fetches an offer by its ID
+func (m *mysqlBuyRequestOffer) GetOffer(ctx context.Context, ID int64) (*entity.BuyRequestOffer, error) {
+	query := `
+		SELECT * FROM buy_request_offers
+		WHERE id = ?
+	`
+	result := &entity.BuyRequestOffer{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// CountPendingOffers counts the offers on a buy request waiting for an answer
+func (m *mysqlBuyRequestOffer) CountPendingOffers(ctx context.Context, buyRequestID int64) (int64, error) {
+	var count int64
+	err := m.db.GetContext(ctx, &count,
+		`SELECT COUNT(id) FROM buy_request_offers WHERE buy_request_id = ? AND status = ?`,
+		buyRequestID, entity.BuyRequestOfferStatusPending,
+	)
+	return count, err
+}
+
+// UpdateOfferStatus updates the status of an offer, along with the product and
+// transaction created when it is accepted. Only a pending offer is updated, so it
+// is answered once even when it is withdrawn or accepted concurrently
+func (m *mysqlBuyRequestOffer) UpdateOfferStatus(ctx context.Context, ID int64, offer *entity.BuyRequestOffer) error {
+	offer.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE buy_request_offers SET
+		status = ?, product_id = ?, transaction_id = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		offer.Status, offer.ProductID, offer.TransactionID, offer.UpdatedAt,
+		ID, entity.BuyRequestOfferStatusPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return api.ErrOfferNotPending
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating offer status (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// RejectPendingOffers rejects every offer on a buy request still waiting for an answer
+func (m *mysqlBuyRequestOffer) RejectPendingOffers(ctx context.Context, buyRequestID int64) error {
+	query := `
+		UPDATE buy_request_offers SET
+		status = ?, updated_at = ?
+		WHERE buy_request_id = ? AND status = ?
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query,
+		entity.BuyRequestOfferStatusRejected, time.Now(),
+		buyRequestID, entity.BuyRequestOfferStatusPending,
+	)
+	return err
+}
+
+// GetSellerOffers fetches every offer made by a seller, oldest first
+func (m *mysqlBuyRequestOffer) GetSellerOffers(ctx context.Context, sellerID int64) ([]entity.BuyRequestOffer, error) {
+	query := `
+		SELECT * FROM buy_request_offers
+		WHERE seller_id = ?
+		ORDER BY id ASC
+	`
+	results := []entity.BuyRequestOffer{}
+	err := m.db.SelectContext(ctx, &results, query, sellerID)
+	return results, err
+}
+
+// WithdrawSellerOffers withdraws the pending offers of a seller, and clears the
+// notes of all of them
+func (m *mysqlBuyRequestOffer) WithdrawSellerOffers(ctx context.Context, sellerID int64) error {
+	query := `
+		UPDATE buy_request_offers SET
+		status = IF(status = ?, ?, status), notes = '', updated_at = ?
+		WHERE seller_id = ?
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query,
+		entity.BuyRequestOfferStatusPending, entity.BuyRequestOfferStatusWithdrawn, time.Now(),
+		sellerID,
+	)
+	return err
+}
@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlBuyRequestTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	requests api.BuyRequestRepository
+	offers   api.BuyRequestOfferRepository
+}
+
+func (s *mysqlBuyRequestTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.requests = repository.NewMysqlBuyRequest(s.db)
+	s.offers = repository.NewMysqlBuyRequestOffer(s.db)
+}
+
+func (s *mysqlBuyRequestTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlBuyRequestTestSuite) TestAnsweredBuyRequestIsNotUpdated() {
+	offerID := int64(3)
+	request := &entity.BuyRequest{Status: entity.BuyRequestStatusFulfilled, AcceptedOfferID: &offerID}
+
+	prep := s.mock.ExpectPrepare(regexp.QuoteMeta("WHERE id = ? AND status = ?"))
+	prep.ExpectExec().WithArgs(
+		entity.BuyRequestStatusFulfilled, &offerID, AnyTime{}, int64(7), entity.BuyRequestStatusOpen,
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.requests.UpdateBuyRequestStatus(context.Background(), 7, request)
+
+	s.Equal(api.ErrBuyRequestClosed, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlBuyRequestTestSuite) TestAnsweredOfferIsNotUpdated() {
+	offer := &entity.BuyRequestOffer{Status: entity.BuyRequestOfferStatusAccepted}
+
+	prep := s.mock.ExpectPrepare(regexp.QuoteMeta("WHERE id = ? AND status = ?"))
+	prep.ExpectExec().WithArgs(
+		entity.BuyRequestOfferStatusAccepted, offer.ProductID, offer.TransactionID, AnyTime{},
+		int64(3), entity.BuyRequestOfferStatusPending,
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.offers.UpdateOfferStatus(context.Background(), 3, offer)
+
+	s.Equal(api.ErrOfferNotPending, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlBuyRequest(t *testing.T) {
+	suite.Run(t, new(mysqlBuyRequestTestSuite))
+}
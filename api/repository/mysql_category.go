@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlCategory struct {
+	db *sqlx.DB
+}
+
+// NewMysqlCategory creates a new instance of MySQL category repository
+func NewMysqlCategory(db *sql.DB) api.CategoryRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlCategory{newDB}
+}
+
+// CreateCategory inserts a new product category
+func (m *mysqlCategory) CreateCategory(ctx context.Context, category *entity.Category) error {
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	query := `INSERT INTO categories
+		(parent_id, name, slug, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?)
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		category.ParentID, category.Name, category.Slug,
+		category.CreatedAt, category.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	category.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetCategories fetches every category, ordered by name
+func (m *mysqlCategory) GetCategories(ctx context.Context) ([]entity.Category, error) {
+	query := `
+		SELECT * FROM categories
+		ORDER BY name ASC, id ASC
+	`
+	results := []entity.Category{}
+	err := m.db.SelectContext(ctx, &results, query)
+	return results, err
+}
+
+// GetCategory fetches a category by its ID
+func (m *mysqlCategory) GetCategory(ctx context.Context, ID int64) (*entity.Category, error) {
+	query := `
+		SELECT * FROM categories
+		WHERE id = ?
+	`
+	result := &entity.Category{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateCategory updates the name, slug and parent of a category
+func (m *mysqlCategory) UpdateCategory(ctx context.Context, ID int64, category *entity.Category) error {
+	category.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE categories SET
+		parent_id = ?, name = ?, slug = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		category.ParentID, category.Name, category.Slug, category.UpdatedAt,
+		ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating category (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// DeleteCategory removes a category
+func (m *mysqlCategory) DeleteCategory(ctx context.Context, ID int64) error {
+	prep, err := m.db.PrepareContext(ctx, `DELETE FROM categories WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting category (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlConversation struct {
+	db *sqlx.DB
+}
+
+// NewMysqlConversation creates a new instance of MySQL conversation repository
+func NewMysqlConversation(db *sql.DB) api.ConversationRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlConversation{newDB}
+}
+
+// UpsertConversation inserts a conversation, unless a conversation of the same
+// scope exists. Either way, the ID of the conversation is set
+func (m *mysqlConversation) UpsertConversation(ctx context.Context, conversation *entity.Conversation) error {
+	now := time.Now()
+	conversation.CreatedAt = now
+	conversation.UpdatedAt = now
+
+	// LAST_INSERT_ID(id) makes the existing row ID the inserted ID on duplicates
+	query := `INSERT INTO conversations
+		(scope, product_id, transaction_id, buyer_id, seller_id, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
+	`
+	res, err := m.db.ExecContext(ctx, query,
+		conversation.Scope, conversation.ProductID, conversation.TransactionID,
+		conversation.BuyerID, conversation.SellerID, conversation.CreatedAt,
+		conversation.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	conversation.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetConversation fetches a conversation by its ID
+func (m *mysqlConversation) GetConversation(ctx context.Context, ID int64) (*entity.Conversation, error) {
+	query := `
+		SELECT * FROM conversations
+		WHERE id = ?
+	`
+	result := &entity.Conversation{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetUserConversations fetches a page of the conversations of a user, the most
+// recently active first
+func (m *mysqlConversation) GetUserConversations(ctx context.Context, userID int64, page entity.Page) ([]entity.Conversation, int64, error) {
+	filteredQueries := []interface{}{sqlm.Or(
+		sqlm.Exp("buyer_id", "=", sqlm.P(userID)),
+		sqlm.Exp("seller_id", "=", sqlm.P(userID)),
+	)}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM conversations",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM conversations",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", defaultOrder,
+		buildLimit(page),
+	)
+	results := []entity.Conversation{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// UpdateReadReceipts writes the read receipts of both participants. Receipts never
+// move backwards, so concurrent updates can't unread messages
+func (m *mysqlConversation) UpdateReadReceipts(ctx context.Context, ID int64, conversation *entity.Conversation) error {
+	query := `
+		UPDATE conversations SET
+		buyer_last_read_id = GREATEST(buyer_last_read_id, ?),
+		seller_last_read_id = GREATEST(seller_last_read_id, ?)
+		WHERE id = ?
+	`
+	res, err := m.db.ExecContext(ctx, query,
+		conversation.BuyerLastReadID, conversation.SellerLastReadID, ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows > 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating read receipts (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
@@ -22,42 +22,36 @@ func NewMysqlDevice(db *sql.DB) api.DeviceRepository {
 	return &mysqlDevice{newDB}
 }
 
-func (m *mysqlDevice) GetUserDevice(ctx context.Context, userID int64) (*entity.Device, error) {
+// GetUserDevices fetches all devices registered by a user, the most recently
+// registered first
+func (m *mysqlDevice) GetUserDevices(ctx context.Context, userID int64) ([]entity.Device, error) {
 	query := `
 		SELECT * FROM user_devices
 		WHERE user_id = ?
 		ORDER BY updated_at DESC
-		LIMIT 1
 	`
-	result := &entity.Device{}
-	err := m.db.GetContext(ctx, result, query, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, api.ErrNotFound
-		} else {
-			return nil, err
-		}
-	}
-	return result, nil
+	results := []entity.Device{}
+	err := m.db.SelectContext(ctx, &results, query, userID)
+	return results, err
 }
 
-func (m *mysqlDevice) InsertUserDevice(ctx context.Context, device *entity.Device) error {
+// UpsertUserDevice registers a device for a user. A device is keyed by its device
+// ID, so a device previously registered by another user moves to the new user
+func (m *mysqlDevice) UpsertUserDevice(ctx context.Context, device *entity.Device) error {
 	now := time.Now()
 	device.CreatedAt = now
 	device.UpdatedAt = now
 
+	// LAST_INSERT_ID(id) makes the existing row ID the inserted ID on duplicates
 	query := `INSERT INTO user_devices
 		(device_id, platform, user_agent, user_id, created_at, updated_at)
 		VALUES
 		(?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		id = LAST_INSERT_ID(id), platform = VALUES(platform), user_agent = VALUES(user_agent),
+		user_id = VALUES(user_id), updated_at = VALUES(updated_at)
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
-	if err != nil {
-		return err
-	}
-
-	// execute query
-	res, err := prep.ExecContext(ctx,
+	res, err := m.db.ExecContext(ctx, query,
 		device.DeviceID, device.Platform, device.UserAgent, device.UserID,
 		device.CreatedAt, device.UpdatedAt,
 	)
@@ -69,24 +63,13 @@ func (m *mysqlDevice) InsertUserDevice(ctx context.Context, device *entity.Devic
 	return err
 }
 
-func (m *mysqlDevice) UpdateUserDevice(ctx context.Context, ID int64, newDevice *entity.Device) error {
-	now := time.Now()
-	newDevice.UpdatedAt = now
-
+// RemoveUserDevice unregisters a device of a user
+func (m *mysqlDevice) RemoveUserDevice(ctx context.Context, userID int64, deviceID string) error {
 	query := `
-		UPDATE user_devices SET
-		device_id = ?, platform = ?, user_agent = ?, updated_at = ?
-		WHERE id = ?
+		DELETE FROM user_devices
+		WHERE user_id = ? AND device_id = ?
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
-	if err != nil {
-		return err
-	}
-
-	res, err := prep.ExecContext(ctx,
-		newDevice.DeviceID, newDevice.Platform, newDevice.UserAgent,
-		newDevice.UpdatedAt, ID,
-	)
+	res, err := m.db.ExecContext(ctx, query, userID, deviceID)
 	if err != nil {
 		return err
 	}
@@ -95,37 +78,38 @@ func (m *mysqlDevice) UpdateUserDevice(ctx context.Context, ID int64, newDevice
 	if err != nil {
 		return err
 	}
+	if affectedRows == 0 {
+		return api.ErrNotFound
+	}
 	if affectedRows != 1 {
-		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating device data (total rows affected: %d)", affectedRows))
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting device (total rows affected: %d)", affectedRows))
 	}
 
 	return nil
 }
 
-func (m *mysqlDevice) RemoveDevice(ctx context.Context, ID int64) error {
-	now := time.Now()
-	query := `
-		UPDATE user_devices SET
-		deleted_at = ?
-		WHERE id = ?
-	`
-	prep, err := m.db.PrepareContext(ctx, query)
-	if err != nil {
-		return err
+// RemoveDevices unregisters devices whoever they belong to, and returns how many
+// were registered
+func (m *mysqlDevice) RemoveDevices(ctx context.Context, deviceIDs []string) (int64, error) {
+	if len(deviceIDs) == 0 {
+		return 0, nil
 	}
 
-	res, err := prep.ExecContext(ctx, now, ID)
+	query, args, err := sqlx.In(`DELETE FROM user_devices WHERE device_id IN (?)`, deviceIDs)
 	if err != nil {
-		return err
+		return 0, errors.Wrap(err, "error building delete devices query")
 	}
 
-	affectedRows, err := res.RowsAffected()
+	res, err := m.db.ExecContext(ctx, m.db.Rebind(query), args...)
 	if err != nil {
-		return err
-	}
-	if affectedRows != 1 {
-		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting device (total rows affected: %d)", affectedRows))
+		return 0, err
 	}
 
-	return nil
+	return res.RowsAffected()
+}
+
+// RemoveUserDevices removes every device of a user, so nothing is pushed to them anymore
+func (m *mysqlDevice) RemoveUserDevices(ctx context.Context, userID int64) error {
+	_, err := conn(ctx, m.db).ExecContext(ctx, `DELETE FROM user_devices WHERE user_id = ?`, userID)
+	return err
 }
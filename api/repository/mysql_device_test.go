@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/repository"
+)
+
+type mysqlDeviceTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.DeviceRepository
+}
+
+func (s *mysqlDeviceTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlDevice(s.db)
+}
+
+func (s *mysqlDeviceTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlDeviceTestSuite) TestRemoveUserDeviceOfAnotherUser() {
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_devices WHERE user_id = ? AND device_id = ?")).WithArgs(
+		int64(3), "token-of-user-5",
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.repo.RemoveUserDevice(context.Background(), 3, "token-of-user-5")
+
+	s.Equal(api.ErrNotFound, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlDeviceTestSuite) TestRemoveDevicesPrunesAllTokens() {
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_devices WHERE device_id IN (?, ?)")).WithArgs(
+		"stale-token", "invalid-token",
+	).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	total, err := s.repo.RemoveDevices(context.Background(), []string{"stale-token", "invalid-token"})
+
+	s.NoError(err)
+	s.Equal(int64(2), total)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlDevice(t *testing.T) {
+	suite.Run(t, new(mysqlDeviceTestSuite))
+}
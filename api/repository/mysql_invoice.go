@@ -33,7 +33,7 @@ func (m *mysqlInvoice) InsertInvoice(ctx context.Context, invoice *entity.Invoic
 			receipt_proof, created_at, updated_at)
 		VALUES
 		(?, ?, ?, ?, ?, ?, ?, ?)`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return errors.Wrap(err, "error preparing insert invoice query")
 	}
@@ -95,7 +95,7 @@ func (m *mysqlInvoice) UpdateInvoice(ctx context.Context, invoiceID int64, invoi
 		invoice_code = ?, coded_price = ?, payment_method = ?, status = ?,
 		paid_at = ?, receipt_proof = ?, updated_at = ?
 		WHERE id = ?`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return errors.Wrap(err, "error preparing update invoice")
 	}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type mysqlLoginHistory struct {
+	db *sqlx.DB
+}
+
+// NewMysqlLoginHistory creates a new instance of MySQL user login history repository
+func NewMysqlLoginHistory(db *sql.DB) api.LoginHistoryRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlLoginHistory{newDB}
+}
+
+// InsertLogin records a successful login
+func (m *mysqlLoginHistory) InsertLogin(ctx context.Context, login *entity.UserLogin) error {
+	login.CreatedAt = time.Now()
+
+	query := `INSERT INTO user_logins
+		(user_id, ip_address, user_agent, created_at)
+		VALUES
+		(?, ?, ?, ?)`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing insert user login query")
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		login.UserID, login.IPAddress, login.UserAgent, login.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error executing insert user login query")
+	}
+
+	login.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetUserLogins fetches the login history of a user, latest first
+func (m *mysqlLoginHistory) GetUserLogins(ctx context.Context, userID int64, limit, offset int) ([]entity.UserLogin, int64, error) {
+	var count int64
+	err := m.db.GetContext(ctx, &count, `SELECT COUNT(id) FROM user_logins WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT * FROM user_logins
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+		LIMIT ?, ?
+	`
+	results := []entity.UserLogin{}
+	err = m.db.SelectContext(ctx, &results, query, userID, offset, limit)
+	return results, count, err
+}
+
+// DeleteUserLogins removes the login history of a user
+func (m *mysqlLoginHistory) DeleteUserLogins(ctx context.Context, userID int64) error {
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, `DELETE FROM user_logins WHERE user_id = ?`)
+	if err != nil {
+		return err
+	}
+
+	_, err = prep.ExecContext(ctx, userID)
+	return err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type mysqlLoginThrottle struct {
+	db *sqlx.DB
+}
+
+// NewMysqlLoginThrottle creates a new instance of MySQL failed login counter repository
+func NewMysqlLoginThrottle(db *sql.DB) api.LoginThrottleRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlLoginThrottle{newDB}
+}
+
+// GetThrottle fetches the failed login counter of a key
+func (m *mysqlLoginThrottle) GetThrottle(ctx context.Context, key string) (*entity.LoginThrottle, error) {
+	query := `
+		SELECT * FROM login_throttles
+		WHERE throttle_key = ?
+		LIMIT 1
+	`
+	result := &entity.LoginThrottle{}
+	err := m.db.GetContext(ctx, result, query, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// IncrementThrottle counts a failed login of a key, then returns the stored counter.
+// The counter is incremented in place so concurrent failures are all counted, and
+// restarts when the key is not locked and its last failure is before windowStart
+func (m *mysqlLoginThrottle) IncrementThrottle(ctx context.Context, key string, failedAt, windowStart time.Time) (*entity.LoginThrottle, error) {
+	now := time.Now()
+
+	// the counter and the lock are updated before last_failed_at, which their
+	// conditions still read the previous value of
+	query := `INSERT INTO login_throttles
+		(throttle_key, failed_count, locked_until, last_failed_at, created_at, updated_at)
+		VALUES
+		(?, 1, NULL, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		failed_count = IF(last_failed_at < ? AND (locked_until IS NULL OR locked_until <= ?), 1, failed_count + 1),
+		locked_until = IF(last_failed_at < ? AND (locked_until IS NULL OR locked_until <= ?), NULL, locked_until),
+		last_failed_at = VALUES(last_failed_at), updated_at = VALUES(updated_at)`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "error preparing increment login throttle query")
+	}
+	defer prep.Close()
+
+	_, err = prep.ExecContext(ctx,
+		key, failedAt, now, now,
+		windowStart, failedAt, windowStart, failedAt,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing increment login throttle query")
+	}
+
+	return m.GetThrottle(ctx, key)
+}
+
+// LockThrottle locks a key out until lockedUntil, unless it is already locked longer
+func (m *mysqlLoginThrottle) LockThrottle(ctx context.Context, key string, lockedUntil time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = ?, updated_at = ?
+		WHERE throttle_key = ? AND (locked_until IS NULL OR locked_until < ?)`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing lock login throttle query")
+	}
+	defer prep.Close()
+
+	_, err = prep.ExecContext(ctx, lockedUntil, time.Now(), key, lockedUntil)
+	if err != nil {
+		return errors.Wrap(err, "error executing lock login throttle query")
+	}
+
+	return nil
+}
+
+// ResetThrottle removes the failed login counter of a key
+func (m *mysqlLoginThrottle) ResetThrottle(ctx context.Context, key string) error {
+	query := `DELETE FROM login_throttles WHERE throttle_key = ?`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing reset login throttle query")
+	}
+	defer prep.Close()
+
+	_, err = prep.ExecContext(ctx, key)
+	return err
+}
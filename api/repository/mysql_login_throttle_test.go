@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/repository"
+)
+
+type mysqlLoginThrottleTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.LoginThrottleRepository
+}
+
+func (s *mysqlLoginThrottleTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlLoginThrottle(s.db)
+}
+
+func (s *mysqlLoginThrottleTestSuite) TearDownTest() {
+	s.db.Close()
+}
+
+func (s *mysqlLoginThrottleTestSuite) TestIncrementCountsInPlace() {
+	failedAt := time.Now()
+	windowStart := failedAt.Add(-time.Hour)
+
+	s.mock.ExpectPrepare(regexp.QuoteMeta("failed_count = IF(last_failed_at < ? AND (locked_until IS NULL OR locked_until <= ?), 1, failed_count + 1)")).
+		ExpectExec().
+		WithArgs("account:rocky@sejastip.id", failedAt, sqlmock.AnyArg(), sqlmock.AnyArg(), windowStart, failedAt, windowStart, failedAt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// the count of concurrent failures is read back from the row
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM login_throttles")).
+		WithArgs("account:rocky@sejastip.id").
+		WillReturnRows(sqlmock.NewRows([]string{"throttle_key", "failed_count", "last_failed_at"}).
+			AddRow("account:rocky@sejastip.id", 6, failedAt))
+
+	throttle, err := s.repo.IncrementThrottle(context.Background(), "account:rocky@sejastip.id", failedAt, windowStart)
+
+	s.Require().NoError(err)
+	s.Equal(6, throttle.FailedCount)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlLoginThrottleTestSuite) TestLockKeepsTheLongerLockout() {
+	lockedUntil := time.Now().Add(time.Minute)
+
+	s.mock.ExpectPrepare(regexp.QuoteMeta("WHERE throttle_key = ? AND (locked_until IS NULL OR locked_until < ?)")).
+		ExpectExec().
+		WithArgs(lockedUntil, sqlmock.AnyArg(), "account:rocky@sejastip.id", lockedUntil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.NoError(s.repo.LockThrottle(context.Background(), "account:rocky@sejastip.id", lockedUntil))
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlLoginThrottle(t *testing.T) {
+	suite.Run(t, new(mysqlLoginThrottleTestSuite))
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlMessage struct {
+	db *sqlx.DB
+}
+
+// NewMysqlMessage creates a new instance of MySQL chat message repository
+func NewMysqlMessage(db *sql.DB) api.MessageRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlMessage{newDB}
+}
+
+// CreateMessage inserts a new message and bumps the activity of its conversation
+func (m *mysqlMessage) CreateMessage(ctx context.Context, message *entity.Message) error {
+	now := time.Now()
+	message.CreatedAt = now
+	message.UpdatedAt = now
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO messages
+		(conversation_id, sender_id, body, attachment, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?)`,
+		message.ConversationID, message.SenderID, message.Body, message.Attachment,
+		message.CreatedAt, message.UpdatedAt,
+	)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "error inserting message")
+	}
+
+	message.ID, err = res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE conversations SET
+		last_message_at = ?, updated_at = ?
+		WHERE id = ?`,
+		message.CreatedAt, message.CreatedAt, message.ConversationID,
+	)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "error updating conversation activity")
+	}
+
+	return tx.Commit()
+}
+
+// GetMessage fetches a message by its ID
+func (m *mysqlMessage) GetMessage(ctx context.Context, ID int64) (*entity.Message, error) {
+	query := `
+		SELECT * FROM messages
+		WHERE id = ?
+	`
+	result := &entity.Message{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMessages fetches a page of the messages of a conversation, latest first
+func (m *mysqlMessage) GetMessages(ctx context.Context, conversationID int64, page entity.Page) ([]entity.Message, int64, error) {
+	filteredQueries := []interface{}{sqlm.Exp("conversation_id", "=", sqlm.P(conversationID))}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM messages",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM messages",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", defaultOrder,
+		buildLimit(page),
+	)
+	results := []entity.Message{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// GetMessagesAfter fetches the messages of a conversation sent after a message,
+// oldest first
+func (m *mysqlMessage) GetMessagesAfter(ctx context.Context, conversationID, afterID int64, limit int) ([]entity.Message, error) {
+	query := `
+		SELECT * FROM messages
+		WHERE conversation_id = ? AND id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`
+	results := []entity.Message{}
+	err := m.db.SelectContext(ctx, &results, query, conversationID, afterID, limit)
+	return results, err
+}
+
+// CountUnreadMessages counts the messages a participant received after its last read message
+func (m *mysqlMessage) CountUnreadMessages(ctx context.Context, conversationID, recipientID, lastReadID int64) (int64, error) {
+	var count int64
+	err := m.db.GetContext(ctx, &count,
+		`SELECT COUNT(id) FROM messages WHERE conversation_id = ? AND sender_id <> ? AND id > ?`,
+		conversationID, recipientID, lastReadID,
+	)
+	return count, err
+}
+
+// AnonymizeUserMessages clears the body and attachment of the messages sent by a
+// user. The messages are kept, so the conversations still make sense to the
+// other participants
+func (m *mysqlMessage) AnonymizeUserMessages(ctx context.Context, senderID int64) error {
+	query := `
+		UPDATE messages SET
+		body = '', attachment = '', updated_at = ?
+		WHERE sender_id = ?
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query, time.Now(), senderID)
+	return err
+}
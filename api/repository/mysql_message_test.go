@@ -0,0 +1,63 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlMessageTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.MessageRepository
+}
+
+func (s *mysqlMessageTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlMessage(s.db)
+}
+
+func (s *mysqlMessageTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlMessageTestSuite) TestCreateMessageBumpsConversation() {
+	message := &entity.Message{
+		ConversationID: 6,
+		SenderID:       3,
+		Body:           "Kapan barangnya dikirim?",
+	}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).WithArgs(
+		int64(6), int64(3), "Kapan barangnya dikirim?", "", AnyTime{}, AnyTime{},
+	).WillReturnResult(sqlmock.NewResult(21, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE conversations SET")).WithArgs(
+		AnyTime{}, AnyTime{}, int64(6),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	err := s.repo.CreateMessage(context.Background(), message)
+
+	s.NoError(err)
+	s.Equal(int64(21), message.ID)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlMessage(t *testing.T) {
+	suite.Run(t, new(mysqlMessageTestSuite))
+}
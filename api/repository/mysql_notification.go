@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlNotification struct {
+	db *sqlx.DB
+}
+
+// NewMysqlNotification creates a new instance of MySQL notification inbox repository
+func NewMysqlNotification(db *sql.DB) api.NotificationRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlNotification{newDB}
+}
+
+func (m *mysqlNotification) CreateNotification(ctx context.Context, notification *entity.Notification) error {
+	now := time.Now()
+	notification.CreatedAt = now
+	notification.UpdatedAt = now
+
+	query := `INSERT INTO notifications
+		(user_id, type, title, content, transaction_id, invoice_id, conversation_id, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := conn(ctx, m.db).ExecContext(ctx, query,
+		notification.UserID, notification.Type, notification.Title, notification.Content,
+		notification.TransactionID, notification.InvoiceID, notification.ConversationID,
+		notification.CreatedAt, notification.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	notification.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetNotification fetches a notification by its ID
+func (m *mysqlNotification) GetNotification(ctx context.Context, ID int64) (*entity.Notification, error) {
+	query := `
+		SELECT * FROM notifications
+		WHERE id = ?
+	`
+	result := &entity.Notification{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetUserNotifications fetches a page of the notifications of a user, latest first
+func (m *mysqlNotification) GetUserNotifications(ctx context.Context, userID int64, page entity.Page) ([]entity.Notification, int64, error) {
+	filteredQueries := []interface{}{
+		sqlm.Exp("user_id", "=", sqlm.P(userID)),
+	}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM notifications",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM notifications",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", defaultOrder,
+		buildLimit(page),
+	)
+	results := []entity.Notification{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+func (m *mysqlNotification) CountUnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		SELECT COUNT(id) FROM notifications
+		WHERE user_id = ? AND read_at IS NULL
+	`
+	var count int64
+	err := m.db.GetContext(ctx, &count, query, userID)
+	return count, err
+}
+
+// MarkNotificationRead marks a notification as read. The read time of a notification
+// which was already read is kept
+func (m *mysqlNotification) MarkNotificationRead(ctx context.Context, ID int64, readAt time.Time) error {
+	query := `
+		UPDATE notifications SET
+		read_at = ?
+		WHERE id = ? AND read_at IS NULL
+	`
+	res, err := m.db.ExecContext(ctx, query, readAt, ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows > 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when marking notification as read (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead marks all unread notifications of a user as read, and
+// returns how many were unread
+func (m *mysqlNotification) MarkAllNotificationsRead(ctx context.Context, userID int64, readAt time.Time) (int64, error) {
+	query := `
+		UPDATE notifications SET
+		read_at = ?
+		WHERE user_id = ? AND read_at IS NULL
+	`
+	res, err := m.db.ExecContext(ctx, query, readAt, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// DeleteUserNotifications deletes the whole inbox of a user
+func (m *mysqlNotification) DeleteUserNotifications(ctx context.Context, userID int64) error {
+	_, err := conn(ctx, m.db).ExecContext(ctx, `DELETE FROM notifications WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlNotificationTemplate struct {
+	db *sqlx.DB
+}
+
+// NewMysqlNotificationTemplate creates a new instance of MySQL notification template repository
+func NewMysqlNotificationTemplate(db *sql.DB) api.NotificationTemplateRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlNotificationTemplate{newDB}
+}
+
+// GetNotificationTemplate fetches the template of an event in a language
+func (m *mysqlNotificationTemplate) GetNotificationTemplate(ctx context.Context, event, locale string) (*entity.NotificationTemplate, error) {
+	query := `
+		SELECT * FROM notification_templates
+		WHERE event = ? AND locale = ?
+	`
+	result := &entity.NotificationTemplate{}
+	err := m.db.GetContext(ctx, result, query, event, locale)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *mysqlNotificationTemplate) GetNotificationTemplates(ctx context.Context) ([]entity.NotificationTemplate, error) {
+	query := `
+		SELECT * FROM notification_templates
+		ORDER BY event, locale
+	`
+	results := []entity.NotificationTemplate{}
+	err := m.db.SelectContext(ctx, &results, query)
+	return results, err
+}
+
+// UpsertNotificationTemplate inserts the template of an event in a language, or
+// replaces the existing one
+func (m *mysqlNotificationTemplate) UpsertNotificationTemplate(ctx context.Context, template *entity.NotificationTemplate) error {
+	now := time.Now()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	// LAST_INSERT_ID(id) makes the existing row ID the inserted ID on duplicates
+	query := `INSERT INTO notification_templates
+		(event, locale, title, content, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		id = LAST_INSERT_ID(id), title = VALUES(title), content = VALUES(content),
+		updated_at = VALUES(updated_at)
+	`
+	res, err := m.db.ExecContext(ctx, query,
+		template.Event, template.Locale, template.Title, template.Content,
+		template.CreatedAt, template.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	template.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteNotificationTemplate removes the template of an event in a language, so
+// the default one is used again
+func (m *mysqlNotificationTemplate) DeleteNotificationTemplate(ctx context.Context, event, locale string) error {
+	query := `
+		DELETE FROM notification_templates
+		WHERE event = ? AND locale = ?
+	`
+	res, err := m.db.ExecContext(ctx, query, event, locale)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return api.ErrNotFound
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting notification template (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
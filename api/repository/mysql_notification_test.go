@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlNotificationTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.NotificationRepository
+}
+
+func (s *mysqlNotificationTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlNotification(s.db)
+}
+
+func (s *mysqlNotificationTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlNotificationTestSuite) TestCreateNotificationStoresDeepLinks() {
+	transactionID := int64(12)
+	notification := entity.NewNotification(4, entity.NotificationData{
+		Type:          entity.NotificationTypeTransaction,
+		Title:         "Hi Budi, ada transaksi baru!",
+		Content:       "Ada yang ingin membeli Kit Kat dari kamu.",
+		TransactionID: &transactionID,
+	})
+
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notifications")).WithArgs(
+		int64(4), entity.NotificationTypeTransaction, "Hi Budi, ada transaksi baru!",
+		"Ada yang ingin membeli Kit Kat dari kamu.", &transactionID, nil, nil,
+		AnyTime{}, AnyTime{},
+	).WillReturnResult(sqlmock.NewResult(30, 1))
+
+	err := s.repo.CreateNotification(context.Background(), notification)
+
+	s.NoError(err)
+	s.Equal(int64(30), notification.ID)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlNotificationTestSuite) TestMarkAllNotificationsReadOnlyTouchesUnread() {
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL")).WithArgs(
+		AnyTime{}, int64(4),
+	).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	total, err := s.repo.MarkAllNotificationsRead(context.Background(), 4, time.Now())
+
+	s.NoError(err)
+	s.Equal(int64(3), total)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlNotification(t *testing.T) {
+	suite.Run(t, new(mysqlNotificationTestSuite))
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlOutbox struct {
+	db *sqlx.DB
+}
+
+// NewMysqlOutbox creates a new instance of MySQL pubsub outbox repository
+func NewMysqlOutbox(db *sql.DB) api.OutboxRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlOutbox{newDB}
+}
+
+// InsertOutboxMessage queues a message, in the transaction of the context if any,
+// so it is only published when the change it announces is committed
+func (m *mysqlOutbox) InsertOutboxMessage(ctx context.Context, message *entity.OutboxMessage) error {
+	now := time.Now()
+	message.CreatedAt = now
+	message.UpdatedAt = now
+
+	query := `INSERT INTO outbox_messages
+		(topic, payload, status, attempts, last_error, next_attempt_at, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := conn(ctx, m.db).ExecContext(ctx, query,
+		message.Topic, message.Payload, message.Status, message.Attempts, message.LastError,
+		message.NextAttemptAt, message.CreatedAt, message.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	message.ID, err = res.LastInsertId()
+	return err
+}
+
+// ClaimOutboxMessages fetches the pending messages due for an attempt, oldest first,
+// and postpones their next attempt to leaseUntil so concurrent relays skip them.
+// A relay dying in the middle leaves them to be retried once the lease is over
+func (m *mysqlOutbox) ClaimOutboxMessages(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.OutboxMessage, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error starting transaction")
+	}
+
+	results := []entity.OutboxMessage{}
+	err = tx.SelectContext(ctx, &results, `
+		SELECT * FROM outbox_messages
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY id ASC
+		LIMIT ?
+		FOR UPDATE`,
+		entity.OutboxStatusPending, now, limit,
+	)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "error selecting due outbox messages")
+	}
+	if len(results) == 0 {
+		tx.Rollback()
+		return results, nil
+	}
+
+	IDs := []int64{}
+	for i := range results {
+		IDs = append(IDs, results[i].ID)
+		results[i].NextAttemptAt = leaseUntil
+	}
+	query, args, err := sqlx.In(`UPDATE outbox_messages SET next_attempt_at = ? WHERE id IN (?)`, leaseUntil, IDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "error leasing outbox messages")
+	}
+
+	return results, tx.Commit()
+}
+
+// GetOutboxMessage fetches an outbox message by its ID
+func (m *mysqlOutbox) GetOutboxMessage(ctx context.Context, ID int64) (*entity.OutboxMessage, error) {
+	query := `
+		SELECT * FROM outbox_messages
+		WHERE id = ?
+	`
+	result := &entity.OutboxMessage{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetOutboxMessages fetches a page of the outbox messages having the status, latest first
+func (m *mysqlOutbox) GetOutboxMessages(ctx context.Context, status entity.OutboxStatus, page entity.Page) ([]entity.OutboxMessage, int64, error) {
+	filteredQueries := []interface{}{
+		sqlm.Exp("status", "=", sqlm.P(status)),
+	}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM outbox_messages",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM outbox_messages",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", defaultOrder,
+		buildLimit(page),
+	)
+	results := []entity.OutboxMessage{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// UpdateOutboxMessage records the outcome of a publishing attempt
+func (m *mysqlOutbox) UpdateOutboxMessage(ctx context.Context, ID int64, message *entity.OutboxMessage) error {
+	message.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE outbox_messages SET
+		status = ?, attempts = ?, last_error = ?, next_attempt_at = ?,
+		published_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	res, err := m.db.ExecContext(ctx, query,
+		message.Status, message.Attempts, message.LastError, message.NextAttemptAt,
+		message.PublishedAt, message.UpdatedAt,
+		ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating outbox message (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
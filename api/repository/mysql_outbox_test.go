@@ -0,0 +1,101 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlOutboxTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo       api.OutboxRepository
+	transactor api.Transactor
+}
+
+func (s *mysqlOutboxTestSuite) SetupTest() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlOutbox(s.db)
+	s.transactor = repository.NewMysqlTransactor(s.db)
+}
+
+func (s *mysqlOutboxTestSuite) TearDownTest() {
+	s.db.Close()
+}
+
+func (s *mysqlOutboxTestSuite) TestInsertJoinsTransactionOfContext() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox_messages")).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	s.mock.ExpectRollback()
+
+	message, _ := entity.NewOutboxMessage(entity.TopicPushNotification, map[string]string{"device": "phone"})
+	err := s.transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := s.repo.InsertOutboxMessage(ctx, message); err != nil {
+			return err
+		}
+		// the change being announced fails, so the message must not be kept
+		return errors.New("error updating transaction")
+	})
+
+	s.Error(err)
+	s.Equal(int64(7), message.ID)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlOutboxTestSuite) TestClaimLeasesDueMessages() {
+	now := time.Now()
+	leaseUntil := now.Add(time.Minute)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM outbox_messages")).
+		WithArgs(entity.OutboxStatusPending, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "payload", "status", "attempts"}).
+			AddRow(3, entity.TopicPushNotification, "{}", 0, 0).
+			AddRow(4, entity.TopicPushNotification, "{}", 0, 2))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox_messages SET next_attempt_at = ? WHERE id IN (?, ?)")).
+		WithArgs(leaseUntil, int64(3), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectCommit()
+
+	messages, err := s.repo.ClaimOutboxMessages(context.Background(), now, leaseUntil, 10)
+
+	s.NoError(err)
+	s.Len(messages, 2)
+	s.Equal(leaseUntil, messages[1].NextAttemptAt)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlOutboxTestSuite) TestClaimWithoutDueMessages() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM outbox_messages")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.mock.ExpectRollback()
+
+	now := time.Now()
+	messages, err := s.repo.ClaimOutboxMessages(context.Background(), now, now.Add(time.Minute), 10)
+
+	s.NoError(err)
+	s.Empty(messages)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlOutbox(t *testing.T) {
+	suite.Run(t, new(mysqlOutboxTestSuite))
+}
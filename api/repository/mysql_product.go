@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,12 +20,6 @@ type mysqlProduct struct {
 	db *sqlx.DB
 }
 
-var allowedFilters = map[string]struct{}{
-	"q":          struct{}{},
-	"seller_id":  struct{}{},
-	"country_id": struct{}{},
-}
-
 func NewMysqlProduct(db *sql.DB) api.ProductRepository {
 	newDB := sqlx.NewDb(db, "mysql")
 	return &mysqlProduct{newDB}
@@ -35,12 +31,12 @@ func (m *mysqlProduct) CreateProduct(ctx context.Context, product *entity.Produc
 	product.UpdatedAt = now
 
 	query := `INSERT INTO products
-		(title, description, price, seller_id, country_id, image, status,
-		from_date, to_date, created_at, updated_at)
+		(title, description, price, seller_id, country_id, category_id, trip_id,
+		reserved_for, image, status, from_date, to_date, created_at, updated_at)
 		VALUES
-		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -48,7 +44,7 @@ func (m *mysqlProduct) CreateProduct(ctx context.Context, product *entity.Produc
 	// execute query
 	res, err := prep.ExecContext(ctx,
 		product.Title, product.Description, product.Price, product.SellerID,
-		product.CountryID, product.Image, product.Status, product.FromDate,
+		product.CountryID, product.CategoryID, product.TripID, product.ReservedFor, product.Image, product.Status, product.FromDate,
 		product.ToDate, product.CreatedAt, product.UpdatedAt,
 	)
 	if err != nil {
@@ -78,27 +74,64 @@ func (m *mysqlProduct) GetProductsByUser(ctx context.Context, userID int64, limi
 	return results, count, err
 }
 
-func (m *mysqlProduct) GetProductsByFilter(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.Product, int64, error) {
-	filteredQueries := buildDynamicQuery(filter)
-	countQuery, countArgs := sqlm.Build(
-		"SELECT COUNT(id) FROM products",
+// GetProductsByFilter fetches a page of the products matching the filter spec
+func (m *mysqlProduct) GetProductsByFilter(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.Product, int64, error) {
+	filteredQueries := buildDynamicQuery(spec)
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM products",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM products",
 		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", buildOrderBy(spec.Sort, defaultOrder),
+		buildLimit(page),
 	)
+	results := []entity.Product{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
 
+// GetRankedProductsByFilter fetches the filtered products among rankedIDs. Unless the spec
+// has its own sort, the order of rankedIDs is kept, e.g. the relevance order of a search
+func (m *mysqlProduct) GetRankedProductsByFilter(ctx context.Context, rankedIDs []int64, spec *entity.FilterSpec, page entity.Page) ([]entity.Product, int64, error) {
+	if len(rankedIDs) == 0 {
+		return []entity.Product{}, 0, nil
+	}
+
+	filteredQueries := append(buildDynamicQuery(spec), sqlm.Exp("id IN", sqlm.F("(1, 2)", rankedIDs)))
 	var count int64
-	err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
-	if err != nil {
-		return nil, 0, err
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM products",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
 	query, args := sqlm.Build(
 		"SELECT * FROM products",
 		"WHERE", sqlm.And(filteredQueries),
-		"ORDER BY updated_at DESC",
-		sqlm.Exp("LIMIT", sqlm.P(offset), ",", sqlm.P(limit)),
+		"ORDER BY", buildOrderBy(spec.Sort, sqlm.F("FIELD(1, 2)", "id", rankedIDs)),
+		buildLimit(page),
 	)
 	results := []entity.Product{}
-	err = m.db.SelectContext(ctx, &results, query, args...)
+	err := m.db.SelectContext(ctx, &results, query, args...)
 	return results, count, err
 }
 
@@ -108,7 +141,7 @@ func (m *mysqlProduct) GetProduct(ctx context.Context, ID int64) (*entity.Produc
 		WHERE id = ?
 	`
 	result := &entity.Product{}
-	err := m.db.GetContext(ctx, result, query, ID)
+	err := conn(ctx, m.db).GetContext(ctx, result, query, ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, api.ErrNotFound
@@ -123,8 +156,8 @@ func (m *mysqlProduct) UpdateProduct(ctx context.Context, ID int64, newProduct *
 
 	query := `
 		UPDATE products SET
-		title = ?, description = ?, price = ?, country_id = ?, status = ?,
-		from_date = ?, to_date = ?, updated_at = ?
+		title = ?, description = ?, price = ?, country_id = ?, category_id = ?,
+		trip_id = ?, status = ?, from_date = ?, to_date = ?, updated_at = ?
 		WHERE id = ?
 	`
 	prep, err := m.db.PrepareContext(ctx, query)
@@ -134,7 +167,7 @@ func (m *mysqlProduct) UpdateProduct(ctx context.Context, ID int64, newProduct *
 
 	res, err := prep.ExecContext(ctx,
 		newProduct.Title, newProduct.Description, newProduct.Price,
-		newProduct.CountryID, newProduct.Status, newProduct.FromDate,
+		newProduct.CountryID, newProduct.CategoryID, newProduct.TripID, newProduct.Status, newProduct.FromDate,
 		newProduct.ToDate, newProduct.UpdatedAt,
 		ID,
 	)
@@ -160,7 +193,7 @@ func (m *mysqlProduct) DeleteProduct(ctx context.Context, ID int64) error {
 		deleted_at = ?
 		WHERE id = ?
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -181,19 +214,263 @@ func (m *mysqlProduct) DeleteProduct(ctx context.Context, ID int64) error {
 	return nil
 }
 
-func buildDynamicQuery(filter entity.DynamicFilter) []interface{} {
+// patchableProductColumns are the columns PatchProduct may write
+var patchableProductColumns = map[string]bool{
+	"title":       true,
+	"description": true,
+	"price":       true,
+	"country_id":  true,
+	"category_id": true,
+	"trip_id":     true,
+	"status":      true,
+	"from_date":   true,
+	"to_date":     true,
+}
+
+// PatchProduct writes the changed columns of a product, leaving the other columns untouched
+func (m *mysqlProduct) PatchProduct(ctx context.Context, ID int64, changes map[string]interface{}) error {
+	columns := []string{}
+	for column := range changes {
+		if !patchableProductColumns[column] {
+			return errors.Errorf("column %s of products can't be patched", column)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	assignments := []string{}
+	args := []interface{}{}
+	for _, column := range columns {
+		assignments = append(assignments, column+" = ?")
+		args = append(args, changes[column])
+	}
+	assignments = append(assignments, "updated_at = ?")
+	args = append(args, time.Now(), ID)
+
+	query := fmt.Sprintf("UPDATE products SET %s WHERE id = ?", strings.Join(assignments, ", "))
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when patching product (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// UpdateProductCover sets the legacy image column of a product to its cover image
+func (m *mysqlProduct) UpdateProductCover(ctx context.Context, ID int64, image string) error {
+	query := `
+		UPDATE products SET
+		image = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = prep.ExecContext(ctx, image, time.Now(), ID)
+	return err
+}
+
+// GetProductTags fetches the tags of a product, in the order they were set
+func (m *mysqlProduct) GetProductTags(ctx context.Context, productID int64) ([]string, error) {
+	query := `
+		SELECT tag FROM product_tags
+		WHERE product_id = ?
+		ORDER BY id ASC
+	`
+	results := []string{}
+	err := m.db.SelectContext(ctx, &results, query, productID)
+	return results, err
+}
+
+// SetProductTags replaces the tags of a product, within the transaction running
+// in the context if any
+func (m *mysqlProduct) SetProductTags(ctx context.Context, productID int64, tags []string) error {
+	transactor := &mysqlTransactor{m.db}
+	return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, err := conn(ctx, m.db).ExecContext(ctx, `DELETE FROM product_tags WHERE product_id = ?`, productID)
+		if err != nil {
+			return errors.Wrap(err, "error deleting product tags")
+		}
+
+		now := time.Now()
+		for _, tag := range tags {
+			_, err = conn(ctx, m.db).ExecContext(ctx,
+				`INSERT INTO product_tags (product_id, tag, created_at) VALUES (?, ?, ?)`,
+				productID, tag, now,
+			)
+			if err != nil {
+				return errors.Wrap(err, "error inserting product tag")
+			}
+		}
+		return nil
+	})
+}
+
+// CountProductsByCategory counts the products of each category a buyer can browse,
+// which are open by default like in GetProductsByFilter. Only products sold in the
+// country are counted when countryID is set
+func (m *mysqlProduct) CountProductsByCategory(ctx context.Context, countryID int64) (map[int64]int64, error) {
+	filters := []interface{}{
+		sqlm.Exp("deleted_at IS NULL"),
+		sqlm.Exp("reserved_for IS NULL"),
+		sqlm.Exp("category_id IS NOT NULL"),
+		buildAvailabilityCondition(int64(entity.ProductAvailabilityOpen), time.Now()),
+	}
+	if countryID > 0 {
+		filters = append(filters, sqlm.Exp("country_id", "=", sqlm.P(countryID)))
+	}
+
+	query, args := sqlm.Build(
+		"SELECT category_id, COUNT(id) AS total FROM products",
+		"WHERE", sqlm.And(filters),
+		"GROUP BY category_id",
+	)
+	rows := []struct {
+		CategoryID int64 `db:"category_id"`
+		Total      int64 `db:"total"`
+	}{}
+	if err := m.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	counts := map[int64]int64{}
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Total
+	}
+	return counts, nil
+}
+
+// CountProductsInCategory counts the products of a category, whatever their
+// availability
+func (m *mysqlProduct) CountProductsInCategory(ctx context.Context, categoryID int64) (int64, error) {
+	var count int64
+	err := m.db.GetContext(ctx, &count,
+		`SELECT COUNT(id) FROM products WHERE category_id = ? AND deleted_at IS NULL`,
+		categoryID,
+	)
+	return count, err
+}
+
+// CountProductsByTrip counts the listed products of a trip
+func (m *mysqlProduct) CountProductsByTrip(ctx context.Context, tripID int64) (int64, error) {
+	var count int64
+	err := m.db.GetContext(ctx, &count,
+		`SELECT COUNT(id) FROM products WHERE trip_id = ? AND deleted_at IS NULL`,
+		tripID,
+	)
+	return count, err
+}
+
+// SyncTripProducts writes the country and the offering window of a trip to its
+// listed products, and returns the number of updated products. Closed products
+// open again when the trip still accepts orders
+func (m *mysqlProduct) SyncTripProducts(ctx context.Context, trip *entity.Trip) (int64, error) {
+	now := time.Now()
+
+	query := `
+		UPDATE products SET
+		country_id = ?, from_date = ?, to_date = ?,
+		status = IF(status = ? AND ?, ?, status), updated_at = ?
+		WHERE trip_id = ? AND deleted_at IS NULL
+	`
+	res, err := conn(ctx, m.db).ExecContext(ctx, query,
+		trip.CountryID, trip.FromDate, trip.OrderDeadline(),
+		entity.ProductStatusClosed, trip.AcceptsOrders(now), entity.ProductStatusIdle, now,
+		trip.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CloseExpiredProducts closes the products whose offering window ended before the
+// day of now, and returns the number of closed products
+func (m *mysqlProduct) CloseExpiredProducts(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE products SET
+		status = ?, updated_at = ?
+		WHERE deleted_at IS NULL AND status <> ? AND to_date < ?
+	`
+	res, err := m.db.ExecContext(ctx, query,
+		entity.ProductStatusClosed, now, entity.ProductStatusClosed, now.Format("2006-01-02"),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func buildDynamicQuery(spec *entity.FilterSpec) []interface{} {
 	var filters []interface{}
 	// to handle no filter
 	filters = append(filters, sqlm.Exp("deleted_at IS NULL"))
-	for key, val := range filter {
-		if _, ok := allowedFilters[key]; ok {
-			if key == "q" {
-				filters = append(filters, sqlm.Exp("title", "LIKE", sqlm.P(fmt.Sprintf("%%%s%%", val))))
-			} else {
-				filters = append(filters, sqlm.Exp(key, "=", sqlm.P(val)))
-			}
+	filters = append(filters, sqlm.Exp("reserved_for IS NULL"))
+
+	availability, ok := spec.Value("availability")
+	if !ok {
+		availability = int64(entity.ProductAvailabilityOpen)
+	}
+	if condition := buildAvailabilityCondition(availability, time.Now()); condition != nil {
+		filters = append(filters, condition)
+	}
+
+	for _, filter := range spec.Filters {
+		switch filter.Key {
+		case "availability":
+			// already applied above
+		case "tag":
+			filters = append(filters, sqlm.Exp(
+				"id IN (SELECT product_id FROM product_tags WHERE",
+				buildFilterExpression("tag", filter),
+				")",
+			))
+		default:
+			filters = append(filters, buildFilterExpression(filter.Column, filter))
 		}
 	}
 
 	return filters
 }
+
+// buildAvailabilityCondition selects the products of an availability on the day
+// of now. Products past their window are closed even before the closing job runs
+func buildAvailabilityCondition(availability interface{}, now time.Time) sqlm.Expression {
+	today := now.Format("2006-01-02")
+	switch availability {
+	case int64(entity.ProductAvailabilityUpcoming):
+		return sqlm.And(
+			sqlm.Exp("status", "<>", sqlm.P(entity.ProductStatusClosed)),
+			sqlm.Exp("from_date", ">", sqlm.P(today)),
+		)
+	case int64(entity.ProductAvailabilityClosed):
+		return sqlm.Or(
+			sqlm.Exp("status", "=", sqlm.P(entity.ProductStatusClosed)),
+			sqlm.Exp("to_date", "<", sqlm.P(today)),
+		)
+	case int64(entity.ProductAvailabilityAll):
+		return nil
+	default:
+		return sqlm.And(
+			sqlm.Exp("status", "<>", sqlm.P(entity.ProductStatusClosed)),
+			sqlm.Exp("from_date", "<=", sqlm.P(today)),
+			sqlm.Exp("to_date", ">=", sqlm.P(today)),
+		)
+	}
+}
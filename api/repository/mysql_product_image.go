@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlProductImage struct {
+	db *sqlx.DB
+}
+
+// NewMysqlProductImage creates a new instance of MySQL product image repository
+func NewMysqlProductImage(db *sql.DB) api.ProductImageRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlProductImage{newDB}
+}
+
+// CreateImage inserts a new image of a product
+func (m *mysqlProductImage) CreateImage(ctx context.Context, image *entity.ProductImage) error {
+	now := time.Now()
+	image.CreatedAt = now
+	image.UpdatedAt = now
+
+	query := `INSERT INTO product_images
+		(product_id, url, path, position, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?)
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := prep.ExecContext(ctx,
+		image.ProductID, image.URL, image.Path, image.Position,
+		image.CreatedAt, image.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	image.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetProductImages fetches the images of a product, ordered by position
+func (m *mysqlProductImage) GetProductImages(ctx context.Context, productID int64) ([]entity.ProductImage, error) {
+	query := `
+		SELECT * FROM product_images
+		WHERE product_id = ?
+		ORDER BY position ASC, id ASC
+	`
+	results := []entity.ProductImage{}
+	err := conn(ctx, m.db).SelectContext(ctx, &results, query, productID)
+	return results, err
+}
+
+// GetImage fetches a product image by its ID
+func (m *mysqlProductImage) GetImage(ctx context.Context, ID int64) (*entity.ProductImage, error) {
+	query := `
+		SELECT * FROM product_images
+		WHERE id = ?
+	`
+	result := &entity.ProductImage{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteImage removes a product image row
+func (m *mysqlProductImage) DeleteImage(ctx context.Context, ID int64) error {
+	prep, err := m.db.PrepareContext(ctx, `DELETE FROM product_images WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+
+	res, err := prep.ExecContext(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting product image (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// UpdateImagePositions sets the position of each image to its index in orderedIDs
+func (m *mysqlProductImage) UpdateImagePositions(ctx context.Context, productID int64, orderedIDs []int64) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+
+	prep, err := tx.PrepareContext(ctx, `UPDATE product_images SET position = ?, updated_at = ? WHERE id = ? AND product_id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer prep.Close()
+
+	now := time.Now()
+	for position, ID := range orderedIDs {
+		if _, err := prep.ExecContext(ctx, position, now, ID, productID); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "error updating product image position")
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlProductSearch struct {
+	db *sqlx.DB
+}
+
+// NewMysqlProductSearch creates a product search index backed by the FULLTEXT
+// indexes of the products table
+func NewMysqlProductSearch(db *sql.DB) api.ProductSearchIndex {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlProductSearch{newDB}
+}
+
+// IndexProduct does nothing, InnoDB keeps FULLTEXT indexes up to date on every write
+func (m *mysqlProductSearch) IndexProduct(ctx context.Context, product *entity.Product) error {
+	return nil
+}
+
+// RemoveProduct does nothing, deleted products are excluded when searching
+func (m *mysqlProductSearch) RemoveProduct(ctx context.Context, productID int64) error {
+	return nil
+}
+
+// SearchProducts ranks products by the relevance of their title and description
+// to the query. Title matches weigh twice as much as description matches
+func (m *mysqlProductSearch) SearchProducts(ctx context.Context, query string, limit int) ([]int64, error) {
+	sqlQuery := `
+		SELECT id FROM products
+		WHERE deleted_at IS NULL
+		AND MATCH(title, description) AGAINST (? IN NATURAL LANGUAGE MODE)
+		ORDER BY
+		(MATCH(title) AGAINST (? IN NATURAL LANGUAGE MODE) * 2 +
+		MATCH(title, description) AGAINST (? IN NATURAL LANGUAGE MODE)) DESC,
+		id DESC
+		LIMIT ?
+	`
+	results := []int64{}
+	err := m.db.SelectContext(ctx, &results, sqlQuery, query, query, query, limit)
+	return results, err
+}
+
+// Rebuild optimizes the products table, which rebuilds its FULLTEXT indexes
+// and purges the entries of deleted rows
+func (m *mysqlProductSearch) Rebuild(ctx context.Context, products []entity.Product) error {
+	_, err := m.db.ExecContext(ctx, `OPTIMIZE TABLE products`)
+	return err
+}
@@ -0,0 +1,136 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlProductTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.ProductRepository
+}
+
+func (s *mysqlProductTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlProduct(s.db)
+}
+
+func (s *mysqlProductTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlProductTestSuite) TestPatchProductOnlyWritesChanges() {
+	prep := s.mock.ExpectPrepare(regexp.QuoteMeta("UPDATE products SET price = ?, title = ?, updated_at = ? WHERE id = ?"))
+	prep.ExpectExec().WithArgs(
+		uint(15000), "Matcha Kit Kat", AnyTime{}, int64(7),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := s.repo.PatchProduct(context.Background(), 7, map[string]interface{}{
+		"title": "Matcha Kit Kat",
+		"price": uint(15000),
+	})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductTestSuite) TestPatchProductRejectsUnknownColumns() {
+	err := s.repo.PatchProduct(context.Background(), 7, map[string]interface{}{
+		"seller_id": int64(2),
+	})
+
+	s.Error(err)
+}
+
+func (s *mysqlProductTestSuite) TestSyncTripProductsCopiesTripWindow() {
+	fromDate := time.Now().AddDate(0, 0, 3).Truncate(24 * time.Hour)
+	trip := &entity.Trip{
+		ID:         4,
+		CountryID:  2,
+		FromDate:   fromDate,
+		ToDate:     fromDate.AddDate(0, 0, 9),
+		CutoffDate: fromDate.AddDate(0, 0, 7),
+	}
+
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET country_id = ?, from_date = ?, to_date = ?, status = IF(status = ? AND ?, ?, status), updated_at = ? WHERE trip_id = ? AND deleted_at IS NULL")).WithArgs(
+		int64(2), fromDate, trip.OrderDeadline(),
+		entity.ProductStatusClosed, true, entity.ProductStatusIdle, AnyTime{},
+		int64(4),
+	).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	total, err := s.repo.SyncTripProducts(context.Background(), trip)
+
+	s.NoError(err)
+	s.Equal(int64(3), total)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductTestSuite) TestProductsAreOpenByDefault() {
+	today := time.Now().Format("2006-01-02")
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM products WHERE (deleted_at IS NULL AND reserved_for IS NULL AND (status <> 3 AND from_date <= ? AND to_date >= ?))")).WithArgs(
+		today, today,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err := s.repo.GetProductsByFilter(context.Background(), entity.NewFilterSpec(), entity.Page{Limit: 10, SkipCount: true})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductTestSuite) TestAllProductsAreNotFilteredByAvailability() {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM products WHERE (deleted_at IS NULL AND reserved_for IS NULL) ORDER BY")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	spec := entity.NewFilterSpec().Where("availability", entity.FilterOperatorEq, int64(entity.ProductAvailabilityAll))
+	_, _, err := s.repo.GetProductsByFilter(context.Background(), spec, entity.Page{Limit: 10, SkipCount: true})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductTestSuite) TestClosedProductsAreNotCountedByCategory() {
+	today := time.Now().Format("2006-01-02")
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT category_id, COUNT(id) AS total FROM products WHERE (deleted_at IS NULL AND reserved_for IS NULL AND category_id IS NOT NULL AND (status <> 3 AND from_date <= ? AND to_date >= ?)) GROUP BY category_id")).WithArgs(
+		today, today,
+	).WillReturnRows(sqlmock.NewRows([]string{"category_id", "total"}).AddRow(1, 2))
+
+	counts, err := s.repo.CountProductsByCategory(context.Background(), 0)
+
+	s.NoError(err)
+	s.Equal(map[int64]int64{1: 2}, counts)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductTestSuite) TestCloseExpiredProducts() {
+	now := time.Date(2019, 12, 10, 0, 5, 0, 0, time.UTC)
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET status = ?, updated_at = ? WHERE deleted_at IS NULL AND status <> ? AND to_date < ?")).WithArgs(
+		entity.ProductStatusClosed, now, entity.ProductStatusClosed, "2019-12-10",
+	).WillReturnResult(sqlmock.NewResult(0, 4))
+
+	total, err := s.repo.CloseExpiredProducts(context.Background(), now)
+
+	s.NoError(err)
+	s.Equal(int64(4), total)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlProduct(t *testing.T) {
+	suite.Run(t, new(mysqlProductTestSuite))
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlProductVariant struct {
+	db *sqlx.DB
+}
+
+// NewMysqlProductVariant creates a new instance of MySQL product variant repository
+func NewMysqlProductVariant(db *sql.DB) api.ProductVariantRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlProductVariant{newDB}
+}
+
+// CreateVariant inserts a new variant of a product
+func (m *mysqlProductVariant) CreateVariant(ctx context.Context, variant *entity.ProductVariant) error {
+	now := time.Now()
+	variant.CreatedAt = now
+	variant.UpdatedAt = now
+
+	query := `INSERT INTO product_variants
+		(product_id, options, price, stock, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?)
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		variant.ProductID, variant.Options, variant.Price, variant.Stock,
+		variant.CreatedAt, variant.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	variant.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetProductVariants fetches the variants of a product, in the order they were added
+func (m *mysqlProductVariant) GetProductVariants(ctx context.Context, productID int64) ([]entity.ProductVariant, error) {
+	query := `
+		SELECT * FROM product_variants
+		WHERE product_id = ? AND deleted_at IS NULL
+		ORDER BY id ASC
+	`
+	results := []entity.ProductVariant{}
+	err := conn(ctx, m.db).SelectContext(ctx, &results, query, productID)
+	return results, err
+}
+
+// GetVariant fetches a variant by its ID. Deleted variants are still returned,
+// as past transactions refer to them
+func (m *mysqlProductVariant) GetVariant(ctx context.Context, ID int64) (*entity.ProductVariant, error) {
+	query := `
+		SELECT * FROM product_variants
+		WHERE id = ?
+	`
+	result := &entity.ProductVariant{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateVariant updates the options, price and stock of a variant. The variant is
+// only updated while its stock is still previousStock, so the stock taken by the
+// orders placed in the meantime is not overwritten
+func (m *mysqlProductVariant) UpdateVariant(ctx context.Context, ID int64, variant *entity.ProductVariant, previousStock *uint) error {
+	variant.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE product_variants SET
+		options = ?, price = ?, stock = ?, updated_at = ?
+		WHERE id = ? AND stock <=> ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		variant.Options, variant.Price, variant.Stock, variant.UpdatedAt,
+		ID, previousStock,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return api.ErrVariantStockChanged
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating product variant (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// DeleteVariant soft-deletes a variant
+func (m *mysqlProductVariant) DeleteVariant(ctx context.Context, ID int64) error {
+	query := `
+		UPDATE product_variants SET
+		deleted_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx, time.Now(), ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting product variant (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// ReserveVariantStock takes the ordered quantity from the stock of a variant. The
+// check and the update happen in one statement, so concurrent orders can't oversell
+func (m *mysqlProductVariant) ReserveVariantStock(ctx context.Context, ID int64, quantity uint) error {
+	query := `
+		UPDATE product_variants SET
+		stock = stock - ?, updated_at = ?
+		WHERE id = ? AND (stock IS NULL OR stock >= ?)
+	`
+	res, err := conn(ctx, m.db).ExecContext(ctx, query, quantity, time.Now(), ID, quantity)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return api.ErrVariantOutOfStock
+	}
+
+	return nil
+}
+
+// ReleaseVariantStock gives the quantity of a cancelled order back to the stock of a variant
+func (m *mysqlProductVariant) ReleaseVariantStock(ctx context.Context, ID int64, quantity uint) error {
+	query := `
+		UPDATE product_variants SET
+		stock = stock + ?, updated_at = ?
+		WHERE id = ? AND stock IS NOT NULL
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query, quantity, time.Now(), ID)
+	return err
+}
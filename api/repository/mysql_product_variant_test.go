@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlProductVariantTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.ProductVariantRepository
+}
+
+func (s *mysqlProductVariantTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlProductVariant(s.db)
+}
+
+func (s *mysqlProductVariantTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlProductVariantTestSuite) TestReserveOutOfStockVariant() {
+	s.mock.ExpectExec(regexp.QuoteMeta("WHERE id = ? AND (stock IS NULL OR stock >= ?)")).WithArgs(
+		uint(3), AnyTime{}, int64(4), uint(3),
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.repo.ReserveVariantStock(context.Background(), 4, 3)
+
+	s.Equal(api.ErrVariantOutOfStock, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductVariantTestSuite) TestReserveAndReleaseJoinTheTransaction() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("stock = stock - ?")).WithArgs(
+		uint(2), AnyTime{}, int64(4), uint(2),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta("stock = stock + ?")).WithArgs(
+		uint(2), AnyTime{}, int64(4),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectRollback()
+
+	transactor := repository.NewMysqlTransactor(s.db)
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := s.repo.ReserveVariantStock(ctx, 4, 2); err != nil {
+			return err
+		}
+		if err := s.repo.ReleaseVariantStock(ctx, 4, 2); err != nil {
+			return err
+		}
+		return api.ErrNotFound
+	})
+
+	s.Equal(api.ErrNotFound, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlProductVariantTestSuite) TestUpdateVariantWhoseStockChanged() {
+	previousStock, stock := uint(5), uint(10)
+	variant := &entity.ProductVariant{
+		Options: entity.VariantOptions{{Name: "Ukuran", Value: "42"}},
+		Stock:   &stock,
+	}
+
+	prep := s.mock.ExpectPrepare(regexp.QuoteMeta("WHERE id = ? AND stock <=> ?"))
+	prep.ExpectExec().WithArgs(
+		variant.Options, variant.Price, variant.Stock, AnyTime{}, int64(4), &previousStock,
+	).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := s.repo.UpdateVariant(context.Background(), 4, variant, &previousStock)
+
+	s.Equal(api.ErrVariantStockChanged, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlProductVariant(t *testing.T) {
+	suite.Run(t, new(mysqlProductVariantTestSuite))
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+// mysqlErrDuplicateEntry is the error number of MySQL for a violated unique index
+const mysqlErrDuplicateEntry = 1062
+
+type mysqlReview struct {
+	db *sqlx.DB
+}
+
+// NewMysqlReview creates a new instance of MySQL review repository
+func NewMysqlReview(db *sql.DB) api.ReviewRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlReview{newDB}
+}
+
+// CreateReview inserts a new review and adds its rating to the aggregate rating
+// of the seller. Both writes are made in the transaction running in the context
+func (m *mysqlReview) CreateReview(ctx context.Context, review *entity.Review) error {
+	now := time.Now()
+	review.CreatedAt = now
+	review.UpdatedAt = now
+
+	res, err := conn(ctx, m.db).ExecContext(ctx, `INSERT INTO reviews
+		(transaction_id, product_id, buyer_id, seller_id, rating, text, photos,
+		created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		review.TransactionID, review.ProductID, review.BuyerID, review.SellerID,
+		review.Rating, review.Text, review.Photos, review.CreatedAt, review.UpdatedAt,
+	)
+	if err != nil {
+		// the transaction was reviewed concurrently
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return api.ErrDuplicateReview
+		}
+		return errors.Wrap(err, "error inserting review")
+	}
+
+	review.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, m.db).ExecContext(ctx, `
+		UPDATE users SET
+		rating_sum = rating_sum + ?, review_count = review_count + 1
+		WHERE id = ?`,
+		review.Rating, review.SellerID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error updating seller rating")
+	}
+
+	return nil
+}
+
+// GetReview fetches a review by its ID
+func (m *mysqlReview) GetReview(ctx context.Context, ID int64) (*entity.Review, error) {
+	query := `
+		SELECT * FROM reviews
+		WHERE id = ?
+	`
+	result := &entity.Review{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetReviewByTransaction fetches the review of a transaction
+func (m *mysqlReview) GetReviewByTransaction(ctx context.Context, transactionID int64) (*entity.Review, error) {
+	query := `
+		SELECT * FROM reviews
+		WHERE transaction_id = ?
+	`
+	result := &entity.Review{}
+	err := m.db.GetContext(ctx, result, query, transactionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetSellerReviews fetches a page of the reviews received by a seller, latest
+// first. Replies bump updated_at, so reviews are ordered by their creation instead
+func (m *mysqlReview) GetSellerReviews(ctx context.Context, sellerID int64, page entity.Page) ([]entity.Review, int64, error) {
+	filteredQueries := []interface{}{sqlm.Exp("seller_id", "=", sqlm.P(sellerID))}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM reviews",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetConditionOn("created_at", page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM reviews",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", createdOrder,
+		buildLimit(page),
+	)
+	results := []entity.Review{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// ReplyReview writes the reply of the seller to a review
+func (m *mysqlReview) ReplyReview(ctx context.Context, ID int64, review *entity.Review) error {
+	review.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE reviews SET
+		reply = ?, replied_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx, review.Reply, review.RepliedAt, review.UpdatedAt, ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when replying review (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// GetUserReviews fetches every review written by a user as a buyer or received
+// as a seller, oldest first
+func (m *mysqlReview) GetUserReviews(ctx context.Context, userID int64) ([]entity.Review, error) {
+	query := `
+		SELECT * FROM reviews
+		WHERE buyer_id = ? OR seller_id = ?
+		ORDER BY id ASC
+	`
+	results := []entity.Review{}
+	err := m.db.SelectContext(ctx, &results, query, userID, userID)
+	return results, err
+}
+
+// AnonymizeUserReviews clears the text and photos of the reviews written by a user,
+// and the replies written by the user as a seller. Ratings are kept, since they
+// make up the rating of the sellers
+func (m *mysqlReview) AnonymizeUserReviews(ctx context.Context, userID int64) error {
+	now := time.Now()
+	_, err := conn(ctx, m.db).ExecContext(ctx,
+		`UPDATE reviews SET text = '', photos = '[]', updated_at = ? WHERE buyer_id = ?`,
+		now, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, m.db).ExecContext(ctx,
+		`UPDATE reviews SET reply = '', updated_at = ? WHERE seller_id = ? AND reply <> ''`,
+		now, userID,
+	)
+	return err
+}
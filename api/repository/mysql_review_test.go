@@ -0,0 +1,118 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlReviewTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.ReviewRepository
+}
+
+func (s *mysqlReviewTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlReview(s.db)
+}
+
+func (s *mysqlReviewTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlReviewTestSuite) TestCreateReviewUpdatesSellerRating() {
+	review := &entity.Review{
+		TransactionID: 11,
+		ProductID:     5,
+		BuyerID:       3,
+		SellerID:      2,
+		Rating:        4,
+		Text:          "Barang sesuai pesanan",
+		Photos:        entity.ReviewPhotos{},
+	}
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO reviews")).WithArgs(
+		int64(11), int64(5), int64(3), int64(2), uint(4), "Barang sesuai pesanan", review.Photos,
+		AnyTime{}, AnyTime{},
+	).WillReturnResult(sqlmock.NewResult(9, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET rating_sum = rating_sum + ?, review_count = review_count + 1 WHERE id = ?")).WithArgs(
+		uint(4), int64(2),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	transactor := repository.NewMysqlTransactor(s.db)
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		return s.repo.CreateReview(ctx, review)
+	})
+
+	s.NoError(err)
+	s.Equal(int64(9), review.ID)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlReviewTestSuite) TestConcurrentReviewIsDuplicate() {
+	s.mock.ExpectExec(regexp.QuoteMeta("INSERT INTO reviews")).WillReturnError(&mysql.MySQLError{
+		Number:  1062,
+		Message: "Duplicate entry '11' for key 'index_reviews_on_transaction_id'",
+	})
+
+	err := s.repo.CreateReview(context.Background(), &entity.Review{TransactionID: 11, Photos: entity.ReviewPhotos{}})
+
+	s.Equal(api.ErrDuplicateReview, err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlReviewTestSuite) TestSellerReviewsArePagedByCreation() {
+	after := &entity.Cursor{UpdatedAt: time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC), ID: 9}
+
+	// a reply bumps updated_at, which must not move the review between pages
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM reviews WHERE (seller_id = 2 AND (created_at < ? OR (created_at = ? AND id < 9))) ORDER BY created_at DESC, id DESC LIMIT 10")).WithArgs(
+		after.UpdatedAt, after.UpdatedAt,
+	).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err := s.repo.GetSellerReviews(context.Background(), 2, entity.Page{Limit: 10, CursorMode: true, After: after, SkipCount: true})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func (s *mysqlReviewTestSuite) TestAnonymizeUserReviewsJoinsTheTransaction() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE reviews SET text = '', photos = '[]', updated_at = ? WHERE buyer_id = ?")).WithArgs(
+		AnyTime{}, int64(3),
+	).WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE reviews SET reply = '', updated_at = ? WHERE seller_id = ? AND reply <> ''")).WithArgs(
+		AnyTime{}, int64(3),
+	).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	transactor := repository.NewMysqlTransactor(s.db)
+	err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		return s.repo.AnonymizeUserReviews(ctx, 3)
+	})
+
+	s.NoError(err)
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestMysqlReview(t *testing.T) {
+	suite.Run(t, new(mysqlReviewTestSuite))
+}
@@ -31,7 +31,7 @@ func (m *mysqlShipping) InsertShipping(ctx context.Context, shipping *entity.Tra
 		(transaction_id, awb_number, courier, created_at, updated_at)
 		VALUES
 		(?, ?, ?, ?, ?)`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return errors.Wrap(err, "error preparing insert shipping query")
 	}
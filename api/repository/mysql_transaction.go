@@ -20,10 +20,6 @@ type mysqlTransaction struct {
 	db *sqlx.DB
 }
 
-var transactionAllowedFilters = map[string]struct{}{
-	"product_id": struct{}{},
-}
-
 func NewMysqlTransaction(db *sql.DB) api.TransactionRepository {
 	newDB := sqlx.NewDb(db, "mysql")
 	return &mysqlTransaction{newDB}
@@ -35,18 +31,18 @@ func (m *mysqlTransaction) CreateTransaction(ctx context.Context, transaction *e
 	transaction.UpdatedAt = now
 
 	query := `INSERT INTO transactions
-		(product_id, buyer_id, seller_id, buyer_address_id, quantity,
+		(product_id, variant_id, buyer_id, seller_id, buyer_address_id, quantity,
 			notes, total_price, invoice_id, created_at, updated_at)
 		VALUES
-		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	prep, err := m.db.PrepareContext(ctx, query)
+		(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return errors.Wrap(err, "error preparing insert transaction query")
 	}
 	defer prep.Close()
 
 	res, err := prep.ExecContext(ctx,
-		transaction.ProductID, transaction.BuyerID, transaction.SellerID,
+		transaction.ProductID, transaction.VariantID, transaction.BuyerID, transaction.SellerID,
 		transaction.BuyerAddressID, transaction.Quantity, transaction.Notes,
 		transaction.TotalPrice, transaction.InvoiceID, transaction.CreatedAt, transaction.UpdatedAt,
 	)
@@ -64,7 +60,7 @@ func (m *mysqlTransaction) GetTransaction(ctx context.Context, transactionID int
 		WHERE id = ?
 	`
 	result := &entity.Transaction{}
-	err := m.db.GetContext(ctx, result, query, transactionID)
+	err := conn(ctx, m.db).GetContext(ctx, result, query, transactionID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, api.ErrNotFound
@@ -76,51 +72,67 @@ func (m *mysqlTransaction) GetTransaction(ctx context.Context, transactionID int
 	return result, nil
 }
 
-func (m *mysqlTransaction) GetTransactions(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.Transaction, int64, error) {
-	filteredQueries := buildTransactionDynamicQuery(filter)
-	countQuery, countArgs := sqlm.Build(
-		"SELECT COUNT(id) FROM transactions",
-		"WHERE", sqlm.And(filteredQueries),
-	)
+// GetTransactions fetches a page of the transactions of a user matching the filter spec
+func (m *mysqlTransaction) GetTransactions(ctx context.Context, userID int64, spec *entity.FilterSpec, page entity.Page) ([]entity.Transaction, int64, error) {
+	filteredQueries := buildTransactionDynamicQuery(userID, spec)
 
 	var count int64
-	err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
-	if err != nil {
-		return nil, 0, err
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM transactions",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
 
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
 	query, args := sqlm.Build(
 		"SELECT * FROM transactions",
 		"WHERE", sqlm.And(filteredQueries),
-		"ORDER BY updated_at DESC",
-		sqlm.Exp("LIMIT", sqlm.P(offset), ",", sqlm.P(limit)),
+		"ORDER BY", buildOrderBy(spec.Sort, defaultOrder),
+		buildLimit(page),
 	)
 	results := []entity.Transaction{}
-	err = m.db.SelectContext(ctx, &results, query, args...)
+	err := m.db.SelectContext(ctx, &results, query, args...)
 	return results, count, err
 }
 
-func buildTransactionDynamicQuery(filter entity.DynamicFilter) []sqlm.Expression {
+func buildTransactionDynamicQuery(userID int64, spec *entity.FilterSpec) []sqlm.Expression {
 	var filters []sqlm.Expression
 
 	// we define default cases here
 	var defaultExpression sqlm.Expression
-	switch role, _ := filter["role"]; role {
-	case "buyer":
-		defaultExpression = sqlm.Exp("buyer_id", "=", sqlm.P(filter["buyer_id"]))
-	case "seller":
-		defaultExpression = sqlm.Exp("seller_id", "=", sqlm.P(filter["seller_id"]))
+	role, _ := spec.Value("role")
+	switch role {
+	case int64(entity.TransactionRoleBuyer):
+		defaultExpression = sqlm.Exp("buyer_id", "=", sqlm.P(userID))
+	case int64(entity.TransactionRoleSeller):
+		defaultExpression = sqlm.Exp("seller_id", "=", sqlm.P(userID))
 	default:
 		defaultExpression = sqlm.Or(
-			sqlm.Exp("buyer_id", "=", sqlm.P(filter["buyer_id"])),
-			sqlm.Exp("seller_id", "=", sqlm.P(filter["seller_id"])),
+			sqlm.Exp("buyer_id", "=", sqlm.P(userID)),
+			sqlm.Exp("seller_id", "=", sqlm.P(userID)),
 		)
 	}
 	filters = append(filters, defaultExpression)
 
-	for key, val := range filter {
-		if _, ok := transactionAllowedFilters[key]; ok {
-			filters = append(filters, sqlm.Exp(key, "=", sqlm.P(val)))
+	for _, filter := range spec.Filters {
+		switch filter.Key {
+		case "role":
+			// already applied above
+		case "invoice_code":
+			filters = append(filters, sqlm.Exp(
+				"id IN (SELECT transaction_id FROM invoices WHERE",
+				buildFilterExpression("invoice_code", filter),
+				")",
+			))
+		default:
+			filters = append(filters, buildFilterExpression(filter.Column, filter))
 		}
 	}
 
@@ -134,7 +146,7 @@ func (m *mysqlTransaction) UpdateTransactionState(ctx context.Context, transacti
 	query := `UPDATE transactions SET
 		status = ?, invoice_id = ?, paid_at = ?, finished_at = ?, updated_at = ?
 		WHERE id = ?`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return errors.Wrap(err, "error preparing update transaction query")
 	}
@@ -158,3 +170,19 @@ func (m *mysqlTransaction) UpdateTransactionState(ctx context.Context, transacti
 
 	return nil
 }
+
+// CountUserTransactionsByStatus counts the transactions of a user, either as buyer or seller,
+// having one of the provided statuses
+func (m *mysqlTransaction) CountUserTransactionsByStatus(ctx context.Context, userID int64, statuses []int) (int64, error) {
+	query, args, err := sqlx.In(
+		`SELECT COUNT(id) FROM transactions WHERE (buyer_id = ? OR seller_id = ?) AND status IN (?)`,
+		userID, userID, statuses,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "error building count transaction query")
+	}
+
+	var count int64
+	err = m.db.GetContext(ctx, &count, m.db.Rebind(query), args...)
+	return count, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"sejastip.id/api"
+)
+
+type txContextKey struct{}
+
+type mysqlTransactor struct {
+	db *sqlx.DB
+}
+
+// NewMysqlTransactor creates a new instance of MySQL transaction runner
+func NewMysqlTransactor(db *sql.DB) api.Transactor {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlTransactor{newDB}
+}
+
+// WithinTransaction runs fn in a database transaction, committed when fn succeeds
+// and rolled back otherwise. A transaction already running in the context is joined
+func (m *mysqlTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error starting transaction")
+	}
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// executor is implemented by both the database and its transactions
+type executor interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// conn returns the transaction running in the context, or the database when
+// there is none
+func conn(ctx context.Context, db *sqlx.DB) executor {
+	if tx, ok := ctx.Value(txContextKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/shuoli84/sqlm"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type mysqlTrip struct {
+	db *sqlx.DB
+}
+
+// NewMysqlTrip creates a new instance of MySQL trip repository
+func NewMysqlTrip(db *sql.DB) api.TripRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlTrip{newDB}
+}
+
+// CreateTrip inserts a new trip
+func (m *mysqlTrip) CreateTrip(ctx context.Context, trip *entity.Trip) error {
+	now := time.Now()
+	trip.CreatedAt = now
+	trip.UpdatedAt = now
+
+	query := `INSERT INTO trips
+		(seller_id, country_id, cities, from_date, to_date, cutoff_date,
+		luggage_capacity, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		trip.SellerID, trip.CountryID, trip.Cities, trip.FromDate, trip.ToDate,
+		trip.CutoffDate, trip.LuggageCapacity, trip.CreatedAt, trip.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	trip.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetTrips fetches a page of the trips matching the filter spec
+func (m *mysqlTrip) GetTrips(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.Trip, int64, error) {
+	filteredQueries := []interface{}{sqlm.Exp("deleted_at IS NULL")}
+	for _, filter := range spec.Filters {
+		filteredQueries = append(filteredQueries, buildFilterExpression(filter.Column, filter))
+	}
+
+	var count int64
+	if !page.SkipCount {
+		countQuery, countArgs := sqlm.Build(
+			"SELECT COUNT(id) FROM trips",
+			"WHERE", sqlm.And(filteredQueries),
+		)
+		err := m.db.GetContext(ctx, &count, countQuery, countArgs...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if page.After != nil {
+		filteredQueries = append(filteredQueries, buildKeysetCondition(page.After))
+	}
+	query, args := sqlm.Build(
+		"SELECT * FROM trips",
+		"WHERE", sqlm.And(filteredQueries),
+		"ORDER BY", buildOrderBy(spec.Sort, defaultOrder),
+		buildLimit(page),
+	)
+	results := []entity.Trip{}
+	err := m.db.SelectContext(ctx, &results, query, args...)
+	return results, count, err
+}
+
+// GetTrip fetches a trip by its ID
+func (m *mysqlTrip) GetTrip(ctx context.Context, ID int64) (*entity.Trip, error) {
+	query := `
+		SELECT * FROM trips
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	result := &entity.Trip{}
+	err := m.db.GetContext(ctx, result, query, ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateTrip overwrites the country, cities, dates and luggage capacity of a trip
+func (m *mysqlTrip) UpdateTrip(ctx context.Context, ID int64, trip *entity.Trip) error {
+	trip.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE trips SET
+		country_id = ?, cities = ?, from_date = ?, to_date = ?, cutoff_date = ?,
+		luggage_capacity = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		trip.CountryID, trip.Cities, trip.FromDate, trip.ToDate, trip.CutoffDate,
+		trip.LuggageCapacity, trip.UpdatedAt,
+		ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating trip (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// DeleteTrip soft deletes a trip
+func (m *mysqlTrip) DeleteTrip(ctx context.Context, ID int64) error {
+	query := `
+		UPDATE trips SET
+		deleted_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx, time.Now(), ID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when deleting trip (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// DeleteSellerTrips soft-deletes every trip of a seller
+func (m *mysqlTrip) DeleteSellerTrips(ctx context.Context, sellerID int64) error {
+	query := `
+		UPDATE trips SET
+		deleted_at = ?
+		WHERE seller_id = ? AND deleted_at IS NULL
+	`
+	_, err := conn(ctx, m.db).ExecContext(ctx, query, time.Now(), sellerID)
+	return err
+}
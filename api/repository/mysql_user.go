@@ -31,11 +31,11 @@ func (m *mysqlUser) CreateUser(ctx context.Context, user *entity.User) error {
 
 	query := `INSERT INTO users
 		(email, name, phone, password, bank_name, bank_account,
-		created_at, updated_at)
+		language, created_at, updated_at)
 		VALUES
-		(?, ?, ?, ?, ?, ?, ?, ?)
+		(?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -43,7 +43,7 @@ func (m *mysqlUser) CreateUser(ctx context.Context, user *entity.User) error {
 	// execute query
 	res, err := prep.ExecContext(ctx,
 		user.Email, user.Name, user.Phone, user.Password, user.BankName,
-		user.BankAccount, user.CreatedAt, user.UpdatedAt,
+		user.BankAccount, user.Language, user.CreatedAt, user.UpdatedAt,
 	)
 	if err != nil {
 		return err
@@ -96,10 +96,11 @@ func (m *mysqlUser) UpdateUser(ctx context.Context, ID int64, user *entity.User)
 	query := `
 		UPDATE users SET
 		email = ?, name = ?, phone = ?, bank_name = ?, bank_account = ?,
+		avatar = ?, language = ?, order_email_opt_out = ?, payment_email_opt_out = ?,
 		updated_at = ?
 		WHERE id = ?
 	`
-	prep, err := m.db.PrepareContext(ctx, query)
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -107,8 +108,9 @@ func (m *mysqlUser) UpdateUser(ctx context.Context, ID int64, user *entity.User)
 	res, err := prep.ExecContext(ctx,
 		user.Email, user.Name, user.Phone,
 		user.BankName, user.BankAccount,
-		user.UpdatedAt,
-		user.ID,
+		user.Avatar, user.Language,
+		user.OrderEmailOptOut, user.PaymentEmailOptOut, user.UpdatedAt,
+		ID,
 	)
 	if err != nil {
 		return err
@@ -125,6 +127,94 @@ func (m *mysqlUser) UpdateUser(ctx context.Context, ID int64, user *entity.User)
 	return nil
 }
 
+// UpdateVerificationStatus updates the email and phone verification timestamps of a user
+func (m *mysqlUser) UpdateVerificationStatus(ctx context.Context, ID int64, user *entity.User) error {
+	now := time.Now()
+	user.UpdatedAt = now
+
+	query := `
+		UPDATE users SET
+		email_verified_at = ?, phone_verified_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := prep.ExecContext(ctx,
+		user.EmailVerifiedAt, user.PhoneVerifiedAt, user.UpdatedAt, ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating user verification (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
+// UpdateLastLogin records the time of a user's latest successful login
+func (m *mysqlUser) UpdateLastLogin(ctx context.Context, ID int64, lastLoginAt time.Time) error {
+	query := `
+		UPDATE users SET
+		last_login_at = ?
+		WHERE id = ?
+	`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = prep.ExecContext(ctx, lastLoginAt, ID)
+	return err
+}
+
+// AnonymizeUser overwrites the personal data of a deleted user, and marks it as deleted
+func (m *mysqlUser) AnonymizeUser(ctx context.Context, ID int64, user *entity.User) error {
+	now := time.Now()
+	user.UpdatedAt = now
+
+	query := `
+		UPDATE users SET
+		email = ?, name = ?, phone = ?, password = ?,
+		bank_name = ?, bank_account = ?, avatar = ?, language = ?,
+		order_email_opt_out = ?, payment_email_opt_out = ?,
+		email_verified_at = ?, phone_verified_at = ?, deleted_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := prep.ExecContext(ctx,
+		user.Email, user.Name, user.Phone, user.Password,
+		user.BankName, user.BankAccount, user.Avatar, user.Language,
+		user.OrderEmailOptOut, user.PaymentEmailOptOut,
+		user.EmailVerifiedAt, user.PhoneVerifiedAt, user.DeletedAt, user.UpdatedAt, ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when anonymizing user (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
+
 // GetUserByEmail fetches a user having the provided email
 func (m *mysqlUser) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
 	query := `
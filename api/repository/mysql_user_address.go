@@ -120,3 +120,20 @@ func (m *mysqlUserAddress) UpdateAddress(ctx context.Context, ID int64, address
 
 	return nil
 }
+
+// AnonymizeUserAddresses clears the address details of all addresses owned by a user.
+// The rows are kept, since transactions still refer to them
+func (m *mysqlUserAddress) AnonymizeUserAddresses(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE user_addresses SET
+		address = '', phone = '', address_name = '', updated_at = ?
+		WHERE user_id = ?
+	`
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = prep.ExecContext(ctx, time.Now(), userID)
+	return err
+}
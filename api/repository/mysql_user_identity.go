@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type mysqlUserIdentity struct {
+	db *sqlx.DB
+}
+
+// NewMysqlUserIdentity creates a new instance of MySQL linked identity repository
+func NewMysqlUserIdentity(db *sql.DB) api.UserIdentityRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlUserIdentity{newDB}
+}
+
+// GetIdentity fetches a linked identity by its issuer and subject
+func (m *mysqlUserIdentity) GetIdentity(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error) {
+	query := `
+		SELECT * FROM user_identities
+		WHERE issuer = ? AND subject = ?
+		LIMIT 1
+	`
+	result := &entity.UserIdentity{}
+	err := m.db.GetContext(ctx, result, query, issuer, subject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CreateIdentity links a new third-party identity to a user
+func (m *mysqlUserIdentity) CreateIdentity(ctx context.Context, identity *entity.UserIdentity) error {
+	now := time.Now()
+	identity.CreatedAt = now
+	identity.UpdatedAt = now
+
+	query := `INSERT INTO user_identities
+		(user_id, issuer, subject, email, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?)`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing insert identity query")
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		identity.UserID, identity.Issuer, identity.Subject, identity.Email,
+		identity.CreatedAt, identity.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error executing insert identity query")
+	}
+
+	identity.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteUserIdentities unlinks all third-party identities of a user
+func (m *mysqlUserIdentity) DeleteUserIdentities(ctx context.Context, userID int64) error {
+	prep, err := conn(ctx, m.db).PrepareContext(ctx, `DELETE FROM user_identities WHERE user_id = ?`)
+	if err != nil {
+		return errors.Wrap(err, "error preparing delete identity query")
+	}
+
+	_, err = prep.ExecContext(ctx, userID)
+	return err
+}
@@ -51,7 +51,7 @@ func (s *mysqlUserTestSuite) TestCreateUser() {
 	prep := s.mock.ExpectPrepare("^INSERT INTO users")
 	prep.ExpectExec().WithArgs(
 		user.Email, user.Name, user.Phone, user.Password, user.BankName,
-		user.BankAccount, AnyTime{}, AnyTime{},
+		user.BankAccount, user.Language, AnyTime{}, AnyTime{},
 	).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	ctx := context.Background()
@@ -67,7 +67,8 @@ func (s *mysqlUserTestSuite) TestUpdateUser() {
 	prep := s.mock.ExpectPrepare("^UPDATE users SET")
 	prep.ExpectExec().WithArgs(
 		user.Email, user.Name, user.Phone, user.BankName,
-		user.BankAccount, AnyTime{}, user.ID,
+		user.BankAccount, user.Avatar, user.Language,
+		user.OrderEmailOptOut, user.PaymentEmailOptOut, AnyTime{}, user.ID,
 	).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	ctx := context.Background()
@@ -76,6 +77,24 @@ func (s *mysqlUserTestSuite) TestUpdateUser() {
 	s.NoError(err)
 }
 
+func (s *mysqlUserTestSuite) TestAnonymizeUser() {
+	user := fixture.StubbedUser()
+	user.ID = 1
+	user.Anonymize("unusable")
+
+	prep := s.mock.ExpectPrepare("^UPDATE users SET")
+	prep.ExpectExec().WithArgs(
+		"deleted-1@deleted.sejastip.id", user.Name, "", "unusable",
+		"", "", "", "id", true, true, nil, nil, AnyTime{}, AnyTime{}, user.ID,
+	).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ctx := context.Background()
+	err := s.repo.AnonymizeUser(ctx, user.ID, &user)
+
+	s.NoError(err)
+	s.NotNil(user.DeletedAt)
+}
+
 func TestMysqlUser(t *testing.T) {
 	suite.Run(t, new(mysqlUserTestSuite))
 }
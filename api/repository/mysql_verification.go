@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type mysqlVerification struct {
+	db *sqlx.DB
+}
+
+// NewMysqlVerification creates a new instance of MySQL contact verification repository
+func NewMysqlVerification(db *sql.DB) api.VerificationRepository {
+	newDB := sqlx.NewDb(db, "mysql")
+	return &mysqlVerification{newDB}
+}
+
+// CreateVerification inserts a new verification request
+func (m *mysqlVerification) CreateVerification(ctx context.Context, verification *entity.Verification) error {
+	now := time.Now()
+	verification.CreatedAt = now
+	verification.UpdatedAt = now
+
+	query := `INSERT INTO user_verifications
+		(user_id, channel, target, code, attempts, expired_at, created_at, updated_at)
+		VALUES
+		(?, ?, ?, ?, ?, ?, ?, ?)`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing insert verification query")
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		verification.UserID, verification.Channel, verification.Target, verification.Code,
+		verification.Attempts, verification.ExpiredAt, verification.CreatedAt, verification.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error executing insert verification query")
+	}
+
+	verification.ID, err = res.LastInsertId()
+	return err
+}
+
+// GetLatestVerification fetches the most recent verification request of a user on a channel
+func (m *mysqlVerification) GetLatestVerification(ctx context.Context, userID int64, channel string) (*entity.Verification, error) {
+	query := `
+		SELECT * FROM user_verifications
+		WHERE user_id = ? AND channel = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	result := &entity.Verification{}
+	err := m.db.GetContext(ctx, result, query, userID, channel)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetVerificationByCode fetches a verification request by its hashed code
+func (m *mysqlVerification) GetVerificationByCode(ctx context.Context, channel, code string) (*entity.Verification, error) {
+	query := `
+		SELECT * FROM user_verifications
+		WHERE channel = ? AND code = ?
+		LIMIT 1
+	`
+	result := &entity.Verification{}
+	err := m.db.GetContext(ctx, result, query, channel, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, api.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CountVerificationsSince counts the verification requests made by a user on a channel after a certain time
+func (m *mysqlVerification) CountVerificationsSince(ctx context.Context, userID int64, channel string, since time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(id) FROM user_verifications
+		WHERE user_id = ? AND channel = ? AND created_at >= ?
+	`
+	var count int64
+	err := m.db.GetContext(ctx, &count, query, userID, channel, since)
+	return count, err
+}
+
+// UpdateVerification updates the attempt counter and verification time of a verification request
+func (m *mysqlVerification) UpdateVerification(ctx context.Context, ID int64, verification *entity.Verification) error {
+	now := time.Now()
+	verification.UpdatedAt = now
+
+	query := `UPDATE user_verifications SET
+		attempts = ?, verified_at = ?, updated_at = ?
+		WHERE id = ?`
+	prep, err := m.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "error preparing update verification query")
+	}
+	defer prep.Close()
+
+	res, err := prep.ExecContext(ctx,
+		verification.Attempts, verification.VerifiedAt, verification.UpdatedAt, ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "error executing update verification query")
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New(fmt.Sprintf("Unexpected behavior detected when updating verification (total rows affected: %d)", affectedRows))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/repository"
+)
+
+type mysqlVerificationTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *sql.DB
+
+	repo api.VerificationRepository
+}
+
+func (s *mysqlVerificationTestSuite) SetupSuite() {
+	var err error
+	s.db, s.mock, err = sqlmock.New()
+	if err != nil {
+		s.T().Fatalf("error opening mock db: %v", err)
+	}
+
+	s.repo = repository.NewMysqlVerification(s.db)
+}
+
+func (s *mysqlVerificationTestSuite) TearDownSuite() {
+	s.db.Close()
+}
+
+func (s *mysqlVerificationTestSuite) TestCreateVerification() {
+	verification := entity.Verification{
+		UserID:    1,
+		Channel:   entity.VerificationChannelPhone,
+		Target:    "628961234321",
+		Code:      "hashedcode",
+		ExpiredAt: time.Now().Add(5 * time.Minute),
+	}
+
+	prep := s.mock.ExpectPrepare("^INSERT INTO user_verifications")
+	prep.ExpectExec().WithArgs(
+		verification.UserID, verification.Channel, verification.Target, verification.Code,
+		0, AnyTime{}, AnyTime{}, AnyTime{},
+	).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ctx := context.Background()
+	err := s.repo.CreateVerification(ctx, &verification)
+
+	s.NoError(err)
+	s.Equal(verification.ID, int64(1))
+}
+
+func (s *mysqlVerificationTestSuite) TestGetLatestVerificationNotFound() {
+	s.mock.ExpectQuery("^SELECT \\* FROM user_verifications").
+		WithArgs(int64(1), entity.VerificationChannelEmail).
+		WillReturnError(sql.ErrNoRows)
+
+	ctx := context.Background()
+	_, err := s.repo.GetLatestVerification(ctx, 1, entity.VerificationChannelEmail)
+
+	s.Equal(api.ErrNotFound, err)
+}
+
+func TestMysqlVerification(t *testing.T) {
+	suite.Run(t, new(mysqlVerificationTestSuite))
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/pkg/errors"
+	"sejastip.id/api/entity"
 )
 
 // ResponseBody is our default structure for API responses
@@ -38,6 +39,66 @@ func NewMetaPagination(status, limit, offset, total int) MetaPagination {
 	}
 }
 
+// MetaCursorPagination is an extended version of MetaInfo with cursor pagination info.
+// Total is omitted when counting is skipped, and NextCursor is empty on the last page
+type MetaCursorPagination struct {
+	Status     int    `json:"status"`
+	Limit      int    `json:"limit"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// NewMetaCursorPagination to create cursor pagination meta
+func NewMetaCursorPagination(status, limit int, total *int, nextCursor string) MetaCursorPagination {
+	return MetaCursorPagination{
+		Status:     status,
+		Limit:      limit,
+		Total:      total,
+		NextCursor: nextCursor,
+	}
+}
+
+// NewPageMeta creates the pagination meta matching the page mode: offset pages
+// keep the original meta for older clients
+func NewPageMeta(status int, page entity.Page, total int64, nextCursor string) interface{} {
+	if !page.CursorMode {
+		return NewMetaPagination(status, page.Limit, page.Offset, int(total))
+	}
+
+	var totalPtr *int
+	if !page.SkipCount {
+		t := int(total)
+		totalPtr = &t
+	}
+	return NewMetaCursorPagination(status, page.Limit, totalPtr, nextCursor)
+}
+
+// MetaUnreadPagination is an extended version of MetaPagination with the count of unread items
+type MetaUnreadPagination struct {
+	MetaPagination
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// MetaUnreadCursorPagination is an extended version of MetaCursorPagination with
+// the count of unread items
+type MetaUnreadCursorPagination struct {
+	MetaCursorPagination
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// NewUnreadPageMeta creates the pagination meta matching the page mode, along with
+// the count of unread items of the listing
+func NewUnreadPageMeta(status int, page entity.Page, total int64, nextCursor string, unread int64) interface{} {
+	switch meta := NewPageMeta(status, page, total, nextCursor).(type) {
+	case MetaPagination:
+		return MetaUnreadPagination{meta, unread}
+	case MetaCursorPagination:
+		return MetaUnreadCursorPagination{meta, unread}
+	default:
+		return meta
+	}
+}
+
 type ErrorBody struct {
 	Message string `json:"message,omitempty"`
 	Code    int    `json:"code,omitempty"`
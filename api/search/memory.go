@@ -0,0 +1,186 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+// BM25 parameters, and the weight of a title term occurrence relative to
+// a description term occurrence
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2
+)
+
+type document struct {
+	// weighted term frequencies over the title and description
+	terms  map[string]float64
+	length float64
+}
+
+// MemoryIndex is an embedded full-text product index ranking results with BM25.
+// It lives in the process memory, so it has to be rebuilt on every start
+type MemoryIndex struct {
+	mu          sync.RWMutex
+	documents   map[int64]*document
+	postings    map[string]map[int64]struct{}
+	totalLength float64
+}
+
+// NewMemoryIndex creates an empty in-memory product index
+func NewMemoryIndex() api.ProductSearchIndex {
+	return &MemoryIndex{
+		documents: map[int64]*document{},
+		postings:  map[string]map[int64]struct{}{},
+	}
+}
+
+// IndexProduct adds or replaces a product in the index. Deleted products, and
+// products reserved for a buyer, are removed
+func (idx *MemoryIndex) IndexProduct(ctx context.Context, product *entity.Product) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(product.ID)
+	if product.DeletedAt == nil && product.ReservedFor == nil {
+		idx.add(product)
+	}
+	return nil
+}
+
+// RemoveProduct removes a product from the index
+func (idx *MemoryIndex) RemoveProduct(ctx context.Context, productID int64) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(productID)
+	return nil
+}
+
+// SearchProducts returns the IDs of products matching any of the query terms,
+// most relevant first
+func (idx *MemoryIndex) SearchProducts(ctx context.Context, query string, limit int) ([]int64, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	total := float64(len(idx.documents))
+	if total == 0 {
+		return []int64{}, nil
+	}
+	avgLength := idx.totalLength / total
+
+	scores := map[int64]float64{}
+	for _, term := range uniqueTerms(tokenize(query)) {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+		for id := range postings {
+			doc := idx.documents[id]
+			tf := doc.terms[term]
+			norm := bm25K1 * (1 - bm25B + bm25B*doc.length/avgLength)
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		// newer products first on equal relevance
+		return ids[i] > ids[j]
+	})
+
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// Rebuild replaces the whole index content with the provided products
+func (idx *MemoryIndex) Rebuild(ctx context.Context, products []entity.Product) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.documents = map[int64]*document{}
+	idx.postings = map[string]map[int64]struct{}{}
+	idx.totalLength = 0
+	for i := range products {
+		if products[i].DeletedAt == nil {
+			idx.add(&products[i])
+		}
+	}
+	return nil
+}
+
+func (idx *MemoryIndex) add(product *entity.Product) {
+	doc := &document{terms: map[string]float64{}}
+	for _, term := range tokenize(product.Title) {
+		doc.terms[term] += titleWeight
+		doc.length += titleWeight
+	}
+	for _, term := range tokenize(product.Description) {
+		doc.terms[term]++
+		doc.length++
+	}
+
+	idx.documents[product.ID] = doc
+	idx.totalLength += doc.length
+	for term := range doc.terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = map[int64]struct{}{}
+		}
+		idx.postings[term][product.ID] = struct{}{}
+	}
+}
+
+func (idx *MemoryIndex) remove(productID int64) {
+	doc, ok := idx.documents[productID]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		delete(idx.postings[term], productID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.documents, productID)
+}
+
+// tokenize lowercases a text and splits it into terms of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]struct{}{}
+	result := []string{}
+	for _, term := range terms {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+		result = append(result, term)
+	}
+	return result
+}
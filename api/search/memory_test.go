@@ -0,0 +1,78 @@
+package search_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/search"
+)
+
+type memoryIndexTestSuite struct {
+	suite.Suite
+	index api.ProductSearchIndex
+}
+
+func (s *memoryIndexTestSuite) SetupTest() {
+	s.index = search.NewMemoryIndex()
+	err := s.index.Rebuild(context.Background(), []entity.Product{
+		{ID: 1, Title: "Tas Kulit Jepang", Description: "Tas tangan dari Tokyo"},
+		{ID: 2, Title: "Matcha Kyoto", Description: "Bubuk teh hijau, cocok untuk oleh-oleh dari Jepang"},
+		{ID: 3, Title: "Coklat Swiss", Description: "Coklat batangan"},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *memoryIndexTestSuite) TestSearchRanksTitleMatchesFirst() {
+	ids, err := s.index.SearchProducts(context.Background(), "jepang", 10)
+
+	s.NoError(err)
+	s.Equal([]int64{1, 2}, ids)
+}
+
+func (s *memoryIndexTestSuite) TestSearchIsCaseAndPunctuationInsensitive() {
+	ids, err := s.index.SearchProducts(context.Background(), "OLEH-OLEH!", 10)
+
+	s.NoError(err)
+	s.Equal([]int64{2}, ids)
+}
+
+func (s *memoryIndexTestSuite) TestIndexProductReplacesPreviousContent() {
+	ctx := context.Background()
+	err := s.index.IndexProduct(ctx, &entity.Product{ID: 3, Title: "Coklat Jepang"})
+	s.Require().NoError(err)
+
+	ids, err := s.index.SearchProducts(ctx, "swiss", 10)
+	s.NoError(err)
+	s.Empty(ids)
+
+	ids, err = s.index.SearchProducts(ctx, "jepang", 10)
+	s.NoError(err)
+	s.ElementsMatch([]int64{1, 2, 3}, ids)
+}
+
+func (s *memoryIndexTestSuite) TestRemovedAndDeletedProductsAreNotFound() {
+	ctx := context.Background()
+	s.Require().NoError(s.index.RemoveProduct(ctx, 1))
+	now := time.Now()
+	s.Require().NoError(s.index.IndexProduct(ctx, &entity.Product{ID: 2, Title: "Matcha Kyoto", DeletedAt: &now}))
+
+	ids, err := s.index.SearchProducts(ctx, "jepang matcha", 10)
+
+	s.NoError(err)
+	s.Empty(ids)
+}
+
+func (s *memoryIndexTestSuite) TestSearchLimit() {
+	ids, err := s.index.SearchProducts(context.Background(), "jepang", 1)
+
+	s.NoError(err)
+	s.Equal([]int64{1}, ids)
+}
+
+func TestMemoryIndex(t *testing.T) {
+	suite.Run(t, new(memoryIndexTestSuite))
+}
@@ -2,10 +2,17 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"sejastip.id/api/entity"
 )
 
+// User and Bank are kept for the fixtures, which predate the entity package
+type (
+	User = entity.User
+	Bank = entity.Bank
+)
+
 // UserRepository is a contract for structs implementing user storage
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *entity.User) error
@@ -13,6 +20,40 @@ type UserRepository interface {
 	GetUser(ctx context.Context, ID int64) (*entity.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
 	UpdateUser(ctx context.Context, ID int64, user *entity.User) error
+	UpdateVerificationStatus(ctx context.Context, ID int64, user *entity.User) error
+	UpdateLastLogin(ctx context.Context, ID int64, lastLoginAt time.Time) error
+	AnonymizeUser(ctx context.Context, ID int64, user *entity.User) error
+}
+
+// LoginThrottleRepository is a contract for structs implementing failed login counter storage
+type LoginThrottleRepository interface {
+	GetThrottle(ctx context.Context, key string) (*entity.LoginThrottle, error)
+	IncrementThrottle(ctx context.Context, key string, failedAt, windowStart time.Time) (*entity.LoginThrottle, error)
+	LockThrottle(ctx context.Context, key string, lockedUntil time.Time) error
+	ResetThrottle(ctx context.Context, key string) error
+}
+
+// UserIdentityRepository is a contract for structs implementing linked third-party identity storage
+type UserIdentityRepository interface {
+	GetIdentity(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *entity.UserIdentity) error
+	DeleteUserIdentities(ctx context.Context, userID int64) error
+}
+
+// LoginHistoryRepository is a contract for structs implementing user login history storage
+type LoginHistoryRepository interface {
+	InsertLogin(ctx context.Context, login *entity.UserLogin) error
+	GetUserLogins(ctx context.Context, userID int64, limit, offset int) ([]entity.UserLogin, int64, error)
+	DeleteUserLogins(ctx context.Context, userID int64) error
+}
+
+// VerificationRepository is a contract for structs implementing contact verification storage
+type VerificationRepository interface {
+	CreateVerification(ctx context.Context, verification *entity.Verification) error
+	GetLatestVerification(ctx context.Context, userID int64, channel string) (*entity.Verification, error)
+	GetVerificationByCode(ctx context.Context, channel, code string) (*entity.Verification, error)
+	CountVerificationsSince(ctx context.Context, userID int64, channel string, since time.Time) (int64, error)
+	UpdateVerification(ctx context.Context, ID int64, verification *entity.Verification) error
 }
 
 // BankRepository is a contract for structs implementing banks storage
@@ -34,10 +75,118 @@ type CountryRepository interface {
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product *entity.Product) error
 	GetProductsByUser(ctx context.Context, userID int64, limit, offset int) ([]entity.Product, int64, error)
-	GetProductsByFilter(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.Product, int64, error)
+	GetProductsByFilter(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.Product, int64, error)
+	GetRankedProductsByFilter(ctx context.Context, rankedIDs []int64, spec *entity.FilterSpec, page entity.Page) ([]entity.Product, int64, error)
 	GetProduct(ctx context.Context, ID int64) (*entity.Product, error)
 	UpdateProduct(ctx context.Context, ID int64, newProduct *entity.Product) error
+	PatchProduct(ctx context.Context, ID int64, changes map[string]interface{}) error
 	DeleteProduct(ctx context.Context, ID int64) error
+	UpdateProductCover(ctx context.Context, ID int64, image string) error
+	GetProductTags(ctx context.Context, productID int64) ([]string, error)
+	SetProductTags(ctx context.Context, productID int64, tags []string) error
+	CountProductsByCategory(ctx context.Context, countryID int64) (map[int64]int64, error)
+	CountProductsInCategory(ctx context.Context, categoryID int64) (int64, error)
+	CountProductsByTrip(ctx context.Context, tripID int64) (int64, error)
+	SyncTripProducts(ctx context.Context, trip *entity.Trip) (int64, error)
+	CloseExpiredProducts(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ProductVariantRepository is a contract for structs implementing product variant storage
+type ProductVariantRepository interface {
+	CreateVariant(ctx context.Context, variant *entity.ProductVariant) error
+	GetProductVariants(ctx context.Context, productID int64) ([]entity.ProductVariant, error)
+	GetVariant(ctx context.Context, ID int64) (*entity.ProductVariant, error)
+	UpdateVariant(ctx context.Context, ID int64, variant *entity.ProductVariant, previousStock *uint) error
+	DeleteVariant(ctx context.Context, ID int64) error
+	ReserveVariantStock(ctx context.Context, ID int64, quantity uint) error
+	ReleaseVariantStock(ctx context.Context, ID int64, quantity uint) error
+}
+
+// BuyRequestRepository is a contract for structs implementing buy request storage
+type BuyRequestRepository interface {
+	CreateBuyRequest(ctx context.Context, request *entity.BuyRequest) error
+	GetBuyRequests(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.BuyRequest, int64, error)
+	GetBuyRequest(ctx context.Context, ID int64) (*entity.BuyRequest, error)
+	UpdateBuyRequestStatus(ctx context.Context, ID int64, request *entity.BuyRequest) error
+	AnonymizeUserBuyRequests(ctx context.Context, buyerID int64) error
+}
+
+// BuyRequestOfferRepository is a contract for structs implementing buy request offer storage
+type BuyRequestOfferRepository interface {
+	CreateOffer(ctx context.Context, offer *entity.BuyRequestOffer) error
+	GetOffers(ctx context.Context, buyRequestID int64) ([]entity.BuyRequestOffer, error)
+	GetOffer(ctx context.Context, ID int64) (*entity.BuyRequestOffer, error)
+	CountPendingOffers(ctx context.Context, buyRequestID int64) (int64, error)
+	UpdateOfferStatus(ctx context.Context, ID int64, offer *entity.BuyRequestOffer) error
+	RejectPendingOffers(ctx context.Context, buyRequestID int64) error
+	GetSellerOffers(ctx context.Context, sellerID int64) ([]entity.BuyRequestOffer, error)
+	WithdrawSellerOffers(ctx context.Context, sellerID int64) error
+}
+
+// TripRepository is a contract for structs implementing seller trip storage
+type TripRepository interface {
+	CreateTrip(ctx context.Context, trip *entity.Trip) error
+	GetTrips(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.Trip, int64, error)
+	GetTrip(ctx context.Context, ID int64) (*entity.Trip, error)
+	UpdateTrip(ctx context.Context, ID int64, trip *entity.Trip) error
+	DeleteTrip(ctx context.Context, ID int64) error
+	DeleteSellerTrips(ctx context.Context, sellerID int64) error
+}
+
+// ReviewRepository is a contract for structs implementing transaction review storage
+type ReviewRepository interface {
+	CreateReview(ctx context.Context, review *entity.Review) error
+	GetReview(ctx context.Context, ID int64) (*entity.Review, error)
+	GetReviewByTransaction(ctx context.Context, transactionID int64) (*entity.Review, error)
+	GetSellerReviews(ctx context.Context, sellerID int64, page entity.Page) ([]entity.Review, int64, error)
+	ReplyReview(ctx context.Context, ID int64, review *entity.Review) error
+	GetUserReviews(ctx context.Context, userID int64) ([]entity.Review, error)
+	AnonymizeUserReviews(ctx context.Context, userID int64) error
+}
+
+// ConversationRepository is a contract for structs implementing chat conversation storage
+type ConversationRepository interface {
+	UpsertConversation(ctx context.Context, conversation *entity.Conversation) error
+	GetConversation(ctx context.Context, ID int64) (*entity.Conversation, error)
+	GetUserConversations(ctx context.Context, userID int64, page entity.Page) ([]entity.Conversation, int64, error)
+	UpdateReadReceipts(ctx context.Context, ID int64, conversation *entity.Conversation) error
+}
+
+// MessageRepository is a contract for structs implementing chat message storage
+type MessageRepository interface {
+	CreateMessage(ctx context.Context, message *entity.Message) error
+	GetMessage(ctx context.Context, ID int64) (*entity.Message, error)
+	GetMessages(ctx context.Context, conversationID int64, page entity.Page) ([]entity.Message, int64, error)
+	GetMessagesAfter(ctx context.Context, conversationID, afterID int64, limit int) ([]entity.Message, error)
+	CountUnreadMessages(ctx context.Context, conversationID, recipientID, lastReadID int64) (int64, error)
+	AnonymizeUserMessages(ctx context.Context, senderID int64) error
+}
+
+// CategoryRepository is a contract for structs implementing product category storage
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, category *entity.Category) error
+	GetCategories(ctx context.Context) ([]entity.Category, error)
+	GetCategory(ctx context.Context, ID int64) (*entity.Category, error)
+	UpdateCategory(ctx context.Context, ID int64, category *entity.Category) error
+	DeleteCategory(ctx context.Context, ID int64) error
+}
+
+// ProductImageRepository is a contract for structs implementing product image storage
+type ProductImageRepository interface {
+	CreateImage(ctx context.Context, image *entity.ProductImage) error
+	GetProductImages(ctx context.Context, productID int64) ([]entity.ProductImage, error)
+	GetImage(ctx context.Context, ID int64) (*entity.ProductImage, error)
+	DeleteImage(ctx context.Context, ID int64) error
+	UpdateImagePositions(ctx context.Context, productID int64, orderedIDs []int64) error
+}
+
+// ProductSearchIndex is a contract for full-text indexes of product listings.
+// SearchProducts returns the IDs of matching products, most relevant first
+type ProductSearchIndex interface {
+	IndexProduct(ctx context.Context, product *entity.Product) error
+	RemoveProduct(ctx context.Context, productID int64) error
+	SearchProducts(ctx context.Context, query string, limit int) ([]int64, error)
+	Rebuild(ctx context.Context, products []entity.Product) error
 }
 
 // UserAddressRepository is a contract for structs implementing user address storage
@@ -46,14 +195,16 @@ type UserAddressRepository interface {
 	GetUserAddresses(ctx context.Context, userID int64, limit, offset int) ([]entity.UserAddress, int64, error)
 	GetUserAddress(ctx context.Context, ID int64) (*entity.UserAddress, error)
 	UpdateAddress(ctx context.Context, ID int64, newAddress *entity.UserAddress) error
+	AnonymizeUserAddresses(ctx context.Context, userID int64) error
 }
 
 // TransactionRepository is a contract for structs implementing transaction storage
 type TransactionRepository interface {
-	GetTransactions(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.Transaction, int64, error)
+	GetTransactions(ctx context.Context, userID int64, spec *entity.FilterSpec, page entity.Page) ([]entity.Transaction, int64, error)
 	GetTransaction(ctx context.Context, transactionID int64) (*entity.Transaction, error)
 	CreateTransaction(ctx context.Context, transaction *entity.Transaction) error
 	UpdateTransactionState(ctx context.Context, transactionID int64, transaction *entity.Transaction) error
+	CountUserTransactionsByStatus(ctx context.Context, userID int64, statuses []int) (int64, error)
 }
 
 // InvoiceRepository is a contract for structs implementing transaction invoice storage
@@ -72,21 +223,102 @@ type ShippingRepository interface {
 
 // DeviceRepository is a contract for structs implementing device storage
 type DeviceRepository interface {
-	GetUserDevice(ctx context.Context, userID int64) (*entity.Device, error)
-	InsertUserDevice(ctx context.Context, device *entity.Device) error
-	UpdateUserDevice(ctx context.Context, ID int64, newDevice *entity.Device) error
-	RemoveDevice(ctx context.Context, ID int64) error
+	GetUserDevices(ctx context.Context, userID int64) ([]entity.Device, error)
+	UpsertUserDevice(ctx context.Context, device *entity.Device) error
+	RemoveUserDevice(ctx context.Context, userID int64, deviceID string) error
+	RemoveDevices(ctx context.Context, deviceIDs []string) (int64, error)
+	RemoveUserDevices(ctx context.Context, userID int64) error
+}
+
+// NotificationRepository is a contract for structs implementing notification inbox storage
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, notification *entity.Notification) error
+	GetNotification(ctx context.Context, ID int64) (*entity.Notification, error)
+	GetUserNotifications(ctx context.Context, userID int64, page entity.Page) ([]entity.Notification, int64, error)
+	CountUnreadNotifications(ctx context.Context, userID int64) (int64, error)
+	MarkNotificationRead(ctx context.Context, ID int64, readAt time.Time) error
+	MarkAllNotificationsRead(ctx context.Context, userID int64, readAt time.Time) (int64, error)
+	DeleteUserNotifications(ctx context.Context, userID int64) error
+}
+
+// NotificationTemplateRepository is a contract for structs implementing storage of
+// the notification templates overriding the default ones
+type NotificationTemplateRepository interface {
+	GetNotificationTemplate(ctx context.Context, event, locale string) (*entity.NotificationTemplate, error)
+	GetNotificationTemplates(ctx context.Context) ([]entity.NotificationTemplate, error)
+	UpsertNotificationTemplate(ctx context.Context, template *entity.NotificationTemplate) error
+	DeleteNotificationTemplate(ctx context.Context, event, locale string) error
+}
+
+// OutboxRepository is a contract for structs implementing storage of the messages
+// waiting to be published to pubsub
+type OutboxRepository interface {
+	InsertOutboxMessage(ctx context.Context, message *entity.OutboxMessage) error
+	ClaimOutboxMessages(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.OutboxMessage, error)
+	GetOutboxMessage(ctx context.Context, ID int64) (*entity.OutboxMessage, error)
+	GetOutboxMessages(ctx context.Context, status entity.OutboxStatus, page entity.Page) ([]entity.OutboxMessage, int64, error)
+	UpdateOutboxMessage(ctx context.Context, ID int64, message *entity.OutboxMessage) error
+}
+
+// Transactor is a contract for structs running repository writes in a single database
+// transaction. Repositories joining transactions pick it up from the context
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // UserUsecase is a contract for usecases related to users
 type UserUsecase interface {
-	Register(ctx context.Context, user *entity.User) (*entity.UserPublic, error)
+	Register(ctx context.Context, user *entity.User) (*entity.UserProfile, error)
 	GetUser(ctx context.Context, ID int64) (*entity.UserPublic, error)
+	GetProfile(ctx context.Context, ID int64) (*entity.UserProfile, error)
+	UpdateProfile(ctx context.Context, userID int64, form *entity.UserUpdateForm) (*entity.UserProfile, error)
+}
+
+// VerificationUsecase is a contract for usecases related to contact verification
+type VerificationUsecase interface {
+	RequestEmailVerification(ctx context.Context, userID int64) (*entity.VerificationRequestResponse, error)
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPhoneVerification(ctx context.Context, userID int64) (*entity.VerificationRequestResponse, error)
+	VerifyPhone(ctx context.Context, userID int64, form *entity.PhoneVerificationForm) error
+}
+
+// Notifier is a contract for structs pushing notifications to the devices of users
+type Notifier interface {
+	PublishNotification(ctx context.Context, notification *entity.NotificationRequest) error
+}
+
+// MessagePublisher is a contract for structs publishing raw messages to pubsub topics
+type MessagePublisher interface {
+	Publish(ctx context.Context, topic string, data []byte) error
+}
+
+// EventPublisher is a contract for structs publishing domain events to their consumers
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, event entity.DomainEvent) error
+}
+
+// Mailer is a contract for structs delivering transactional emails
+type Mailer interface {
+	SendEmail(ctx context.Context, email *entity.Email) error
+}
+
+// OTPSender is a contract for structs delivering verification codes to users
+type OTPSender interface {
+	SendEmailVerification(ctx context.Context, email, link string) error
+	SendPhoneOTP(ctx context.Context, phone, code string) error
+}
+
+// IDTokenVerifier is a contract for structs verifying third-party OIDC ID tokens
+type IDTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, rawToken string) (*entity.OIDCIdentity, error)
 }
 
 // AuthUsecase is a contract for usecase related to authentication
 type AuthUsecase interface {
 	AuthenticateUser(ctx context.Context, auth *entity.AuthCredentials) (*entity.AuthResponse, error)
+	AuthenticateOIDC(ctx context.Context, form *entity.OIDCAuthForm) (*entity.AuthResponse, error)
+	GetLoginHistory(ctx context.Context, userID int64, limit, offset int) ([]entity.UserLoginPublic, int64, error)
+	GetJWKS(ctx context.Context) entity.JWKS
 }
 
 // BankUsecase is a contract for usecase related to bank data
@@ -108,11 +340,67 @@ type CountryUsecase interface {
 // ProductUsecase is a contract for structs implementing product usecase
 type ProductUsecase interface {
 	CreateProduct(ctx context.Context, product *entity.Product) (*entity.ProductPublic, error)
-	GetProductsByFilter(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.ProductPublic, int64, error)
+	GetProductsByFilter(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.ProductPublic, int64, error)
 	GetProduct(ctx context.Context, ID int64) (*entity.ProductPublic, error)
 	UpdateProduct(ctx context.Context, productID, userID int64, newProduct *entity.Product) (*entity.ProductPublic, error)
+	PatchProduct(ctx context.Context, productID, userID int64, form *entity.ProductUpdateForm) (*entity.ProductPublic, error)
 	DeleteProduct(ctx context.Context, productID, userID int64) error
-	UploadProductImage(ctx context.Context, filename string, content []byte) (string, error)
+	UploadProductImage(ctx context.Context, filename string, content []byte) (*entity.ProductImage, error)
+	AddProductImage(ctx context.Context, productID, userID int64, form *entity.ProductImageForm) ([]entity.ProductImagePublic, error)
+	RemoveProductImage(ctx context.Context, productID, imageID, userID int64) ([]entity.ProductImagePublic, error)
+	ReorderProductImages(ctx context.Context, productID, userID int64, form *entity.ProductImageOrderForm) ([]entity.ProductImagePublic, error)
+	AddProductVariant(ctx context.Context, productID, userID int64, form *entity.ProductVariantForm) (*entity.ProductPublic, error)
+	UpdateProductVariant(ctx context.Context, productID, variantID, userID int64, form *entity.ProductVariantForm) (*entity.ProductPublic, error)
+	RemoveProductVariant(ctx context.Context, productID, variantID, userID int64) (*entity.ProductPublic, error)
+	RebuildSearchIndex(ctx context.Context) (int, error)
+	CloseExpiredProducts(ctx context.Context) (int64, error)
+}
+
+// CategoryUsecase is a contract for structs implementing product category usecase
+type CategoryUsecase interface {
+	CreateCategory(ctx context.Context, form *entity.CategoryForm) (*entity.Category, error)
+	GetCategories(ctx context.Context, countryID int64) ([]entity.CategoryPublic, error)
+	UpdateCategory(ctx context.Context, ID int64, form *entity.CategoryForm) (*entity.Category, error)
+	DeleteCategory(ctx context.Context, ID int64) error
+}
+
+// TripUsecase is a contract for structs implementing seller trip usecase
+type TripUsecase interface {
+	CreateTrip(ctx context.Context, userID int64, form *entity.TripForm) (*entity.TripPublic, error)
+	GetTrips(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.TripPublic, int64, error)
+	GetTrip(ctx context.Context, ID int64) (*entity.TripPublic, error)
+	UpdateTrip(ctx context.Context, ID, userID int64, form *entity.TripForm) (*entity.TripPublic, error)
+	DeleteTrip(ctx context.Context, ID, userID int64) error
+}
+
+// ReviewUsecase is a contract for structs implementing transaction review usecase
+type ReviewUsecase interface {
+	CreateReview(ctx context.Context, transactionID, userID int64, form *entity.ReviewForm) (*entity.ReviewPublic, error)
+	ReplyReview(ctx context.Context, reviewID, userID int64, form *entity.ReviewReplyForm) (*entity.ReviewPublic, error)
+	GetUserReviews(ctx context.Context, userID int64, page entity.Page) ([]entity.ReviewPublic, int64, error)
+}
+
+// MessageUsecase is a contract for structs implementing buyer and seller chat usecase
+type MessageUsecase interface {
+	StartConversation(ctx context.Context, userID int64, form *entity.ConversationForm) (*entity.ConversationPublic, error)
+	GetConversations(ctx context.Context, userID int64, page entity.Page) ([]entity.ConversationPublic, int64, error)
+	GetConversation(ctx context.Context, ID, userID int64) (*entity.ConversationPublic, error)
+	SendMessage(ctx context.Context, conversationID, userID int64, form *entity.MessageForm) (*entity.MessagePublic, error)
+	GetMessages(ctx context.Context, conversationID, userID int64, page entity.Page) ([]entity.MessagePublic, int64, error)
+	WaitMessages(ctx context.Context, conversationID, userID, afterID int64, timeout time.Duration) ([]entity.MessagePublic, error)
+	MarkRead(ctx context.Context, conversationID, userID int64, form *entity.ReadReceiptForm) error
+}
+
+// BuyRequestUsecase is a contract for structs implementing buy request usecase
+type BuyRequestUsecase interface {
+	CreateBuyRequest(ctx context.Context, userID int64, form *entity.BuyRequestForm) (*entity.BuyRequestPublic, error)
+	GetBuyRequests(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.BuyRequestPublic, int64, error)
+	GetBuyRequest(ctx context.Context, ID int64) (*entity.BuyRequestPublic, error)
+	CancelBuyRequest(ctx context.Context, ID, userID int64) error
+	CreateOffer(ctx context.Context, buyRequestID, userID int64, form *entity.BuyRequestOfferForm) (*entity.BuyRequestOfferPublic, error)
+	GetOffers(ctx context.Context, buyRequestID, userID int64) ([]entity.BuyRequestOfferPublic, error)
+	WithdrawOffer(ctx context.Context, buyRequestID, offerID, userID int64) error
+	AcceptOffer(ctx context.Context, buyRequestID, offerID, userID int64, form *entity.AcceptOfferForm) (*entity.TransactionPublic, error)
 }
 
 // UserAddressUsecase is a contract for structs implementing user address usecase
@@ -125,12 +413,18 @@ type UserAddressUsecase interface {
 
 // TransactionUsecase is a contract for structs implementing transactions usecase
 type TransactionUsecase interface {
-	GetTransactions(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]*entity.TransactionPublic, int64, error)
+	GetTransactions(ctx context.Context, userID int64, spec *entity.FilterSpec, page entity.Page) ([]*entity.TransactionPublic, int64, error)
 	GetTransaction(ctx context.Context, transactionID int64) (*entity.TransactionPublic, error)
 	CreateTransaction(ctx context.Context, transactionForm *entity.TransactionForm, userID int64) (*entity.TransactionPublic, error)
 	UpdateTransaction(ctx context.Context, transactionID int64, form *entity.UpdateTransactionForm) error
 }
 
+// AccountUsecase is a contract for usecases related to a user's personal data
+type AccountUsecase interface {
+	ExportAccount(ctx context.Context, userID int64) (*entity.AccountExport, error)
+	DeleteAccount(ctx context.Context, userID int64) error
+}
+
 type InvoiceUsecase interface {
 	InsertInvoice(ctx context.Context, form *entity.InvoiceCreateForm) (*entity.InvoicePublic, error)
 	GetInvoice(ctx context.Context, invoiceID int64) (*entity.InvoicePublic, error)
@@ -139,4 +433,24 @@ type InvoiceUsecase interface {
 
 type DeviceUsecase interface {
 	UpsertDevice(ctx context.Context, device *entity.Device) error
+	RemoveDevice(ctx context.Context, deviceID string) error
+	PruneDevices(ctx context.Context, deviceIDs []string) (int64, error)
+}
+
+// NotificationUsecase is a contract for usecases related to the notification inbox
+type NotificationUsecase interface {
+	GetNotifications(ctx context.Context, userID int64, page entity.Page) ([]entity.NotificationPublic, int64, int64, error)
+	MarkRead(ctx context.Context, ID, userID int64) error
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+	GetTemplates(ctx context.Context) ([]entity.NotificationTemplatePublic, error)
+	UpdateTemplate(ctx context.Context, event, locale string, form *entity.NotificationTemplateForm) (*entity.NotificationTemplatePublic, error)
+	ResetTemplate(ctx context.Context, event, locale string) (*entity.NotificationTemplatePublic, error)
+}
+
+// OutboxUsecase is a contract for usecases related to the pubsub outbox
+type OutboxUsecase interface {
+	Enqueue(ctx context.Context, topic string, payload interface{}) error
+	RelayMessages(ctx context.Context) (int64, error)
+	GetMessages(ctx context.Context, status string, page entity.Page) ([]entity.OutboxMessagePublic, int64, error)
+	ReplayMessage(ctx context.Context, ID int64) (*entity.OutboxMessagePublic, error)
 }
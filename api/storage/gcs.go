@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"cloud.google.com/go/storage"
 	"github.com/pkg/errors"
@@ -65,6 +66,21 @@ func (s GCS) Get(filename string) ([]byte, error) {
 	return contents, nil
 }
 
+// PathOf returns the name of a file of the bucket from its public URL
+func (s GCS) PathOf(url string) string {
+	prefix := fmt.Sprintf("https://storage.googleapis.com/%s/", s.BucketID)
+	if !strings.HasPrefix(url, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(url, prefix)
+}
+
+// Delete removes a file from the bucket. Deleting a missing file is not an error
 func (s GCS) Delete(filename string) error {
+	ctx := context.Background()
+	err := s.client.Object(filename).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return errors.Wrap(err, "error in deleting file from GCS")
+	}
 	return nil
 }
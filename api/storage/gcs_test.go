@@ -0,0 +1,16 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sejastip.id/api/storage"
+)
+
+func TestGCSPathOf(t *testing.T) {
+	s := storage.GCS{BucketID: "sejastip"}
+
+	assert.Equal(t, "products/abc.jpg", s.PathOf("https://storage.googleapis.com/sejastip/products/abc.jpg"))
+	assert.Equal(t, "", s.PathOf("https://storage.googleapis.com/another-bucket/products/abc.jpg"))
+	assert.Equal(t, "", s.PathOf("https://example.com/products/abc.jpg"))
+}
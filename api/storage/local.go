@@ -3,7 +3,9 @@ package storage
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
 type LocalStorage struct{}
@@ -27,6 +29,21 @@ func (s LocalStorage) Get(filename string) ([]byte, error) {
 	return ioutil.ReadFile(filepath)
 }
 
+// PathOf returns the name of a stored file from the absolute path returned by Store
+func (s LocalStorage) PathOf(url string) string {
+	dir, err := filepath.Abs("./public")
+	if err != nil || !strings.HasPrefix(url, dir+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(url, dir+"/")
+}
+
+// Delete removes a stored file. Deleting a missing file is not an error
 func (s LocalStorage) Delete(filename string) error {
+	filepath := fmt.Sprintf("./public/%s", filename)
+	err := os.Remove(filepath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
@@ -5,4 +5,7 @@ type Storage interface {
 	Store(string, []byte) (string, error)
 	Get(string) ([]byte, error)
 	Delete(string) error
+	// PathOf returns the name of a stored file from its URL, or an empty string
+	// when the URL is not one of the files of the storage
+	PathOf(string) string
 }
@@ -0,0 +1,419 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+// the page size used when collecting all data of a user
+const accountExportPageSize = 100
+
+// AccountProvider is a wrapper of dependencies used by the implementation of AccountUsecase
+type AccountProvider struct {
+	UserRepo         api.UserRepository
+	AddressRepo      api.UserAddressRepository
+	ProductRepo      api.ProductRepository
+	CountryRepo      api.CountryRepository
+	TransactionRepo  api.TransactionRepository
+	InvoiceRepo      api.InvoiceRepository
+	LoginHistoryRepo api.LoginHistoryRepository
+	IdentityRepo     api.UserIdentityRepository
+	TripRepo         api.TripRepository
+	BuyRequestRepo   api.BuyRequestRepository
+	OfferRepo        api.BuyRequestOfferRepository
+	ReviewRepo       api.ReviewRepository
+	ConversationRepo api.ConversationRepository
+	MessageRepo      api.MessageRepository
+	NotificationRepo api.NotificationRepository
+	DeviceRepo       api.DeviceRepository
+	SearchIndex      api.ProductSearchIndex
+	Transactor       api.Transactor
+}
+
+type accountUsecase struct {
+	*AccountProvider
+}
+
+// NewAccountUsecase creates an instance of AccountUsecase
+func NewAccountUsecase(pvd *AccountProvider) api.AccountUsecase {
+	return &accountUsecase{pvd}
+}
+
+// ExportAccount collects every data we store about a user
+func (uc *accountUsecase) ExportAccount(ctx context.Context, userID int64) (*entity.AccountExport, error) {
+	user, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching user")
+	}
+	profile := user.ConvertToPublic()
+
+	export := &entity.AccountExport{
+		Profile:  profile,
+		Language: user.Language,
+		EmailPreferences: entity.EmailPreferences{
+			Order:   !user.OrderEmailOptOut,
+			Payment: !user.PaymentEmailOptOut,
+		},
+		Addresses:       []entity.UserAddressPublic{},
+		Products:        []entity.ProductPublic{},
+		Trips:           []entity.TripPublic{},
+		BuyRequests:     []entity.BuyRequestPublic{},
+		Offers:          []entity.BuyRequestOfferPublic{},
+		Transactions:    []entity.TransactionExport{},
+		Invoices:        []entity.InvoicePublic{},
+		Reviews:         []entity.ReviewPublic{},
+		ReceivedReviews: []entity.ReviewPublic{},
+		Conversations:   []entity.ConversationExport{},
+		Notifications:   []entity.NotificationPublic{},
+		Devices:         []entity.DevicePublic{},
+		Logins:          []entity.UserLoginPublic{},
+		ExportedAt:      time.Now(),
+	}
+
+	for offset := 0; ; offset += accountExportPageSize {
+		addresses, _, err := uc.AddressRepo.GetUserAddresses(ctx, userID, accountExportPageSize, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching addresses")
+		}
+		for _, address := range addresses {
+			export.Addresses = append(export.Addresses, address.ConvertToPublic())
+		}
+		if len(addresses) < accountExportPageSize {
+			break
+		}
+	}
+
+	countries := map[int64]*entity.Country{}
+	getCountry := func(ID int64) (*entity.Country, error) {
+		country, ok := countries[ID]
+		if !ok {
+			country, err = uc.CountryRepo.GetCountry(ctx, ID)
+			if err != nil && err != api.ErrNotFound {
+				return nil, err
+			}
+			countries[ID] = country
+		}
+		return country, nil
+	}
+
+	for offset := 0; ; offset += accountExportPageSize {
+		products, _, err := uc.ProductRepo.GetProductsByUser(ctx, userID, accountExportPageSize, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching products")
+		}
+		for _, product := range products {
+			country, err := getCountry(product.CountryID)
+			if err != nil {
+				return nil, errors.Wrap(err, "error fetching product country")
+			}
+
+			productPublic := product.ConvertToPublic(country, user)
+			// the seller is the exporting user, which is already in the profile
+			productPublic.Seller = nil
+			export.Products = append(export.Products, productPublic)
+		}
+		if len(products) < accountExportPageSize {
+			break
+		}
+	}
+
+	// the user is the seller, buyer or sender of everything below, which is
+	// already in the profile
+	spec := entity.NewFilterSpec().Where("seller_id", entity.FilterOperatorEq, userID)
+	spec.Sort = []entity.SortOrder{{Column: "id"}}
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		trips, _, err := uc.TripRepo.GetTrips(ctx, spec, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching trips")
+		}
+		for _, trip := range trips {
+			country, err := getCountry(trip.CountryID)
+			if err != nil {
+				return nil, errors.Wrap(err, "error fetching trip country")
+			}
+			tripPublic := trip.ConvertToPublic(country, user)
+			tripPublic.Seller = nil
+			export.Trips = append(export.Trips, tripPublic)
+		}
+		if len(trips) < accountExportPageSize {
+			break
+		}
+	}
+
+	spec = entity.NewFilterSpec().Where("buyer_id", entity.FilterOperatorEq, userID)
+	spec.Sort = []entity.SortOrder{{Column: "id"}}
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		requests, _, err := uc.BuyRequestRepo.GetBuyRequests(ctx, spec, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching buy requests")
+		}
+		for _, request := range requests {
+			country, err := getCountry(request.CountryID)
+			if err != nil {
+				return nil, errors.Wrap(err, "error fetching buy request country")
+			}
+			requestPublic := request.ConvertToPublic(country, user, 0)
+			requestPublic.Buyer = nil
+			export.BuyRequests = append(export.BuyRequests, requestPublic)
+		}
+		if len(requests) < accountExportPageSize {
+			break
+		}
+	}
+
+	offers, err := uc.OfferRepo.GetSellerOffers(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching offers")
+	}
+	for _, offer := range offers {
+		offerPublic := offer.ConvertToPublic(user)
+		offerPublic.Seller = nil
+		export.Offers = append(export.Offers, offerPublic)
+	}
+
+	spec = &entity.FilterSpec{Sort: []entity.SortOrder{{Column: "id"}}}
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		transactions, _, err := uc.TransactionRepo.GetTransactions(ctx, userID, spec, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching transactions")
+		}
+		for _, transaction := range transactions {
+			export.Transactions = append(export.Transactions, transaction.ConvertToExport(userID))
+			if transaction.InvoiceID == nil {
+				continue
+			}
+
+			invoice, err := uc.InvoiceRepo.GetInvoiceFromTransaction(ctx, transaction.ID)
+			if err != nil && err != api.ErrNotFound {
+				return nil, errors.Wrap(err, "error fetching invoice")
+			}
+			if invoice != nil {
+				export.Invoices = append(export.Invoices, invoice.ConvertToPublic())
+			}
+		}
+		if len(transactions) < accountExportPageSize {
+			break
+		}
+	}
+
+	reviews, err := uc.ReviewRepo.GetUserReviews(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching reviews")
+	}
+	for _, review := range reviews {
+		// the buyer of a received review is someone else
+		reviewPublic := review.ConvertToPublic(user)
+		reviewPublic.Buyer = nil
+		if review.BuyerID == userID {
+			export.Reviews = append(export.Reviews, reviewPublic)
+		} else {
+			export.ReceivedReviews = append(export.ReceivedReviews, reviewPublic)
+		}
+	}
+
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		conversations, _, err := uc.ConversationRepo.GetUserConversations(ctx, userID, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching conversations")
+		}
+		for _, conversation := range conversations {
+			conversationExport := entity.ConversationExport{
+				ConversationPublic: conversation.ConvertToPublic(user, user, 0),
+				Messages:           []entity.MessagePublic{},
+			}
+			conversationExport.Buyer = nil
+			conversationExport.Seller = nil
+
+			for afterID := int64(0); ; {
+				messages, err := uc.MessageRepo.GetMessagesAfter(ctx, conversation.ID, afterID, accountExportPageSize)
+				if err != nil {
+					return nil, errors.Wrap(err, "error fetching messages")
+				}
+				for _, message := range messages {
+					conversationExport.Messages = append(conversationExport.Messages, message.ConvertToPublic(&conversation))
+					afterID = message.ID
+				}
+				if len(messages) < accountExportPageSize {
+					break
+				}
+			}
+			export.Conversations = append(export.Conversations, conversationExport)
+		}
+		if len(conversations) < accountExportPageSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		notifications, _, err := uc.NotificationRepo.GetUserNotifications(ctx, userID, page)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching notifications")
+		}
+		for _, notification := range notifications {
+			export.Notifications = append(export.Notifications, notification.ConvertToPublic())
+		}
+		if len(notifications) < accountExportPageSize {
+			break
+		}
+	}
+
+	devices, err := uc.DeviceRepo.GetUserDevices(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching devices")
+	}
+	for _, device := range devices {
+		export.Devices = append(export.Devices, device.ConvertToPublic())
+	}
+
+	for offset := 0; ; offset += accountExportPageSize {
+		logins, _, err := uc.LoginHistoryRepo.GetUserLogins(ctx, userID, accountExportPageSize, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching login history")
+		}
+		for _, login := range logins {
+			export.Logins = append(export.Logins, login.ConvertToPublic())
+		}
+		if len(logins) < accountExportPageSize {
+			break
+		}
+	}
+
+	return export, nil
+}
+
+// DeleteAccount removes the personal data of a user. Transactions and invoices are
+// kept for bookkeeping, so the user row is anonymized instead of deleted
+func (uc *accountUsecase) DeleteAccount(ctx context.Context, userID int64) error {
+	user, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "error fetching user")
+	}
+
+	count, err := uc.TransactionRepo.CountUserTransactionsByStatus(ctx, userID, entity.OpenTransactionStatuses)
+	if err != nil {
+		return errors.Wrap(err, "error counting open transactions")
+	}
+	if count > 0 {
+		return api.ErrAccountHasOpenTransactions
+	}
+
+	// replace the password with a random one nobody knows
+	password, err := generateVerificationToken()
+	if err != nil {
+		return errors.Wrap(err, "error generating password")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 6)
+	if err != nil {
+		return errors.Wrap(err, "error encrypting password")
+	}
+
+	productIDs := []int64{}
+	for offset := 0; ; offset += accountExportPageSize {
+		products, _, err := uc.ProductRepo.GetProductsByUser(ctx, userID, accountExportPageSize, offset)
+		if err != nil {
+			return errors.Wrap(err, "error fetching products")
+		}
+		for _, product := range products {
+			productIDs = append(productIDs, product.ID)
+		}
+		if len(products) < accountExportPageSize {
+			break
+		}
+	}
+
+	spec := entity.NewFilterSpec().
+		Where("buyer_id", entity.FilterOperatorEq, userID).
+		Where("status", entity.FilterOperatorEq, entity.BuyRequestStatusOpen)
+	openRequestIDs := []int64{}
+	for offset := 0; ; offset += accountExportPageSize {
+		page := entity.Page{Limit: accountExportPageSize, Offset: offset, SkipCount: true}
+		requests, _, err := uc.BuyRequestRepo.GetBuyRequests(ctx, spec, page)
+		if err != nil {
+			return errors.Wrap(err, "error fetching buy requests")
+		}
+		for _, request := range requests {
+			openRequestIDs = append(openRequestIDs, request.ID)
+		}
+		if len(requests) < accountExportPageSize {
+			break
+		}
+	}
+
+	// either the whole account is removed, or nothing is
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		// delete the listings first, so nobody can buy from a deleted account
+		for _, productID := range productIDs {
+			if err := uc.ProductRepo.DeleteProduct(ctx, productID); err != nil {
+				return errors.Wrap(err, "error deleting product")
+			}
+		}
+		if err := uc.TripRepo.DeleteSellerTrips(ctx, userID); err != nil {
+			return errors.Wrap(err, "error deleting trips")
+		}
+
+		for _, requestID := range openRequestIDs {
+			if err := uc.OfferRepo.RejectPendingOffers(ctx, requestID); err != nil {
+				return errors.Wrap(err, "error rejecting offers")
+			}
+		}
+		if err := uc.BuyRequestRepo.AnonymizeUserBuyRequests(ctx, userID); err != nil {
+			return errors.Wrap(err, "error anonymizing buy requests")
+		}
+		if err := uc.OfferRepo.WithdrawSellerOffers(ctx, userID); err != nil {
+			return errors.Wrap(err, "error withdrawing offers")
+		}
+
+		if err := uc.ReviewRepo.AnonymizeUserReviews(ctx, userID); err != nil {
+			return errors.Wrap(err, "error anonymizing reviews")
+		}
+		if err := uc.MessageRepo.AnonymizeUserMessages(ctx, userID); err != nil {
+			return errors.Wrap(err, "error anonymizing messages")
+		}
+		if err := uc.NotificationRepo.DeleteUserNotifications(ctx, userID); err != nil {
+			return errors.Wrap(err, "error deleting notifications")
+		}
+		if err := uc.DeviceRepo.RemoveUserDevices(ctx, userID); err != nil {
+			return errors.Wrap(err, "error removing devices")
+		}
+
+		if err := uc.AddressRepo.AnonymizeUserAddresses(ctx, userID); err != nil {
+			return errors.Wrap(err, "error anonymizing addresses")
+		}
+		if err := uc.IdentityRepo.DeleteUserIdentities(ctx, userID); err != nil {
+			return errors.Wrap(err, "error unlinking identities")
+		}
+		if err := uc.LoginHistoryRepo.DeleteUserLogins(ctx, userID); err != nil {
+			return errors.Wrap(err, "error deleting login history")
+		}
+
+		// the email preferences and language are reset along with the profile
+		user.Anonymize(string(hashedPassword))
+		if err := uc.UserRepo.AnonymizeUser(ctx, userID, user); err != nil {
+			return errors.Wrap(err, "error anonymizing user")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the search index is not part of the transaction, it is cleaned up once the
+	// listings are gone
+	for _, productID := range productIDs {
+		if err := uc.SearchIndex.RemoveProduct(ctx, productID); err != nil {
+			return errors.Wrap(err, "error removing product from search index")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+type txContextKey struct{}
+
+// memoryTransactor keeps the writes made inside a transaction, and drops them
+// when the transaction is rolled back. A transaction already running is joined
+type memoryTransactor struct {
+	writes    []string
+	committed []string
+}
+
+func (t *memoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txContextKey{}) != nil {
+		return fn(ctx)
+	}
+
+	t.writes = nil
+	if err := fn(context.WithValue(ctx, txContextKey{}, true)); err != nil {
+		t.writes = nil
+		return err
+	}
+	t.committed = append(t.committed, t.writes...)
+	return nil
+}
+
+// write records a write, which must be made inside the transaction
+func (t *memoryTransactor) write(ctx context.Context, name string) error {
+	if ctx.Value(txContextKey{}) == nil {
+		return errors.New(name + " outside of the transaction")
+	}
+	t.writes = append(t.writes, name)
+	return nil
+}
+
+type accountProductRepo struct {
+	api.ProductRepository
+	tx       *memoryTransactor
+	products []entity.Product
+}
+
+func (r *accountProductRepo) GetProductsByUser(ctx context.Context, userID int64, limit, offset int) ([]entity.Product, int64, error) {
+	if offset >= len(r.products) {
+		return []entity.Product{}, 0, nil
+	}
+	return r.products[offset:], 0, nil
+}
+
+func (r *accountProductRepo) DeleteProduct(ctx context.Context, ID int64) error {
+	return r.tx.write(ctx, "products")
+}
+
+type accountTripRepo struct {
+	api.TripRepository
+	tx *memoryTransactor
+}
+
+func (r *accountTripRepo) GetTrips(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.Trip, int64, error) {
+	return []entity.Trip{{ID: 1, SellerID: 4, CountryID: 1}}, 0, nil
+}
+
+func (r *accountTripRepo) DeleteSellerTrips(ctx context.Context, sellerID int64) error {
+	return r.tx.write(ctx, "trips")
+}
+
+type accountBuyRequestRepo struct {
+	api.BuyRequestRepository
+	tx *memoryTransactor
+}
+
+func (r *accountBuyRequestRepo) GetBuyRequests(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.BuyRequest, int64, error) {
+	return []entity.BuyRequest{{ID: 7, BuyerID: 4, CountryID: 1, Status: entity.BuyRequestStatusOpen}}, 0, nil
+}
+
+func (r *accountBuyRequestRepo) AnonymizeUserBuyRequests(ctx context.Context, buyerID int64) error {
+	return r.tx.write(ctx, "buy_requests")
+}
+
+type accountOfferRepo struct {
+	api.BuyRequestOfferRepository
+	tx *memoryTransactor
+}
+
+func (r *accountOfferRepo) GetSellerOffers(ctx context.Context, sellerID int64) ([]entity.BuyRequestOffer, error) {
+	return []entity.BuyRequestOffer{{ID: 3, BuyRequestID: 9, SellerID: sellerID, Notes: "Bisa titip"}}, nil
+}
+
+func (r *accountOfferRepo) RejectPendingOffers(ctx context.Context, buyRequestID int64) error {
+	return r.tx.write(ctx, "offers on buy requests")
+}
+
+func (r *accountOfferRepo) WithdrawSellerOffers(ctx context.Context, sellerID int64) error {
+	return r.tx.write(ctx, "buy_request_offers")
+}
+
+type accountReviewRepo struct {
+	api.ReviewRepository
+	tx *memoryTransactor
+}
+
+func (r *accountReviewRepo) GetUserReviews(ctx context.Context, userID int64) ([]entity.Review, error) {
+	return []entity.Review{
+		{ID: 1, BuyerID: userID, SellerID: 5, Rating: 5, Text: "Mantap"},
+		{ID: 2, BuyerID: 6, SellerID: userID, Rating: 4, Reply: "Terima kasih"},
+	}, nil
+}
+
+func (r *accountReviewRepo) AnonymizeUserReviews(ctx context.Context, userID int64) error {
+	return r.tx.write(ctx, "reviews")
+}
+
+type accountConversationRepo struct {
+	api.ConversationRepository
+}
+
+func (r *accountConversationRepo) GetUserConversations(ctx context.Context, userID int64, page entity.Page) ([]entity.Conversation, int64, error) {
+	return []entity.Conversation{{ID: 8, BuyerID: userID, SellerID: 5}}, 0, nil
+}
+
+type accountMessageRepo struct {
+	api.MessageRepository
+	tx *memoryTransactor
+}
+
+func (r *accountMessageRepo) GetMessagesAfter(ctx context.Context, conversationID, afterID int64, limit int) ([]entity.Message, error) {
+	if afterID > 0 {
+		return []entity.Message{}, nil
+	}
+	return []entity.Message{
+		{ID: 1, ConversationID: conversationID, SenderID: 4, Body: "Masih ada?"},
+		{ID: 2, ConversationID: conversationID, SenderID: 5, Body: "Masih"},
+	}, nil
+}
+
+func (r *accountMessageRepo) AnonymizeUserMessages(ctx context.Context, senderID int64) error {
+	return r.tx.write(ctx, "messages")
+}
+
+type accountNotificationRepo struct {
+	api.NotificationRepository
+	tx *memoryTransactor
+}
+
+func (r *accountNotificationRepo) GetUserNotifications(ctx context.Context, userID int64, page entity.Page) ([]entity.Notification, int64, error) {
+	return []entity.Notification{{ID: 1, UserID: userID, Type: entity.NotificationTypeAccount}}, 0, nil
+}
+
+func (r *accountNotificationRepo) DeleteUserNotifications(ctx context.Context, userID int64) error {
+	return r.tx.write(ctx, "notifications")
+}
+
+type accountDeviceRepo struct {
+	fakeDeviceRepo
+	tx  *memoryTransactor
+	err error
+}
+
+func (r *accountDeviceRepo) RemoveUserDevices(ctx context.Context, userID int64) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.tx.write(ctx, "user_devices")
+}
+
+type accountAddressRepo struct {
+	api.UserAddressRepository
+	tx *memoryTransactor
+}
+
+func (r *accountAddressRepo) GetUserAddresses(ctx context.Context, userID int64, limit, offset int) ([]entity.UserAddress, int64, error) {
+	return []entity.UserAddress{}, 0, nil
+}
+
+func (r *accountAddressRepo) AnonymizeUserAddresses(ctx context.Context, userID int64) error {
+	return r.tx.write(ctx, "user_addresses")
+}
+
+type accountIdentityRepo struct {
+	api.UserIdentityRepository
+	tx *memoryTransactor
+}
+
+func (r *accountIdentityRepo) DeleteUserIdentities(ctx context.Context, userID int64) error {
+	return r.tx.write(ctx, "user_identities")
+}
+
+type accountLoginHistoryRepo struct {
+	api.LoginHistoryRepository
+	tx *memoryTransactor
+}
+
+func (r *accountLoginHistoryRepo) GetUserLogins(ctx context.Context, userID int64, limit, offset int) ([]entity.UserLogin, int64, error) {
+	return []entity.UserLogin{}, 0, nil
+}
+
+func (r *accountLoginHistoryRepo) DeleteUserLogins(ctx context.Context, userID int64) error {
+	return r.tx.write(ctx, "user_logins")
+}
+
+type accountUserRepo struct {
+	*memoryUserRepo
+	tx *memoryTransactor
+}
+
+func (r *accountUserRepo) AnonymizeUser(ctx context.Context, ID int64, user *entity.User) error {
+	if err := r.tx.write(ctx, "users"); err != nil {
+		return err
+	}
+	return r.memoryUserRepo.UpdateUser(ctx, ID, user)
+}
+
+type accountTransactionRepo struct {
+	api.TransactionRepository
+}
+
+func (r *accountTransactionRepo) CountUserTransactionsByStatus(ctx context.Context, userID int64, statuses []int) (int64, error) {
+	return 0, nil
+}
+
+func (r *accountTransactionRepo) GetTransactions(ctx context.Context, userID int64, spec *entity.FilterSpec, page entity.Page) ([]entity.Transaction, int64, error) {
+	return []entity.Transaction{}, 0, nil
+}
+
+type accountCountryRepo struct {
+	api.CountryRepository
+}
+
+func (r *accountCountryRepo) GetCountry(ctx context.Context, ID int64) (*entity.Country, error) {
+	return &entity.Country{ID: ID, Name: "Jepang"}, nil
+}
+
+type memorySearchIndex struct {
+	api.ProductSearchIndex
+	removed []int64
+}
+
+func (i *memorySearchIndex) RemoveProduct(ctx context.Context, productID int64) error {
+	i.removed = append(i.removed, productID)
+	return nil
+}
+
+type accountTestSuite struct {
+	suite.Suite
+	tx      *memoryTransactor
+	users   *memoryUserRepo
+	devices *accountDeviceRepo
+	index   *memorySearchIndex
+	uc      api.AccountUsecase
+}
+
+func (s *accountTestSuite) SetupTest() {
+	s.tx = &memoryTransactor{}
+	s.users = newMemoryUserRepo(entity.User{
+		ID:       4,
+		Email:    "rocky@sejastip.id",
+		Name:     "Rocky Balboa",
+		Password: hashPassword("adrian"),
+		Language: entity.LocaleEnglish,
+	})
+	s.devices = &accountDeviceRepo{tx: s.tx}
+	s.index = &memorySearchIndex{}
+	s.uc = usecase.NewAccountUsecase(&usecase.AccountProvider{
+		UserRepo:         &accountUserRepo{s.users, s.tx},
+		AddressRepo:      &accountAddressRepo{tx: s.tx},
+		ProductRepo:      &accountProductRepo{tx: s.tx, products: []entity.Product{{ID: 11, SellerID: 4, CountryID: 1}}},
+		CountryRepo:      &accountCountryRepo{},
+		TransactionRepo:  &accountTransactionRepo{},
+		LoginHistoryRepo: &accountLoginHistoryRepo{tx: s.tx},
+		IdentityRepo:     &accountIdentityRepo{tx: s.tx},
+		TripRepo:         &accountTripRepo{tx: s.tx},
+		BuyRequestRepo:   &accountBuyRequestRepo{tx: s.tx},
+		OfferRepo:        &accountOfferRepo{tx: s.tx},
+		ReviewRepo:       &accountReviewRepo{tx: s.tx},
+		ConversationRepo: &accountConversationRepo{},
+		MessageRepo:      &accountMessageRepo{tx: s.tx},
+		NotificationRepo: &accountNotificationRepo{tx: s.tx},
+		DeviceRepo:       s.devices,
+		SearchIndex:      s.index,
+		Transactor:       s.tx,
+	})
+}
+
+func (s *accountTestSuite) TestExportCoversEveryTable() {
+	export, err := s.uc.ExportAccount(context.Background(), 4)
+	s.Require().NoError(err)
+
+	s.Equal(entity.LocaleEnglish, export.Language)
+	s.Equal(entity.EmailPreferences{Order: true, Payment: true}, export.EmailPreferences)
+	s.Len(export.Products, 1)
+	s.Len(export.Trips, 1)
+	s.Len(export.BuyRequests, 1)
+	s.Require().Len(export.Offers, 1)
+	s.Equal("Bisa titip", export.Offers[0].Notes)
+	s.Require().Len(export.Reviews, 1)
+	s.Equal("Mantap", export.Reviews[0].Text)
+	s.Require().Len(export.ReceivedReviews, 1)
+	s.Equal("Terima kasih", export.ReceivedReviews[0].Reply)
+	s.Require().Len(export.Conversations, 1)
+	s.Len(export.Conversations[0].Messages, 2)
+	s.Len(export.Notifications, 1)
+	s.Len(export.Devices, 2)
+
+	// nobody else is in the export
+	s.Nil(export.ReceivedReviews[0].Buyer)
+	s.Nil(export.Conversations[0].Seller)
+}
+
+func (s *accountTestSuite) TestDeleteCoversEveryTableInOneTransaction() {
+	s.NoError(s.uc.DeleteAccount(context.Background(), 4))
+
+	s.ElementsMatch([]string{
+		"products", "trips", "offers on buy requests", "buy_requests", "buy_request_offers",
+		"reviews", "messages", "notifications", "user_devices",
+		"user_addresses", "user_identities", "user_logins", "users",
+	}, s.tx.committed)
+	s.Equal([]int64{11}, s.index.removed)
+
+	user := s.users.users[4]
+	s.NotNil(user.DeletedAt)
+	s.Equal(entity.DefaultLocale, user.Language)
+	s.True(user.OrderEmailOptOut)
+	s.True(user.PaymentEmailOptOut)
+}
+
+func (s *accountTestSuite) TestFailedDeleteIsRolledBack() {
+	s.devices.err = errors.New("connection reset")
+
+	s.Error(s.uc.DeleteAccount(context.Background(), 4))
+
+	s.Empty(s.tx.committed)
+	s.Empty(s.index.removed)
+	s.Nil(s.users.users[4].DeletedAt)
+}
+
+func TestAccount(t *testing.T) {
+	suite.Run(t, new(accountTestSuite))
+}
@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -10,12 +13,36 @@ import (
 	"github.com/pkg/errors"
 	"sejastip.id/api"
 	"sejastip.id/api/entity"
+	"sejastip.id/api/infra"
 )
 
+const (
+	// failed attempts allowed before a lockout, per account and per IP address
+	maxAccountLoginFailures = 5
+	maxIPLoginFailures      = 20
+
+	// the first lockout lasts baseLoginLockout, and doubles on every next failure
+	baseLoginLockout = time.Minute
+	maxLoginLockout  = 24 * time.Hour
+
+	// failed attempts older than this window are no longer counted
+	loginFailureWindow = time.Hour
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so the response
+// takes as long as for a wrong password and doesn't tell which emails are registered
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("sejastip"), 6)
+
 // AuthProvider is a wrapper of dependencies used by the implementation of AuthUsecase
 type AuthProvider struct {
-	UserRepository api.UserRepository
-	JWTPrivateKey  string
+	UserRepository   api.UserRepository
+	ThrottleRepo     api.LoginThrottleRepository
+	LoginHistoryRepo api.LoginHistoryRepository
+	IdentityRepo     api.UserIdentityRepository
+	IDTokenVerifier  api.IDTokenVerifier
+	Events           api.EventPublisher
+	Transactor       api.Transactor
+	Keys             *infra.KeySet
 }
 
 type authUsecase struct {
@@ -29,18 +56,155 @@ func NewAuthUsecase(pvd *AuthProvider) api.AuthUsecase {
 
 // AuthenticateUser handles user authentication based on the provided credentials
 func (u *authUsecase) AuthenticateUser(ctx context.Context, auth *entity.AuthCredentials) (*entity.AuthResponse, error) {
+	auth.Normalize()
+	accountKey := "account:" + strings.ToLower(auth.Email)
+	ipKey := "ip:" + auth.IPAddress
+
+	// reject right away if either the account or the IP address is locked
+	for _, key := range []string{accountKey, ipKey} {
+		locked, err := u.isLocked(ctx, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "Authentication failed")
+		}
+		if locked {
+			return nil, api.ErrLoginLocked
+		}
+	}
+
 	// get the user first
 	user, err := u.AuthProvider.UserRepository.GetUserByEmail(ctx, auth.Email)
-	if err != nil {
+	if err != nil && err != api.ErrNotFound {
 		return nil, errors.Wrap(err, "Authentication failed")
 	}
 
-	// Check for user password validity
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(auth.Password))
-	if err != nil {
+	// Check for user password validity. Unknown emails are treated as
+	// failed attempts too, so they can't be told apart from wrong passwords
+	hashedPassword := dummyPasswordHash
+	if user != nil {
+		hashedPassword = []byte(user.Password)
+	}
+	passwordErr := bcrypt.CompareHashAndPassword(hashedPassword, []byte(auth.Password))
+	if user == nil || passwordErr != nil {
+		u.recordFailure(ctx, accountKey, maxAccountLoginFailures)
+		u.recordFailure(ctx, ipKey, maxIPLoginFailures)
 		return nil, api.ErrInvalidCredentials
 	}
 
+	if err := u.AuthProvider.ThrottleRepo.ResetThrottle(ctx, accountKey); err != nil {
+		log.Printf("error resetting login throttle for user %d: %v", user.ID, err)
+	}
+
+	return u.issueToken(ctx, user, auth.IPAddress, auth.UserAgent)
+}
+
+// AuthenticateOIDC signs a user in with an ID token issued by a trusted third-party
+// provider. The identity is linked to the user having the same email once that
+// user has verified it, or to a newly created user if there is none
+func (u *authUsecase) AuthenticateOIDC(ctx context.Context, form *entity.OIDCAuthForm) (*entity.AuthResponse, error) {
+	if strings.TrimSpace(form.IDToken) == "" {
+		return nil, api.ErrInvalidIDToken
+	}
+
+	identity, err := u.AuthProvider.IDTokenVerifier.VerifyIDToken(ctx, form.IDToken)
+	if err != nil {
+		log.Printf("error verifying ID token: %v", err)
+		return nil, api.ErrInvalidIDToken
+	}
+
+	// the identity might already be linked to a user
+	linked, err := u.AuthProvider.IdentityRepo.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil && err != api.ErrNotFound {
+		return nil, errors.Wrap(err, "error fetching linked identity")
+	}
+	if linked != nil {
+		user, err := u.AuthProvider.UserRepository.GetUser(ctx, linked.UserID)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching linked user")
+		}
+		return u.issueToken(ctx, user, form.IPAddress, form.UserAgent)
+	}
+
+	// linking by email is only safe when the issuer has verified it
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, api.ErrUnverifiedIDTokenEmail
+	}
+
+	user, err := u.AuthProvider.UserRepository.GetUserByEmail(ctx, identity.Email)
+	if err != nil && err != api.ErrNotFound {
+		return nil, errors.Wrap(err, "error fetching user by email")
+	}
+	if user == nil {
+		user, err = u.createOIDCUser(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	} else if !user.IsEmailVerified() {
+		// anyone could have registered the email with a password of their own, so
+		// the account is only linked once its owner has proven the email
+		return nil, api.ErrUnverifiedAccountEmail
+	}
+
+	err = u.AuthProvider.IdentityRepo.CreateIdentity(ctx, &entity.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error linking identity")
+	}
+
+	return u.issueToken(ctx, user, form.IPAddress, form.UserAgent)
+}
+
+// createOIDCUser registers a user from a verified identity. The user has no
+// usable password, and still needs to complete its phone and bank details
+func (u *authUsecase) createOIDCUser(ctx context.Context, identity *entity.OIDCIdentity) (*entity.User, error) {
+	password, err := generateVerificationToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating password")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 6)
+	if err != nil {
+		return nil, errors.Wrap(err, "error encrypting password")
+	}
+
+	// keep only the characters allowed in a user name
+	name := strings.TrimSpace(regexp.MustCompile(`[^A-Za-z0-9\s]`).ReplaceAllString(identity.Name, ""))
+	if name == "" {
+		name = strings.Split(identity.Email, "@")[0]
+	}
+	if len(name) > 50 {
+		name = name[:50]
+	}
+
+	user := &entity.User{
+		Email:    identity.Email,
+		Name:     name,
+		Password: string(hashedPassword),
+		Language: entity.DefaultLocale,
+	}
+	err = withinTransaction(ctx, u.AuthProvider.Transactor, func(ctx context.Context) error {
+		if err := u.AuthProvider.UserRepository.CreateUser(ctx, user); err != nil {
+			return errors.Wrap(err, "error creating user")
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := u.AuthProvider.UserRepository.UpdateVerificationStatus(ctx, user.ID, user); err != nil {
+			return errors.Wrap(err, "error updating user verification status")
+		}
+		return publishEvent(ctx, u.AuthProvider.Events, entity.NewUserRegistered(user, entity.RegistrationMethodOIDC))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// issueToken creates a signed access token for the user, and records the login
+func (u *authUsecase) issueToken(ctx context.Context, user *entity.User, ipAddress, userAgent string) (*entity.AuthResponse, error) {
 	// After all credentials are valid, we create a claim to store all user data
 	createdAt := time.Now()
 	expirationTime := time.Now().Add(96 * time.Hour)
@@ -55,14 +219,26 @@ func (u *authUsecase) AuthenticateUser(ctx context.Context, auth *entity.AuthCre
 		},
 	}
 
-	// Then we create the token with HS256 algorithm
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// And sign it with our private key
-	tokenString, err := token.SignedString([]byte(u.AuthProvider.JWTPrivateKey))
+	// Then we sign the token with the currently active key
+	tokenString, err := u.AuthProvider.Keys.Sign(claims)
 	if err != nil {
 		return nil, err
 	}
 
+	// record the login, a failure here should not prevent the user from logging in
+	err = u.AuthProvider.UserRepository.UpdateLastLogin(ctx, user.ID, createdAt)
+	if err != nil {
+		log.Printf("error updating last login of user %d: %v", user.ID, err)
+	}
+	err = u.AuthProvider.LoginHistoryRepo.InsertLogin(ctx, &entity.UserLogin{
+		UserID:    user.ID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		log.Printf("error recording login history of user %d: %v", user.ID, err)
+	}
+
 	authResponse := &entity.AuthResponse{
 		Token:     tokenString,
 		CreatedAt: createdAt,
@@ -70,3 +246,58 @@ func (u *authUsecase) AuthenticateUser(ctx context.Context, auth *entity.AuthCre
 	}
 	return authResponse, nil
 }
+
+// GetLoginHistory returns the successful logins of a user, latest first
+func (u *authUsecase) GetLoginHistory(ctx context.Context, userID int64, limit, offset int) ([]entity.UserLoginPublic, int64, error) {
+	logins, count, err := u.AuthProvider.LoginHistoryRepo.GetUserLogins(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error fetching login history")
+	}
+
+	loginsPublic := []entity.UserLoginPublic{}
+	for _, login := range logins {
+		loginsPublic = append(loginsPublic, login.ConvertToPublic())
+	}
+
+	return loginsPublic, count, nil
+}
+
+// GetJWKS returns the public keys used to verify our access tokens
+func (u *authUsecase) GetJWKS(ctx context.Context) entity.JWKS {
+	return u.AuthProvider.Keys.JWKS()
+}
+
+func (u *authUsecase) isLocked(ctx context.Context, key string) (bool, error) {
+	throttle, err := u.AuthProvider.ThrottleRepo.GetThrottle(ctx, key)
+	if err != nil {
+		if err == api.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return throttle.IsLocked(), nil
+}
+
+// recordFailure increments the failed attempts of a key, and locks it out
+// with an exponentially growing duration once maxFailures is reached
+func (u *authUsecase) recordFailure(ctx context.Context, key string, maxFailures int) {
+	now := time.Now()
+	throttle, err := u.AuthProvider.ThrottleRepo.IncrementThrottle(ctx, key, now, now.Add(-loginFailureWindow))
+	if err != nil {
+		log.Printf("error saving login throttle %s: %v", key, err)
+		return
+	}
+	if throttle.FailedCount < maxFailures {
+		return
+	}
+
+	// the lockout follows the stored count, which includes concurrent failures
+	lockout := baseLoginLockout << uint(throttle.FailedCount-maxFailures)
+	if lockout > maxLoginLockout || lockout <= 0 {
+		lockout = maxLoginLockout
+	}
+	if err := u.AuthProvider.ThrottleRepo.LockThrottle(ctx, key, now.Add(lockout)); err != nil {
+		log.Printf("error locking login throttle %s: %v", key, err)
+	}
+}
@@ -0,0 +1,285 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/infra"
+	"sejastip.id/api/usecase"
+)
+
+type memoryThrottleRepo struct {
+	throttles map[string]entity.LoginThrottle
+}
+
+func (r *memoryThrottleRepo) GetThrottle(ctx context.Context, key string) (*entity.LoginThrottle, error) {
+	throttle, ok := r.throttles[key]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return &throttle, nil
+}
+
+func (r *memoryThrottleRepo) IncrementThrottle(ctx context.Context, key string, failedAt, windowStart time.Time) (*entity.LoginThrottle, error) {
+	throttle, ok := r.throttles[key]
+	if !ok || (!throttle.IsLocked() && throttle.LastFailedAt.Before(windowStart)) {
+		throttle = entity.LoginThrottle{ThrottleKey: key}
+	}
+	throttle.FailedCount++
+	throttle.LastFailedAt = failedAt
+	r.throttles[key] = throttle
+	return &throttle, nil
+}
+
+func (r *memoryThrottleRepo) LockThrottle(ctx context.Context, key string, lockedUntil time.Time) error {
+	throttle := r.throttles[key]
+	if throttle.LockedUntil == nil || throttle.LockedUntil.Before(lockedUntil) {
+		throttle.LockedUntil = &lockedUntil
+	}
+	r.throttles[key] = throttle
+	return nil
+}
+
+func (r *memoryThrottleRepo) ResetThrottle(ctx context.Context, key string) error {
+	delete(r.throttles, key)
+	return nil
+}
+
+type memoryLoginHistoryRepo struct {
+	api.LoginHistoryRepository
+	logins []entity.UserLogin
+}
+
+func (r *memoryLoginHistoryRepo) InsertLogin(ctx context.Context, login *entity.UserLogin) error {
+	r.logins = append(r.logins, *login)
+	return nil
+}
+
+// UpdateLastLogin is only called on a successful login
+func (r *memoryUserRepo) UpdateLastLogin(ctx context.Context, ID int64, lastLoginAt time.Time) error {
+	user := r.users[ID]
+	user.LastLoginAt = &lastLoginAt
+	r.users[ID] = user
+	return nil
+}
+
+type authThrottleTestSuite struct {
+	suite.Suite
+	throttles *memoryThrottleRepo
+	logins    *memoryLoginHistoryRepo
+	uc        api.AuthUsecase
+}
+
+func (s *authThrottleTestSuite) SetupTest() {
+	s.throttles = &memoryThrottleRepo{throttles: map[string]entity.LoginThrottle{}}
+	s.logins = &memoryLoginHistoryRepo{}
+	s.uc = usecase.NewAuthUsecase(&usecase.AuthProvider{
+		UserRepository: newMemoryUserRepo(entity.User{
+			ID:       4,
+			Email:    "rocky@sejastip.id",
+			Password: hashPassword("adrian"),
+		}),
+		ThrottleRepo:     s.throttles,
+		LoginHistoryRepo: s.logins,
+		Keys:             infra.NewKeySet("", "secret", time.Time{}),
+	})
+}
+
+func (s *authThrottleTestSuite) login(email, password, ipAddress string) error {
+	_, err := s.uc.AuthenticateUser(context.Background(), &entity.AuthCredentials{
+		Email:     email,
+		Password:  password,
+		IPAddress: ipAddress,
+	})
+	return err
+}
+
+func (s *authThrottleTestSuite) TestAccountIsLockedAfterFailedAttempts() {
+	for i := 0; i < 4; i++ {
+		s.Equal(api.ErrInvalidCredentials, s.login("rocky@sejastip.id", "apollo", "198.51.100.7"))
+	}
+	s.Nil(s.throttles.throttles["account:rocky@sejastip.id"].LockedUntil)
+
+	s.Equal(api.ErrInvalidCredentials, s.login("rocky@sejastip.id", "apollo", "198.51.100.7"))
+	throttle := s.throttles.throttles["account:rocky@sejastip.id"]
+	s.Require().NotNil(throttle.LockedUntil)
+	s.WithinDuration(time.Now().Add(time.Minute), *throttle.LockedUntil, time.Second)
+
+	// even the right password is rejected, from any address
+	s.Equal(api.ErrLoginLocked, s.login("rocky@sejastip.id", "adrian", "203.0.113.9"))
+	s.Empty(s.logins.logins)
+}
+
+func (s *authThrottleTestSuite) TestLockoutDoublesOnEveryNextFailure() {
+	lockedUntil := time.Now().Add(-time.Second)
+	s.throttles.throttles["account:rocky@sejastip.id"] = entity.LoginThrottle{
+		ThrottleKey:  "account:rocky@sejastip.id",
+		FailedCount:  6,
+		LockedUntil:  &lockedUntil,
+		LastFailedAt: time.Now().Add(-2 * time.Minute),
+	}
+
+	s.Equal(api.ErrInvalidCredentials, s.login("rocky@sejastip.id", "apollo", "198.51.100.7"))
+
+	throttle := s.throttles.throttles["account:rocky@sejastip.id"]
+	s.Equal(7, throttle.FailedCount)
+	s.WithinDuration(time.Now().Add(4*time.Minute), *throttle.LockedUntil, time.Second)
+}
+
+func (s *authThrottleTestSuite) TestOldFailuresAreForgotten() {
+	s.throttles.throttles["account:rocky@sejastip.id"] = entity.LoginThrottle{
+		ThrottleKey:  "account:rocky@sejastip.id",
+		FailedCount:  4,
+		LastFailedAt: time.Now().Add(-2 * time.Hour),
+	}
+
+	s.Equal(api.ErrInvalidCredentials, s.login("rocky@sejastip.id", "apollo", "198.51.100.7"))
+
+	throttle := s.throttles.throttles["account:rocky@sejastip.id"]
+	s.Equal(1, throttle.FailedCount)
+	s.Nil(throttle.LockedUntil)
+}
+
+func (s *authThrottleTestSuite) TestAddressIsLockedAcrossAccounts() {
+	for i := 0; i < 20; i++ {
+		email := fmt.Sprintf("guess%d@sejastip.id", i)
+		s.Equal(api.ErrInvalidCredentials, s.login(email, "apollo", "198.51.100.7"))
+	}
+
+	// unknown emails are counted like wrong passwords
+	s.Equal(1, s.throttles.throttles["account:guess0@sejastip.id"].FailedCount)
+	s.Equal(api.ErrLoginLocked, s.login("rocky@sejastip.id", "adrian", "198.51.100.7"))
+	s.NoError(s.login("rocky@sejastip.id", "adrian", "203.0.113.9"))
+}
+
+func (s *authThrottleTestSuite) TestSuccessfulLoginResetsTheAccount() {
+	s.Equal(api.ErrInvalidCredentials, s.login("rocky@sejastip.id", "apollo", "198.51.100.7"))
+
+	s.NoError(s.login("Rocky@sejastip.id", "adrian", "198.51.100.7"))
+
+	s.NotContains(s.throttles.throttles, "account:rocky@sejastip.id")
+	// failures from the address still count
+	s.Equal(1, s.throttles.throttles["ip:198.51.100.7"].FailedCount)
+	s.Require().Len(s.logins.logins, 1)
+	s.Equal(entity.UserLogin{UserID: 4, IPAddress: "198.51.100.7"}, s.logins.logins[0])
+}
+
+func TestAuthThrottle(t *testing.T) {
+	suite.Run(t, new(authThrottleTestSuite))
+}
+
+type fakeIDTokenVerifier struct {
+	identity entity.OIDCIdentity
+}
+
+func (v *fakeIDTokenVerifier) VerifyIDToken(ctx context.Context, token string) (*entity.OIDCIdentity, error) {
+	identity := v.identity
+	return &identity, nil
+}
+
+type memoryIdentityRepo struct {
+	api.UserIdentityRepository
+	identities []entity.UserIdentity
+}
+
+func (r *memoryIdentityRepo) GetIdentity(ctx context.Context, issuer, subject string) (*entity.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, api.ErrNotFound
+}
+
+func (r *memoryIdentityRepo) CreateIdentity(ctx context.Context, identity *entity.UserIdentity) error {
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+type oidcSignInTestSuite struct {
+	suite.Suite
+	users      *memoryUserRepo
+	identities *memoryIdentityRepo
+	verifier   *fakeIDTokenVerifier
+	uc         api.AuthUsecase
+}
+
+func (s *oidcSignInTestSuite) SetupTest() {
+	s.users = newMemoryUserRepo(entity.User{
+		ID:       1,
+		Email:    "rocky@sejastip.id",
+		Password: hashPassword("adrian"),
+	})
+	s.identities = &memoryIdentityRepo{}
+	s.verifier = &fakeIDTokenVerifier{identity: entity.OIDCIdentity{
+		Issuer:        "https://accounts.google.com",
+		Subject:       "1234567890",
+		Email:         "rocky@sejastip.id",
+		EmailVerified: true,
+		Name:          "Rocky Balboa",
+	}}
+	s.uc = usecase.NewAuthUsecase(&usecase.AuthProvider{
+		UserRepository:   s.users,
+		ThrottleRepo:     &memoryThrottleRepo{throttles: map[string]entity.LoginThrottle{}},
+		LoginHistoryRepo: &memoryLoginHistoryRepo{},
+		IdentityRepo:     s.identities,
+		IDTokenVerifier:  s.verifier,
+		Keys:             infra.NewKeySet("", "secret", time.Time{}),
+	})
+}
+
+func (s *oidcSignInTestSuite) signIn() error {
+	_, err := s.uc.AuthenticateOIDC(context.Background(), &entity.OIDCAuthForm{IDToken: "id-token"})
+	return err
+}
+
+func (s *oidcSignInTestSuite) TestUnverifiedAccountIsNotLinked() {
+	// the email was registered with a password by someone else
+	s.Equal(api.ErrUnverifiedAccountEmail, s.signIn())
+
+	s.Empty(s.identities.identities)
+	s.Nil(s.users.users[1].EmailVerifiedAt)
+}
+
+func (s *oidcSignInTestSuite) TestVerifiedAccountIsLinked() {
+	verifiedAt := time.Now()
+	user := s.users.users[1]
+	user.EmailVerifiedAt = &verifiedAt
+	s.users.users[1] = user
+
+	s.NoError(s.signIn())
+
+	s.Equal([]entity.UserIdentity{{
+		UserID:  1,
+		Issuer:  "https://accounts.google.com",
+		Subject: "1234567890",
+		Email:   "rocky@sejastip.id",
+	}}, s.identities.identities)
+}
+
+func (s *oidcSignInTestSuite) TestNewEmailSignsUp() {
+	s.verifier.identity.Email = "adrian@sejastip.id"
+
+	s.NoError(s.signIn())
+
+	s.Require().Len(s.identities.identities, 1)
+	user := s.users.users[s.identities.identities[0].UserID]
+	s.Equal("adrian@sejastip.id", user.Email)
+	s.NotNil(user.EmailVerifiedAt)
+}
+
+func (s *oidcSignInTestSuite) TestEmailUnverifiedByTheIssuer() {
+	s.verifier.identity.EmailVerified = false
+
+	s.Equal(api.ErrUnverifiedIDTokenEmail, s.signIn())
+	s.Empty(s.identities.identities)
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	suite.Run(t, new(oidcSignInTestSuite))
+}
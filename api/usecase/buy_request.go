@@ -0,0 +1,395 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/storage"
+	"sejastip.id/api/util"
+)
+
+type BuyRequestProvider struct {
+	BuyRequestRepo api.BuyRequestRepository
+	OfferRepo      api.BuyRequestOfferRepository
+	UserRepo       api.UserRepository
+	CountryRepo    api.CountryRepository
+
+	// accepting an offer lists the product and places the order through the
+	// same usecases a seller and a buyer would use
+	ProductUsecase     api.ProductUsecase
+	TransactionUsecase api.TransactionUsecase
+
+	Storage    storage.Storage
+	Transactor api.Transactor
+}
+
+type buyRequestUsecase struct {
+	*BuyRequestProvider
+}
+
+func NewBuyRequestUsecase(pvd *BuyRequestProvider) api.BuyRequestUsecase {
+	return &buyRequestUsecase{pvd}
+}
+
+func (uc *buyRequestUsecase) CreateBuyRequest(ctx context.Context, userID int64, form *entity.BuyRequestForm) (*entity.BuyRequestPublic, error) {
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	// only buyers with verified contact details may post requests
+	buyer, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buyer")
+	}
+	if !buyer.IsVerified() {
+		return nil, api.ErrUnverifiedContact
+	}
+
+	country, err := uc.CountryRepo.GetCountry(ctx, form.CountryID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, extension, err := util.DecodeUploadedBase64File(form.ImageFile)
+	if err != nil {
+		return nil, api.ValidationError(fmt.Errorf("Error parsing file: %v", err))
+	}
+	path := "buy-requests/" + strings.ToLower(uuid.New().String()+extension)
+	url, err := uc.Storage.Store(path, file)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in uploading file")
+	}
+
+	neededBy, _ := time.Parse("2006-01-02", form.NeededBy)
+	request := &entity.BuyRequest{
+		BuyerID:     userID,
+		CountryID:   form.CountryID,
+		Title:       form.Title,
+		Description: form.Description,
+		Image:       url,
+		MaxBudget:   form.MaxBudget,
+		Quantity:    form.Quantity,
+		NeededBy:    neededBy,
+		Status:      entity.BuyRequestStatusOpen,
+	}
+	err = uc.BuyRequestRepo.CreateBuyRequest(ctx, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in creating buy request")
+	}
+
+	requestPublic := request.ConvertToPublic(country, buyer, 0)
+	return &requestPublic, nil
+}
+
+// GetBuyRequests returns the buy request feed. Unless a status is filtered,
+// only open requests which are still needed are listed
+func (uc *buyRequestUsecase) GetBuyRequests(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.BuyRequestPublic, int64, error) {
+	if err := page.Validate(spec); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	if _, ok := spec.Value("status"); !ok {
+		spec.Where("status", entity.FilterOperatorEq, int64(entity.BuyRequestStatusOpen))
+		spec.Where("needed_by", entity.FilterOperatorGte, time.Now().Format("2006-01-02"))
+	}
+
+	requests, total, err := uc.BuyRequestRepo.GetBuyRequests(ctx, spec, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error in fetching buy requests by filter")
+	}
+
+	requestsPublic := []entity.BuyRequestPublic{}
+	for _, request := range requests {
+		requestPublic, err := uc.convertToPublic(ctx, &request)
+		if err != nil {
+			return nil, 0, err
+		}
+		requestsPublic = append(requestsPublic, *requestPublic)
+	}
+
+	return requestsPublic, total, nil
+}
+
+func (uc *buyRequestUsecase) GetBuyRequest(ctx context.Context, ID int64) (*entity.BuyRequestPublic, error) {
+	request, err := uc.BuyRequestRepo.GetBuyRequest(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buy request")
+	}
+
+	return uc.convertToPublic(ctx, request)
+}
+
+// CancelBuyRequest cancels an open request of the buyer, rejecting the offers
+// still waiting for an answer
+func (uc *buyRequestUsecase) CancelBuyRequest(ctx context.Context, ID, userID int64) error {
+	request, err := uc.getOwnedBuyRequest(ctx, ID, userID)
+	if err != nil {
+		return err
+	}
+
+	if request.Status != entity.BuyRequestStatusOpen {
+		return api.ErrBuyRequestClosed
+	}
+
+	err = uc.OfferRepo.RejectPendingOffers(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error in rejecting pending offers")
+	}
+
+	request.Status = entity.BuyRequestStatusCancelled
+	err = uc.BuyRequestRepo.UpdateBuyRequestStatus(ctx, ID, request)
+	if err != nil {
+		return errors.Wrap(err, "error in cancelling buy request")
+	}
+
+	return nil
+}
+
+func (uc *buyRequestUsecase) CreateOffer(ctx context.Context, buyRequestID, userID int64, form *entity.BuyRequestOfferForm) (*entity.BuyRequestOfferPublic, error) {
+	request, err := uc.BuyRequestRepo.GetBuyRequest(ctx, buyRequestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buy request")
+	}
+
+	if request.BuyerID == userID {
+		return nil, api.ErrOfferOwnBuyRequest
+	}
+	if !request.IsOpen(time.Now()) {
+		return nil, api.ErrBuyRequestClosed
+	}
+
+	if err := form.Validate(request); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	// only sellers with verified contact details may make offers, as they
+	// would list a product once the offer is accepted
+	seller, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching seller")
+	}
+	if !seller.IsVerified() {
+		return nil, api.ErrUnverifiedContact
+	}
+
+	offers, err := uc.OfferRepo.GetOffers(ctx, buyRequestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching offers")
+	}
+	for _, offer := range offers {
+		if offer.SellerID == userID && offer.Status == entity.BuyRequestOfferStatusPending {
+			return nil, api.ErrDuplicateOffer
+		}
+	}
+
+	fromDate, _ := time.Parse("2006-01-02", form.FromDate)
+	toDate, _ := time.Parse("2006-01-02", form.ToDate)
+	offer := &entity.BuyRequestOffer{
+		BuyRequestID: buyRequestID,
+		SellerID:     userID,
+		Price:        form.Price,
+		FromDate:     fromDate,
+		ToDate:       toDate,
+		Notes:        form.Notes,
+		Status:       entity.BuyRequestOfferStatusPending,
+	}
+	err = uc.OfferRepo.CreateOffer(ctx, offer)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in creating offer")
+	}
+
+	offerPublic := offer.ConvertToPublic(seller)
+	return &offerPublic, nil
+}
+
+// GetOffers returns every offer on the request to its buyer, and only their own
+// offers to the other users
+func (uc *buyRequestUsecase) GetOffers(ctx context.Context, buyRequestID, userID int64) ([]entity.BuyRequestOfferPublic, error) {
+	request, err := uc.BuyRequestRepo.GetBuyRequest(ctx, buyRequestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buy request")
+	}
+
+	offers, err := uc.OfferRepo.GetOffers(ctx, buyRequestID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching offers")
+	}
+
+	offersPublic := []entity.BuyRequestOfferPublic{}
+	for _, offer := range offers {
+		if request.BuyerID != userID && offer.SellerID != userID {
+			continue
+		}
+
+		seller, err := uc.UserRepo.GetUser(ctx, offer.SellerID)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in fetching offer seller")
+		}
+		offersPublic = append(offersPublic, offer.ConvertToPublic(seller))
+	}
+
+	return offersPublic, nil
+}
+
+func (uc *buyRequestUsecase) WithdrawOffer(ctx context.Context, buyRequestID, offerID, userID int64) error {
+	offer, err := uc.getRequestOffer(ctx, buyRequestID, offerID)
+	if err != nil {
+		return err
+	}
+
+	if offer.SellerID != userID {
+		return api.ErrEditBuyRequestForbidden
+	}
+	if offer.Status != entity.BuyRequestOfferStatusPending {
+		return api.ErrOfferNotPending
+	}
+
+	offer.Status = entity.BuyRequestOfferStatusWithdrawn
+	err = uc.OfferRepo.UpdateOfferStatus(ctx, offerID, offer)
+	if err != nil {
+		return errors.Wrap(err, "error in withdrawing offer")
+	}
+
+	return nil
+}
+
+// AcceptOffer accepts an offer on behalf of the buyer. The seller gets a product
+// made from the request at the offered price and trip window, reserved for the
+// buyer, and the buyer places an order of it, then the other pending offers are
+// rejected. It all happens in one database transaction, which only answers a
+// request that is still open, so concurrent accepts can't both go through
+func (uc *buyRequestUsecase) AcceptOffer(ctx context.Context, buyRequestID, offerID, userID int64, form *entity.AcceptOfferForm) (*entity.TransactionPublic, error) {
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	request, err := uc.getOwnedBuyRequest(ctx, buyRequestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !request.IsOpen(time.Now()) {
+		return nil, api.ErrBuyRequestClosed
+	}
+
+	offer, err := uc.getRequestOffer(ctx, buyRequestID, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Status != entity.BuyRequestOfferStatusPending {
+		return nil, api.ErrOfferNotPending
+	}
+
+	var transaction *entity.TransactionPublic
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		// answer the request first, a concurrent accept waits for its row and
+		// then finds it no longer open
+		request.Status = entity.BuyRequestStatusFulfilled
+		request.AcceptedOfferID = &offer.ID
+		err := uc.BuyRequestRepo.UpdateBuyRequestStatus(ctx, buyRequestID, request)
+		if err != nil {
+			return errors.Wrap(err, "error in fulfilling buy request")
+		}
+
+		// the offered trip window becomes the offering window of the product
+		product := &entity.Product{
+			Title:       request.Title,
+			Description: request.Description,
+			Price:       offer.Price,
+			SellerID:    offer.SellerID,
+			CountryID:   request.CountryID,
+			ReservedFor: &request.BuyerID,
+			Image:       request.Image,
+			FromDate:    offer.FromDate,
+			ToDate:      offer.ToDate.Add(24*time.Hour - time.Second),
+			Images:      []entity.ProductImage{{URL: request.Image}},
+		}
+		product.NormalizeCreate()
+		productPublic, err := uc.ProductUsecase.CreateProduct(ctx, product)
+		if err != nil {
+			return errors.Wrap(err, "error in listing offered product")
+		}
+
+		transaction, err = uc.TransactionUsecase.CreateTransaction(ctx, &entity.TransactionForm{
+			ProductID: productPublic.ID,
+			Quantity:  request.Quantity,
+			AddressID: form.AddressID,
+			Notes:     form.Notes,
+		}, userID)
+		if err != nil {
+			return err
+		}
+
+		offer.Status = entity.BuyRequestOfferStatusAccepted
+		offer.ProductID = &productPublic.ID
+		offer.TransactionID = &transaction.ID
+		err = uc.OfferRepo.UpdateOfferStatus(ctx, offerID, offer)
+		if err != nil {
+			return errors.Wrap(err, "error in accepting offer")
+		}
+
+		err = uc.OfferRepo.RejectPendingOffers(ctx, buyRequestID)
+		if err != nil {
+			return errors.Wrap(err, "error in rejecting pending offers")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// getOwnedBuyRequest fetches a buy request, after checking it is posted by the user
+func (uc *buyRequestUsecase) getOwnedBuyRequest(ctx context.Context, ID, userID int64) (*entity.BuyRequest, error) {
+	request, err := uc.BuyRequestRepo.GetBuyRequest(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buy request")
+	}
+
+	if request.BuyerID != userID {
+		return nil, api.ErrEditBuyRequestForbidden
+	}
+	return request, nil
+}
+
+// getRequestOffer fetches an offer, after checking it is made on the buy request
+func (uc *buyRequestUsecase) getRequestOffer(ctx context.Context, buyRequestID, offerID int64) (*entity.BuyRequestOffer, error) {
+	offer, err := uc.OfferRepo.GetOffer(ctx, offerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching offer")
+	}
+
+	if offer.BuyRequestID != buyRequestID {
+		return nil, api.ErrNotFound
+	}
+	return offer, nil
+}
+
+func (uc *buyRequestUsecase) convertToPublic(ctx context.Context, request *entity.BuyRequest) (*entity.BuyRequestPublic, error) {
+	buyer, err := uc.UserRepo.GetUser(ctx, request.BuyerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching buyer details from buy request")
+	}
+
+	country, err := uc.CountryRepo.GetCountry(ctx, request.CountryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching country details from buy request")
+	}
+
+	offerCount, err := uc.OfferRepo.CountPendingOffers(ctx, request.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in counting offers of buy request")
+	}
+
+	requestPublic := request.ConvertToPublic(country, buyer, offerCount)
+	return &requestPublic, nil
+}
@@ -0,0 +1,183 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+type acceptBuyRequestRepo struct {
+	api.BuyRequestRepository
+	tx      *memoryTransactor
+	request entity.BuyRequest
+	// answered tells the request was answered since it was fetched
+	answered bool
+}
+
+func (r *acceptBuyRequestRepo) GetBuyRequest(ctx context.Context, ID int64) (*entity.BuyRequest, error) {
+	request := r.request
+	return &request, nil
+}
+
+func (r *acceptBuyRequestRepo) UpdateBuyRequestStatus(ctx context.Context, ID int64, request *entity.BuyRequest) error {
+	if r.answered {
+		return api.ErrBuyRequestClosed
+	}
+	return r.tx.write(ctx, "buy_requests")
+}
+
+type acceptOfferRepo struct {
+	api.BuyRequestOfferRepository
+	tx    *memoryTransactor
+	offer entity.BuyRequestOffer
+	// withdrawn tells the offer was withdrawn since it was fetched
+	withdrawn bool
+}
+
+func (r *acceptOfferRepo) GetOffer(ctx context.Context, ID int64) (*entity.BuyRequestOffer, error) {
+	offer := r.offer
+	return &offer, nil
+}
+
+func (r *acceptOfferRepo) UpdateOfferStatus(ctx context.Context, ID int64, offer *entity.BuyRequestOffer) error {
+	if r.withdrawn {
+		return api.ErrOfferNotPending
+	}
+	return r.tx.write(ctx, "accept offer")
+}
+
+func (r *acceptOfferRepo) RejectPendingOffers(ctx context.Context, buyRequestID int64) error {
+	return r.tx.write(ctx, "reject offers")
+}
+
+type acceptProductUsecase struct {
+	api.ProductUsecase
+	tx      *memoryTransactor
+	product *entity.Product
+}
+
+func (uc *acceptProductUsecase) CreateProduct(ctx context.Context, product *entity.Product) (*entity.ProductPublic, error) {
+	if err := uc.tx.write(ctx, "products"); err != nil {
+		return nil, err
+	}
+	product.ID = 9
+	uc.product = product
+	return &entity.ProductPublic{ID: product.ID}, nil
+}
+
+type acceptTransactionUsecase struct {
+	api.TransactionUsecase
+	tx  *memoryTransactor
+	err error
+}
+
+func (uc *acceptTransactionUsecase) CreateTransaction(ctx context.Context, form *entity.TransactionForm, userID int64) (*entity.TransactionPublic, error) {
+	if uc.err != nil {
+		return nil, uc.err
+	}
+	if err := uc.tx.write(ctx, "transactions"); err != nil {
+		return nil, err
+	}
+	return &entity.TransactionPublic{ID: 11}, nil
+}
+
+type acceptOfferTestSuite struct {
+	suite.Suite
+	tx           *memoryTransactor
+	requests     *acceptBuyRequestRepo
+	offers       *acceptOfferRepo
+	products     *acceptProductUsecase
+	transactions *acceptTransactionUsecase
+	uc           api.BuyRequestUsecase
+}
+
+func (s *acceptOfferTestSuite) SetupTest() {
+	s.tx = &memoryTransactor{}
+	s.requests = &acceptBuyRequestRepo{tx: s.tx, request: entity.BuyRequest{
+		ID:        7,
+		BuyerID:   5,
+		CountryID: 1,
+		Title:     "Tokyo Banana",
+		Image:     "https://storage.googleapis.com/sejastip/buy-requests/banana.jpg",
+		Quantity:  2,
+		NeededBy:  time.Now().AddDate(0, 0, 14),
+		Status:    entity.BuyRequestStatusOpen,
+	}}
+	s.offers = &acceptOfferRepo{tx: s.tx, offer: entity.BuyRequestOffer{
+		ID:           3,
+		BuyRequestID: 7,
+		SellerID:     2,
+		Price:        100000,
+		FromDate:     time.Now(),
+		ToDate:       time.Now().AddDate(0, 0, 7),
+		Status:       entity.BuyRequestOfferStatusPending,
+	}}
+	s.products = &acceptProductUsecase{tx: s.tx}
+	s.transactions = &acceptTransactionUsecase{tx: s.tx}
+	s.uc = usecase.NewBuyRequestUsecase(&usecase.BuyRequestProvider{
+		BuyRequestRepo:     s.requests,
+		OfferRepo:          s.offers,
+		ProductUsecase:     s.products,
+		TransactionUsecase: s.transactions,
+		Transactor:         s.tx,
+	})
+}
+
+func (s *acceptOfferTestSuite) accept() (*entity.TransactionPublic, error) {
+	return s.uc.AcceptOffer(context.Background(), 7, 3, 5, &entity.AcceptOfferForm{AddressID: 4})
+}
+
+func (s *acceptOfferTestSuite) TestAcceptedOfferPlacesTheOrder() {
+	transaction, err := s.accept()
+
+	s.Require().NoError(err)
+	s.Equal(int64(11), transaction.ID)
+	s.Equal([]string{"buy_requests", "products", "transactions", "accept offer", "reject offers"}, s.tx.committed)
+}
+
+func (s *acceptOfferTestSuite) TestProductIsReservedForTheBuyer() {
+	_, err := s.accept()
+
+	s.Require().NoError(err)
+	s.Equal(int64(2), s.products.product.SellerID)
+	s.Equal(int64(5), *s.products.product.ReservedFor)
+}
+
+func (s *acceptOfferTestSuite) TestRequestAnsweredConcurrentlyIsNotAccepted() {
+	s.requests.answered = true
+
+	_, err := s.accept()
+
+	s.Equal(api.ErrBuyRequestClosed, errors.Cause(err))
+	s.Nil(s.products.product)
+	s.Empty(s.tx.committed)
+}
+
+func (s *acceptOfferTestSuite) TestOfferWithdrawnConcurrentlyRollsBack() {
+	s.offers.withdrawn = true
+
+	_, err := s.accept()
+
+	s.Equal(api.ErrOfferNotPending, errors.Cause(err))
+	// the product and the order are rolled back along with the request
+	s.Empty(s.tx.committed)
+}
+
+func (s *acceptOfferTestSuite) TestFailedOrderRollsTheProductBack() {
+	s.transactions.err = errors.New("connection reset")
+
+	_, err := s.accept()
+
+	s.Error(err)
+	s.Empty(s.tx.committed)
+}
+
+func TestAcceptOffer(t *testing.T) {
+	suite.Run(t, new(acceptOfferTestSuite))
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type CategoryProvider struct {
+	CategoryRepo api.CategoryRepository
+	ProductRepo  api.ProductRepository
+}
+
+type categoryUsecase struct {
+	*CategoryProvider
+}
+
+func NewCategoryUsecase(pvd *CategoryProvider) api.CategoryUsecase {
+	return &categoryUsecase{pvd}
+}
+
+func (u *categoryUsecase) CreateCategory(ctx context.Context, form *entity.CategoryForm) (*entity.Category, error) {
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	categories, err := u.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching categories")
+	}
+	if err := validateCategoryPlacement(categories, 0, form); err != nil {
+		return nil, err
+	}
+
+	category := &entity.Category{
+		ParentID: form.ParentID,
+		Name:     form.Name,
+		Slug:     form.Slug,
+	}
+	err = u.CategoryRepo.CreateCategory(ctx, category)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in creating category")
+	}
+
+	return category, nil
+}
+
+// GetCategories returns the category tree along with the number of listed products
+// of each category. Only products sold in the country are counted when countryID is set
+func (u *categoryUsecase) GetCategories(ctx context.Context, countryID int64) ([]entity.CategoryPublic, error) {
+	categories, err := u.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching categories")
+	}
+
+	counts, err := u.ProductRepo.CountProductsByCategory(ctx, countryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in counting products by category")
+	}
+
+	return entity.NewCategoryTree(categories).ConvertToPublic(counts), nil
+}
+
+func (u *categoryUsecase) UpdateCategory(ctx context.Context, ID int64, form *entity.CategoryForm) (*entity.Category, error) {
+	category, err := u.CategoryRepo.GetCategory(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching category")
+	}
+
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	categories, err := u.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching categories")
+	}
+	if err := validateCategoryPlacement(categories, ID, form); err != nil {
+		return nil, err
+	}
+
+	category.ParentID = form.ParentID
+	category.Name = form.Name
+	category.Slug = form.Slug
+	err = u.CategoryRepo.UpdateCategory(ctx, ID, category)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating category")
+	}
+
+	return category, nil
+}
+
+// DeleteCategory removes a category, as long as no subcategory or product uses it
+func (u *categoryUsecase) DeleteCategory(ctx context.Context, ID int64) error {
+	if _, err := u.CategoryRepo.GetCategory(ctx, ID); err != nil {
+		return errors.Wrap(err, "error in fetching category")
+	}
+
+	categories, err := u.CategoryRepo.GetCategories(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error in fetching categories")
+	}
+	for _, category := range categories {
+		if category.ParentID != nil && *category.ParentID == ID {
+			return api.ErrCategoryInUse
+		}
+	}
+
+	// closed and reserved products still belong to the category
+	total, err := u.ProductRepo.CountProductsInCategory(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error in counting category products")
+	}
+	if total > 0 {
+		return api.ErrCategoryInUse
+	}
+
+	err = u.CategoryRepo.DeleteCategory(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error in deleting category")
+	}
+
+	return nil
+}
+
+// validateCategoryPlacement checks the slug is not used by another category, and
+// the parent exists and is not the category itself or one of its descendants.
+// ID is 0 for a new category
+func validateCategoryPlacement(categories []entity.Category, ID int64, form *entity.CategoryForm) error {
+	parentFound := form.ParentID == nil
+	for _, category := range categories {
+		if category.ID != ID && category.Slug == form.Slug {
+			return api.CustomValidationError("Slug kategori %s sudah digunakan", form.Slug)
+		}
+		if form.ParentID != nil && category.ID == *form.ParentID {
+			parentFound = true
+		}
+	}
+	if !parentFound {
+		return api.CustomValidationError("Kategori induk tidak ditemukan")
+	}
+
+	if ID > 0 && form.ParentID != nil && entity.NewCategoryTree(categories).IsDescendant(*form.ParentID, ID) {
+		return api.CustomValidationError("Kategori tidak dapat dipindahkan ke dalam subkategorinya sendiri")
+	}
+
+	return nil
+}
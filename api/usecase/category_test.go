@@ -0,0 +1,135 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+type memoryCategoryRepo struct {
+	categories []entity.Category
+}
+
+func (r *memoryCategoryRepo) CreateCategory(ctx context.Context, category *entity.Category) error {
+	category.ID = int64(len(r.categories) + 1)
+	r.categories = append(r.categories, *category)
+	return nil
+}
+
+func (r *memoryCategoryRepo) GetCategories(ctx context.Context) ([]entity.Category, error) {
+	return append([]entity.Category{}, r.categories...), nil
+}
+
+func (r *memoryCategoryRepo) GetCategory(ctx context.Context, ID int64) (*entity.Category, error) {
+	for _, category := range r.categories {
+		if category.ID == ID {
+			return &category, nil
+		}
+	}
+	return nil, api.ErrNotFound
+}
+
+func (r *memoryCategoryRepo) UpdateCategory(ctx context.Context, ID int64, category *entity.Category) error {
+	for i := range r.categories {
+		if r.categories[i].ID == ID {
+			r.categories[i] = *category
+		}
+	}
+	return nil
+}
+
+func (r *memoryCategoryRepo) DeleteCategory(ctx context.Context, ID int64) error {
+	for i := range r.categories {
+		if r.categories[i].ID == ID {
+			r.categories = append(r.categories[:i], r.categories[i+1:]...)
+			return nil
+		}
+	}
+	return api.ErrNotFound
+}
+
+type categoryCountProductRepo struct {
+	api.ProductRepository
+	counts map[int64]int64
+}
+
+func (r *categoryCountProductRepo) CountProductsByCategory(ctx context.Context, countryID int64) (map[int64]int64, error) {
+	return r.counts, nil
+}
+
+func (r *categoryCountProductRepo) CountProductsInCategory(ctx context.Context, categoryID int64) (int64, error) {
+	return r.counts[categoryID], nil
+}
+
+type categoryTestSuite struct {
+	suite.Suite
+	categories *memoryCategoryRepo
+	products   *categoryCountProductRepo
+	uc         api.CategoryUsecase
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func (s *categoryTestSuite) SetupTest() {
+	s.categories = &memoryCategoryRepo{categories: []entity.Category{
+		{ID: 1, Name: "Kecantikan", Slug: "kecantikan"},
+		{ID: 2, ParentID: int64Ptr(1), Name: "Perawatan Kulit", Slug: "perawatan-kulit"},
+		{ID: 3, Name: "Makanan", Slug: "makanan"},
+	}}
+	s.products = &categoryCountProductRepo{counts: map[int64]int64{}}
+	s.uc = usecase.NewCategoryUsecase(&usecase.CategoryProvider{
+		CategoryRepo: s.categories,
+		ProductRepo:  s.products,
+	})
+}
+
+func (s *categoryTestSuite) TestSlugIsDerivedFromTheName() {
+	category, err := s.uc.CreateCategory(context.Background(), &entity.CategoryForm{
+		Name:     "Tabir Surya",
+		ParentID: int64Ptr(2),
+	})
+
+	s.NoError(err)
+	s.Equal("tabir-surya", category.Slug)
+}
+
+func (s *categoryTestSuite) TestSlugMustBeUnique() {
+	_, err := s.uc.CreateCategory(context.Background(), &entity.CategoryForm{Name: "Makanan"})
+
+	s.EqualError(err, "Slug kategori makanan sudah digunakan")
+}
+
+func (s *categoryTestSuite) TestParentMustExist() {
+	_, err := s.uc.CreateCategory(context.Background(), &entity.CategoryForm{Name: "Serum", ParentID: int64Ptr(9)})
+
+	s.EqualError(err, "Kategori induk tidak ditemukan")
+}
+
+func (s *categoryTestSuite) TestCategoryCantMoveUnderItsDescendant() {
+	_, err := s.uc.UpdateCategory(context.Background(), 1, &entity.CategoryForm{
+		Name:     "Kecantikan",
+		ParentID: int64Ptr(2),
+	})
+
+	s.EqualError(err, "Kategori tidak dapat dipindahkan ke dalam subkategorinya sendiri")
+}
+
+func (s *categoryTestSuite) TestCategoryInUseIsNotDeleted() {
+	s.Equal(api.ErrCategoryInUse, s.uc.DeleteCategory(context.Background(), 1))
+
+	s.products.counts[3] = 1
+	s.Equal(api.ErrCategoryInUse, s.uc.DeleteCategory(context.Background(), 3))
+
+	s.NoError(s.uc.DeleteCategory(context.Background(), 2))
+	s.Len(s.categories.categories, 2)
+}
+
+func TestCategory(t *testing.T) {
+	suite.Run(t, new(categoryTestSuite))
+}
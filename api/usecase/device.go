@@ -21,28 +21,44 @@ func NewDeviceUsecase(pvd *DeviceProvider) api.DeviceUsecase {
 	return &deviceUsecase{pvd}
 }
 
+// UpsertDevice registers a device of the logged in user. A user can have many
+// devices, each getting its own push notifications
 func (uc *deviceUsecase) UpsertDevice(ctx context.Context, device *entity.Device) error {
 	meta := api.MetaFromContext(ctx)
 	if meta.ID < 1 {
 		return api.ErrForbidden
 	}
-	existingDevice, err := uc.DeviceRepo.GetUserDevice(ctx, meta.ID)
-	if err != nil && err != api.ErrNotFound {
-		return errors.Wrap(err, "error fetching device")
+
+	device.UserID = meta.ID
+	err := uc.DeviceRepo.UpsertUserDevice(ctx, device)
+	if err != nil {
+		return errors.Wrap(err, "error registering device")
 	}
 
-	if existingDevice != nil {
-		err = uc.DeviceRepo.UpdateUserDevice(ctx, existingDevice.ID, device)
-		if err != nil {
-			return errors.Wrap(err, "error updating existing device")
-		}
-	} else {
-		device.UserID = meta.ID
-		err = uc.DeviceRepo.InsertUserDevice(ctx, device)
-		if err != nil {
-			return errors.Wrap(err, "error inserting new device")
-		}
+	return nil
+}
+
+// RemoveDevice unregisters a device of the logged in user, e.g. on logout
+func (uc *deviceUsecase) RemoveDevice(ctx context.Context, deviceID string) error {
+	meta := api.MetaFromContext(ctx)
+	if meta.ID < 1 {
+		return api.ErrForbidden
+	}
+
+	err := uc.DeviceRepo.RemoveUserDevice(ctx, meta.ID, deviceID)
+	if err != nil {
+		return errors.Wrap(err, "error removing device")
 	}
 
 	return nil
 }
+
+// PruneDevices unregisters the devices whose tokens are rejected by the push service
+func (uc *deviceUsecase) PruneDevices(ctx context.Context, deviceIDs []string) (int64, error) {
+	total, err := uc.DeviceRepo.RemoveDevices(ctx, deviceIDs)
+	if err != nil {
+		return 0, errors.Wrap(err, "error pruning devices")
+	}
+
+	return total, nil
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+// OutboxEventPublisher queues domain events in the outbox, to be relayed to their
+// dedicated topics. Published within a database transaction, an event is only
+// relayed once the change it describes is committed
+type OutboxEventPublisher struct {
+	Outbox api.OutboxUsecase
+}
+
+func NewOutboxEventPublisher(outbox api.OutboxUsecase) *OutboxEventPublisher {
+	return &OutboxEventPublisher{outbox}
+}
+
+func (p *OutboxEventPublisher) PublishEvent(ctx context.Context, event entity.DomainEvent) error {
+	return p.Outbox.Enqueue(ctx, entity.EventTopic(event), entity.NewEventEnvelope(event, time.Now()))
+}
+
+// publishEvent publishes a domain event. Like notifications, a failure is returned
+// so the change the event describes is rolled back along with it
+func publishEvent(ctx context.Context, publisher api.EventPublisher, event entity.DomainEvent) error {
+	if publisher == nil {
+		return nil
+	}
+
+	if err := publisher.PublishEvent(ctx, event); err != nil {
+		return errors.Wrapf(err, "error publishing %s event", event.EventName())
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+// eventOutboxRepo keeps the queued messages, which must be queued in the transaction
+// of the change they describe
+type eventOutboxRepo struct {
+	api.OutboxRepository
+	tx       *memoryTransactor
+	messages []entity.OutboxMessage
+	err      error
+}
+
+func (r *eventOutboxRepo) InsertOutboxMessage(ctx context.Context, message *entity.OutboxMessage) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.tx.write(ctx, "outbox "+message.Topic); err != nil {
+		return err
+	}
+	r.messages = append(r.messages, *message)
+	return nil
+}
+
+type eventUserRepo struct {
+	*memoryUserRepo
+	tx *memoryTransactor
+}
+
+func (r *eventUserRepo) CreateUser(ctx context.Context, user *entity.User) error {
+	if err := r.tx.write(ctx, "users"); err != nil {
+		return err
+	}
+	return r.memoryUserRepo.CreateUser(ctx, user)
+}
+
+type eventProductRepo struct {
+	api.ProductRepository
+	tx *memoryTransactor
+}
+
+func (r *eventProductRepo) CreateProduct(ctx context.Context, product *entity.Product) error {
+	if err := r.tx.write(ctx, "products"); err != nil {
+		return err
+	}
+	product.ID = 3
+	product.CreatedAt = time.Now()
+	return nil
+}
+
+type eventTestSuite struct {
+	suite.Suite
+	tx       *memoryTransactor
+	outbox   *eventOutboxRepo
+	events   *usecase.OutboxEventPublisher
+	users    api.UserUsecase
+	products api.ProductUsecase
+}
+
+func (s *eventTestSuite) SetupTest() {
+	verifiedAt := time.Now()
+	s.tx = &memoryTransactor{}
+	s.outbox = &eventOutboxRepo{tx: s.tx}
+	s.events = usecase.NewOutboxEventPublisher(usecase.NewOutboxUsecase(&usecase.OutboxProvider{OutboxRepo: s.outbox}))
+
+	users := &eventUserRepo{tx: s.tx, memoryUserRepo: newMemoryUserRepo(
+		entity.User{ID: 2, Name: "Seller", EmailVerifiedAt: &verifiedAt, PhoneVerifiedAt: &verifiedAt},
+	)}
+	s.users = usecase.NewUserUsecase(&usecase.UserProvider{
+		UserRepository: users,
+		Events:         s.events,
+		Transactor:     s.tx,
+	})
+	s.products = usecase.NewProductUsecase(&usecase.ProductProvider{
+		ProductRepo: &eventProductRepo{tx: s.tx},
+		UserRepo:    users,
+		CountryRepo: &accountCountryRepo{},
+		SearchIndex: &memorySearchIndex{},
+		Events:      s.events,
+		Transactor:  s.tx,
+	})
+}
+
+// envelope decodes the queued message, along with the topic it is relayed to
+func (s *eventTestSuite) envelope(message entity.OutboxMessage) (string, entity.EventEnvelope) {
+	var envelope struct {
+		entity.EventEnvelope
+		Data json.RawMessage `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(message.Payload), &envelope))
+	s.NotEmpty(envelope.ID)
+	s.NotEmpty(envelope.Data)
+	return message.Topic, envelope.EventEnvelope
+}
+
+func (s *eventTestSuite) TestEventsArePublishedToTheirTopics() {
+	cases := []struct {
+		event entity.DomainEvent
+		name  string
+		topic string
+	}{
+		{entity.UserRegisteredV1{}, entity.EventUserRegistered, "events-user-registered"},
+		{entity.ProductListedV1{}, entity.EventProductListed, "events-product-listed"},
+		{entity.TransactionCreatedV1{}, entity.EventTransactionCreated, "events-transaction-created"},
+		{entity.TransactionStatusChangedV1{}, entity.EventTransactionStatusChanged, "events-transaction-status-changed"},
+		{entity.InvoicePaidV1{}, entity.EventInvoicePaid, "events-invoice-paid"},
+	}
+
+	ctx := context.WithValue(context.Background(), txContextKey{}, true)
+	for i, c := range cases {
+		s.Require().NoError(s.events.PublishEvent(ctx, c.event))
+
+		topic, envelope := s.envelope(s.outbox.messages[i])
+		s.Equal(c.topic, topic, c.name)
+		s.Equal(c.name, envelope.Name)
+		s.Equal(1, envelope.Version, c.name)
+	}
+}
+
+func (s *eventTestSuite) register() (*entity.UserProfile, error) {
+	return s.users.Register(context.Background(), &entity.User{
+		Email:    "rocky@sejastip.id",
+		Name:     "Rocky Balboa",
+		Phone:    "081234567890",
+		Password: "adrian",
+	})
+}
+
+func (s *eventTestSuite) TestRegistrationIsPublishedWithTheUser() {
+	user, err := s.register()
+
+	s.Require().NoError(err)
+	s.Equal([]string{"users", "outbox events-user-registered"}, s.tx.committed)
+
+	var registered struct {
+		Data entity.UserRegisteredV1 `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(s.outbox.messages[0].Payload), &registered))
+	s.Equal(user.ID, registered.Data.UserID)
+	s.Equal(entity.RegistrationMethodPassword, registered.Data.Method)
+}
+
+func (s *eventTestSuite) TestFailedOutboxInsertRollsTheRegistrationBack() {
+	s.outbox.err = errors.New("connection reset")
+
+	_, err := s.register()
+
+	s.Error(err)
+	s.Empty(s.tx.committed)
+}
+
+func (s *eventTestSuite) list() (*entity.ProductPublic, error) {
+	return s.products.CreateProduct(context.Background(), &entity.Product{
+		Title:     "Tokyo Banana",
+		Price:     100000,
+		SellerID:  2,
+		CountryID: 1,
+		Image:     "https://storage.googleapis.com/sejastip/products/banana.jpg",
+		FromDate:  time.Now(),
+		ToDate:    time.Now().AddDate(0, 0, 7),
+	})
+}
+
+func (s *eventTestSuite) TestListingIsPublishedWithTheProduct() {
+	product, err := s.list()
+
+	s.Require().NoError(err)
+	s.Equal([]string{"products", "outbox events-product-listed"}, s.tx.committed)
+
+	var listed struct {
+		Data entity.ProductListedV1 `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(s.outbox.messages[0].Payload), &listed))
+	s.Equal(product.ID, listed.Data.ProductID)
+	s.Equal(int64(2), listed.Data.SellerID)
+}
+
+func (s *eventTestSuite) TestFailedOutboxInsertRollsTheListingBack() {
+	s.outbox.err = errors.New("connection reset")
+
+	_, err := s.list()
+
+	s.Error(err)
+	s.Empty(s.tx.committed)
+}
+
+func TestEvent(t *testing.T) {
+	suite.Run(t, new(eventTestSuite))
+}
@@ -18,7 +18,11 @@ import (
 type InvoiceProvider struct {
 	InvoiceRepo     api.InvoiceRepository
 	TransactionRepo api.TransactionRepository
+	ProductRepo     api.ProductRepository
 	UserRepo        api.UserRepository
+	Notifications   *NotificationSender
+	Events          api.EventPublisher
+	Transactor      api.Transactor
 
 	Storage storage.Storage
 }
@@ -70,17 +74,26 @@ func (uc *InvoiceUsecase) InsertInvoice(ctx context.Context, form *entity.Invoic
 		PaidAt:        nil,
 		ReceiptProof:  "",
 	}
-	err = uc.InvoiceRepo.InsertInvoice(ctx, invoice)
-	if err != nil {
-		return nil, errors.Wrap(err, "error inserting invoice")
-	}
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		err := uc.InvoiceRepo.InsertInvoice(ctx, invoice)
+		if err != nil {
+			return errors.Wrap(err, "error inserting invoice")
+		}
+
+		// update transaction to include invoice ID
+		transaction.InvoiceID = &invoice.ID
+		err = uc.TransactionRepo.UpdateTransactionState(ctx, transaction.ID, transaction)
+		if err != nil {
+			return errors.Wrap(err, "error updating transaction")
+		}
 
-	// update transaction to include invoice ID
-	transaction.InvoiceID = &invoice.ID
-	err = uc.TransactionRepo.UpdateTransactionState(ctx, transaction.ID, transaction)
+		// the buyer keeps the payment instructions in its inbox
+		return uc.notify(ctx, transaction.BuyerID, entity.NotificationEventInvoiceCreated, invoice)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "error updating transaction")
+		return nil, err
 	}
+	uc.email(ctx, entity.EmailEventInvoiceIssued, transaction, invoice)
 
 	invoicePublic := invoice.ConvertToPublic()
 	return &invoicePublic, nil
@@ -133,20 +146,46 @@ func (uc *InvoiceUsecase) UpdateInvoice(ctx context.Context, invoiceID int64, fo
 	}
 
 	if form.Status == "paid" {
+		alreadyPaid := invoice.Status == entity.InvoiceStatusPaid
 		now := time.Now()
 		invoice.Status = entity.InvoiceStatusPaid
 		invoice.PaidAt = &now
-		err = uc.InvoiceRepo.UpdateInvoice(ctx, invoiceID, invoice)
+		err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+			err := uc.InvoiceRepo.UpdateInvoice(ctx, invoiceID, invoice)
+			if err != nil {
+				return errors.Wrap(err, "error updating invoice")
+			}
+
+			// update transaction to paid
+			previousStatus := transaction.Status
+			transaction.Status = entity.TransactionStatusPaid
+			transaction.PaidAt = &now
+			err = uc.TransactionRepo.UpdateTransactionState(ctx, transaction.ID, transaction)
+			if err != nil {
+				return errors.Wrap(err, "error updating transaction")
+			}
+
+			if !alreadyPaid {
+				err = uc.notify(ctx, transaction.SellerID, entity.NotificationEventInvoicePaid, invoice)
+				if err != nil {
+					return err
+				}
+				err = publishEvent(ctx, uc.Events, entity.NewInvoicePaid(invoice))
+				if err != nil {
+					return err
+				}
+			}
+			if transaction.Status != previousStatus {
+				return publishEvent(ctx, uc.Events, entity.NewTransactionStatusChanged(transaction, previousStatus, transaction.BuyerID, now))
+			}
+			return nil
+		})
 		if err != nil {
-			return nil, errors.Wrap(err, "error updating invoice")
+			return nil, err
 		}
 
-		// update transaction to paid
-		transaction.Status = entity.TransactionStatusPaid
-		transaction.PaidAt = &now
-		err = uc.TransactionRepo.UpdateTransactionState(ctx, transaction.ID, transaction)
-		if err != nil {
-			return nil, errors.Wrap(err, "error updating transaction")
+		if !alreadyPaid {
+			uc.email(ctx, entity.EmailEventPaymentConfirmed, transaction, invoice)
 		}
 	}
 
@@ -157,3 +196,31 @@ func (uc *InvoiceUsecase) UpdateInvoice(ctx context.Context, invoiceID int64, fo
 func (uc *InvoiceUsecase) uploadReceiptProof(ctx context.Context, filename string, content []byte) (string, error) {
 	return uc.Storage.Store("invoice_proofs/"+strings.ToLower(filename), content)
 }
+
+func (uc *InvoiceUsecase) notify(ctx context.Context, userID int64, event string, invoice *entity.Invoice) error {
+	return uc.Notifications.Send(ctx, userID, entity.InvoiceNotificationParams{
+		Event:         event,
+		TransactionID: invoice.TransactionID,
+		InvoiceID:     invoice.ID,
+		InvoiceCode:   invoice.InvoiceCode,
+		Amount:        invoice.CodedPrice,
+	})
+}
+
+// email sends the buyer the receipt of the invoice
+func (uc *InvoiceUsecase) email(ctx context.Context, event string, transaction *entity.Transaction, invoice *entity.Invoice) {
+	var productTitle string
+	if product, _ := uc.ProductRepo.GetProduct(ctx, transaction.ProductID); product != nil {
+		productTitle = product.Title
+	}
+
+	uc.Notifications.Email(ctx, transaction.BuyerID, entity.OrderEmailParams{
+		Event:         event,
+		TransactionID: transaction.ID,
+		ProductTitle:  productTitle,
+		Quantity:      transaction.Quantity,
+		TotalPrice:    invoice.CodedPrice,
+		InvoiceCode:   invoice.InvoiceCode,
+		PaymentMethod: invoice.PaymentMethod,
+	})
+}
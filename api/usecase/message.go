@@ -0,0 +1,382 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/storage"
+	"sejastip.id/api/util"
+)
+
+// the maximum number of messages returned to a long poll at once
+const messagePollLimit = 50
+
+// how often a long poll checks the database, to pick up the messages sent
+// through the other instances of the API
+const messagePollInterval = 2 * time.Second
+
+type MessageProvider struct {
+	ConversationRepo api.ConversationRepository
+	MessageRepo      api.MessageRepository
+	TransactionRepo  api.TransactionRepository
+	ProductRepo      api.ProductRepository
+	UserRepo         api.UserRepository
+	Notifications    *NotificationSender
+
+	Storage storage.Storage
+}
+
+type messageUsecase struct {
+	*MessageProvider
+	hub *messageHub
+}
+
+func NewMessageUsecase(pvd *MessageProvider) api.MessageUsecase {
+	return &messageUsecase{pvd, newMessageHub()}
+}
+
+// StartConversation opens the conversation of a transaction for its buyer or
+// seller, or the enquiry of the user on a product. An existing conversation
+// is returned as is
+func (uc *messageUsecase) StartConversation(ctx context.Context, userID int64, form *entity.ConversationForm) (*entity.ConversationPublic, error) {
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	conversation := &entity.Conversation{}
+	if form.TransactionID != nil {
+		transaction, err := uc.TransactionRepo.GetTransaction(ctx, *form.TransactionID)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching transaction")
+		}
+		if transaction.BuyerID != userID && transaction.SellerID != userID {
+			return nil, api.ErrChatForbidden
+		}
+
+		conversation.Scope = entity.TransactionConversationScope(transaction.ID)
+		conversation.ProductID = transaction.ProductID
+		conversation.TransactionID = &transaction.ID
+		conversation.BuyerID = transaction.BuyerID
+		conversation.SellerID = transaction.SellerID
+	} else {
+		product, err := uc.ProductRepo.GetProduct(ctx, *form.ProductID)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching product")
+		}
+		if product.SellerID == userID {
+			return nil, api.ErrEnquireOwnProduct
+		}
+
+		conversation.Scope = entity.EnquiryConversationScope(product.ID, userID)
+		conversation.ProductID = product.ID
+		conversation.BuyerID = userID
+		conversation.SellerID = product.SellerID
+	}
+
+	err := uc.ConversationRepo.UpsertConversation(ctx, conversation)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in opening conversation")
+	}
+
+	return uc.GetConversation(ctx, conversation.ID, userID)
+}
+
+// GetConversations returns a page of the conversations of the user, the most
+// recently active first
+func (uc *messageUsecase) GetConversations(ctx context.Context, userID int64, page entity.Page) ([]entity.ConversationPublic, int64, error) {
+	if err := page.Validate(&entity.FilterSpec{}); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	conversations, total, err := uc.ConversationRepo.GetUserConversations(ctx, userID, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error in fetching conversations")
+	}
+
+	conversationsPublic := []entity.ConversationPublic{}
+	for _, conversation := range conversations {
+		conversationPublic, err := uc.convertToPublic(ctx, &conversation, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		conversationsPublic = append(conversationsPublic, *conversationPublic)
+	}
+
+	return conversationsPublic, total, nil
+}
+
+func (uc *messageUsecase) GetConversation(ctx context.Context, ID, userID int64) (*entity.ConversationPublic, error) {
+	conversation, err := uc.getJoinedConversation(ctx, ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.convertToPublic(ctx, conversation, userID)
+}
+
+// SendMessage sends a message to the other participant, who is notified through
+// the waiting long polls and a push notification
+func (uc *messageUsecase) SendMessage(ctx context.Context, conversationID, userID int64, form *entity.MessageForm) (*entity.MessagePublic, error) {
+	conversation, err := uc.getJoinedConversation(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	message := &entity.Message{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Body:           form.Body,
+	}
+	if form.AttachmentFile != "" {
+		file, extension, err := util.DecodeUploadedBase64File(form.AttachmentFile)
+		if err != nil {
+			return nil, api.ValidationError(fmt.Errorf("Error parsing file: %v", err))
+		}
+
+		path := "messages/" + strings.ToLower(uuid.New().String()+extension)
+		message.Attachment, err = uc.Storage.Store(path, file)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in uploading file")
+		}
+	}
+
+	err = uc.MessageRepo.CreateMessage(ctx, message)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in sending message")
+	}
+
+	// the sender has read everything up to its own message
+	conversation.MarkRead(userID, message.ID)
+	err = uc.ConversationRepo.UpdateReadReceipts(ctx, conversationID, conversation)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating read receipts")
+	}
+
+	uc.hub.publish(conversationID)
+	uc.notifyRecipient(ctx, conversation, message)
+
+	messagePublic := message.ConvertToPublic(conversation)
+	return &messagePublic, nil
+}
+
+// GetMessages returns a page of the messages of a conversation, latest first
+func (uc *messageUsecase) GetMessages(ctx context.Context, conversationID, userID int64, page entity.Page) ([]entity.MessagePublic, int64, error) {
+	if err := page.Validate(&entity.FilterSpec{}); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	conversation, err := uc.getJoinedConversation(ctx, conversationID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages, total, err := uc.MessageRepo.GetMessages(ctx, conversationID, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error in fetching messages")
+	}
+
+	messagesPublic := []entity.MessagePublic{}
+	for _, message := range messages {
+		messagesPublic = append(messagesPublic, message.ConvertToPublic(conversation))
+	}
+
+	return messagesPublic, total, nil
+}
+
+// WaitMessages returns the messages sent after a message, oldest first. When
+// there is none yet, it waits for one until the timeout, then returns an empty list
+func (uc *messageUsecase) WaitMessages(ctx context.Context, conversationID, userID, afterID int64, timeout time.Duration) ([]entity.MessagePublic, error) {
+	if _, err := uc.getJoinedConversation(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(messagePollInterval)
+	defer ticker.Stop()
+
+	for {
+		// subscribe before checking, so a message sent in between is not missed
+		wake := uc.hub.subscribe(conversationID)
+		messages, err := uc.MessageRepo.GetMessagesAfter(ctx, conversationID, afterID, messagePollLimit)
+		if err != nil {
+			uc.hub.unsubscribe(conversationID, wake)
+			return nil, errors.Wrap(err, "error in fetching new messages")
+		}
+
+		if len(messages) > 0 {
+			uc.hub.unsubscribe(conversationID, wake)
+
+			// reload the conversation for up to date read receipts
+			conversation, err := uc.ConversationRepo.GetConversation(ctx, conversationID)
+			if err != nil {
+				return nil, errors.Wrap(err, "error fetching conversation")
+			}
+
+			messagesPublic := []entity.MessagePublic{}
+			for _, message := range messages {
+				messagesPublic = append(messagesPublic, message.ConvertToPublic(conversation))
+			}
+			return messagesPublic, nil
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+			uc.hub.unsubscribe(conversationID, wake)
+		case <-deadline.C:
+			uc.hub.unsubscribe(conversationID, wake)
+			return []entity.MessagePublic{}, nil
+		case <-ctx.Done():
+			uc.hub.unsubscribe(conversationID, wake)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// MarkRead moves the read receipt of the user up to a message of the conversation
+func (uc *messageUsecase) MarkRead(ctx context.Context, conversationID, userID int64, form *entity.ReadReceiptForm) error {
+	if err := form.Validate(); err != nil {
+		return api.ValidationError(err)
+	}
+
+	conversation, err := uc.getJoinedConversation(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+
+	message, err := uc.MessageRepo.GetMessage(ctx, form.MessageID)
+	if err != nil {
+		return errors.Wrap(err, "error fetching message")
+	}
+	if message.ConversationID != conversationID {
+		return api.ErrNotFound
+	}
+
+	conversation.MarkRead(userID, message.ID)
+	err = uc.ConversationRepo.UpdateReadReceipts(ctx, conversationID, conversation)
+	if err != nil {
+		return errors.Wrap(err, "error in updating read receipts")
+	}
+
+	return nil
+}
+
+// getJoinedConversation fetches a conversation, after checking the user takes part in it
+func (uc *messageUsecase) getJoinedConversation(ctx context.Context, ID, userID int64) (*entity.Conversation, error) {
+	conversation, err := uc.ConversationRepo.GetConversation(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching conversation")
+	}
+
+	if !conversation.IsParticipant(userID) {
+		return nil, api.ErrChatForbidden
+	}
+	return conversation, nil
+}
+
+func (uc *messageUsecase) notifyRecipient(ctx context.Context, conversation *entity.Conversation, message *entity.Message) {
+	sender, _ := uc.UserRepo.GetUser(ctx, message.SenderID)
+	if sender == nil {
+		return
+	}
+
+	recipientID := conversation.Counterpart(message.SenderID)
+	err := uc.Notifications.Send(ctx, recipientID, entity.MessageNotificationParams{
+		ConversationID: conversation.ID,
+		TransactionID:  conversation.TransactionID,
+		SenderName:     sender.Name,
+		Body:           message.Body,
+	})
+	// the message is already sent, so a notification failing to be stored is only logged
+	if err != nil {
+		log.Printf("error notifying user %d of message %d: %v", recipientID, message.ID, err)
+	}
+}
+
+func (uc *messageUsecase) convertToPublic(ctx context.Context, conversation *entity.Conversation, userID int64) (*entity.ConversationPublic, error) {
+	buyer, err := uc.UserRepo.GetUser(ctx, conversation.BuyerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching conversation buyer")
+	}
+
+	seller, err := uc.UserRepo.GetUser(ctx, conversation.SellerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching conversation seller")
+	}
+
+	unread, err := uc.MessageRepo.CountUnreadMessages(ctx, conversation.ID, userID, conversation.LastReadID(userID))
+	if err != nil {
+		return nil, errors.Wrap(err, "error in counting unread messages")
+	}
+
+	conversationPublic := conversation.ConvertToPublic(buyer, seller, unread)
+	return &conversationPublic, nil
+}
+
+// messageHub wakes up the long polls waiting on a conversation when a message is
+// sent through this instance. Polls on the other instances pick the message up
+// on their next database check
+type messageHub struct {
+	mu      sync.Mutex
+	waiters map[int64][]chan struct{}
+}
+
+func newMessageHub() *messageHub {
+	return &messageHub{waiters: map[int64][]chan struct{}{}}
+}
+
+// subscribe returns a channel closed on the next message of the conversation
+func (h *messageHub) subscribe(conversationID int64) chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan struct{})
+	h.waiters[conversationID] = append(h.waiters[conversationID], ch)
+	return ch
+}
+
+// unsubscribe forgets a channel which was not woken up
+func (h *messageHub) unsubscribe(conversationID int64, ch chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	waiters := h.waiters[conversationID]
+	for i, waiter := range waiters {
+		if waiter == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(h.waiters, conversationID)
+	} else {
+		h.waiters[conversationID] = waiters
+	}
+}
+
+// publish wakes up every poll waiting on the conversation
+func (h *messageHub) publish(conversationID int64) {
+	h.mu.Lock()
+	waiters := h.waiters[conversationID]
+	delete(h.waiters, conversationID)
+	h.mu.Unlock()
+
+	for _, ch := range waiters {
+		close(ch)
+	}
+}
@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type NotificationProvider struct {
+	NotificationRepo api.NotificationRepository
+	TemplateRepo     api.NotificationTemplateRepository
+}
+
+type notificationUsecase struct {
+	*NotificationProvider
+}
+
+func NewNotificationUsecase(pvd *NotificationProvider) api.NotificationUsecase {
+	return &notificationUsecase{pvd}
+}
+
+// GetNotifications returns a page of the inbox of the user, latest first, along
+// with the number of unread notifications
+func (uc *notificationUsecase) GetNotifications(ctx context.Context, userID int64, page entity.Page) ([]entity.NotificationPublic, int64, int64, error) {
+	if err := page.Validate(&entity.FilterSpec{}); err != nil {
+		return nil, 0, 0, api.ValidationError(err)
+	}
+
+	notifications, total, err := uc.NotificationRepo.GetUserNotifications(ctx, userID, page)
+	if err != nil {
+		return nil, 0, 0, errors.Wrap(err, "error in fetching notifications")
+	}
+
+	unread, err := uc.NotificationRepo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, 0, 0, errors.Wrap(err, "error in counting unread notifications")
+	}
+
+	notificationsPublic := []entity.NotificationPublic{}
+	for _, notification := range notifications {
+		notificationsPublic = append(notificationsPublic, notification.ConvertToPublic())
+	}
+
+	return notificationsPublic, total, unread, nil
+}
+
+func (uc *notificationUsecase) MarkRead(ctx context.Context, ID, userID int64) error {
+	notification, err := uc.NotificationRepo.GetNotification(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error fetching notification")
+	}
+
+	// notifications of other users are hidden, rather than forbidden
+	if notification.UserID != userID {
+		return api.ErrNotFound
+	}
+
+	err = uc.NotificationRepo.MarkNotificationRead(ctx, ID, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "error in marking notification as read")
+	}
+	return nil
+}
+
+// MarkAllRead marks the whole inbox of the user as read, and returns the number
+// of notifications which were unread
+func (uc *notificationUsecase) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	total, err := uc.NotificationRepo.MarkAllNotificationsRead(ctx, userID, time.Now())
+	if err != nil {
+		return 0, errors.Wrap(err, "error in marking notifications as read")
+	}
+	return total, nil
+}
+
+// GetTemplates returns the templates of every event in every language, the
+// database ones overriding the default ones
+func (uc *notificationUsecase) GetTemplates(ctx context.Context) ([]entity.NotificationTemplatePublic, error) {
+	templates, err := uc.TemplateRepo.GetNotificationTemplates(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching notification templates")
+	}
+
+	custom := map[string]entity.NotificationTemplate{}
+	for _, template := range templates {
+		custom[template.Event+":"+template.Locale] = template
+	}
+
+	templatesPublic := []entity.NotificationTemplatePublic{}
+	for _, template := range entity.DefaultNotificationTemplates {
+		if override, ok := custom[template.Event+":"+template.Locale]; ok {
+			templatesPublic = append(templatesPublic, override.ConvertToPublic(true))
+			continue
+		}
+		templatesPublic = append(templatesPublic, template.ConvertToPublic(false))
+	}
+
+	return templatesPublic, nil
+}
+
+// UpdateTemplate overrides the default template of an event in a language
+func (uc *notificationUsecase) UpdateTemplate(ctx context.Context, event, locale string, form *entity.NotificationTemplateForm) (*entity.NotificationTemplatePublic, error) {
+	if err := form.Validate(event, locale); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	template := &entity.NotificationTemplate{
+		Event:   event,
+		Locale:  locale,
+		Title:   form.Title,
+		Content: form.Content,
+	}
+	err := uc.TemplateRepo.UpsertNotificationTemplate(ctx, template)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating notification template")
+	}
+
+	templatePublic := template.ConvertToPublic(true)
+	return &templatePublic, nil
+}
+
+// ResetTemplate removes the template override of an event in a language, and
+// returns the default template used from now on
+func (uc *notificationUsecase) ResetTemplate(ctx context.Context, event, locale string) (*entity.NotificationTemplatePublic, error) {
+	err := uc.TemplateRepo.DeleteNotificationTemplate(ctx, event, locale)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in resetting notification template")
+	}
+
+	template, ok := entity.DefaultNotificationTemplate(event, locale)
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+
+	templatePublic := template.ConvertToPublic(false)
+	return &templatePublic, nil
+}
+
+// NotificationSender records notifications in the inbox of their users, written in
+// the language of the users, then pushes them to all devices of the users. It also
+// emails the users about their orders
+type NotificationSender struct {
+	NotificationRepo api.NotificationRepository
+	TemplateRepo     api.NotificationTemplateRepository
+	UserRepo         api.UserRepository
+	DeviceRepo       api.DeviceRepository
+	Notifier         api.Notifier
+	Mailer           api.Mailer
+	Transactor       api.Transactor
+}
+
+// Send notifies the user. The inbox entry and its pushes are stored together,
+// joining the transaction of the change being notified if any, so failing to
+// store them is returned to roll the change back. A notification failing to
+// render is only logged instead
+func (s *NotificationSender) Send(ctx context.Context, userID int64, params entity.NotificationParams) error {
+	if s == nil {
+		return nil
+	}
+
+	locale := entity.DefaultLocale
+	if user, _ := s.UserRepo.GetUser(ctx, userID); user != nil && user.Language != "" {
+		locale = user.Language
+	}
+
+	data, err := s.render(ctx, locale, params)
+	if err != nil {
+		log.Printf("error rendering notification %s for user %d: %v", params.NotificationEvent(), userID, err)
+		return nil
+	}
+
+	return withinTransaction(ctx, s.Transactor, func(ctx context.Context) error {
+		if err := s.store(ctx, userID, data); err != nil {
+			return err
+		}
+		return s.push(ctx, userID, data)
+	})
+}
+
+func (s *NotificationSender) store(ctx context.Context, userID int64, data entity.NotificationData) error {
+	if s.NotificationRepo == nil {
+		return nil
+	}
+
+	err := s.NotificationRepo.CreateNotification(ctx, entity.NewNotification(userID, data))
+	if err != nil {
+		return errors.Wrapf(err, "error storing notification for user %d", userID)
+	}
+	return nil
+}
+
+func (s *NotificationSender) push(ctx context.Context, userID int64, data entity.NotificationData) error {
+	if s.DeviceRepo == nil || s.Notifier == nil {
+		return nil
+	}
+
+	devices, err := s.DeviceRepo.GetUserDevices(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching devices of user %d", userID)
+	}
+
+	for _, device := range devices {
+		err := s.Notifier.PublishNotification(ctx, &entity.NotificationRequest{
+			Device: device.DeviceID,
+			UserID: userID,
+			Data:   data,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error pushing notification to device %d of user %d", device.ID, userID)
+		}
+	}
+	return nil
+}
+
+// Email sends the order email of the event to the user, unless the user opted out
+// of its category. Like notifications, failures are only logged
+func (s *NotificationSender) Email(ctx context.Context, userID int64, params entity.OrderEmailParams) {
+	if s == nil || s.Mailer == nil {
+		return
+	}
+
+	user, err := s.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		log.Printf("error fetching user %d to email: %v", userID, err)
+		return
+	}
+	if user.DeletedAt != nil || user.Email == "" || !user.AcceptsEmail(entity.EmailCategory(params.Event)) {
+		return
+	}
+
+	params.RecipientName = user.Name
+	email, err := entity.RenderOrderEmail(user.Email, user.Language, params)
+	if err != nil {
+		log.Printf("error rendering email %s for user %d: %v", params.Event, userID, err)
+		return
+	}
+
+	err = s.Mailer.SendEmail(ctx, email)
+	if err != nil {
+		log.Printf("error sending email %s to user %d: %v", params.Event, userID, err)
+	}
+}
+
+// render renders the notification with the template of its event in the language.
+// A template edited in the database overrides the default one, unless it is broken
+func (s *NotificationSender) render(ctx context.Context, locale string, params entity.NotificationParams) (entity.NotificationData, error) {
+	event := params.NotificationEvent()
+	if s.TemplateRepo != nil {
+		template, err := s.TemplateRepo.GetNotificationTemplate(ctx, event, locale)
+		if err != nil && err != api.ErrNotFound {
+			log.Printf("error fetching notification template %s (%s): %v", event, locale, err)
+		}
+		if template != nil {
+			data, err := template.Render(params)
+			if err == nil {
+				return data, nil
+			}
+			log.Printf("error rendering notification template %s (%s), using the default one: %v", event, locale, err)
+		}
+	}
+
+	template, ok := entity.DefaultNotificationTemplate(event, locale)
+	if !ok {
+		return entity.NotificationData{}, errors.Errorf("no template for notification %s", event)
+	}
+	return template.Render(params)
+}
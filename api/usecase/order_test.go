@@ -0,0 +1,297 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+type orderTransactionRepo struct {
+	api.TransactionRepository
+	tx           *memoryTransactor
+	transactions map[int64]entity.Transaction
+	err          error
+}
+
+func (r *orderTransactionRepo) CreateTransaction(ctx context.Context, transaction *entity.Transaction) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.tx.write(ctx, "transactions"); err != nil {
+		return err
+	}
+	transaction.ID = int64(len(r.transactions) + 1)
+	r.transactions[transaction.ID] = *transaction
+	return nil
+}
+
+func (r *orderTransactionRepo) GetTransaction(ctx context.Context, ID int64) (*entity.Transaction, error) {
+	transaction, ok := r.transactions[ID]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return &transaction, nil
+}
+
+func (r *orderTransactionRepo) UpdateTransactionState(ctx context.Context, ID int64, transaction *entity.Transaction) error {
+	if err := r.tx.write(ctx, "transactions"); err != nil {
+		return err
+	}
+	r.transactions[ID] = *transaction
+	return nil
+}
+
+// orderVariantRepo records the stock moves, which must be made inside the transaction
+type orderVariantRepo struct {
+	api.ProductVariantRepository
+	tx       *memoryTransactor
+	variants []entity.ProductVariant
+}
+
+func (r *orderVariantRepo) GetProductVariants(ctx context.Context, productID int64) ([]entity.ProductVariant, error) {
+	return append([]entity.ProductVariant{}, r.variants...), nil
+}
+
+func (r *orderVariantRepo) GetVariant(ctx context.Context, ID int64) (*entity.ProductVariant, error) {
+	for _, variant := range r.variants {
+		if variant.ID == ID {
+			return &variant, nil
+		}
+	}
+	return nil, api.ErrNotFound
+}
+
+func (r *orderVariantRepo) ReserveVariantStock(ctx context.Context, ID int64, quantity uint) error {
+	for _, variant := range r.variants {
+		if variant.ID == ID && variant.Stock != nil && *variant.Stock < quantity {
+			return api.ErrVariantOutOfStock
+		}
+	}
+	return r.tx.write(ctx, "reserve stock")
+}
+
+func (r *orderVariantRepo) ReleaseVariantStock(ctx context.Context, ID int64, quantity uint) error {
+	return r.tx.write(ctx, "release stock")
+}
+
+type orderAddressRepo struct {
+	api.UserAddressRepository
+}
+
+func (r *orderAddressRepo) GetUserAddress(ctx context.Context, ID int64) (*entity.UserAddress, error) {
+	return &entity.UserAddress{ID: ID, UserID: 5}, nil
+}
+
+type orderShippingRepo struct {
+	fakeShippingRepo
+}
+
+func (r *orderShippingRepo) GetShipping(ctx context.Context, transactionID int64) (*entity.TransactionShipping, error) {
+	return nil, api.ErrNotFound
+}
+
+// orderOutboxRepo queues the messages in the transaction of the order
+type orderOutboxRepo struct {
+	api.OutboxRepository
+	tx  *memoryTransactor
+	err error
+}
+
+func (r *orderOutboxRepo) InsertOutboxMessage(ctx context.Context, message *entity.OutboxMessage) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.tx.write(ctx, "outbox "+message.Topic)
+}
+
+func uintPtr(i uint) *uint {
+	return &i
+}
+
+type orderTestSuite struct {
+	suite.Suite
+	tx           *memoryTransactor
+	products     *ownedProductRepo
+	transactions *orderTransactionRepo
+	outbox       *orderOutboxRepo
+	uc           api.TransactionUsecase
+}
+
+func (s *orderTestSuite) SetupTest() {
+	verifiedAt := time.Now()
+	s.tx = &memoryTransactor{}
+	s.products = &ownedProductRepo{product: entity.Product{
+		ID:        3,
+		SellerID:  2,
+		Title:     "Tokyo Banana",
+		Price:     100000,
+		CountryID: 1,
+		ToDate:    time.Now().Add(24 * time.Hour),
+	}}
+	s.transactions = &orderTransactionRepo{tx: s.tx, transactions: map[int64]entity.Transaction{}}
+	s.outbox = &orderOutboxRepo{tx: s.tx}
+	outbox := usecase.NewOutboxUsecase(&usecase.OutboxProvider{OutboxRepo: s.outbox})
+	users := newMemoryUserRepo(
+		entity.User{ID: 2, Name: "Seller", EmailVerifiedAt: &verifiedAt, PhoneVerifiedAt: &verifiedAt},
+		entity.User{ID: 5, Name: "Buyer", EmailVerifiedAt: &verifiedAt, PhoneVerifiedAt: &verifiedAt},
+	)
+	s.uc = usecase.NewTransactionUsecase(&usecase.TransactionProvider{
+		TransactionRepo: s.transactions,
+		ShippingRepo:    &orderShippingRepo{},
+		UserRepo:        users,
+		ProductRepo:     s.products,
+		VariantRepo: &orderVariantRepo{tx: s.tx, variants: []entity.ProductVariant{
+			{ID: 1, ProductID: 3, Options: entity.VariantOptions{{Name: "Isi", Value: "8"}}, Stock: uintPtr(5)},
+			{ID: 2, ProductID: 3, Options: entity.VariantOptions{{Name: "Isi", Value: "12"}}, Price: uintPtr(140000), Stock: uintPtr(1)},
+		}},
+		AddressRepo: &orderAddressRepo{},
+		CountryRepo: &accountCountryRepo{},
+		Notifications: &usecase.NotificationSender{
+			UserRepo:   users,
+			DeviceRepo: &fakeDeviceRepo{},
+			Notifier:   usecase.NewOutboxNotifier(outbox),
+			Transactor: s.tx,
+		},
+		Events:     usecase.NewOutboxEventPublisher(outbox),
+		Transactor: s.tx,
+	})
+}
+
+func (s *orderTestSuite) order(variantID int64, quantity uint) (*entity.TransactionPublic, error) {
+	return s.uc.CreateTransaction(context.Background(), &entity.TransactionForm{
+		ProductID: 3,
+		VariantID: &variantID,
+		AddressID: 7,
+		Quantity:  quantity,
+	}, 5)
+}
+
+func (s *orderTestSuite) TestVariantIsPricedOnItsOwn() {
+	transaction, err := s.order(2, 1)
+	s.Require().NoError(err)
+	s.Equal(int64(140000), transaction.TotalPrice)
+	s.Equal(uint(140000), transaction.Variant.Price)
+
+	// variants without a price use the price of the product
+	transaction, err = s.order(1, 2)
+	s.Require().NoError(err)
+	s.Equal(int64(200000), transaction.TotalPrice)
+}
+
+func (s *orderTestSuite) TestStockIsReservedWithTheOrder() {
+	_, err := s.order(1, 2)
+
+	s.NoError(err)
+	s.Equal([]string{"reserve stock", "transactions"}, s.tx.committed[:2])
+}
+
+func (s *orderTestSuite) TestEventAndPushesAreQueuedWithTheOrder() {
+	_, err := s.order(1, 2)
+
+	s.NoError(err)
+	s.Equal([]string{
+		"reserve stock",
+		"transactions",
+		"outbox " + entity.EventTopic(entity.TransactionCreatedV1{}),
+		"outbox " + entity.TopicPushNotification,
+		"outbox " + entity.TopicPushNotification,
+	}, s.tx.committed)
+}
+
+func (s *orderTestSuite) TestFailedOutboxInsertRollsTheOrderBack() {
+	s.outbox.err = errors.New("connection reset")
+
+	_, err := s.order(1, 2)
+
+	s.Error(err)
+	// neither the order nor its reservation outlive the lost event
+	s.Empty(s.tx.committed)
+}
+
+func (s *orderTestSuite) TestOutOfStockVariantIsNotOrdered() {
+	_, err := s.order(2, 2)
+
+	s.Equal(api.ErrVariantOutOfStock, errors.Cause(err))
+	s.Empty(s.tx.committed)
+	s.Empty(s.transactions.transactions)
+}
+
+func (s *orderTestSuite) TestFailedOrderRollsTheReservationBack() {
+	s.transactions.err = errors.New("connection reset")
+
+	_, err := s.order(1, 2)
+
+	s.Error(err)
+	// nothing to release by hand, the reservation was never committed
+	s.Empty(s.tx.committed)
+}
+
+func (s *orderTestSuite) TestCancelledOrderReleasesTheStock() {
+	_, err := s.order(1, 2)
+	s.Require().NoError(err)
+	s.tx.committed = nil
+
+	sellerCtx := context.WithValue(context.Background(), api.ContextKeyName, entity.ResourceClaims{ID: 2})
+	err = s.uc.UpdateTransaction(sellerCtx, 1, &entity.UpdateTransactionForm{Status: "rejected"})
+
+	s.NoError(err)
+	s.Equal([]string{"transactions", "release stock"}, s.tx.committed[:2])
+}
+
+func (s *orderTestSuite) TestCancelledOrderIsNotReopened() {
+	_, err := s.order(1, 2)
+	s.Require().NoError(err)
+
+	sellerCtx := context.WithValue(context.Background(), api.ContextKeyName, entity.ResourceClaims{ID: 2})
+	s.Require().NoError(s.uc.UpdateTransaction(sellerCtx, 1, &entity.UpdateTransactionForm{Status: "rejected"}))
+	s.tx.committed = nil
+
+	err = s.uc.UpdateTransaction(sellerCtx, 1, &entity.UpdateTransactionForm{Status: "paid"})
+
+	// rejecting it again would release the same stock twice
+	s.Equal(api.ErrInvalidTransactionStateTransition, err)
+	s.Empty(s.tx.committed)
+	s.Equal(entity.TransactionStatusRejected, s.transactions.transactions[1].Status)
+}
+
+func (s *orderTestSuite) TestClosedProductIsNotOrdered() {
+	s.products.product.Status = entity.ProductStatusClosed
+
+	_, err := s.order(1, 1)
+
+	s.Equal(api.ErrProductClosed, err)
+	s.Empty(s.tx.committed)
+}
+
+func (s *orderTestSuite) TestProductPastItsWindowIsNotOrdered() {
+	// the closing job has not run yet
+	s.products.product.ToDate = time.Now().AddDate(0, 0, -1)
+
+	_, err := s.order(1, 1)
+
+	s.Equal(api.ErrProductClosed, err)
+	s.Empty(s.tx.committed)
+}
+
+func (s *orderTestSuite) TestReservedProductIsOnlyOrderedByItsBuyer() {
+	s.products.product.ReservedFor = int64Ptr(6)
+
+	_, err := s.order(1, 1)
+
+	s.Equal(api.ErrProductReserved, err)
+	s.Empty(s.tx.committed)
+
+	s.products.product.ReservedFor = int64Ptr(5)
+	_, err = s.order(1, 1)
+	s.NoError(err)
+}
+
+func TestOrder(t *testing.T) {
+	suite.Run(t, new(orderTestSuite))
+}
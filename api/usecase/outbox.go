@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+const (
+	// outboxBatchSize is how many messages a relay run claims at most
+	outboxBatchSize = 100
+	// outboxLease is how long a claimed message is hidden from other relays
+	outboxLease = time.Minute
+)
+
+type OutboxProvider struct {
+	OutboxRepo api.OutboxRepository
+	Publisher  api.MessagePublisher
+
+	// MaxAttempts is how many times a message is published before it is dead-lettered
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every next one
+	// up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type outboxUsecase struct {
+	*OutboxProvider
+}
+
+func NewOutboxUsecase(pvd *OutboxProvider) api.OutboxUsecase {
+	return &outboxUsecase{pvd}
+}
+
+// Enqueue queues a message to be published to the topic by the relay. Called with
+// the context of a database transaction, the message is queued in the transaction
+func (uc *outboxUsecase) Enqueue(ctx context.Context, topic string, payload interface{}) error {
+	message, err := entity.NewOutboxMessage(topic, payload)
+	if err != nil {
+		return errors.Wrap(err, "error encoding outbox message")
+	}
+
+	err = uc.OutboxRepo.InsertOutboxMessage(ctx, message)
+	if err != nil {
+		return errors.Wrap(err, "error inserting outbox message")
+	}
+	return nil
+}
+
+// RelayMessages publishes the messages due for an attempt, then returns how many
+// were published. Failed messages are retried later, until they run out of attempts
+func (uc *outboxUsecase) RelayMessages(ctx context.Context) (int64, error) {
+	now := time.Now()
+	messages, err := uc.OutboxRepo.ClaimOutboxMessages(ctx, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "error claiming outbox messages")
+	}
+
+	var total int64
+	for i := range messages {
+		message := &messages[i]
+		err := uc.Publisher.Publish(ctx, message.Topic, []byte(message.Payload))
+		if err != nil {
+			message.MarkFailed(err, time.Now(), uc.MaxAttempts, uc.Backoff, uc.MaxBackoff)
+		} else {
+			message.MarkPublished(time.Now())
+			total++
+		}
+
+		err = uc.OutboxRepo.UpdateOutboxMessage(ctx, message.ID, message)
+		if err != nil {
+			return total, errors.Wrap(err, "error updating outbox message")
+		}
+	}
+
+	return total, nil
+}
+
+// GetMessages returns a page of the outbox messages having the status, latest first.
+// Failed messages are listed when no status is provided
+func (uc *outboxUsecase) GetMessages(ctx context.Context, status string, page entity.Page) ([]entity.OutboxMessagePublic, int64, error) {
+	if err := page.Validate(&entity.FilterSpec{}); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	if status == "" {
+		status = "failed"
+	}
+	outboxStatus, ok := entity.ParseOutboxStatus(status)
+	if !ok {
+		return nil, 0, api.CustomValidationError("Status %s tidak dikenal", status)
+	}
+
+	messages, total, err := uc.OutboxRepo.GetOutboxMessages(ctx, outboxStatus, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error fetching outbox messages")
+	}
+
+	messagesPublic := []entity.OutboxMessagePublic{}
+	for _, message := range messages {
+		messagesPublic = append(messagesPublic, message.ConvertToPublic())
+	}
+
+	return messagesPublic, total, nil
+}
+
+// ReplayMessage queues a dead-lettered message again, with fresh attempts
+func (uc *outboxUsecase) ReplayMessage(ctx context.Context, ID int64) (*entity.OutboxMessagePublic, error) {
+	message, err := uc.OutboxRepo.GetOutboxMessage(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching outbox message")
+	}
+
+	if message.Status != entity.OutboxStatusFailed {
+		return nil, api.CustomValidationError("Hanya pesan yang gagal yang dapat dikirim ulang")
+	}
+
+	message.Replay(time.Now())
+	err = uc.OutboxRepo.UpdateOutboxMessage(ctx, ID, message)
+	if err != nil {
+		return nil, errors.Wrap(err, "error replaying outbox message")
+	}
+
+	messagePublic := message.ConvertToPublic()
+	return &messagePublic, nil
+}
+
+// OutboxNotifier queues push notifications in the outbox instead of publishing
+// them during the request
+type OutboxNotifier struct {
+	Outbox api.OutboxUsecase
+}
+
+func NewOutboxNotifier(outbox api.OutboxUsecase) *OutboxNotifier {
+	return &OutboxNotifier{outbox}
+}
+
+func (n *OutboxNotifier) PublishNotification(ctx context.Context, notification *entity.NotificationRequest) error {
+	return n.Outbox.Enqueue(ctx, entity.TopicPushNotification, notification)
+}
+
+// withinTransaction runs fn in a database transaction, so the messages queued by fn
+// are only published once its changes are committed. Without a transactor, fn
+// simply runs on its own
+func withinTransaction(ctx context.Context, transactor api.Transactor, fn func(ctx context.Context) error) error {
+	if transactor == nil {
+		return fn(ctx)
+	}
+	return transactor.WithinTransaction(ctx, fn)
+}
@@ -0,0 +1,138 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+// fakeOutboxRepo keeps the messages in memory, claiming ignores the lease
+type fakeOutboxRepo struct {
+	api.OutboxRepository
+	messages map[int64]entity.OutboxMessage
+}
+
+func (r *fakeOutboxRepo) ClaimOutboxMessages(ctx context.Context, now, leaseUntil time.Time, limit int) ([]entity.OutboxMessage, error) {
+	results := []entity.OutboxMessage{}
+	for _, message := range r.messages {
+		if message.Status == entity.OutboxStatusPending && !message.NextAttemptAt.After(now) {
+			results = append(results, message)
+		}
+	}
+	return results, nil
+}
+
+func (r *fakeOutboxRepo) GetOutboxMessage(ctx context.Context, ID int64) (*entity.OutboxMessage, error) {
+	message, ok := r.messages[ID]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return &message, nil
+}
+
+func (r *fakeOutboxRepo) UpdateOutboxMessage(ctx context.Context, ID int64, message *entity.OutboxMessage) error {
+	r.messages[ID] = *message
+	return nil
+}
+
+type fakePublisher struct {
+	err       error
+	published [][]byte
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, topic string, data []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, data)
+	return nil
+}
+
+type outboxTestSuite struct {
+	suite.Suite
+	repo      *fakeOutboxRepo
+	publisher *fakePublisher
+	uc        api.OutboxUsecase
+}
+
+func (s *outboxTestSuite) SetupTest() {
+	s.repo = &fakeOutboxRepo{messages: map[int64]entity.OutboxMessage{
+		1: {ID: 1, Topic: entity.TopicPushNotification, Payload: `{"device":"phone"}`, NextAttemptAt: time.Now()},
+	}}
+	s.publisher = &fakePublisher{}
+	s.uc = usecase.NewOutboxUsecase(&usecase.OutboxProvider{
+		OutboxRepo:  s.repo,
+		Publisher:   s.publisher,
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+		MaxBackoff:  time.Hour,
+	})
+}
+
+func (s *outboxTestSuite) TestRelayPublishesDueMessages() {
+	total, err := s.uc.RelayMessages(context.Background())
+
+	s.NoError(err)
+	s.Equal(int64(1), total)
+	s.Equal([][]byte{[]byte(`{"device":"phone"}`)}, s.publisher.published)
+	s.Equal(entity.OutboxStatusPublished, s.repo.messages[1].Status)
+	s.NotNil(s.repo.messages[1].PublishedAt)
+}
+
+func (s *outboxTestSuite) TestFailedMessageIsRetriedWithBackoffThenDeadLettered() {
+	s.publisher.err = errors.New("pubsub unavailable")
+
+	_, err := s.uc.RelayMessages(context.Background())
+	s.NoError(err)
+	message := s.repo.messages[1]
+	s.Equal(entity.OutboxStatusPending, message.Status)
+	s.Equal("pubsub unavailable", message.LastError)
+	s.WithinDuration(time.Now().Add(time.Minute), message.NextAttemptAt, time.Second)
+
+	// the second retry waits twice as long
+	message.NextAttemptAt = time.Now()
+	s.repo.messages[1] = message
+	s.uc.RelayMessages(context.Background())
+	message = s.repo.messages[1]
+	s.WithinDuration(time.Now().Add(2*time.Minute), message.NextAttemptAt, time.Second)
+
+	// the last attempt dead-letters it
+	message.NextAttemptAt = time.Now()
+	s.repo.messages[1] = message
+	s.uc.RelayMessages(context.Background())
+	s.Equal(entity.OutboxStatusFailed, s.repo.messages[1].Status)
+	s.Equal(3, s.repo.messages[1].Attempts)
+
+	// and it is not relayed anymore
+	s.publisher.err = nil
+	total, _ := s.uc.RelayMessages(context.Background())
+	s.Zero(total)
+}
+
+func (s *outboxTestSuite) TestReplayOnlyFailedMessages() {
+	_, err := s.uc.ReplayMessage(context.Background(), 1)
+	s.Error(err)
+
+	message := s.repo.messages[1]
+	message.Status = entity.OutboxStatusFailed
+	message.Attempts = 3
+	s.repo.messages[1] = message
+
+	replayed, err := s.uc.ReplayMessage(context.Background(), 1)
+	s.NoError(err)
+	s.Equal("pending", replayed.Status)
+	s.Zero(replayed.Attempts)
+
+	total, _ := s.uc.RelayMessages(context.Background())
+	s.Equal(int64(1), total)
+}
+
+func TestOutbox(t *testing.T) {
+	suite.Run(t, new(outboxTestSuite))
+}
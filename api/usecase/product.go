@@ -2,19 +2,37 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	"sejastip.id/api"
 	"sejastip.id/api/entity"
 	"sejastip.id/api/storage"
+	"sejastip.id/api/util"
 )
 
+// the maximum number of search hits considered when searching products
+const maxProductSearchResults = 1000
+
+// the number of products loaded at once when rebuilding the search index
+const searchIndexBatchSize = 500
+
 type ProductProvider struct {
-	ProductRepo api.ProductRepository
-	UserRepo    api.UserRepository
-	CountryRepo api.CountryRepository
+	ProductRepo      api.ProductRepository
+	ProductImageRepo api.ProductImageRepository
+	CategoryRepo     api.CategoryRepository
+	TripRepo         api.TripRepository
+	VariantRepo      api.ProductVariantRepository
+	UserRepo         api.UserRepository
+	CountryRepo      api.CountryRepository
+	SearchIndex      api.ProductSearchIndex
+	Events           api.EventPublisher
+	Transactor       api.Transactor
 
 	Storage storage.Storage
 }
@@ -28,18 +46,76 @@ func NewProductUsecase(pvd *ProductProvider) api.ProductUsecase {
 }
 
 func (uc *productUsecase) CreateProduct(ctx context.Context, product *entity.Product) (*entity.ProductPublic, error) {
+	// products listed for a trip are sold in its country, within its window
+	if product.TripID != nil && *product.TripID > 0 {
+		if _, err := uc.attachTrip(ctx, product, *product.TripID, product.SellerID); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := product.ValidateCreate(); err != nil {
 		return nil, api.ValidationError(err)
 	}
-	_, err := uc.Provider.CountryRepo.GetCountry(ctx, product.CountryID)
+
+	// only sellers with verified contact details may list products
+	seller, err := uc.Provider.UserRepo.GetUser(ctx, product.SellerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching seller")
+	}
+	if !seller.IsVerified() {
+		return nil, api.ErrUnverifiedContact
+	}
+
+	_, err = uc.Provider.CountryRepo.GetCountry(ctx, product.CountryID)
 	if err != nil {
 		return nil, err
 	}
 
-	err = uc.Provider.ProductRepo.CreateProduct(ctx, product)
+	if err := uc.validateCategory(ctx, product.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if err := validateVariants(product.Variants); err != nil {
+		return nil, err
+	}
+
+	// the product is listed along with its details and the event of its listing
+	err = withinTransaction(ctx, uc.Provider.Transactor, func(ctx context.Context) error {
+		err := uc.Provider.ProductRepo.CreateProduct(ctx, product)
+		if err != nil {
+			return errors.Wrap(err, "error in creating product")
+		}
+
+		if len(product.Tags) > 0 {
+			err = uc.Provider.ProductRepo.SetProductTags(ctx, product.ID, product.Tags)
+			if err != nil {
+				return errors.Wrap(err, "error in saving product tags")
+			}
+		}
+
+		for i := range product.Variants {
+			product.Variants[i].ProductID = product.ID
+			err = uc.Provider.VariantRepo.CreateVariant(ctx, &product.Variants[i])
+			if err != nil {
+				return errors.Wrap(err, "error in saving product variant")
+			}
+		}
+
+		// images uploaded along with the product, the first one is the cover
+		for i := range product.Images {
+			product.Images[i].ProductID = product.ID
+			product.Images[i].Position = i
+			err = uc.Provider.ProductImageRepo.CreateImage(ctx, &product.Images[i])
+			if err != nil {
+				return errors.Wrap(err, "error in saving product image")
+			}
+		}
+		return publishEvent(ctx, uc.Provider.Events, entity.NewProductListed(product))
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "error in creating product")
+		return nil, err
 	}
+	uc.indexProduct(ctx, product)
 
 	user, country, _ := uc.fetchProductAdditionalInfo(ctx, *product)
 
@@ -47,14 +123,43 @@ func (uc *productUsecase) CreateProduct(ctx context.Context, product *entity.Pro
 	return &productPublic, nil
 }
 
-func (uc *productUsecase) GetProductsByFilter(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]entity.ProductPublic, int64, error) {
-	products, count, err := uc.Provider.ProductRepo.GetProductsByFilter(ctx, filter, limit, offset)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "error in fetching products by filter")
+func (uc *productUsecase) GetProductsByFilter(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.ProductPublic, int64, error) {
+	if err := page.Validate(spec); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	if err := uc.expandCategoryFilters(ctx, spec); err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		products []entity.Product
+		count    int64
+		err      error
+	)
+	if spec.Search != "" {
+		// search results are ranked by relevance instead of recency
+		ids, err := uc.Provider.SearchIndex.SearchProducts(ctx, spec.Search, maxProductSearchResults)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "error in searching products")
+		}
+		products, count, err = uc.Provider.ProductRepo.GetRankedProductsByFilter(ctx, ids, spec, page)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "error in fetching searched products")
+		}
+	} else {
+		products, count, err = uc.Provider.ProductRepo.GetProductsByFilter(ctx, spec, page)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "error in fetching products by filter")
+		}
 	}
 
 	publicProducts := []entity.ProductPublic{}
 	for _, product := range products {
+		if err := uc.loadProductRelations(ctx, &product); err != nil {
+			return nil, count, err
+		}
+
 		user, country, err := uc.fetchProductAdditionalInfo(ctx, product)
 		if err != nil {
 			return nil, count, err
@@ -72,6 +177,10 @@ func (uc *productUsecase) GetProduct(ctx context.Context, ID int64) (*entity.Pro
 		return nil, errors.Wrap(err, "error in fetching product")
 	}
 
+	if err := uc.loadProductRelations(ctx, product); err != nil {
+		return nil, err
+	}
+
 	user, country, err := uc.fetchProductAdditionalInfo(ctx, *product)
 	if err != nil {
 		return nil, err
@@ -92,11 +201,121 @@ func (uc *productUsecase) UpdateProduct(ctx context.Context, productID, userID i
 		return nil, api.ErrEditProductForbidden
 	}
 
+	if err := uc.validateCategory(ctx, newProduct.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if newProduct.TripID != nil {
+		if _, err := uc.attachTrip(ctx, newProduct, *newProduct.TripID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// tags are only replaced when provided
+	newProduct.Tags = entity.NormalizeTags(newProduct.Tags)
+	if err := entity.ValidateTags(newProduct.Tags); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
 	err = uc.Provider.ProductRepo.UpdateProduct(ctx, productID, newProduct)
 	if err != nil {
 		return nil, errors.Wrap(err, "error in updating product")
 	}
 
+	if newProduct.Tags != nil {
+		err = uc.Provider.ProductRepo.SetProductTags(ctx, productID, newProduct.Tags)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in updating product tags")
+		}
+	}
+
+	updatedProduct, err := uc.Provider.ProductRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching updated product")
+	}
+	uc.indexProduct(ctx, updatedProduct)
+
+	return uc.GetProduct(ctx, productID)
+}
+
+// PatchProduct updates the fields provided in the form. A new image replaces
+// the cover image, whose file is deleted from the storage
+func (uc *productUsecase) PatchProduct(ctx context.Context, productID, userID int64, form *entity.ProductUpdateForm) (*entity.ProductPublic, error) {
+	// check first if the product is owned by the user
+	product, err := uc.Provider.ProductRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching product")
+	}
+
+	if product.SellerID != userID {
+		return nil, api.ErrEditProductForbidden
+	}
+
+	form.Normalize()
+	if err := form.ValidateUpdate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	if form.CountryID != nil {
+		_, err = uc.Provider.CountryRepo.GetCountry(ctx, *form.CountryID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uc.validateCategory(ctx, form.CategoryID.Value); err != nil {
+		return nil, err
+	}
+
+	// the country and the offering window of a trip product follow the trip
+	tripID := product.TripID
+	if form.TripID != nil {
+		tripID = form.TripID
+	}
+	if tripID != nil && (form.CountryID != nil || form.FromDate != nil || form.ToDate != nil) {
+		return nil, api.CustomValidationError("Negara dan waktu penawaran produk mengikuti perjalanannya")
+	}
+
+	changes := form.Apply(product)
+	if form.TripID != nil {
+		tripChanges, err := uc.attachTrip(ctx, product, *form.TripID, userID)
+		if err != nil {
+			return nil, err
+		}
+		for column, value := range tripChanges {
+			changes[column] = value
+		}
+	}
+	if product.FromDate.After(product.ToDate) {
+		return nil, api.CustomValidationError("Waktu mulai penawaran tidak boleh setelah waktu akhir penawaran")
+	}
+
+	if len(changes) > 0 {
+		err = uc.Provider.ProductRepo.PatchProduct(ctx, productID, changes)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in updating product")
+		}
+	}
+
+	if form.Tags != nil {
+		err = uc.Provider.ProductRepo.SetProductTags(ctx, productID, form.Tags)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in updating product tags")
+		}
+	}
+
+	if form.ImageFile != nil {
+		if err := uc.replaceProductCover(ctx, productID, *form.ImageFile); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedProduct, err := uc.Provider.ProductRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching updated product")
+	}
+	uc.indexProduct(ctx, updatedProduct)
+
 	return uc.GetProduct(ctx, productID)
 }
 
@@ -118,11 +337,478 @@ func (uc *productUsecase) DeleteProduct(ctx context.Context, productID, userID i
 		return errors.Wrap(err, "error in deleting product")
 	}
 
+	if err := uc.Provider.SearchIndex.RemoveProduct(ctx, productID); err != nil {
+		log.Printf("error removing product %d from search index: %v", productID, err)
+	}
+
 	return nil
 }
 
-func (u *productUsecase) UploadProductImage(ctx context.Context, filename string, content []byte) (string, error) {
-	return u.Provider.Storage.Store("products/"+strings.ToLower(filename), content)
+// RebuildSearchIndex reindexes every product listing, and returns the number of indexed products
+func (uc *productUsecase) RebuildSearchIndex(ctx context.Context) (int, error) {
+	// a stable order, so products updated in the meantime are not skipped.
+	// Closed products are indexed too, listings filter them out
+	spec := &entity.FilterSpec{Sort: []entity.SortOrder{{Column: "id"}}}
+	spec.Where("availability", entity.FilterOperatorEq, int64(entity.ProductAvailabilityAll))
+	products := []entity.Product{}
+	for offset := 0; ; offset += searchIndexBatchSize {
+		page := entity.Page{Limit: searchIndexBatchSize, Offset: offset, SkipCount: true}
+		batch, _, err := uc.Provider.ProductRepo.GetProductsByFilter(ctx, spec, page)
+		if err != nil {
+			return 0, errors.Wrap(err, "error fetching products to index")
+		}
+		products = append(products, batch...)
+		if len(batch) < searchIndexBatchSize {
+			break
+		}
+	}
+
+	if err := uc.Provider.SearchIndex.Rebuild(ctx, products); err != nil {
+		return 0, errors.Wrap(err, "error rebuilding search index")
+	}
+
+	return len(products), nil
+}
+
+// CloseExpiredProducts closes the products whose offering window has ended, and
+// returns the number of closed products
+func (uc *productUsecase) CloseExpiredProducts(ctx context.Context) (int64, error) {
+	total, err := uc.Provider.ProductRepo.CloseExpiredProducts(ctx, time.Now())
+	if err != nil {
+		return 0, errors.Wrap(err, "error closing expired products")
+	}
+	return total, nil
+}
+
+// indexProduct updates the search index of a product. The index can always be
+// rebuilt, so a failure here should not fail the product write
+func (uc *productUsecase) indexProduct(ctx context.Context, product *entity.Product) {
+	if err := uc.Provider.SearchIndex.IndexProduct(ctx, product); err != nil {
+		log.Printf("error indexing product %d: %v", product.ID, err)
+	}
+}
+
+func (u *productUsecase) UploadProductImage(ctx context.Context, filename string, content []byte) (*entity.ProductImage, error) {
+	path := "products/" + strings.ToLower(filename)
+	url, err := u.Provider.Storage.Store(path, content)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.ProductImage{URL: url, Path: path}, nil
+}
+
+// AddProductImage uploads a new image of a product and appends it after the
+// current images. It returns the images of the product in their order
+func (uc *productUsecase) AddProductImage(ctx context.Context, productID, userID int64, form *entity.ProductImageForm) ([]entity.ProductImagePublic, error) {
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	images, err := uc.getOwnedProductImages(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) >= entity.MaxProductImages {
+		return nil, api.ErrTooManyProductImages
+	}
+
+	image, err := uc.uploadEncodedImage(ctx, form.ImageFile)
+	if err != nil {
+		return nil, err
+	}
+
+	image.ProductID = productID
+	image.Position = len(images)
+	err = uc.Provider.ProductImageRepo.CreateImage(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in saving product image")
+	}
+
+	return uc.syncProductCover(ctx, productID)
+}
+
+// RemoveProductImage removes an image of a product, and deletes its file from the storage
+func (uc *productUsecase) RemoveProductImage(ctx context.Context, productID, imageID, userID int64) ([]entity.ProductImagePublic, error) {
+	images, err := uc.getOwnedProductImages(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed *entity.ProductImage
+	remaining := []int64{}
+	for i := range images {
+		if images[i].ID == imageID {
+			removed = &images[i]
+			continue
+		}
+		remaining = append(remaining, images[i].ID)
+	}
+	if removed == nil {
+		return nil, api.ErrNotFound
+	}
+
+	err = uc.Provider.ProductImageRepo.DeleteImage(ctx, imageID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in deleting product image")
+	}
+
+	// close the gap left by the removed image
+	err = uc.Provider.ProductImageRepo.UpdateImagePositions(ctx, productID, remaining)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating product image positions")
+	}
+
+	uc.deleteImageFile(removed)
+
+	return uc.syncProductCover(ctx, productID)
+}
+
+// ReorderProductImages sets the order of the images of a product, the first
+// image becomes the cover
+func (uc *productUsecase) ReorderProductImages(ctx context.Context, productID, userID int64, form *entity.ProductImageOrderForm) ([]entity.ProductImagePublic, error) {
+	images, err := uc.getOwnedProductImages(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := form.Validate(images); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	err = uc.Provider.ProductImageRepo.UpdateImagePositions(ctx, productID, form.ImageIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating product image positions")
+	}
+
+	return uc.syncProductCover(ctx, productID)
+}
+
+// AddProductVariant adds a variant to a product. It returns the updated product
+func (uc *productUsecase) AddProductVariant(ctx context.Context, productID, userID int64, form *entity.ProductVariantForm) (*entity.ProductPublic, error) {
+	variants, err := uc.getOwnedProductVariants(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) >= entity.MaxProductVariants {
+		return nil, api.CustomValidationError("Produk tidak boleh memiliki lebih dari %d varian", entity.MaxProductVariants)
+	}
+
+	form.Normalize()
+	if err := form.Validate(variants); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	variant := &entity.ProductVariant{
+		ProductID: productID,
+		Options:   form.Options,
+		Price:     form.Price,
+		Stock:     form.Stock,
+	}
+	err = uc.Provider.VariantRepo.CreateVariant(ctx, variant)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in saving product variant")
+	}
+
+	return uc.GetProduct(ctx, productID)
+}
+
+// UpdateProductVariant replaces the options, price and stock of a variant. It fails
+// with ErrVariantStockChanged when an order took from the stock in the meantime
+func (uc *productUsecase) UpdateProductVariant(ctx context.Context, productID, variantID, userID int64, form *entity.ProductVariantForm) (*entity.ProductPublic, error) {
+	variants, err := uc.getOwnedProductVariants(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var variant *entity.ProductVariant
+	others := []entity.ProductVariant{}
+	for i := range variants {
+		if variants[i].ID == variantID {
+			variant = &variants[i]
+			continue
+		}
+		others = append(others, variants[i])
+	}
+	if variant == nil {
+		return nil, api.ErrNotFound
+	}
+
+	form.Normalize()
+	if err := form.Validate(others); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	previousStock := variant.Stock
+	variant.Options = form.Options
+	variant.Price = form.Price
+	variant.Stock = form.Stock
+	err = uc.Provider.VariantRepo.UpdateVariant(ctx, variantID, variant, previousStock)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating product variant")
+	}
+
+	return uc.GetProduct(ctx, productID)
+}
+
+// RemoveProductVariant removes a variant from a product. Past orders of the
+// variant keep referring to it
+func (uc *productUsecase) RemoveProductVariant(ctx context.Context, productID, variantID, userID int64) (*entity.ProductPublic, error) {
+	variants, err := uc.getOwnedProductVariants(ctx, productID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, variant := range variants {
+		if variant.ID == variantID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, api.ErrNotFound
+	}
+
+	err = uc.Provider.VariantRepo.DeleteVariant(ctx, variantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in deleting product variant")
+	}
+
+	return uc.GetProduct(ctx, productID)
+}
+
+// getOwnedProductVariants fetches the variants of a product, after checking the
+// product is owned by the user
+func (uc *productUsecase) getOwnedProductVariants(ctx context.Context, productID, userID int64) ([]entity.ProductVariant, error) {
+	product, err := uc.Provider.ProductRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching product")
+	}
+
+	if product.SellerID != userID {
+		return nil, api.ErrEditProductForbidden
+	}
+
+	variants, err := uc.Provider.VariantRepo.GetProductVariants(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching product variants")
+	}
+	return variants, nil
+}
+
+// validateVariants validates the variants of a new product against each other
+func validateVariants(variants []entity.ProductVariant) error {
+	if len(variants) > entity.MaxProductVariants {
+		return api.CustomValidationError("Produk tidak boleh memiliki lebih dari %d varian", entity.MaxProductVariants)
+	}
+
+	for i := range variants {
+		form := entity.ProductVariantForm{
+			Options: variants[i].Options,
+			Price:   variants[i].Price,
+			Stock:   variants[i].Stock,
+		}
+		form.Normalize()
+		if err := form.Validate(variants[:i]); err != nil {
+			return api.ValidationError(err)
+		}
+		variants[i].Options = form.Options
+	}
+	return nil
+}
+
+// replaceProductCover replaces the cover image of a product with a new image,
+// and deletes the file of the replaced image
+func (uc *productUsecase) replaceProductCover(ctx context.Context, productID int64, encodedFile string) error {
+	images, err := uc.Provider.ProductImageRepo.GetProductImages(ctx, productID)
+	if err != nil {
+		return errors.Wrap(err, "error in fetching product images")
+	}
+
+	image, err := uc.uploadEncodedImage(ctx, encodedFile)
+	if err != nil {
+		return err
+	}
+
+	image.ProductID = productID
+	image.Position = 0
+	err = uc.Provider.ProductImageRepo.CreateImage(ctx, image)
+	if err != nil {
+		return errors.Wrap(err, "error in saving product image")
+	}
+
+	if len(images) > 0 {
+		replaced := images[0]
+		err = uc.Provider.ProductImageRepo.DeleteImage(ctx, replaced.ID)
+		if err != nil {
+			return errors.Wrap(err, "error in deleting replaced product image")
+		}
+
+		uc.deleteImageFile(&replaced)
+	}
+
+	_, err = uc.syncProductCover(ctx, productID)
+	return err
+}
+
+// deleteImageFile deletes the file of a removed image from the storage. Images
+// migrated from the single image column have no path, it is found from their URL.
+// The image is already gone, so a failure only leaves an orphan file behind
+func (uc *productUsecase) deleteImageFile(image *entity.ProductImage) {
+	path := image.Path
+	if path == "" {
+		path = uc.Provider.Storage.PathOf(image.URL)
+	}
+	if path == "" {
+		log.Printf("error deleting product image file: no storage path for %s", image.URL)
+		return
+	}
+
+	if err := uc.Provider.Storage.Delete(path); err != nil {
+		log.Printf("error deleting product image file %s: %v", path, err)
+	}
+}
+
+// uploadEncodedImage decodes a base64 uploaded image, and stores it under a random name
+func (uc *productUsecase) uploadEncodedImage(ctx context.Context, encodedFile string) (*entity.ProductImage, error) {
+	file, extension, err := util.DecodeUploadedBase64File(encodedFile)
+	if err != nil {
+		return nil, api.ValidationError(fmt.Errorf("Error parsing file: %v", err))
+	}
+
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), extension)
+	image, err := uc.UploadProductImage(ctx, filename, file)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in uploading file")
+	}
+	return image, nil
+}
+
+// getOwnedProductImages fetches the images of a product, after checking the
+// product is owned by the user
+func (uc *productUsecase) getOwnedProductImages(ctx context.Context, productID, userID int64) ([]entity.ProductImage, error) {
+	product, err := uc.Provider.ProductRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching product")
+	}
+
+	if product.SellerID != userID {
+		return nil, api.ErrEditProductForbidden
+	}
+
+	images, err := uc.Provider.ProductImageRepo.GetProductImages(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching product images")
+	}
+	return images, nil
+}
+
+// syncProductCover copies the URL of the first image to the image column of the
+// product, which is still read by older clients, and returns the ordered images
+func (uc *productUsecase) syncProductCover(ctx context.Context, productID int64) ([]entity.ProductImagePublic, error) {
+	images, err := uc.Provider.ProductImageRepo.GetProductImages(ctx, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching product images")
+	}
+
+	cover := ""
+	if len(images) > 0 {
+		cover = images[0].URL
+	}
+	err = uc.Provider.ProductRepo.UpdateProductCover(ctx, productID, cover)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in updating product cover")
+	}
+
+	publicImages := []entity.ProductImagePublic{}
+	for _, image := range images {
+		publicImages = append(publicImages, image.ConvertToPublic())
+	}
+	return publicImages, nil
+}
+
+// loadProductRelations loads the images and tags of a product
+func (uc *productUsecase) loadProductRelations(ctx context.Context, product *entity.Product) error {
+	images, err := uc.Provider.ProductImageRepo.GetProductImages(ctx, product.ID)
+	if err != nil {
+		return errors.Wrap(err, "error in fetching product images")
+	}
+	product.Images = images
+
+	tags, err := uc.Provider.ProductRepo.GetProductTags(ctx, product.ID)
+	if err != nil {
+		return errors.Wrap(err, "error in fetching product tags")
+	}
+	product.Tags = tags
+
+	variants, err := uc.Provider.VariantRepo.GetProductVariants(ctx, product.ID)
+	if err != nil {
+		return errors.Wrap(err, "error in fetching product variants")
+	}
+	product.Variants = variants
+	return nil
+}
+
+// validateCategory checks the category of a product exists, products may have no category
+func (uc *productUsecase) validateCategory(ctx context.Context, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+
+	_, err := uc.Provider.CategoryRepo.GetCategory(ctx, *categoryID)
+	if err == api.ErrNotFound {
+		return api.CustomValidationError("Kategori produk tidak ditemukan")
+	}
+	if err != nil {
+		return errors.Wrap(err, "error in fetching product category")
+	}
+	return nil
+}
+
+// attachTrip lists a product for a trip of the user, copying the country and the
+// offering window of the trip. It returns the changed columns of the product
+func (uc *productUsecase) attachTrip(ctx context.Context, product *entity.Product, tripID, userID int64) (map[string]interface{}, error) {
+	trip, err := uc.Provider.TripRepo.GetTrip(ctx, tripID)
+	if err == api.ErrNotFound {
+		return nil, api.CustomValidationError("Perjalanan produk tidak ditemukan")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching product trip")
+	}
+
+	if trip.SellerID != userID {
+		return nil, api.ErrEditTripForbidden
+	}
+	return trip.ApplyTo(product, time.Now()), nil
+}
+
+// expandCategoryFilters replaces the categories filtered by with the categories
+// and all their descendants, so browsing a category lists its subcategories too
+func (uc *productUsecase) expandCategoryFilters(ctx context.Context, spec *entity.FilterSpec) error {
+	var tree *entity.CategoryTree
+	for i, filter := range spec.Filters {
+		if filter.Key != "category_id" {
+			continue
+		}
+
+		if tree == nil {
+			categories, err := uc.Provider.CategoryRepo.GetCategories(ctx)
+			if err != nil {
+				return errors.Wrap(err, "error in fetching categories")
+			}
+			tree = entity.NewCategoryTree(categories)
+		}
+
+		values := []interface{}{}
+		for _, value := range filter.Values {
+			for _, id := range tree.Descendants(value.(int64)) {
+				values = append(values, id)
+			}
+		}
+		spec.Filters[i].Operator = entity.FilterOperatorIn
+		spec.Filters[i].Values = values
+	}
+	return nil
 }
 
 func (uc *productUsecase) fetchProductAdditionalInfo(ctx context.Context, product entity.Product) (*entity.User, *entity.Country, error) {
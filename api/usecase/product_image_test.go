@@ -0,0 +1,194 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/usecase"
+)
+
+type memoryProductImageRepo struct {
+	api.ProductImageRepository
+	images []entity.ProductImage
+}
+
+func (r *memoryProductImageRepo) CreateImage(ctx context.Context, image *entity.ProductImage) error {
+	image.ID = int64(len(r.images) + 100)
+	r.images = append([]entity.ProductImage{*image}, r.images...)
+	return nil
+}
+
+func (r *memoryProductImageRepo) GetProductImages(ctx context.Context, productID int64) ([]entity.ProductImage, error) {
+	return append([]entity.ProductImage{}, r.images...), nil
+}
+
+func (r *memoryProductImageRepo) DeleteImage(ctx context.Context, ID int64) error {
+	for i := range r.images {
+		if r.images[i].ID == ID {
+			r.images = append(r.images[:i], r.images[i+1:]...)
+			return nil
+		}
+	}
+	return api.ErrNotFound
+}
+
+func (r *memoryProductImageRepo) UpdateImagePositions(ctx context.Context, productID int64, orderedIDs []int64) error {
+	return nil
+}
+
+type ownedProductRepo struct {
+	api.ProductRepository
+	product entity.Product
+}
+
+func (r *ownedProductRepo) GetProduct(ctx context.Context, ID int64) (*entity.Product, error) {
+	product := r.product
+	return &product, nil
+}
+
+func (r *ownedProductRepo) UpdateProductCover(ctx context.Context, ID int64, image string) error {
+	r.product.Image = image
+	return nil
+}
+
+func (r *ownedProductRepo) PatchProduct(ctx context.Context, ID int64, changes map[string]interface{}) error {
+	if categoryID, ok := changes["category_id"]; ok {
+		r.product.CategoryID = categoryID.(*int64)
+	}
+	return nil
+}
+
+func (r *ownedProductRepo) GetProductTags(ctx context.Context, productID int64) ([]string, error) {
+	return []string{}, nil
+}
+
+type noVariantRepo struct {
+	api.ProductVariantRepository
+}
+
+func (r *noVariantRepo) GetProductVariants(ctx context.Context, productID int64) ([]entity.ProductVariant, error) {
+	return []entity.ProductVariant{}, nil
+}
+
+func (i *memorySearchIndex) IndexProduct(ctx context.Context, product *entity.Product) error {
+	return nil
+}
+
+// memoryStorage keeps the files in memory, under the bucket URLs of GCS
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func (s *memoryStorage) Store(filename string, contents []byte) (string, error) {
+	s.files[filename] = contents
+	return "https://storage.googleapis.com/sejastip/" + filename, nil
+}
+
+func (s *memoryStorage) Get(filename string) ([]byte, error) {
+	return s.files[filename], nil
+}
+
+func (s *memoryStorage) Delete(filename string) error {
+	delete(s.files, filename)
+	return nil
+}
+
+func (s *memoryStorage) PathOf(url string) string {
+	if !strings.HasPrefix(url, "https://storage.googleapis.com/sejastip/") {
+		return ""
+	}
+	return strings.TrimPrefix(url, "https://storage.googleapis.com/sejastip/")
+}
+
+type productImageTestSuite struct {
+	suite.Suite
+	products *ownedProductRepo
+	images   *memoryProductImageRepo
+	storage  *memoryStorage
+	uc       api.ProductUsecase
+}
+
+func (s *productImageTestSuite) SetupTest() {
+	s.images = &memoryProductImageRepo{images: []entity.ProductImage{
+		// migrated from the single image column
+		{ID: 1, ProductID: 5, URL: "https://storage.googleapis.com/sejastip/products/legacy.jpg", Position: 0},
+		{ID: 2, ProductID: 5, URL: "https://storage.googleapis.com/sejastip/products/new.jpg", Path: "products/new.jpg", Position: 1},
+	}}
+	s.storage = &memoryStorage{files: map[string][]byte{
+		"products/legacy.jpg": []byte("legacy"),
+		"products/new.jpg":    []byte("new"),
+	}}
+	s.products = &ownedProductRepo{product: entity.Product{
+		ID:         5,
+		SellerID:   4,
+		CountryID:  1,
+		CategoryID: int64Ptr(3),
+		Image:      "https://storage.googleapis.com/sejastip/products/legacy.jpg",
+	}}
+	s.uc = usecase.NewProductUsecase(&usecase.ProductProvider{
+		ProductRepo:      s.products,
+		ProductImageRepo: s.images,
+		VariantRepo:      &noVariantRepo{},
+		UserRepo:         newMemoryUserRepo(entity.User{ID: 4}),
+		CountryRepo:      &accountCountryRepo{},
+		SearchIndex:      &memorySearchIndex{},
+		Storage:          s.storage,
+	})
+}
+
+func (s *productImageTestSuite) TestRemovingLegacyImageDeletesItsFile() {
+	images, err := s.uc.RemoveProductImage(context.Background(), 5, 1, 4)
+
+	s.NoError(err)
+	s.Len(images, 1)
+	s.NotContains(s.storage.files, "products/legacy.jpg")
+	s.Contains(s.storage.files, "products/new.jpg")
+}
+
+func (s *productImageTestSuite) TestRemovingImageDeletesItsFile() {
+	_, err := s.uc.RemoveProductImage(context.Background(), 5, 2, 4)
+
+	s.NoError(err)
+	s.NotContains(s.storage.files, "products/new.jpg")
+}
+
+func (s *productImageTestSuite) TestForeignURLIsNotDeleted() {
+	s.images.images[0].URL = "https://example.com/products/legacy.jpg"
+
+	_, err := s.uc.RemoveProductImage(context.Background(), 5, 1, 4)
+
+	s.NoError(err)
+	s.Len(s.storage.files, 2)
+}
+
+func (s *productImageTestSuite) TestReplacingLegacyCoverDeletesItsFile() {
+	imageFile := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("cover"))
+
+	product, err := s.uc.PatchProduct(context.Background(), 5, 4, &entity.ProductUpdateForm{ImageFile: &imageFile})
+
+	s.Require().NoError(err)
+	s.NotContains(s.storage.files, "products/legacy.jpg")
+	s.Contains(s.storage.files, "products/new.jpg")
+	s.Len(s.storage.files, 2)
+	s.Equal(s.images.images[0].URL, product.Image)
+}
+
+func (s *productImageTestSuite) TestCategoryIsCleared() {
+	form := entity.ProductUpdateForm{}
+	s.Require().NoError(json.Unmarshal([]byte(`{"category_id": null}`), &form))
+
+	_, err := s.uc.PatchProduct(context.Background(), 5, 4, &form)
+
+	s.NoError(err)
+	s.Nil(s.products.product.CategoryID)
+}
+
+func TestProductImage(t *testing.T) {
+	suite.Run(t, new(productImageTestSuite))
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/storage"
+	"sejastip.id/api/util"
+)
+
+type ReviewProvider struct {
+	ReviewRepo      api.ReviewRepository
+	TransactionRepo api.TransactionRepository
+	UserRepo        api.UserRepository
+	Transactor      api.Transactor
+
+	Storage storage.Storage
+}
+
+type reviewUsecase struct {
+	*ReviewProvider
+}
+
+func NewReviewUsecase(pvd *ReviewProvider) api.ReviewUsecase {
+	return &reviewUsecase{pvd}
+}
+
+// CreateReview lets the buyer of a finished transaction review it once
+func (uc *reviewUsecase) CreateReview(ctx context.Context, transactionID, userID int64, form *entity.ReviewForm) (*entity.ReviewPublic, error) {
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	transaction, err := uc.TransactionRepo.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching transaction")
+	}
+
+	if transaction.BuyerID != userID {
+		return nil, api.ErrEditReviewForbidden
+	}
+	if transaction.Status != entity.TransactionStatusFinished {
+		return nil, api.ErrTransactionNotFinished
+	}
+
+	_, err = uc.ReviewRepo.GetReviewByTransaction(ctx, transactionID)
+	if err == nil {
+		return nil, api.ErrDuplicateReview
+	}
+	if err != api.ErrNotFound {
+		return nil, errors.Wrap(err, "error in review checking")
+	}
+
+	photos := entity.ReviewPhotos{}
+	for _, photoFile := range form.PhotoFiles {
+		file, extension, err := util.DecodeUploadedBase64File(photoFile)
+		if err != nil {
+			return nil, api.ValidationError(fmt.Errorf("Error parsing file: %v", err))
+		}
+
+		path := "reviews/" + strings.ToLower(uuid.New().String()+extension)
+		url, err := uc.Storage.Store(path, file)
+		if err != nil {
+			return nil, errors.Wrap(err, "error in uploading file")
+		}
+		photos = append(photos, url)
+	}
+
+	review := &entity.Review{
+		TransactionID: transaction.ID,
+		ProductID:     transaction.ProductID,
+		BuyerID:       transaction.BuyerID,
+		SellerID:      transaction.SellerID,
+		Rating:        form.Rating,
+		Text:          form.Text,
+		Photos:        photos,
+	}
+	// the review and the rating of the seller are saved together
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		return uc.ReviewRepo.CreateReview(ctx, review)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error in creating review")
+	}
+
+	buyer, err := uc.UserRepo.GetUser(ctx, review.BuyerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching review buyer")
+	}
+
+	reviewPublic := review.ConvertToPublic(buyer)
+	return &reviewPublic, nil
+}
+
+// ReplyReview writes the reply of the seller to a review, replacing its previous reply
+func (uc *reviewUsecase) ReplyReview(ctx context.Context, reviewID, userID int64, form *entity.ReviewReplyForm) (*entity.ReviewPublic, error) {
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	review, err := uc.ReviewRepo.GetReview(ctx, reviewID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching review")
+	}
+
+	if review.SellerID != userID {
+		return nil, api.ErrEditReviewForbidden
+	}
+
+	now := time.Now()
+	review.Reply = form.Reply
+	review.RepliedAt = &now
+	err = uc.ReviewRepo.ReplyReview(ctx, reviewID, review)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in replying review")
+	}
+
+	buyer, err := uc.UserRepo.GetUser(ctx, review.BuyerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching review buyer")
+	}
+
+	reviewPublic := review.ConvertToPublic(buyer)
+	return &reviewPublic, nil
+}
+
+// GetUserReviews returns a page of the reviews a user received as a seller
+func (uc *reviewUsecase) GetUserReviews(ctx context.Context, userID int64, page entity.Page) ([]entity.ReviewPublic, int64, error) {
+	if err := page.Validate(&entity.FilterSpec{}); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	_, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error fetching user")
+	}
+
+	reviews, total, err := uc.ReviewRepo.GetSellerReviews(ctx, userID, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error in fetching user reviews")
+	}
+
+	buyers := map[int64]*entity.User{}
+	reviewsPublic := []entity.ReviewPublic{}
+	for _, review := range reviews {
+		buyer, ok := buyers[review.BuyerID]
+		if !ok {
+			buyer, err = uc.UserRepo.GetUser(ctx, review.BuyerID)
+			if err != nil {
+				return nil, 0, errors.Wrap(err, "error in fetching review buyer")
+			}
+			buyers[review.BuyerID] = buyer
+		}
+		reviewsPublic = append(reviewsPublic, review.ConvertToPublic(buyer))
+	}
+
+	return reviewsPublic, total, nil
+}
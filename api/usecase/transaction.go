@@ -2,7 +2,6 @@ package usecase
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
@@ -10,7 +9,6 @@ import (
 
 	"sejastip.id/api"
 	"sejastip.id/api/entity"
-	"sejastip.id/api/infra"
 )
 
 type TransactionProvider struct {
@@ -18,10 +16,12 @@ type TransactionProvider struct {
 	ShippingRepo    api.ShippingRepository
 	UserRepo        api.UserRepository
 	ProductRepo     api.ProductRepository
+	VariantRepo     api.ProductVariantRepository
 	AddressRepo     api.UserAddressRepository
 	CountryRepo     api.CountryRepository
-	DeviceRepo      api.DeviceRepository
-	Pubsub          *infra.PubsubClient
+	Notifications   *NotificationSender
+	Events          api.EventPublisher
+	Transactor      api.Transactor
 }
 
 type TransactionUsecase struct {
@@ -33,8 +33,12 @@ func NewTransactionUsecase(pvd *TransactionProvider) api.TransactionUsecase {
 }
 
 // GetTransactions
-func (uc *TransactionUsecase) GetTransactions(ctx context.Context, filter entity.DynamicFilter, limit, offset int) ([]*entity.TransactionPublic, int64, error) {
-	transactions, total, err := uc.TransactionRepo.GetTransactions(ctx, filter, limit, offset)
+func (uc *TransactionUsecase) GetTransactions(ctx context.Context, userID int64, spec *entity.FilterSpec, page entity.Page) ([]*entity.TransactionPublic, int64, error) {
+	if err := page.Validate(spec); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	transactions, total, err := uc.TransactionRepo.GetTransactions(ctx, userID, spec, page)
 	if err != nil {
 		return nil, 0, errors.Wrap(err, "error in fetching transactions by filter")
 	}
@@ -103,6 +107,17 @@ func (uc *TransactionUsecase) convertToPublic(ctx context.Context, transaction *
 		return nil, err
 	}
 
+	// get the ordered variant, if any
+	var variantPublic *entity.ProductVariantPublic
+	if transaction.VariantID != nil {
+		variant, err := uc.VariantRepo.GetVariant(ctx, *transaction.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		converted := variant.ConvertToPublic(product.Price)
+		variantPublic = &converted
+	}
+
 	productPublic := product.ConvertToPublic(country, seller)
 	buyerPublic := buyer.ConvertToPublic()
 	buyerAddressPublic := address.ConvertToPublic()
@@ -121,6 +136,7 @@ func (uc *TransactionUsecase) convertToPublic(ctx context.Context, transaction *
 	transactionPublic := &entity.TransactionPublic{
 		ID:           transaction.ID,
 		Product:      &productPublic,
+		Variant:      variantPublic,
 		Buyer:        buyerPublic,
 		BuyerAddress: &buyerAddressPublic,
 		Quantity:     transaction.Quantity,
@@ -148,6 +164,15 @@ func (uc *TransactionUsecase) CreateTransaction(ctx context.Context, transaction
 		return nil, api.ValidationError(err)
 	}
 
+	// only buyers with verified contact details may place orders
+	buyer, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching buyer")
+	}
+	if !buyer.IsVerified() {
+		return nil, api.ErrUnverifiedContact
+	}
+
 	// then, do product validation next
 	product, err := uc.ProductRepo.GetProduct(ctx, transactionForm.ProductID)
 	if err != nil {
@@ -157,6 +182,12 @@ func (uc *TransactionUsecase) CreateTransaction(ctx context.Context, transaction
 	if product.SellerID == userID {
 		return nil, api.ErrBuyOwnProduct
 	}
+	if product.IsClosed(time.Now()) {
+		return nil, api.ErrProductClosed
+	}
+	if product.ReservedFor != nil && *product.ReservedFor != userID {
+		return nil, api.ErrProductReserved
+	}
 
 	// next, do address validation
 	address, err := uc.AddressRepo.GetUserAddress(ctx, transactionForm.AddressID)
@@ -169,36 +200,81 @@ func (uc *TransactionUsecase) CreateTransaction(ctx context.Context, transaction
 		return nil, api.ErrTransactionAddressNotOwned
 	}
 
+	// products with variants are priced per variant, so a variant must be picked
+	price := product.Price
+	variants, err := uc.VariantRepo.GetProductVariants(ctx, product.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in variant checking")
+	}
+	var variant *entity.ProductVariant
+	if transactionForm.VariantID != nil {
+		for i := range variants {
+			if variants[i].ID == *transactionForm.VariantID {
+				variant = &variants[i]
+			}
+		}
+		if variant == nil {
+			return nil, api.CustomValidationError("Varian produk tidak ditemukan")
+		}
+		price = variant.EffectivePrice(product.Price)
+	} else if len(variants) > 0 {
+		return nil, api.CustomValidationError("Pilih varian produk terlebih dahulu")
+	}
+
 	// finally, after lots of relational validations, we create our transaction object
 	transaction := entity.Transaction{
 		ProductID:      transactionForm.ProductID,
+		VariantID:      transactionForm.VariantID,
 		BuyerID:        userID,
 		SellerID:       product.SellerID,
 		InvoiceID:      nil,
 		BuyerAddressID: transactionForm.AddressID,
 		Quantity:       transactionForm.Quantity,
 		Notes:          transactionForm.Notes,
-		TotalPrice:     int64(transactionForm.Quantity * product.Price),
-	}
-	err = uc.TransactionRepo.CreateTransaction(ctx, &transaction)
-	if err != nil {
-		return nil, errors.Wrap(err, "error creating transaction")
+		TotalPrice:     int64(transactionForm.Quantity * price),
 	}
+	// the stock is reserved and the seller is notified in the same database
+	// transaction, so both happen if and only if the order is placed
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		// reserve the stock first, so concurrent orders can't take the same items
+		if variant != nil {
+			err := uc.VariantRepo.ReserveVariantStock(ctx, variant.ID, transactionForm.Quantity)
+			if err != nil {
+				return errors.Wrap(err, "error reserving variant stock")
+			}
+		}
+
+		err := uc.TransactionRepo.CreateTransaction(ctx, &transaction)
+		if err != nil {
+			return err
+		}
+		err = publishEvent(ctx, uc.Events, entity.NewTransactionCreated(&transaction))
+		if err != nil {
+			return err
+		}
 
-	// notify
-	device, _ := uc.DeviceRepo.GetUserDevice(ctx, transaction.SellerID)
-	if device != nil {
 		if user, _ := uc.UserRepo.GetUser(ctx, transaction.SellerID); user != nil {
-			notification := &entity.NotificationRequest{
-				Device: device.DeviceID,
-				UserID: transaction.SellerID,
-			}
-			notification.Data.Title = fmt.Sprintf("Hi %s, ada transaksi baru!", user.Name)
-			notification.Data.Content = fmt.Sprintf("Ada yang ingin membeli %s dari kamu.", product.Title)
-			uc.Pubsub.PublishNotification(ctx, notification)
+			return uc.Notifications.Send(ctx, transaction.SellerID, entity.TransactionNotificationParams{
+				Event:         entity.NotificationEventTransactionCreated,
+				TransactionID: transaction.ID,
+				RecipientName: user.Name,
+				ProductTitle:  product.Title,
+			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating transaction")
 	}
 
+	uc.Notifications.Email(ctx, transaction.BuyerID, entity.OrderEmailParams{
+		Event:         entity.EmailEventOrderPlaced,
+		TransactionID: transaction.ID,
+		ProductTitle:  product.Title,
+		Quantity:      transaction.Quantity,
+		TotalPrice:    transaction.TotalPrice,
+	})
+
 	return uc.GetTransaction(ctx, transaction.ID)
 }
 
@@ -229,28 +305,127 @@ func (uc *TransactionUsecase) UpdateTransaction(ctx context.Context, transaction
 	if !ok {
 		return api.ErrInvalidTransactionStateTransition
 	}
+	// a cancelled order already gave its items back to the stock, so it can't
+	// become active again
+	if isCancelledStatus(transaction.Status) && statusInt != transaction.Status {
+		return api.ErrInvalidTransactionStateTransition
+	}
 	now := time.Now()
+	previousStatus := transaction.Status
 	transaction.Status = statusInt
 	switch transaction.Status {
 	case entity.TransactionStatusPaid:
 		transaction.PaidAt = &now
 	case entity.TransactionStatusFinished:
 		transaction.FinishedAt = &now
-	case entity.TransactionStatusDelivered:
-		shipping := &entity.TransactionShipping{
-			TransactionID: transactionID,
-			AWBNumber:     form.AWBNumber,
-			Courier:       form.Courier,
+	}
+
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		if transaction.Status == entity.TransactionStatusDelivered {
+			shipping := &entity.TransactionShipping{
+				TransactionID: transactionID,
+				AWBNumber:     form.AWBNumber,
+				Courier:       form.Courier,
+			}
+			err := uc.ShippingRepo.InsertShipping(ctx, shipping)
+			if err != nil {
+				return errors.Wrap(err, "error inserting shipping info")
+			}
 		}
-		err = uc.ShippingRepo.InsertShipping(ctx, shipping)
+
+		err := uc.TransactionRepo.UpdateTransactionState(ctx, transactionID, transaction)
 		if err != nil {
-			return errors.Wrap(err, "error inserting shipping info")
+			return errors.Wrap(err, "error updating transaction state")
 		}
-	}
-	err = uc.TransactionRepo.UpdateTransactionState(ctx, transactionID, transaction)
+
+		// cancelled orders give their items back to the variant stock
+		if transaction.VariantID != nil && isCancelledStatus(transaction.Status) && !isCancelledStatus(previousStatus) {
+			err := uc.VariantRepo.ReleaseVariantStock(ctx, *transaction.VariantID, transaction.Quantity)
+			if err != nil {
+				return errors.Wrap(err, "error releasing variant stock")
+			}
+		}
+
+		if transaction.Status != previousStatus {
+			if err := uc.notifyStatusChange(ctx, transaction, userID, form); err != nil {
+				return err
+			}
+			return publishEvent(ctx, uc.Events, entity.NewTransactionStatusChanged(transaction, previousStatus, userID, now))
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrap(err, "error updating transaction state")
+		return err
+	}
+
+	if transaction.Status != previousStatus {
+		uc.emailStatusChange(ctx, transaction, form)
 	}
 
 	return nil
 }
+
+// notifyStatusChange tells the other party of the transaction about its new status
+func (uc *TransactionUsecase) notifyStatusChange(ctx context.Context, transaction *entity.Transaction, userID int64, form *entity.UpdateTransactionForm) error {
+	product, _ := uc.ProductRepo.GetProduct(ctx, transaction.ProductID)
+	if product == nil {
+		return nil
+	}
+
+	events := map[int]string{
+		entity.TransactionStatusPaid:       entity.NotificationEventTransactionPaid,
+		entity.TransactionStatusInProgress: entity.NotificationEventTransactionInProgress,
+		entity.TransactionStatusDelivered:  entity.NotificationEventTransactionDelivered,
+		entity.TransactionStatusFinished:   entity.NotificationEventTransactionFinished,
+		entity.TransactionStatusRejected:   entity.NotificationEventTransactionRejected,
+		entity.TransactionStatusExpired:    entity.NotificationEventTransactionExpired,
+	}
+	event, ok := events[transaction.Status]
+	if !ok {
+		return nil
+	}
+
+	recipientID := transaction.BuyerID
+	if userID == transaction.BuyerID {
+		recipientID = transaction.SellerID
+	}
+
+	return uc.Notifications.Send(ctx, recipientID, entity.TransactionNotificationParams{
+		Event:         event,
+		TransactionID: transaction.ID,
+		ProductTitle:  product.Title,
+		Courier:       form.Courier,
+		AWBNumber:     form.AWBNumber,
+	})
+}
+
+// emailStatusChange emails the buyer about the shipping and the end of its order
+func (uc *TransactionUsecase) emailStatusChange(ctx context.Context, transaction *entity.Transaction, form *entity.UpdateTransactionForm) {
+	emailEvents := map[int]string{
+		entity.TransactionStatusDelivered: entity.EmailEventOrderShipped,
+		entity.TransactionStatusFinished:  entity.EmailEventOrderFinished,
+	}
+	event, ok := emailEvents[transaction.Status]
+	if !ok {
+		return
+	}
+
+	product, _ := uc.ProductRepo.GetProduct(ctx, transaction.ProductID)
+	if product == nil {
+		return
+	}
+
+	uc.Notifications.Email(ctx, transaction.BuyerID, entity.OrderEmailParams{
+		Event:         event,
+		TransactionID: transaction.ID,
+		ProductTitle:  product.Title,
+		Quantity:      transaction.Quantity,
+		TotalPrice:    transaction.TotalPrice,
+		Courier:       form.Courier,
+		AWBNumber:     form.AWBNumber,
+	})
+}
+
+func isCancelledStatus(status int) bool {
+	return status == entity.TransactionStatusRejected || status == entity.TransactionStatusExpired
+}
@@ -0,0 +1,226 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/infra"
+	"sejastip.id/api/usecase"
+)
+
+// the fakes embed their interfaces, so a call to a method which is not faked panics
+
+type fakeTransactionRepo struct {
+	api.TransactionRepository
+	transaction entity.Transaction
+}
+
+func (r *fakeTransactionRepo) GetTransaction(ctx context.Context, ID int64) (*entity.Transaction, error) {
+	transaction := r.transaction
+	return &transaction, nil
+}
+
+func (r *fakeTransactionRepo) UpdateTransactionState(ctx context.Context, ID int64, transaction *entity.Transaction) error {
+	r.transaction = *transaction
+	return nil
+}
+
+type fakeShippingRepo struct {
+	api.ShippingRepository
+}
+
+func (r *fakeShippingRepo) InsertShipping(ctx context.Context, shipping *entity.TransactionShipping) error {
+	return nil
+}
+
+type fakeProductRepo struct {
+	api.ProductRepository
+}
+
+func (r *fakeProductRepo) GetProduct(ctx context.Context, ID int64) (*entity.Product, error) {
+	return &entity.Product{ID: ID, Title: "Tokyo Banana"}, nil
+}
+
+type fakeUserRepo struct {
+	api.UserRepository
+	languages      map[int64]string
+	orderOptedOuts map[int64]bool
+}
+
+func (r *fakeUserRepo) GetUser(ctx context.Context, ID int64) (*entity.User, error) {
+	return &entity.User{
+		ID:               ID,
+		Email:            "rocky@sejastip.id",
+		Name:             "Rocky Balboa",
+		Language:         r.languages[ID],
+		OrderEmailOptOut: r.orderOptedOuts[ID],
+	}, nil
+}
+
+type fakeDeviceRepo struct {
+	api.DeviceRepository
+}
+
+func (r *fakeDeviceRepo) GetUserDevices(ctx context.Context, userID int64) ([]entity.Device, error) {
+	return []entity.Device{
+		{ID: 1, DeviceID: "phone-of-user", UserID: userID},
+		{ID: 2, DeviceID: "tablet-of-user", UserID: userID},
+	}, nil
+}
+
+type transactionNotificationTestSuite struct {
+	suite.Suite
+	notifier *infra.MemoryNotifier
+	mailer   *infra.MemoryMailer
+	events   *infra.LocalEventPublisher
+	users    *fakeUserRepo
+	uc       api.TransactionUsecase
+}
+
+func (s *transactionNotificationTestSuite) SetupTest() {
+	s.notifier = infra.NewMemoryNotifier()
+	s.mailer = infra.NewMemoryMailer()
+	s.events = infra.NewLocalEventPublisher()
+	s.users = &fakeUserRepo{
+		languages:      map[int64]string{5: entity.LocaleIndonesian},
+		orderOptedOuts: map[int64]bool{},
+	}
+	s.uc = usecase.NewTransactionUsecase(&usecase.TransactionProvider{
+		TransactionRepo: &fakeTransactionRepo{transaction: entity.Transaction{
+			ID:        8,
+			ProductID: 3,
+			BuyerID:   5,
+			SellerID:  2,
+			Status:    entity.TransactionStatusInProgress,
+		}},
+		ShippingRepo: &fakeShippingRepo{},
+		ProductRepo:  &fakeProductRepo{},
+		Notifications: &usecase.NotificationSender{
+			UserRepo:   s.users,
+			DeviceRepo: &fakeDeviceRepo{},
+			Notifier:   s.notifier,
+			Mailer:     s.mailer,
+		},
+		Events: s.events,
+	})
+}
+
+func (s *transactionNotificationTestSuite) sellerContext() context.Context {
+	return context.WithValue(context.Background(), api.ContextKeyName, entity.ResourceClaims{ID: 2})
+}
+
+func (s *transactionNotificationTestSuite) TestDeliveredNotifiesBuyerWithAWB() {
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status:    "delivered",
+		AWBNumber: "JNE0123",
+		Courier:   "JNE",
+	})
+	s.NoError(err)
+
+	// every device of the buyer gets the push
+	notifications := s.notifier.Notifications()
+	s.Len(notifications, 2)
+	s.Equal("phone-of-user", notifications[0].Device)
+	s.Equal("tablet-of-user", notifications[1].Device)
+	for _, notification := range notifications {
+		s.Equal(int64(5), notification.UserID)
+		s.Equal(int64(8), *notification.Data.TransactionID)
+		s.Contains(notification.Data.Content, "JNE0123")
+	}
+}
+
+func (s *transactionNotificationTestSuite) TestNotificationUsesRecipientLanguage() {
+	s.users.languages[5] = entity.LocaleEnglish
+
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status: "finished",
+	})
+	s.NoError(err)
+
+	notifications := s.notifier.Notifications()
+	s.NotEmpty(notifications)
+	s.Equal("Order completed", notifications[0].Data.Title)
+	s.Equal("Your order of Tokyo Banana is completed. Don't forget to leave a review!", notifications[0].Data.Content)
+}
+
+func (s *transactionNotificationTestSuite) TestDeliveredEmailsBuyerWithAWB() {
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status:    "delivered",
+		AWBNumber: "JNE0123",
+		Courier:   "JNE",
+	})
+	s.NoError(err)
+
+	emails := s.mailer.Emails()
+	s.Len(emails, 1)
+	s.Equal("rocky@sejastip.id", emails[0].To)
+	s.Equal(entity.EmailCategoryOrder, emails[0].Category)
+	s.Equal("Tokyo Banana sudah dikirim", emails[0].Subject)
+	s.Contains(emails[0].Text, "Nomor resi: JNE0123")
+	s.Contains(emails[0].HTML, "<strong>JNE0123</strong>")
+}
+
+func (s *transactionNotificationTestSuite) TestOptedOutBuyerIsNotEmailed() {
+	s.users.orderOptedOuts[5] = true
+
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status: "finished",
+	})
+	s.NoError(err)
+
+	// the push is still sent
+	s.NotEmpty(s.notifier.Notifications())
+	s.Empty(s.mailer.Emails())
+}
+
+func (s *transactionNotificationTestSuite) TestUnchangedStatusIsNotNotified() {
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status: "in_progress",
+	})
+	s.NoError(err)
+
+	s.Empty(s.notifier.Notifications())
+	s.Empty(s.mailer.Emails())
+	s.Empty(s.events.Events())
+}
+
+func (s *transactionNotificationTestSuite) TestStatusChangeIsPublished() {
+	err := s.uc.UpdateTransaction(s.sellerContext(), 8, &entity.UpdateTransactionForm{
+		Status:    "delivered",
+		AWBNumber: "JNE0123",
+		Courier:   "JNE",
+	})
+	s.NoError(err)
+
+	events := s.events.Events()
+	s.Require().Len(events, 1)
+	s.Equal(entity.EventTransactionStatusChanged, events[0].Name)
+	s.Equal(1, events[0].Version)
+	s.Equal("events-transaction-status-changed", entity.EventTopic(events[0].Data))
+
+	changed := events[0].Data.(entity.TransactionStatusChangedV1)
+	s.Equal(int64(8), changed.TransactionID)
+	s.Equal("in_progress", changed.PreviousStatus)
+	s.Equal("delivered", changed.Status)
+	s.Equal(int64(2), changed.ChangedBy)
+
+	// consumers rely on the field names of the schema
+	data, _ := json.Marshal(events[0])
+	var fields struct {
+		OccurredAt string                 `json:"occurred_at"`
+		Data       map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(data, &fields)
+	s.NotEmpty(fields.OccurredAt)
+	for _, field := range []string{"transaction_id", "previous_status", "status", "changed_by", "changed_at"} {
+		s.Contains(fields.Data, field)
+	}
+}
+
+func TestTransactionNotification(t *testing.T) {
+	suite.Run(t, new(transactionNotificationTestSuite))
+}
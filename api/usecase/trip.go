@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+type TripProvider struct {
+	TripRepo    api.TripRepository
+	ProductRepo api.ProductRepository
+	UserRepo    api.UserRepository
+	CountryRepo api.CountryRepository
+	Transactor  api.Transactor
+}
+
+type tripUsecase struct {
+	*TripProvider
+}
+
+func NewTripUsecase(pvd *TripProvider) api.TripUsecase {
+	return &tripUsecase{pvd}
+}
+
+func (uc *tripUsecase) CreateTrip(ctx context.Context, userID int64, form *entity.TripForm) (*entity.TripPublic, error) {
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	// only sellers with verified contact details may list products for a trip
+	seller, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching seller")
+	}
+	if !seller.IsVerified() {
+		return nil, api.ErrUnverifiedContact
+	}
+
+	country, err := uc.CountryRepo.GetCountry(ctx, form.CountryID)
+	if err != nil {
+		return nil, err
+	}
+
+	trip := &entity.Trip{SellerID: userID}
+	form.Apply(trip)
+	err = uc.TripRepo.CreateTrip(ctx, trip)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in creating trip")
+	}
+
+	tripPublic := trip.ConvertToPublic(country, seller)
+	return &tripPublic, nil
+}
+
+// GetTrips returns the trips matching the filter. Unless active_from is filtered,
+// only trips which have not ended before today are listed
+func (uc *tripUsecase) GetTrips(ctx context.Context, spec *entity.FilterSpec, page entity.Page) ([]entity.TripPublic, int64, error) {
+	if err := page.Validate(spec); err != nil {
+		return nil, 0, api.ValidationError(err)
+	}
+
+	if _, ok := spec.Value("active_from"); !ok {
+		spec.Where("to_date", entity.FilterOperatorGte, time.Now().Format("2006-01-02"))
+	}
+
+	trips, total, err := uc.TripRepo.GetTrips(ctx, spec, page)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "error in fetching trips by filter")
+	}
+
+	tripsPublic := []entity.TripPublic{}
+	for _, trip := range trips {
+		tripPublic, err := uc.convertToPublic(ctx, &trip)
+		if err != nil {
+			return nil, 0, err
+		}
+		tripsPublic = append(tripsPublic, *tripPublic)
+	}
+
+	return tripsPublic, total, nil
+}
+
+func (uc *tripUsecase) GetTrip(ctx context.Context, ID int64) (*entity.TripPublic, error) {
+	trip, err := uc.TripRepo.GetTrip(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching trip")
+	}
+
+	return uc.convertToPublic(ctx, trip)
+}
+
+// UpdateTrip overwrites the trip, and writes its country and dates to the
+// products listed for it in the same transaction
+func (uc *tripUsecase) UpdateTrip(ctx context.Context, ID, userID int64, form *entity.TripForm) (*entity.TripPublic, error) {
+	trip, err := uc.getOwnedTrip(ctx, ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	form.Normalize()
+	if err := form.Validate(); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	_, err = uc.CountryRepo.GetCountry(ctx, form.CountryID)
+	if err != nil {
+		return nil, err
+	}
+
+	form.Apply(trip)
+	err = withinTransaction(ctx, uc.Transactor, func(ctx context.Context) error {
+		if err := uc.TripRepo.UpdateTrip(ctx, ID, trip); err != nil {
+			return errors.Wrap(err, "error in updating trip")
+		}
+
+		if _, err := uc.ProductRepo.SyncTripProducts(ctx, trip); err != nil {
+			return errors.Wrap(err, "error in updating trip products")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.convertToPublic(ctx, trip)
+}
+
+func (uc *tripUsecase) DeleteTrip(ctx context.Context, ID, userID int64) error {
+	_, err := uc.getOwnedTrip(ctx, ID, userID)
+	if err != nil {
+		return err
+	}
+
+	total, err := uc.ProductRepo.CountProductsByTrip(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error in counting trip products")
+	}
+	if total > 0 {
+		return api.ErrTripInUse
+	}
+
+	err = uc.TripRepo.DeleteTrip(ctx, ID)
+	if err != nil {
+		return errors.Wrap(err, "error in deleting trip")
+	}
+
+	return nil
+}
+
+// getOwnedTrip fetches a trip, after checking it is owned by the user
+func (uc *tripUsecase) getOwnedTrip(ctx context.Context, ID, userID int64) (*entity.Trip, error) {
+	trip, err := uc.TripRepo.GetTrip(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching trip")
+	}
+
+	if trip.SellerID != userID {
+		return nil, api.ErrEditTripForbidden
+	}
+	return trip, nil
+}
+
+func (uc *tripUsecase) convertToPublic(ctx context.Context, trip *entity.Trip) (*entity.TripPublic, error) {
+	seller, err := uc.UserRepo.GetUser(ctx, trip.SellerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching seller details from trip")
+	}
+
+	country, err := uc.CountryRepo.GetCountry(ctx, trip.CountryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error in fetching country details from trip")
+	}
+
+	tripPublic := trip.ConvertToPublic(country, seller)
+	return &tripPublic, nil
+}
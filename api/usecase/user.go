@@ -2,16 +2,27 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"log"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 	"sejastip.id/api"
 	"sejastip.id/api/entity"
+	"sejastip.id/api/storage"
+	"sejastip.id/api/util"
 )
 
 // UserProvider is a wrapper of dependencies used by the implementation of UserUsecase
 type UserProvider struct {
 	UserRepository api.UserRepository
+	Verification   api.VerificationUsecase
+	Notifications  *NotificationSender
+	Events         api.EventPublisher
+	Transactor     api.Transactor
+
+	Storage storage.Storage
 }
 
 type userUsecase struct {
@@ -23,7 +34,7 @@ func NewUserUsecase(pvd *UserProvider) api.UserUsecase {
 	return &userUsecase{pvd}
 }
 
-func (u *userUsecase) Register(ctx context.Context, user *entity.User) (*entity.UserPublic, error) {
+func (u *userUsecase) Register(ctx context.Context, user *entity.User) (*entity.UserProfile, error) {
 	user.Normalize()
 	if err := user.Validate(); err != nil {
 		return nil, api.ValidationError(err)
@@ -46,14 +57,45 @@ func (u *userUsecase) Register(ctx context.Context, user *entity.User) (*entity.
 	}
 	user.Password = string(hashedPassword)
 
-	// save the user to repository
-	err = u.UserProvider.UserRepository.CreateUser(ctx, user)
+	// save the user to repository, along with the event of its registration
+	err = withinTransaction(ctx, u.UserProvider.Transactor, func(ctx context.Context) error {
+		if err := u.UserProvider.UserRepository.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return publishEvent(ctx, u.UserProvider.Events, entity.NewUserRegistered(user, entity.RegistrationMethodPassword))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	publicUser := user.ConvertToPublic()
-	return publicUser, nil
+	// send the verification codes right away. A failure here should not fail
+	// the registration, since the user can always request a new code
+	if u.UserProvider.Verification != nil {
+		if _, err := u.UserProvider.Verification.RequestEmailVerification(ctx, user.ID); err != nil {
+			log.Printf("error sending email verification for user %d: %v", user.ID, err)
+		}
+		if _, err := u.UserProvider.Verification.RequestPhoneVerification(ctx, user.ID); err != nil {
+			log.Printf("error sending phone verification for user %d: %v", user.ID, err)
+		}
+	}
+
+	return user.ConvertToProfile(), nil
+}
+
+// deleteAvatarFile deletes a replaced avatar from the storage. Avatars hosted
+// elsewhere are left alone, and a failure only leaves an orphan file behind
+func (u *userUsecase) deleteAvatarFile(avatar string) {
+	if avatar == "" {
+		return
+	}
+	path := u.UserProvider.Storage.PathOf(avatar)
+	if path == "" {
+		return
+	}
+
+	if err := u.UserProvider.Storage.Delete(path); err != nil {
+		log.Printf("error deleting avatar file %s: %v", path, err)
+	}
 }
 
 // GetUser get a single user by ID
@@ -66,3 +108,110 @@ func (u *userUsecase) GetUser(ctx context.Context, ID int64) (*entity.UserPublic
 	publicUser := user.ConvertToPublic()
 	return publicUser, nil
 }
+
+// GetProfile gets the profile of a user, along with its settings
+func (u *userUsecase) GetProfile(ctx context.Context, ID int64) (*entity.UserProfile, error) {
+	user, err := u.UserProvider.UserRepository.GetUser(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching user")
+	}
+
+	return user.ConvertToProfile(), nil
+}
+
+// UpdateProfile updates the provided fields of a user's profile. Changing the
+// email or the payout bank account requires the user's current password
+func (u *userUsecase) UpdateProfile(ctx context.Context, userID int64, form *entity.UserUpdateForm) (*entity.UserProfile, error) {
+	user, err := u.UserProvider.UserRepository.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching user")
+	}
+
+	oldEmail, oldPhone, oldAvatar := user.Email, user.Phone, user.Avatar
+	sensitive := form.Apply(user)
+	user.Normalize()
+	// a phone which is not edited is kept as stored, even if it is not normalized
+	if form.Phone == nil {
+		user.Phone = oldPhone
+	}
+	phoneChanged := user.Phone != oldPhone
+	if err := user.ValidateUpdate(phoneChanged); err != nil {
+		return nil, api.ValidationError(err)
+	}
+
+	if sensitive {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(form.CurrentPassword))
+		if err != nil {
+			return nil, api.ErrInvalidCurrentPassword
+		}
+	}
+
+	emailChanged := user.Email != oldEmail
+	if emailChanged {
+		existingUser, err := u.UserProvider.UserRepository.GetUserByEmail(ctx, user.Email)
+		if err != nil && err != api.ErrNotFound {
+			return nil, errors.Wrap(err, "Error fetching user by email")
+		}
+		if existingUser != nil {
+			return nil, api.CustomValidationError("Email %s sudah digunakan", user.Email)
+		}
+	}
+
+	if form.AvatarFile != nil && *form.AvatarFile != "" {
+		file, extension, err := util.DecodeUploadedBase64File(*form.AvatarFile)
+		if err != nil {
+			return nil, api.ValidationError(fmt.Errorf("Error parsing file: %v", err))
+		}
+
+		filename := fmt.Sprintf("avatars/%s%s", uuid.New().String(), extension)
+		user.Avatar, err = u.UserProvider.Storage.Store(filename, file)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error uploading avatar")
+		}
+	}
+
+	// a changed contact detail has to be verified again, so it is never saved
+	// without resetting its verification
+	err = withinTransaction(ctx, u.UserProvider.Transactor, func(ctx context.Context) error {
+		if err := u.UserProvider.UserRepository.UpdateUser(ctx, userID, user); err != nil {
+			return errors.Wrap(err, "Error updating user")
+		}
+
+		if !emailChanged && !phoneChanged {
+			return nil
+		}
+		if emailChanged {
+			user.EmailVerifiedAt = nil
+		}
+		if phoneChanged {
+			user.PhoneVerifiedAt = nil
+		}
+		if err := u.UserProvider.UserRepository.UpdateVerificationStatus(ctx, userID, user); err != nil {
+			return errors.Wrap(err, "Error resetting user verification")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Avatar != oldAvatar {
+		u.deleteAvatarFile(oldAvatar)
+	}
+
+	if sensitive {
+		u.notifySecurityChange(ctx, user)
+	}
+
+	return user.ConvertToProfile(), nil
+}
+
+// notifySecurityChange warns the user that its email or bank account is changed,
+// so an account takeover can be noticed by the real owner
+func (u *userUsecase) notifySecurityChange(ctx context.Context, user *entity.User) {
+	// the change is already saved, so a notification failing to be stored is only logged
+	err := u.UserProvider.Notifications.Send(ctx, user.ID, entity.AccountNotificationParams{})
+	if err != nil {
+		log.Printf("error notifying user %d of a security change: %v", user.ID, err)
+	}
+}
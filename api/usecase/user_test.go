@@ -0,0 +1,254 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+	"sejastip.id/api/infra"
+	"sejastip.id/api/usecase"
+)
+
+// memoryUserRepo keeps the users in memory, copying them in and out like a database
+type memoryUserRepo struct {
+	api.UserRepository
+	users map[int64]entity.User
+}
+
+func newMemoryUserRepo(users ...entity.User) *memoryUserRepo {
+	r := &memoryUserRepo{users: map[int64]entity.User{}}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *memoryUserRepo) CreateUser(ctx context.Context, user *entity.User) error {
+	user.ID = int64(len(r.users) + 1)
+	user.CreatedAt = time.Now()
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepo) GetUser(ctx context.Context, ID int64) (*entity.User, error) {
+	user, ok := r.users[ID]
+	if !ok {
+		return nil, api.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepo) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		// like the default collation of MySQL
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, api.ErrNotFound
+}
+
+func (r *memoryUserRepo) UpdateUser(ctx context.Context, ID int64, user *entity.User) error {
+	r.users[ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepo) UpdateVerificationStatus(ctx context.Context, ID int64, user *entity.User) error {
+	stored := r.users[ID]
+	stored.EmailVerifiedAt = user.EmailVerifiedAt
+	stored.PhoneVerifiedAt = user.PhoneVerifiedAt
+	r.users[ID] = stored
+	return nil
+}
+
+func hashPassword(password string) string {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(hashed)
+}
+
+// profileUserRepo records the profile writes, which must be made in one transaction
+type profileUserRepo struct {
+	*memoryUserRepo
+	tx  *memoryTransactor
+	err error
+}
+
+func (r *profileUserRepo) UpdateUser(ctx context.Context, ID int64, user *entity.User) error {
+	if err := r.tx.write(ctx, "users"); err != nil {
+		return err
+	}
+	return r.memoryUserRepo.UpdateUser(ctx, ID, user)
+}
+
+func (r *profileUserRepo) UpdateVerificationStatus(ctx context.Context, ID int64, user *entity.User) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.tx.write(ctx, "verification"); err != nil {
+		return err
+	}
+	return r.memoryUserRepo.UpdateVerificationStatus(ctx, ID, user)
+}
+
+type userProfileTestSuite struct {
+	suite.Suite
+	tx       *memoryTransactor
+	users    *memoryUserRepo
+	profiles *profileUserRepo
+	storage  *memoryStorage
+	notifier *infra.MemoryNotifier
+	uc       api.UserUsecase
+}
+
+func (s *userProfileTestSuite) SetupTest() {
+	verifiedAt := time.Now().Add(-time.Hour)
+	s.users = newMemoryUserRepo(entity.User{
+		ID:              4,
+		Email:           "rocky@sejastip.id",
+		Name:            "Rocky Balboa",
+		Password:        hashPassword("adrian"),
+		Language:        entity.LocaleIndonesian,
+		EmailVerifiedAt: &verifiedAt,
+	})
+	s.tx = &memoryTransactor{}
+	s.profiles = &profileUserRepo{memoryUserRepo: s.users, tx: s.tx}
+	s.storage = &memoryStorage{files: map[string][]byte{
+		"avatars/rocky.png": []byte("rocky"),
+	}}
+	s.notifier = infra.NewMemoryNotifier()
+	s.uc = usecase.NewUserUsecase(&usecase.UserProvider{
+		UserRepository: s.profiles,
+		Notifications: &usecase.NotificationSender{
+			UserRepo:   s.users,
+			DeviceRepo: &fakeDeviceRepo{},
+			Notifier:   s.notifier,
+		},
+		Transactor: s.tx,
+		Storage:    s.storage,
+	})
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func (s *userProfileTestSuite) TestNameIsEditableWithoutPhone() {
+	// social sign-ins have no phone
+	user, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Name: stringPtr("Rocky"),
+	})
+
+	s.NoError(err)
+	s.Equal("Rocky", user.Name)
+	s.NotNil(s.users.users[4].EmailVerifiedAt)
+}
+
+func (s *userProfileTestSuite) TestLegacyPhoneIsKeptAsStored() {
+	stored := s.users.users[4]
+	stored.Phone = "0812-345"
+	s.users.users[4] = stored
+
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Name: stringPtr("Rocky"),
+	})
+
+	s.NoError(err)
+	s.Equal("0812-345", s.users.users[4].Phone)
+}
+
+func (s *userProfileTestSuite) TestChangedPhoneIsValidatedAndVerifiedAgain() {
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Phone: stringPtr("12345"),
+	})
+	s.Equal("Nomor telepon tidak valid", err.Error())
+
+	phoneVerifiedAt := time.Now()
+	stored := s.users.users[4]
+	stored.PhoneVerifiedAt = &phoneVerifiedAt
+	s.users.users[4] = stored
+
+	user, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Phone: stringPtr("081234567890"),
+	})
+	s.NoError(err)
+	s.Equal("6281234567890", user.Phone)
+	s.Nil(s.users.users[4].PhoneVerifiedAt)
+	s.Empty(s.notifier.Notifications())
+}
+
+func (s *userProfileTestSuite) TestEmailChangeRequiresCurrentPassword() {
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Email:           stringPtr("rocky@example.com"),
+		CurrentPassword: "apollo",
+	})
+
+	s.Equal(api.ErrInvalidCurrentPassword, err)
+	s.Equal("rocky@sejastip.id", s.users.users[4].Email)
+	s.Empty(s.notifier.Notifications())
+}
+
+func (s *userProfileTestSuite) TestEmailChangeWarnsTheUser() {
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Email:           stringPtr("rocky@example.com"),
+		CurrentPassword: "adrian",
+	})
+	s.NoError(err)
+
+	s.Equal("rocky@example.com", s.users.users[4].Email)
+	s.Nil(s.users.users[4].EmailVerifiedAt)
+
+	// one push to each device of the user
+	notifications := s.notifier.Notifications()
+	s.Len(notifications, 2)
+	s.Equal(entity.NotificationTypeAccount, notifications[0].Data.Type)
+}
+
+func (s *userProfileTestSuite) TestEmailIsSavedWithItsVerificationReset() {
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Email:           stringPtr("rocky@example.com"),
+		CurrentPassword: "adrian",
+	})
+
+	s.NoError(err)
+	s.Equal([]string{"users", "verification"}, s.tx.committed)
+}
+
+func (s *userProfileTestSuite) TestFailedVerificationResetRollsTheEmailBack() {
+	s.profiles.err = errors.New("connection reset")
+
+	_, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		Email:           stringPtr("rocky@example.com"),
+		CurrentPassword: "adrian",
+	})
+
+	s.Error(err)
+	// the new address is never left marked as verified
+	s.Empty(s.tx.committed)
+	s.Empty(s.notifier.Notifications())
+}
+
+func (s *userProfileTestSuite) TestReplacedAvatarIsDeleted() {
+	stored := s.users.users[4]
+	stored.Avatar = "https://storage.googleapis.com/sejastip/avatars/rocky.png"
+	s.users.users[4] = stored
+
+	avatarFile := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("balboa"))
+	user, err := s.uc.UpdateProfile(context.Background(), 4, &entity.UserUpdateForm{
+		AvatarFile: &avatarFile,
+	})
+
+	s.Require().NoError(err)
+	s.NotContains(s.storage.files, "avatars/rocky.png")
+	s.Contains(s.storage.files, s.storage.PathOf(user.Avatar))
+}
+
+func TestUserProfile(t *testing.T) {
+	suite.Run(t, new(userProfileTestSuite))
+}
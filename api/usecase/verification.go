@@ -0,0 +1,261 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"sejastip.id/api"
+	"sejastip.id/api/entity"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	phoneVerificationTTL = 5 * time.Minute
+
+	// a user may only request a new code once per cooldown, and at most
+	// maxVerificationRequests times per verificationRequestWindow
+	verificationCooldown      = time.Minute
+	verificationRequestWindow = time.Hour
+	maxVerificationRequests   = 5
+
+	// the number of wrong OTP submissions before the code is burned
+	maxVerificationAttempts = 5
+)
+
+// VerificationProvider is a wrapper of dependencies used by the implementation of VerificationUsecase
+type VerificationProvider struct {
+	UserRepo         api.UserRepository
+	VerificationRepo api.VerificationRepository
+	OTPSender        api.OTPSender
+
+	// VerificationURL is the URL of the email verification endpoint,
+	// the generated token is appended as query parameter
+	VerificationURL string
+}
+
+type verificationUsecase struct {
+	*VerificationProvider
+}
+
+// NewVerificationUsecase creates an instance of VerificationUsecase
+func NewVerificationUsecase(pvd *VerificationProvider) api.VerificationUsecase {
+	return &verificationUsecase{pvd}
+}
+
+// RequestEmailVerification sends a verification link to the user email
+func (uc *verificationUsecase) RequestEmailVerification(ctx context.Context, userID int64) (*entity.VerificationRequestResponse, error) {
+	user, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching user")
+	}
+	if user.IsEmailVerified() {
+		return nil, api.ErrContactAlreadyVerified
+	}
+
+	token, err := generateVerificationToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating verification token")
+	}
+
+	verification, err := uc.createVerification(ctx, user, entity.VerificationChannelEmail, user.Email, token, emailVerificationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", uc.VerificationURL, url.QueryEscape(token))
+	err = uc.OTPSender.SendEmailVerification(ctx, user.Email, link)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending email verification")
+	}
+
+	return &entity.VerificationRequestResponse{
+		Channel:   verification.Channel,
+		Target:    verification.Target,
+		ExpiredAt: verification.ExpiredAt,
+	}, nil
+}
+
+// VerifyEmail marks the email of the token owner as verified
+func (uc *verificationUsecase) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return api.ErrInvalidVerificationCode
+	}
+
+	verification, err := uc.VerificationRepo.GetVerificationByCode(ctx, entity.VerificationChannelEmail, hashVerificationCode(token))
+	if err != nil {
+		if err == api.ErrNotFound {
+			return api.ErrInvalidVerificationCode
+		}
+		return errors.Wrap(err, "error fetching verification")
+	}
+	if verification.VerifiedAt != nil || verification.IsExpired() {
+		return api.ErrInvalidVerificationCode
+	}
+
+	user, err := uc.UserRepo.GetUser(ctx, verification.UserID)
+	if err != nil {
+		return errors.Wrap(err, "error fetching user")
+	}
+	// the user may have changed its email after the link was sent
+	if user.Email != verification.Target {
+		return api.ErrInvalidVerificationCode
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return uc.completeVerification(ctx, user, verification)
+}
+
+// RequestPhoneVerification sends an OTP to the user phone number
+func (uc *verificationUsecase) RequestPhoneVerification(ctx context.Context, userID int64) (*entity.VerificationRequestResponse, error) {
+	user, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching user")
+	}
+	if user.IsPhoneVerified() {
+		return nil, api.ErrContactAlreadyVerified
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating OTP")
+	}
+
+	verification, err := uc.createVerification(ctx, user, entity.VerificationChannelPhone, user.Phone, code, phoneVerificationTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.OTPSender.SendPhoneOTP(ctx, user.Phone, code)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending phone OTP")
+	}
+
+	return &entity.VerificationRequestResponse{
+		Channel:   verification.Channel,
+		Target:    verification.Target,
+		ExpiredAt: verification.ExpiredAt,
+	}, nil
+}
+
+// VerifyPhone checks the submitted OTP against the latest code sent to the user
+func (uc *verificationUsecase) VerifyPhone(ctx context.Context, userID int64, form *entity.PhoneVerificationForm) error {
+	if err := form.Validate(); err != nil {
+		return api.ValidationError(err)
+	}
+
+	verification, err := uc.VerificationRepo.GetLatestVerification(ctx, userID, entity.VerificationChannelPhone)
+	if err != nil {
+		if err == api.ErrNotFound {
+			return api.ErrInvalidVerificationCode
+		}
+		return errors.Wrap(err, "error fetching verification")
+	}
+	if verification.VerifiedAt != nil || verification.IsExpired() {
+		return api.ErrInvalidVerificationCode
+	}
+	if verification.Attempts >= maxVerificationAttempts {
+		return api.ErrTooManyVerificationAttempts
+	}
+
+	if verification.Code != hashVerificationCode(form.Code) {
+		verification.Attempts++
+		err = uc.VerificationRepo.UpdateVerification(ctx, verification.ID, verification)
+		if err != nil {
+			return errors.Wrap(err, "error updating verification attempts")
+		}
+		return api.ErrInvalidVerificationCode
+	}
+
+	user, err := uc.UserRepo.GetUser(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, "error fetching user")
+	}
+	if user.Phone != verification.Target {
+		return api.ErrInvalidVerificationCode
+	}
+
+	now := time.Now()
+	user.PhoneVerifiedAt = &now
+	return uc.completeVerification(ctx, user, verification)
+}
+
+// createVerification applies the request rate limit, then stores the hashed code
+func (uc *verificationUsecase) createVerification(ctx context.Context, user *entity.User, channel, target, code string, ttl time.Duration) (*entity.Verification, error) {
+	latest, err := uc.VerificationRepo.GetLatestVerification(ctx, user.ID, channel)
+	if err != nil && err != api.ErrNotFound {
+		return nil, errors.Wrap(err, "error fetching latest verification")
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < verificationCooldown {
+		return nil, api.ErrTooManyVerificationAttempts
+	}
+
+	count, err := uc.VerificationRepo.CountVerificationsSince(ctx, user.ID, channel, time.Now().Add(-verificationRequestWindow))
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting verification requests")
+	}
+	if count >= maxVerificationRequests {
+		return nil, api.ErrTooManyVerificationAttempts
+	}
+
+	verification := &entity.Verification{
+		UserID:    user.ID,
+		Channel:   channel,
+		Target:    target,
+		Code:      hashVerificationCode(code),
+		ExpiredAt: time.Now().Add(ttl),
+	}
+	err = uc.VerificationRepo.CreateVerification(ctx, verification)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating verification")
+	}
+
+	return verification, nil
+}
+
+func (uc *verificationUsecase) completeVerification(ctx context.Context, user *entity.User, verification *entity.Verification) error {
+	now := time.Now()
+	verification.VerifiedAt = &now
+	err := uc.VerificationRepo.UpdateVerification(ctx, verification.ID, verification)
+	if err != nil {
+		return errors.Wrap(err, "error updating verification")
+	}
+
+	err = uc.UserRepo.UpdateVerificationStatus(ctx, user.ID, user)
+	if err != nil {
+		return errors.Wrap(err, "error updating user verification status")
+	}
+
+	return nil
+}
+
+// generateVerificationToken creates a random url-safe token for email links
+func generateVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateOTP creates a random 6-digit numeric code
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,9 +4,15 @@ import os
 
 from pyfcm import FCMNotification
 from google.cloud import datastore
+from google.cloud import pubsub_v1
 from datetime import datetime
 
 ds_client = datastore.Client("stunning-strand-255714")
+publisher = pubsub_v1.PublisherClient()
+invalid_devices_topic = publisher.topic_path("stunning-strand-255714", "invalid-device-tokens")
+
+# FCM errors meaning the token will never be valid again
+INVALID_TOKEN_ERRORS = ('NotRegistered', 'InvalidRegistration', 'MismatchSenderId')
 
 def send_push_notification_pubsub(event, context):
     """Triggered from a message on a Cloud Pub/Sub topic.
@@ -21,8 +27,12 @@ def send_push_notification_pubsub(event, context):
     #   device:
     #   user_id:
     #   data: {
+    #     type:
     #     title:
     #     content:
+    #     transaction_id: (optional)
+    #     invoice_id: (optional)
+    #     conversation_id: (optional)
     #   }
     # }
     api_key = os.getenv("FIREBASE_API_KEY")
@@ -32,6 +42,10 @@ def send_push_notification_pubsub(event, context):
       'title': notification_data['data']['title'],
       'message': notification_data['data']['content']
     }
+    # deep links, so the app can open the screen of the notification
+    for link in ('type', 'transaction_id', 'invoice_id', 'conversation_id'):
+      if notification_data['data'].get(link) is not None:
+        additional[link] = str(notification_data['data'][link])
 
     result = push_service.notify_single_device(
       registration_id=notification_data['device'],
@@ -40,6 +54,14 @@ def send_push_notification_pubsub(event, context):
       data_message=additional
     )
 
+    status = 'sent'
+    errors = [r.get('error') for r in result.get('results', [])]
+    if any(e in INVALID_TOKEN_ERRORS for e in errors):
+      # report the token back, so the API stops sending to this device
+      status = 'invalid_device'
+      payload = json.dumps({'device_ids': [notification_data['device']]})
+      publisher.publish(invalid_devices_topic, payload.encode('utf-8')).result()
+
     now = datetime.now()
     key = ds_client.key('NotificationLogs')
     entity = datastore.Entity(key=key, exclude_from_indexes=['title', 'content'])
@@ -48,7 +70,7 @@ def send_push_notification_pubsub(event, context):
       'user_id': notification_data['user_id'],
       'title': notification_data['data']['title'],
       'content': notification_data['data']['content'],
-      'status': 'sent',
+      'status': status,
       'created_at': now,
       'updated_at': now
     })